@@ -0,0 +1,63 @@
+package data
+
+import "reflect"
+
+// Arena 是一个批量分配器，用于批量解析大量文档并且同时丢弃这些文档的场景。
+//
+// 设置了 Arena 的 Parser 会从 Arena 中分配 RawData，并复用解析数组时的临时缓冲。
+// 调用 `Arena#Reset` 后，Arena 会清空所有分配过的 RawData 并在之后的解析中复用它们，
+// 这样在处理下一批文档时不需要重新分配 map，可以有效降低 GC 压力。
+//
+// 需要注意：
+//     - Reset 之后，所有用这个 Arena 解析出来的 Data 都会被清空，不能再使用；
+//     - 数组对应的 slice 依然由 Go 运行时分配，不受 Arena 管理；
+//     - Arena 不是并发安全的。
+type Arena struct {
+	maps []RawData
+	used int
+
+	vals []reflect.Value
+}
+
+// NewArena 创建一个新的 Arena。
+func NewArena() *Arena {
+	return &Arena{}
+}
+
+// Len 返回当前已经分配出去的 RawData 个数。
+func (a *Arena) Len() int {
+	return a.used
+}
+
+// Reset 清空所有已经分配出去的 RawData，使得这些 RawData 可以被再次分配。
+func (a *Arena) Reset() {
+	for _, m := range a.maps[:a.used] {
+		for k := range m {
+			delete(m, k)
+		}
+	}
+
+	a.used = 0
+}
+
+func (a *Arena) newRawData() RawData {
+	if a.used < len(a.maps) {
+		m := a.maps[a.used]
+		a.used++
+		return m
+	}
+
+	m := RawData{}
+	a.maps = append(a.maps, m)
+	a.used++
+	return m
+}
+
+// releaseValues 将临时缓冲截断到 start，并清理掉引用以免阻止 GC 回收。
+func (a *Arena) releaseValues(start int) {
+	for i := start; i < len(a.vals); i++ {
+		a.vals[i] = reflect.Value{}
+	}
+
+	a.vals = a.vals[:start]
+}
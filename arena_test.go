@@ -0,0 +1,48 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestArena(t *testing.T) {
+	a := assert.New(t)
+	buf := &bytes.Buffer{}
+	a.NilError(json.Compact(buf, []byte(complexDataJSON)))
+
+	docs := []string{
+		buf.String(),
+		`{"nested":[[1,2],[{"a":[true,false]},"x"],[]],"m":{"n":{"o":[1.5,2]}}}`,
+		`{}`,
+	}
+	arena := NewArena()
+	p := &Parser{
+		Arena: arena,
+	}
+
+	for round := 0; round < 2; round++ {
+		parsed := make([]Data, 0, len(docs))
+
+		for i, doc := range docs {
+			a.Use(&round, &i, &doc)
+			d, err := p.ParseJSON(doc)
+			a.NilError(err)
+			expected, err := ParseJSON(doc)
+			a.NilError(err)
+			a.Equal(d, expected)
+			parsed = append(parsed, d)
+		}
+
+		// complexData 有 3 个 RawData，第二个文档有 5 个，空文档有 1 个。
+		a.Equal(arena.Len(), 9)
+		a.Equal(len(arena.maps), 9)
+		a.Equal(len(arena.vals), 0)
+
+		arena.Reset()
+		a.Equal(arena.Len(), 0)
+		a.Equal(parsed[0].Len(), 0)
+	}
+}
@@ -0,0 +1,161 @@
+package data
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+)
+
+// Dedup 删除 d 中 query 对应的数组里重复的元素，只保留每个值第一次出现的元素，元素的相对顺序保持不变。
+// 两个元素只有在类型和内容都完全相同的时候才算重复，比如 int64(1) 与 float64(1) 不是重复的元素，
+// 但是 Data 记录的 key 顺序不影响比较结果。
+//
+// 如果 query 对应的值不存在，直接返回；如果不是数组，返回的错误满足 `errors.Is(err, ErrNotArray)`。
+// 与 `Data#Append` 一样，Dedup 会生成一个新的 slice 替换原来的值，但会直接修改 d 内部的 map。
+func (d *Data) Dedup(query string) error {
+	buf := &bytes.Buffer{}
+	seen := map[uint64][]interface{}{}
+
+	return d.filterArray("dedup", query, func(elem interface{}) bool {
+		h := hashValue(buf, elem)
+
+		for _, other := range seen[h] {
+			if sameElem(elem, other) {
+				return false
+			}
+		}
+
+		seen[h] = append(seen[h], elem)
+		return true
+	})
+}
+
+// sameElem 判断 v1 和 v2 的类型和内容是否完全相同，与 reflect.DeepEqual 不同的是，
+// 嵌在其中的 Data 记录的 key 顺序会被忽略，这与 `hashValue` 的规则一致。
+func sameElem(v1, v2 interface{}) bool {
+	d1, ok1 := v1.(Data)
+	d2, ok2 := v2.(Data)
+
+	if ok1 || ok2 {
+		if !ok1 || !ok2 {
+			return false
+		}
+
+		if d1.Len() == 0 || d2.Len() == 0 {
+			return d1.Len() == d2.Len()
+		}
+
+		return sameElem(d1.data, d2.data)
+	}
+
+	if m1, ok := v1.(RawData); ok {
+		m2, ok := v2.(RawData)
+
+		if !ok || len(m1) != len(m2) || (m1 == nil) != (m2 == nil) {
+			return false
+		}
+
+		for k, v := range m1 {
+			if other, ok := m2[k]; !ok || !sameElem(v, other) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	rv1 := reflect.ValueOf(v1)
+	rv2 := reflect.ValueOf(v2)
+
+	if rv1.Kind() != reflect.Slice || rv2.Type() != rv1.Type() {
+		return reflect.DeepEqual(v1, v2)
+	}
+
+	if rv1.Len() != rv2.Len() || rv1.IsNil() != rv2.IsNil() {
+		return false
+	}
+
+	for i := 0; i < rv1.Len(); i++ {
+		if !sameElem(rv1.Index(i).Interface(), rv2.Index(i).Interface()) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Compact 删除 d 中 query 对应的数组里所有的空元素，包括 nil、空字符串、空 object 和空数组，
+// 0、false 等零值不是空元素，会被保留。元素的相对顺序保持不变，数组中的 object 不会被递归处理。
+//
+// 出错的规则与 `Data#Dedup` 相同。
+func (d *Data) Compact(query string) error {
+	return d.filterArray("compact", query, func(elem interface{}) bool {
+		return !isEmptyElem(elem)
+	})
+}
+
+// filterArray 只保留 query 对应的数组中 keep 返回 true 的元素，并将结果设置回 d 中。
+// keep 会按照下标顺序对每个元素调用一次。
+func (d *Data) filterArray(op, query string, keep func(elem interface{}) bool) error {
+	if query == "" {
+		return fmt.Errorf("go-data: query of %v must not be empty", op)
+	}
+
+	v := d.Query(query)
+
+	if v == nil {
+		return nil
+	}
+
+	elems, ok := sliceElems(v)
+
+	if !ok {
+		return fmt.Errorf("%w: cannot %v `%v` of type %T", ErrNotArray, op, query, v)
+	}
+
+	order := orderAt(d.order, query)
+	kept := make([]interface{}, 0, len(elems))
+	var orders []*keyOrder
+
+	for i, elem := range elems {
+		if !keep(elem) {
+			continue
+		}
+
+		kept = append(kept, elem)
+
+		if order != nil {
+			orders = append(orders, order.elem(i))
+		}
+	}
+
+	if len(kept) == len(elems) {
+		return nil
+	}
+
+	// 数组中 object 的顺序信息与下标对应，需要与元素一起调整。
+	if order != nil {
+		order = &keyOrder{
+			elems: orders,
+		}
+	}
+
+	setJSON(d.data, d.order, SplitQuery(query), normalizeSlice(kept), order)
+	return nil
+}
+
+func isEmptyElem(elem interface{}) bool {
+	switch val := elem.(type) {
+	case nil:
+		return true
+	case string:
+		return val == ""
+	case RawData:
+		return len(val) == 0
+	case Data:
+		return val.Len() == 0
+	}
+
+	rv := reflect.ValueOf(elem)
+	return rv.Kind() == reflect.Slice && rv.Len() == 0
+}
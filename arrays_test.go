@@ -0,0 +1,92 @@
+package data
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestDataDedupCompact(t *testing.T) {
+	cases := []struct {
+		Query   string
+		Dedup   interface{}
+		Compact interface{}
+		Err     error
+	}{
+		{"ints", []int64{1, 2, 3}, []int64{1, 2, 1, 3, 2}, nil},
+		{"mixed", []interface{}{int64(1), 1.0, "", nil, RawData{"a": int64(1)}, RawData{}, []int64{}}, []interface{}{int64(1), 1.0, RawData{"a": int64(1)}, RawData{"a": int64(1)}, int64(1)}, nil},
+		{"strs", []string{"a", "", "b"}, []string{"a", "b", "a"}, nil},
+		{"objs.0.list", []bool{false, true}, []bool{false, true, false}, nil},
+		{"not_exist", nil, nil, nil},
+		{"obj", nil, nil, ErrNotArray},
+		{"str", nil, nil, ErrNotArray},
+	}
+
+	for i, c := range cases {
+		a := assert.New(t)
+		a.Use(&i, &c)
+
+		original := Make(RawData{
+			"ints":  []int{1, 2, 1, 3, 2},
+			"mixed": []interface{}{1, 1.0, "", nil, RawData{"a": 1}, RawData{"a": 1}, RawData{}, []int{}, 1},
+			"strs":  []string{"a", "", "b", "a"},
+			"objs":  []RawData{{"list": []bool{false, true, false}}},
+			"obj":   RawData{},
+			"str":   "str",
+		})
+
+		d := original.Clone()
+		err := d.Dedup(c.Query)
+
+		if c.Err != nil {
+			a.Assert(errors.Is(err, c.Err))
+			a.Assert(errors.Is(d.Compact(c.Query), c.Err))
+			a.Equal(d, original)
+			continue
+		}
+
+		a.NilError(err)
+		a.Equal(d.Query(c.Query), c.Dedup)
+
+		d = original.Clone()
+		a.NilError(d.Compact(c.Query))
+		a.Equal(d.Query(c.Query), c.Compact)
+	}
+
+	a := assert.New(t)
+	d := Data{}
+	a.NonNilError(d.Dedup(""))
+	a.NonNilError(d.Compact(""))
+}
+
+func TestDataDedupKeepOrder(t *testing.T) {
+	a := assert.New(t)
+	p := &Parser{
+		KeepOrder: true,
+	}
+	d, err := p.ParseJSON(`{"z":1,"list":[{"b":1,"a":2},null,{"b":1,"a":2},{"y":1,"x":2}],"a":2}`)
+	a.NilError(err)
+	a.NilError(d.Dedup("list"))
+	a.NilError(d.Compact("list"))
+	a.Equal(d.JSON(false), `{"z":1,"list":[{"b":1,"a":2},{"y":1,"x":2}],"a":2}`)
+}
+
+func TestDataDedupEmbeddedData(t *testing.T) {
+	a := assert.New(t)
+	p := &Parser{
+		KeepOrder: true,
+	}
+	d1, err := p.ParseJSON(`{"b":1,"a":2}`)
+	a.NilError(err)
+	d2, err := p.ParseJSON(`{"a":2,"b":1}`)
+	a.NilError(err)
+	d3, err := p.ParseJSON(`{"a":2,"b":"1"}`)
+	a.NilError(err)
+
+	d := Data{data: RawData{
+		"list": []interface{}{d1, d2, d3, RawData{"a": int64(2), "b": int64(1)}, Data{}, emptyData},
+	}}
+	a.NilError(d.Dedup("list"))
+	a.Equal(d.Get("list"), []interface{}{d1, d3, RawData{"a": int64(2), "b": int64(1)}, Data{}})
+}
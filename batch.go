@@ -0,0 +1,64 @@
+package data
+
+// BatchOptions 控制 BatchApply 如何将 patch 分批应用。
+type BatchOptions struct {
+	// MaxBatch 是每一批最多包含的 patch 个数，为 0 时所有 patch 作为一批应用。
+	// 批次越大，需要复制 target 的次数越少，但出错时需要丢弃的修改也越多。
+	MaxBatch int
+}
+
+// BatchApply 使用默认选项将 patches 按顺序应用到 target 上，详见 `BatchOptions#BatchApply`。
+func BatchApply(target *Data, patches []*Patch, opts *BatchOptions) error {
+	if opts == nil {
+		opts = &BatchOptions{}
+	}
+
+	return opts.BatchApply(target, patches)
+}
+
+// BatchApply 将 patches 按顺序应用到 target 上，效果与依次调用 `Patch#Apply` 并替换 target 相同。
+//
+// 与逐个 Apply 不同，每一批 patch 只会复制一次 target，全部应用在同一个副本上之后再替换 target，
+// 在短时间内收到大量小 patch 时可以大幅减少复制的开销。
+//
+// 每一批都是完整应用或者完全不应用的。如果某个 patch 失败，它所在的那一批不会生效，
+// target 中只保留之前各批的修改，并返回 `*BatchError`。nil 的 patch 会被忽略。
+func (opts *BatchOptions) BatchApply(target *Data, patches []*Patch) error {
+	if target == nil {
+		return nil
+	}
+
+	size := opts.MaxBatch
+
+	if size <= 0 {
+		size = len(patches)
+	}
+
+	for start := 0; start < len(patches); start += size {
+		end := start + size
+
+		if end > len(patches) {
+			end = len(patches)
+		}
+
+		d := target.Clone()
+
+		for i, patch := range patches[start:end] {
+			if patch == nil {
+				continue
+			}
+
+			if err := patch.ApplyTo(&d); err != nil {
+				return &BatchError{
+					Index:   start + i,
+					Applied: start,
+					Err:     err,
+				}
+			}
+		}
+
+		*target = d
+	}
+
+	return nil
+}
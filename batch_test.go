@@ -0,0 +1,85 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestBatchApply(t *testing.T) {
+	cases := []struct {
+		MaxBatch int
+		Fail     int
+		Applied  int
+		Count    int64
+	}{
+		{0, -1, 0, 10},
+		{3, -1, 0, 10},
+		{20, -1, 0, 10},
+		{0, 5, 0, 0},
+		{3, 5, 3, 3},
+		{3, 6, 6, 6},
+		{1, 9, 9, 9},
+	}
+
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case%v", i), func(t *testing.T) {
+			a := assert.New(t)
+			a.Use(&i, &c)
+
+			original := Make(RawData{"count": 0})
+			target := original
+			patches := make([]*Patch, 0, 10)
+
+			for n := 1; n <= 10; n++ {
+				p := NewPatch()
+
+				if n-1 == c.Fail {
+					p.Add(nil, map[string]Data{"not_exist": Make(RawData{"a": 1})})
+				} else {
+					p.Add(nil, map[string]Data{"": Make(RawData{"count": n})})
+				}
+
+				patches = append(patches, p)
+			}
+
+			opts := &BatchOptions{
+				MaxBatch: c.MaxBatch,
+			}
+			err := opts.BatchApply(&target, patches)
+
+			if c.Fail < 0 {
+				a.NilError(err)
+			} else {
+				var batchErr *BatchError
+				a.Assert(errors.As(err, &batchErr))
+				a.Equal(batchErr.Index, c.Fail)
+				a.Equal(batchErr.Applied, c.Applied)
+			}
+
+			a.Equal(target.Query("count"), c.Count)
+			a.Equal(original.Query("count"), int64(0))
+		})
+	}
+}
+
+func TestBatchApplyDefault(t *testing.T) {
+	a := assert.New(t)
+	target := Make(RawData{"a": 1})
+	p1 := NewPatch()
+	p1.Add(nil, map[string]Data{"": Make(RawData{"b": 2})})
+	p2 := NewPatch()
+	p2.Add([]string{"a"}, nil)
+
+	a.NilError(BatchApply(&target, []*Patch{p1, nil, p2}, nil))
+	a.Equal(target, Make(RawData{"b": 2}))
+	a.NilError(BatchApply(nil, []*Patch{p1}, nil))
+
+	bad := NewPatch()
+	bad.Add(nil, map[string]Data{"not_exist": Make(RawData{"a": 1})})
+	err := BatchApply(&target, []*Patch{p1, bad}, nil)
+	a.Equal(err.Error(), "go-data: fail to apply patch #1 in batch: fail to apply patch when updating `not_exist`: query not found")
+	a.Equal(target, Make(RawData{"b": 2}))
+}
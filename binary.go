@@ -0,0 +1,592 @@
+package data
+
+import (
+	"encoding"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+)
+
+// 二进制格式的版本号，写在二进制数据的第一个字节。
+const binaryVersion = 2
+
+// maxBinaryDepth 是解析二进制数据时允许的最大嵌套层数，object、array 以及 array 的元素类型都计算在内，
+// 避免恶意数据导致无法恢复的栈溢出。
+const maxBinaryDepth = 10000
+
+// 二进制格式中的类型标记。
+const (
+	binaryTypeNil byte = iota
+	binaryTypeBool
+	binaryTypeInt64
+	binaryTypeUint64
+	binaryTypeFloat64
+	binaryTypeComplex128
+	binaryTypeString
+	binaryTypeTime
+	binaryTypeObject
+	binaryTypeArray
+	binaryTypeAny
+)
+
+var (
+	_ encoding.BinaryMarshaler   = Data{}
+	_ encoding.BinaryUnmarshaler = &Data{}
+	_ gob.GobEncoder             = Data{}
+	_ gob.GobDecoder             = &Data{}
+)
+
+// MarshalBinary 将 d 序列化成紧凑的二进制格式，适合用于消息队列等对数据大小敏感的场景。
+//
+// 二进制格式定义如下：
+//     data   := version object
+//     value  := type payload
+//     object := uvarint(count) (string value)*
+//     array  := elemType uvarint(count) payload*
+//     string := uvarint(len) bytes
+// 其中，version 当前为 2；整数使用 zigzag varint 编码，无符号整数使用 uvarint 编码，浮点数使用 8 字节小端序编码。
+// 对于 array，如果所有元素类型相同（比如 []int64），elemType 是元素类型，每个元素只写 payload；
+// 否则 elemType 是 any，每个元素都会写 type 和 payload。
+// 对于 object，key 总是按照 `Data#Keys` 的顺序输出，保证同样的 Data 总是得到同样的二进制数据。
+func (d Data) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 0, 64)
+	buf = append(buf, binaryVersion)
+	return appendBinaryObject(buf, d.data, d.order)
+}
+
+// UnmarshalBinary 解析 MarshalBinary 生成的二进制数据并设置 d 的值。
+// 解析过程会复制所有用到的数据，调用者在解析完成后可以随意复用 src。
+// 如果数据的嵌套层数超过 10000，返回 ErrInvalidBinary。
+func (d *Data) UnmarshalBinary(src []byte) error {
+	if len(src) == 0 {
+		return ErrInvalidBinary
+	}
+
+	if src[0] != binaryVersion {
+		return fmt.Errorf("%w: unsupported version %v", ErrInvalidBinary, src[0])
+	}
+
+	r := &binaryReader{
+		buf: src[1:],
+	}
+	raw, err := r.readObject()
+
+	if err != nil {
+		return err
+	}
+
+	if len(r.buf) != 0 {
+		return ErrInvalidBinary
+	}
+
+	if len(raw) == 0 {
+		*d = emptyData
+		return nil
+	}
+
+	*d = Data{
+		data: raw,
+	}
+	return nil
+}
+
+// GobEncode 实现 gob.GobEncoder，使用与 MarshalBinary 相同的二进制格式。
+func (d Data) GobEncode() ([]byte, error) {
+	return d.MarshalBinary()
+}
+
+// GobDecode 实现 gob.GobDecoder，解析 GobEncode 生成的二进制数据。
+func (d *Data) GobDecode(src []byte) error {
+	return d.UnmarshalBinary(src)
+}
+
+func appendBinaryObject(buf []byte, d RawData, order *keyOrder) ([]byte, error) {
+	buf = appendUvarint(buf, uint64(len(d)))
+
+	if len(d) == 0 {
+		return buf, nil
+	}
+
+	var err error
+
+	for _, k := range order.sortedKeys(d) {
+		buf = appendBinaryString(buf, k)
+
+		if buf, err = appendBinaryValue(buf, reflect.ValueOf(d[k]), order.child(k)); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf, nil
+}
+
+func appendBinaryString(buf []byte, s string) []byte {
+	buf = appendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+func appendBinaryValue(buf []byte, v reflect.Value, order *keyOrder) ([]byte, error) {
+	for v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+
+	if !v.IsValid() {
+		return append(buf, binaryTypeNil), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return append(buf, binaryTypeNil), nil
+		}
+	}
+
+	t, err := binaryTypeOf(v.Type())
+
+	if err != nil {
+		return nil, err
+	}
+
+	buf = append(buf, t)
+	return appendBinaryPayload(buf, v, order)
+}
+
+func appendBinaryPayload(buf []byte, v reflect.Value, order *keyOrder) ([]byte, error) {
+	if v.Type() == typeOfTime {
+		b, err := v.Interface().(time.Time).MarshalBinary()
+
+		if err != nil {
+			return nil, err
+		}
+
+		buf = appendUvarint(buf, uint64(len(b)))
+		return append(buf, b...), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return append(buf, 1), nil
+		}
+
+		return append(buf, 0), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return appendVarint(buf, v.Int()), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return appendUvarint(buf, v.Uint()), nil
+
+	case reflect.Float32, reflect.Float64:
+		return appendUint64(buf, math.Float64bits(v.Float())), nil
+
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		buf = appendUint64(buf, math.Float64bits(real(c)))
+		return appendUint64(buf, math.Float64bits(imag(c))), nil
+
+	case reflect.String:
+		return appendBinaryString(buf, v.String()), nil
+
+	case reflect.Map:
+		return appendBinaryObject(buf, v.Convert(typeOfObject).Interface().(RawData), order)
+
+	case reflect.Slice, reflect.Array:
+		var err error
+		elemType := v.Type().Elem()
+
+		if buf, err = appendBinaryType(buf, elemType); err != nil {
+			return nil, err
+		}
+
+		l := v.Len()
+		buf = appendUvarint(buf, uint64(l))
+		mixed := elemType.Kind() == reflect.Interface
+
+		for i := 0; i < l; i++ {
+			if mixed {
+				buf, err = appendBinaryValue(buf, v.Index(i), order.elem(i))
+			} else {
+				buf, err = appendBinaryPayload(buf, v.Index(i), order.elem(i))
+			}
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return buf, nil
+	}
+
+	return nil, fmt.Errorf("go-data: cannot marshal value of type %v to binary", v.Type())
+}
+
+// appendBinaryType 写入类型 t 的类型描述，对于 array 会递归的写入元素类型。
+func appendBinaryType(buf []byte, t reflect.Type) ([]byte, error) {
+	bt, err := binaryTypeOf(t)
+
+	if err != nil {
+		return nil, err
+	}
+
+	buf = append(buf, bt)
+
+	if bt == binaryTypeArray {
+		return appendBinaryType(buf, t.Elem())
+	}
+
+	return buf, nil
+}
+
+func binaryTypeOf(t reflect.Type) (byte, error) {
+	if t == typeOfTime {
+		return binaryTypeTime, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return binaryTypeBool, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return binaryTypeInt64, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return binaryTypeUint64, nil
+	case reflect.Float32, reflect.Float64:
+		return binaryTypeFloat64, nil
+	case reflect.Complex64, reflect.Complex128:
+		return binaryTypeComplex128, nil
+	case reflect.String:
+		return binaryTypeString, nil
+	case reflect.Map:
+		if t.ConvertibleTo(typeOfObject) {
+			return binaryTypeObject, nil
+		}
+	case reflect.Slice, reflect.Array:
+		return binaryTypeArray, nil
+	case reflect.Interface:
+		return binaryTypeAny, nil
+	}
+
+	return 0, fmt.Errorf("go-data: cannot marshal value of type %v to binary", t)
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	return append(buf, b[:n]...)
+}
+
+func appendVarint(buf []byte, v int64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(b[:], v)
+	return append(buf, b[:n]...)
+}
+
+func appendUint64(buf []byte, v uint64) []byte {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	return append(buf, b[:]...)
+}
+
+type binaryReader struct {
+	buf   []byte
+	depth int
+}
+
+// enter 进入下一层嵌套，如果超过了 maxBinaryDepth 则返回错误，调用者需要在返回之前调用 leave。
+func (r *binaryReader) enter() error {
+	r.depth++
+
+	if r.depth > maxBinaryDepth {
+		return ErrInvalidBinary
+	}
+
+	return nil
+}
+
+func (r *binaryReader) leave() {
+	r.depth--
+}
+
+func (r *binaryReader) readByte() (byte, error) {
+	if len(r.buf) == 0 {
+		return 0, ErrInvalidBinary
+	}
+
+	b := r.buf[0]
+	r.buf = r.buf[1:]
+	return b, nil
+}
+
+func (r *binaryReader) readUvarint() (uint64, error) {
+	v, n := binary.Uvarint(r.buf)
+
+	if n <= 0 {
+		return 0, ErrInvalidBinary
+	}
+
+	r.buf = r.buf[n:]
+	return v, nil
+}
+
+func (r *binaryReader) readVarint() (int64, error) {
+	v, n := binary.Varint(r.buf)
+
+	if n <= 0 {
+		return 0, ErrInvalidBinary
+	}
+
+	r.buf = r.buf[n:]
+	return v, nil
+}
+
+func (r *binaryReader) readBytes() ([]byte, error) {
+	l, err := r.readUvarint()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if l > uint64(len(r.buf)) {
+		return nil, ErrInvalidBinary
+	}
+
+	b := r.buf[:l]
+	r.buf = r.buf[l:]
+	return b, nil
+}
+
+func (r *binaryReader) readFloat64() (float64, error) {
+	if len(r.buf) < 8 {
+		return 0, ErrInvalidBinary
+	}
+
+	f := math.Float64frombits(binary.LittleEndian.Uint64(r.buf))
+	r.buf = r.buf[8:]
+	return f, nil
+}
+
+func (r *binaryReader) readObject() (RawData, error) {
+	defer r.leave()
+
+	if err := r.enter(); err != nil {
+		return nil, err
+	}
+
+	l, err := r.readUvarint()
+
+	if err != nil {
+		return nil, err
+	}
+
+	// 每个 key-value 至少需要 2 个字节，借此避免恶意数据导致分配过大的内存。
+	if l > uint64(len(r.buf)/2) {
+		return nil, ErrInvalidBinary
+	}
+
+	d := make(RawData, int(l))
+
+	for i := uint64(0); i < l; i++ {
+		k, err := r.readBytes()
+
+		if err != nil {
+			return nil, err
+		}
+
+		t, err := r.readByte()
+
+		if err != nil {
+			return nil, err
+		}
+
+		v, err := r.readPayload(t)
+
+		if err != nil {
+			return nil, err
+		}
+
+		d[string(k)] = v
+	}
+
+	return d, nil
+}
+
+// readType 读取 array 的元素类型描述。
+func (r *binaryReader) readType() (reflect.Type, error) {
+	t, err := r.readByte()
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch t {
+	case binaryTypeBool:
+		return typeOfBool, nil
+	case binaryTypeInt64:
+		return typeOfInt64, nil
+	case binaryTypeUint64:
+		return typeOfUint64, nil
+	case binaryTypeFloat64:
+		return typeOfFloat64, nil
+	case binaryTypeComplex128:
+		return typeOfComplex128, nil
+	case binaryTypeString:
+		return typeOfString, nil
+	case binaryTypeTime:
+		return typeOfTime, nil
+	case binaryTypeObject:
+		return typeOfObject, nil
+	case binaryTypeAny:
+		return typeOfInterface, nil
+	case binaryTypeArray:
+		defer r.leave()
+
+		if err := r.enter(); err != nil {
+			return nil, err
+		}
+
+		elem, err := r.readType()
+
+		if err != nil {
+			return nil, err
+		}
+
+		return reflect.SliceOf(elem), nil
+	}
+
+	return nil, ErrInvalidBinary
+}
+
+func (r *binaryReader) readPayload(t byte) (interface{}, error) {
+	switch t {
+	case binaryTypeNil:
+		return nil, nil
+
+	case binaryTypeBool:
+		b, err := r.readByte()
+
+		if err != nil {
+			return nil, err
+		}
+
+		return b != 0, nil
+
+	case binaryTypeInt64:
+		return r.readVarint()
+
+	case binaryTypeUint64:
+		return r.readUvarint()
+
+	case binaryTypeFloat64:
+		return r.readFloat64()
+
+	case binaryTypeComplex128:
+		re, err := r.readFloat64()
+
+		if err != nil {
+			return nil, err
+		}
+
+		im, err := r.readFloat64()
+
+		if err != nil {
+			return nil, err
+		}
+
+		return complex(re, im), nil
+
+	case binaryTypeString:
+		b, err := r.readBytes()
+
+		if err != nil {
+			return nil, err
+		}
+
+		return string(b), nil
+
+	case binaryTypeTime:
+		b, err := r.readBytes()
+
+		if err != nil {
+			return nil, err
+		}
+
+		var tm time.Time
+
+		if err := tm.UnmarshalBinary(b); err != nil {
+			return nil, err
+		}
+
+		return tm, nil
+
+	case binaryTypeObject:
+		return r.readObject()
+
+	case binaryTypeArray:
+		defer r.leave()
+
+		if err := r.enter(); err != nil {
+			return nil, err
+		}
+
+		elemType, err := r.readType()
+
+		if err != nil {
+			return nil, err
+		}
+
+		l, err := r.readUvarint()
+
+		if err != nil {
+			return nil, err
+		}
+
+		// 每个元素至少需要 1 个字节。
+		if l > uint64(len(r.buf)) {
+			return nil, ErrInvalidBinary
+		}
+
+		elemBinaryType, err := binaryTypeOf(elemType)
+
+		if err != nil {
+			return nil, err
+		}
+
+		slice := reflect.MakeSlice(reflect.SliceOf(elemType), int(l), int(l))
+
+		for i := 0; i < int(l); i++ {
+			et := elemBinaryType
+
+			if et == binaryTypeAny {
+				if et, err = r.readByte(); err != nil {
+					return nil, err
+				}
+			}
+
+			v, err := r.readPayload(et)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if v == nil {
+				continue
+			}
+
+			// 嵌套数组的元素类型是从数据中读出来的，可能与 elemType 不一致。
+			val := reflect.ValueOf(v)
+
+			if !val.Type().AssignableTo(elemType) {
+				return nil, ErrInvalidBinary
+			}
+
+			slice.Index(i).Set(val)
+		}
+
+		return slice.Interface(), nil
+	}
+
+	return nil, ErrInvalidBinary
+}
@@ -0,0 +1,118 @@
+package data
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestDataBinary(t *testing.T) {
+	cases := []Data{
+		Data{},
+		complexData,
+		fullData,
+		Make(RawData{
+			"nil":    nil,
+			"nested": [][]int64{{1, -2}, {3}},
+			"mixed":  []interface{}{true, "s", []string{"a"}, RawData{"k": 1.5}},
+			"empty":  []RawData{},
+			"uint":   uint64(1<<64 - 1),
+		}),
+		Data{
+			data: RawData{
+				"nils": []interface{}{nil, int64(1)},
+			},
+		},
+	}
+	a := assert.New(t)
+
+	for i, c := range cases {
+		a.Use(&i, &c)
+
+		b, err := c.MarshalBinary()
+		a.NilError(err)
+
+		var d Data
+		a.NilError(d.UnmarshalBinary(b))
+		a.Equal(d, c)
+	}
+}
+
+func TestDataBinarySize(t *testing.T) {
+	a := assert.New(t)
+	b, err := complexData.MarshalBinary()
+	a.NilError(err)
+	a.Assert(len(b) < len(complexData.JSON(false)))
+}
+
+func TestDataBinaryError(t *testing.T) {
+	a := assert.New(t)
+	b, err := complexData.MarshalBinary()
+	a.NilError(err)
+
+	var d Data
+	a.NonNilError(d.UnmarshalBinary(nil))
+	a.NonNilError(d.UnmarshalBinary([]byte{1}))
+	a.NonNilError(d.UnmarshalBinary(b[:len(b)-1]))
+	a.NonNilError(d.UnmarshalBinary(append(b, 0)))
+
+	// 嵌套数组的元素类型与外层声明的类型不一致。
+	a.Equal(d.UnmarshalBinary([]byte{2, 1, 1, 'a', 9, 9, 2, 1, 6, 1, 1, 'x'}), ErrInvalidBinary)
+
+	// 嵌套层数过多。
+	a.Equal(d.UnmarshalBinary(deepBinary(maxBinaryDepth*10, []byte{1, 0, 8}, []byte{0})), ErrInvalidBinary)
+	a.Equal(d.UnmarshalBinary(append([]byte{2, 1, 0, 9}, deepBinary(maxBinaryDepth*10, []byte{10, 1, 9}, []byte{10, 0})[1:]...)), ErrInvalidBinary)
+	a.Equal(d.UnmarshalBinary(append([]byte{2, 1, 0, 9}, deepBinary(maxBinaryDepth*10, []byte{9}, []byte{1, 0})[1:]...)), ErrInvalidBinary)
+
+	// 不超过限制的嵌套可以正常解析。
+	a.NilError(d.UnmarshalBinary(deepBinary(maxBinaryDepth-1, []byte{1, 0, 8}, []byte{0})))
+	a.NilError(d.UnmarshalBinary(append([]byte{2, 1, 0, 9}, deepBinary(maxBinaryDepth-2, []byte{10, 1, 9}, []byte{10, 0})[1:]...)))
+	a.NilError(d.UnmarshalBinary(append([]byte{2, 1, 0, 9}, deepBinary(maxBinaryDepth-3, []byte{9}, []byte{1, 0})[1:]...)))
+
+	_, err = Make(RawData{
+		"ch": make(chan int),
+	}).MarshalBinary()
+	a.NilError(err)
+
+	_, err = Data{
+		data: RawData{
+			"ch": make(chan int),
+		},
+	}.MarshalBinary()
+	a.NonNilError(err)
+}
+
+func TestDataGob(t *testing.T) {
+	type Value struct {
+		Name string
+		Data Data
+		List []Data
+	}
+
+	a := assert.New(t)
+	v := &Value{
+		Name: "gob",
+		Data: complexData,
+		List: []Data{fullData, {}},
+	}
+	buf := &bytes.Buffer{}
+	a.NilError(gob.NewEncoder(buf).Encode(v))
+
+	var decoded Value
+	a.NilError(gob.NewDecoder(buf).Decode(&decoded))
+	a.Equal(&decoded, v)
+}
+
+// deepBinary 生成一个将 prefix 重复 n 次、最后以 suffix 结束的二进制数据。
+func deepBinary(n int, prefix, suffix []byte) []byte {
+	buf := make([]byte, 0, 1+n*len(prefix)+len(suffix))
+	buf = append(buf, binaryVersion)
+
+	for i := 0; i < n; i++ {
+		buf = append(buf, prefix...)
+	}
+
+	return append(buf, suffix...)
+}
@@ -0,0 +1,124 @@
+package data
+
+import (
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"github.com/huandu/go-clone"
+)
+
+// Cloner 深拷贝一个 Data 中的值，返回的值不能与 v 共享任何可以被修改的内存。
+//
+// 实现者需要保证这个函数是并发安全的。
+type Cloner func(v interface{}) interface{}
+
+type clonerHolder struct {
+	cloner Cloner
+}
+
+var defaultCloner atomic.Value
+
+// SetCloner 设置全局的 Cloner，设置为 nil 则恢复默认的实现，默认使用 go-clone 的 `clone.Clone`。
+//
+// `Merge`、`Patch#Apply`、`Data#Clone` 等需要深拷贝的地方会优先使用内置的快速实现复制 Data 的标准类型，
+// 包括 RawData、Data、各种标量以及它们的 slice，只有遇到其他类型的值时才会调用 Cloner，
+// 比如 map[int]string 或者自定义类型的 slice。
+// 如果 Data 中经常出现这类非标准类型的值，可以设置一个针对这些类型优化过的 Cloner。
+func SetCloner(cloner Cloner) {
+	defaultCloner.Store(clonerHolder{
+		cloner: cloner,
+	})
+}
+
+func currentCloner() Cloner {
+	if holder, _ := defaultCloner.Load().(clonerHolder); holder.cloner != nil {
+		return holder.cloner
+	}
+
+	return clone.Clone
+}
+
+// cloneValue 深拷贝 Data 中的值 v，标准类型使用快速实现，其他类型交给当前的 Cloner。
+func cloneValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case nil, int64, uint64, float64, complex128, string, bool, time.Time:
+		return v
+
+	case RawData:
+		return cloneRawData(val)
+
+	case Data:
+		return Data{
+			data:  cloneRawData(val.data),
+			order: val.order.clone(),
+		}
+
+	case []interface{}:
+		if val == nil {
+			return val
+		}
+
+		s := make([]interface{}, len(val))
+
+		for i, elem := range val {
+			s[i] = cloneValue(elem)
+		}
+
+		return s
+
+	case []RawData:
+		if val == nil {
+			return val
+		}
+
+		s := make([]RawData, len(val))
+
+		for i, elem := range val {
+			s[i] = cloneRawData(elem)
+		}
+
+		return s
+
+	case []Data:
+		if val == nil {
+			return val
+		}
+
+		s := make([]Data, len(val))
+
+		for i, elem := range val {
+			s[i] = cloneValue(elem).(Data)
+		}
+
+		return s
+
+	case []int64, []uint64, []float64, []complex128, []string, []bool, []time.Time:
+		// 元素都是不可变的值，直接复制底层数组即可。
+		rv := reflect.ValueOf(v)
+
+		if rv.IsNil() {
+			return v
+		}
+
+		s := reflect.MakeSlice(rv.Type(), rv.Len(), rv.Len())
+		reflect.Copy(s, rv)
+		return s.Interface()
+	}
+
+	return currentCloner()(v)
+}
+
+func cloneRawData(d RawData) RawData {
+	if d == nil {
+		return nil
+	}
+
+	cloned := make(RawData, len(d))
+
+	for k, v := range d {
+		cloned[k] = cloneValue(v)
+	}
+
+	return cloned
+}
@@ -0,0 +1,106 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestCloneValue(t *testing.T) {
+	a := assert.New(t)
+	now := time.Now()
+	original := RawData{
+		"int":    int64(1),
+		"time":   now,
+		"ints":   []int64{1, 2},
+		"empty":  []string{},
+		"nil":    []string(nil),
+		"times":  []time.Time{now},
+		"list":   []interface{}{RawData{"a": int64(1)}, []int64{3}},
+		"objs":   []RawData{{"b": int64(2)}},
+		"datas":  []Data{Make(RawData{"c": 3})},
+		"data":   Make(RawData{"d": 4}),
+		"nested": RawData{"e": []string{"x"}},
+		"custom": map[int]string{1: "one"},
+	}
+	cloned := cloneValue(original).(RawData)
+	a.Equal(cloned, original)
+	a.Assert(cloned["empty"].([]string) != nil)
+	a.Assert(cloned["nil"].([]string) == nil)
+
+	// 修改原值不影响副本。
+	original["ints"].([]int64)[0] = 100
+	original["list"].([]interface{})[0].(RawData)["a"] = int64(100)
+	original["list"].([]interface{})[1].([]int64)[0] = 100
+	original["objs"].([]RawData)[0]["b"] = int64(100)
+	original["datas"].([]Data)[0].data["c"] = int64(100)
+	original["data"].(Data).data["d"] = int64(100)
+	original["nested"].(RawData)["e"].([]string)[0] = "y"
+	original["custom"].(map[int]string)[1] = "changed"
+	a.Equal(cloned, RawData{
+		"int":    int64(1),
+		"time":   now,
+		"ints":   []int64{1, 2},
+		"empty":  []string{},
+		"nil":    []string(nil),
+		"times":  []time.Time{now},
+		"list":   []interface{}{RawData{"a": int64(1)}, []int64{3}},
+		"objs":   []RawData{{"b": int64(2)}},
+		"datas":  []Data{Make(RawData{"c": 3})},
+		"data":   Make(RawData{"d": 4}),
+		"nested": RawData{"e": []string{"x"}},
+		"custom": map[int]string{1: "one"},
+	})
+}
+
+func TestSetCloner(t *testing.T) {
+	a := assert.New(t)
+	var cloned []interface{}
+	SetCloner(func(v interface{}) interface{} {
+		cloned = append(cloned, v)
+		return map[int]string{2: "two"}
+	})
+	defer SetCloner(nil)
+
+	d := Make(RawData{
+		"str": "str",
+		"list": []RawData{
+			{"a": 1},
+		},
+	})
+	d.data["custom"] = map[int]string{1: "one"}
+
+	// 标准类型不会调用 Cloner。
+	c := d.Clone()
+	a.Equal(cloned, []interface{}{map[int]string{1: "one"}})
+	a.Equal(c.Get("custom"), map[int]string{2: "two"})
+	a.Equal(c.Get("list"), []RawData{{"a": int64(1)}})
+
+	SetCloner(nil)
+	c = d.Clone()
+	a.Equal(c.Get("custom"), map[int]string{1: "one"})
+	a.Equal(len(cloned), 1)
+}
+
+func BenchmarkDataClone(b *testing.B) {
+	d := Make(RawData{
+		"str":   "abcdefg",
+		"int":   1234,
+		"float": -43.21,
+		"slice": []string{"first", "second"},
+		"list": []RawData{
+			{"a": true, "b": "string"},
+			{"a": false, "c": 123},
+		},
+		"map": RawData{
+			"a":     true,
+			"b":     "string",
+			"times": []time.Time{time.Now()},
+		},
+	})
+
+	for i := 0; i < b.N; i++ {
+		d.Clone()
+	}
+}
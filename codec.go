@@ -0,0 +1,211 @@
+package data
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// Format 是 Codec 使用的序列化格式，可以实现这个接口来支持 msgpack 等其他格式。
+type Format interface {
+	// Name 返回格式的名字，这个名字会写在消息头中，只能包含字母、数字、`-` 和 `_`。
+	Name() string
+
+	// Marshal 将 d 序列化成字节。
+	Marshal(d Data) ([]byte, error)
+
+	// Unmarshal 从 src 中解析 Data，Unmarshal 返回之后调用者可能会复用 src。
+	Unmarshal(src []byte) (Data, error)
+}
+
+// 内置的序列化格式。
+var (
+	FormatJSON   Format = jsonFormat{}   // JSON 格式，与 `Data#JSON` 和 `ParseJSON` 相同。
+	FormatBinary Format = binaryFormat{} // 二进制格式，与 `Data#MarshalBinary` 和 `Data#UnmarshalBinary` 相同。
+)
+
+type jsonFormat struct{}
+
+func (jsonFormat) Name() string {
+	return dataTypeJSON
+}
+
+func (jsonFormat) Marshal(d Data) ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	if err := defaultFormatter.json(buf, d, false); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (jsonFormat) Unmarshal(src []byte) (Data, error) {
+	p := Parser{}
+	return p.ParseJSONBytes(src)
+}
+
+type binaryFormat struct{}
+
+func (binaryFormat) Name() string {
+	return "binary"
+}
+
+func (binaryFormat) Marshal(d Data) ([]byte, error) {
+	return d.MarshalBinary()
+}
+
+func (binaryFormat) Unmarshal(src []byte) (d Data, err error) {
+	err = d.UnmarshalBinary(src)
+	return
+}
+
+// Codec 将 Data 编码成消息队列（比如 Kafka、NATS）中传递的消息，或者反过来解码。
+//
+// 编码后的消息由消息头和 payload 组成，格式为 `<` format `:` version `>` payload，
+// 比如 `<json:2>{"a":1}`，其中 format 是 `Format#Name`，version 是 SchemaVersion。
+// 解码时会根据消息头在 Format 和 Formats 中选择格式，所以使用不同格式的生产者和消费者可以共存，方便逐步迁移。
+// 消费者只接受自己配置的格式，比如只配置了 JSON 的消费者不会解析 `<binary:1>` 消息。
+type Codec struct {
+	Format        Format   // 编码使用的格式，为 nil 时使用 FormatJSON。
+	SchemaVersion int      // 写在消息头中的 schema 版本号，消费者可以据此处理不同版本的消息。
+	Formats       []Format // 解码时除了 Format 之外还支持的格式，内置格式也需要显式加入才能解析。
+}
+
+// Encode 将 d 编码成消息。
+func (c *Codec) Encode(d Data) ([]byte, error) {
+	format := c.format()
+	payload, err := format.Marshal(d)
+
+	if err != nil {
+		return nil, err
+	}
+
+	version := strconv.Itoa(c.SchemaVersion)
+	name := format.Name()
+	buf := make([]byte, 0, len(name)+len(version)+3+len(payload))
+	buf = append(buf, '<')
+	buf = append(buf, name...)
+	buf = append(buf, ':')
+	buf = append(buf, version...)
+	buf = append(buf, '>')
+	buf = append(buf, payload...)
+	return buf, nil
+}
+
+// Decode 从消息中解码 Data，忽略消息头中的版本号。
+func (c *Codec) Decode(src []byte) (Data, error) {
+	d, _, err := c.DecodeVersion(src)
+	return d, err
+}
+
+// DecodeVersion 从消息中解码 Data，同时返回消息头中的 schema 版本号。
+// 如果消息头不合法或者格式不被支持，返回的错误可以通过 `errors.Is(err, ErrInvalidFormat)` 判断。
+func (c *Codec) DecodeVersion(src []byte) (d Data, version int, err error) {
+	if len(src) == 0 || src[0] != '<' {
+		err = ErrInvalidFormat
+		return
+	}
+
+	end := bytes.IndexByte(src, '>')
+
+	if end < 0 {
+		err = ErrInvalidFormat
+		return
+	}
+
+	header := string(src[1:end])
+	sep := bytes.IndexByte(src[1:end], ':')
+
+	if sep < 0 {
+		err = fmt.Errorf("%w: missing schema version in header '%v'", ErrInvalidFormat, header)
+		return
+	}
+
+	name := header[:sep]
+
+	if version, err = strconv.Atoi(header[sep+1:]); err != nil {
+		err = fmt.Errorf("%w: invalid schema version in header '%v'", ErrInvalidFormat, header)
+		return
+	}
+
+	format := c.lookup(name)
+
+	if format == nil {
+		err = fmt.Errorf("%w: unknown data type '%v'", ErrInvalidFormat, name)
+		return
+	}
+
+	d, err = format.Unmarshal(src[end+1:])
+	return
+}
+
+func (c *Codec) format() Format {
+	if c.Format == nil {
+		return FormatJSON
+	}
+
+	return c.Format
+}
+
+func (c *Codec) lookup(name string) Format {
+	if f := c.format(); f.Name() == name {
+		return f
+	}
+
+	for _, f := range c.Formats {
+		if f.Name() == name {
+			return f
+		}
+	}
+
+	return nil
+}
+
+// NATSEncoder 将 Codec 包装成 NATS 的 `nats.Encoder` 接口，可以通过 `nats.RegisterEncoder` 注册使用。
+//
+// Encode 接受 Data、*Data 或者任意可以被 `Encoder#Encode` 转化的值；
+// Decode 接受 *Data 或者任意可以被 `Decoder#Decode` 解析的指针。
+type NATSEncoder struct {
+	Codec Codec
+}
+
+// Encode 将 v 编码成消息，subject 会被忽略。
+func (e *NATSEncoder) Encode(subject string, v interface{}) ([]byte, error) {
+	var d Data
+
+	switch val := v.(type) {
+	case Data:
+		d = val
+	case *Data:
+		if val != nil {
+			d = *val
+		}
+	default:
+		enc := Encoder{}
+		var err error
+
+		if d, err = enc.EncodeE(v); err != nil {
+			return nil, err
+		}
+	}
+
+	return e.Codec.Encode(d)
+}
+
+// Decode 从消息中解码并设置 vPtr 的值，subject 会被忽略。
+func (e *NATSEncoder) Decode(subject string, data []byte, vPtr interface{}) error {
+	d, err := e.Codec.Decode(data)
+
+	if err != nil {
+		return err
+	}
+
+	if ptr, ok := vPtr.(*Data); ok {
+		*ptr = d
+		return nil
+	}
+
+	dec := Decoder{}
+	return dec.Decode(d, vPtr)
+}
@@ -0,0 +1,122 @@
+package data
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/huandu/go-assert"
+)
+
+type upperFormat struct{}
+
+func (upperFormat) Name() string {
+	return "upper"
+}
+
+func (upperFormat) Marshal(d Data) ([]byte, error) {
+	return FormatJSON.Marshal(d)
+}
+
+func (upperFormat) Unmarshal(src []byte) (Data, error) {
+	return FormatJSON.Unmarshal(src)
+}
+
+func TestCodec(t *testing.T) {
+	d := Make(RawData{
+		"a": 1,
+		"b": []string{"x", "y"},
+		"c": RawData{"d": 1.5},
+	})
+	cases := []struct {
+		Codec  Codec
+		Prefix string
+	}{
+		{Codec{}, `<json:0>{"a":1,`},
+		{Codec{SchemaVersion: 3}, `<json:3>{"a":1,`},
+		{Codec{Format: FormatBinary, SchemaVersion: 12}, "<binary:12>\x02"},
+		{Codec{Format: upperFormat{}, SchemaVersion: -1}, `<upper:-1>{"a":1,`},
+	}
+	a := assert.New(t)
+
+	for i, c := range cases {
+		a.Use(&i, &c)
+		msg, err := c.Codec.Encode(d)
+		a.NilError(err)
+		a.Equal(string(msg[:len(c.Prefix)]), c.Prefix)
+
+		decoded, version, err := c.Codec.DecodeVersion(msg)
+		a.NilError(err)
+		a.Equal(version, c.Codec.SchemaVersion)
+		a.Equal(decoded, d)
+	}
+
+	// 消费者可以解码 Format 以及 Formats 中的格式。
+	consumer := Codec{
+		Formats: []Format{FormatBinary, upperFormat{}},
+	}
+
+	for i, c := range cases {
+		a.Use(&i, &c)
+		msg, err := c.Codec.Encode(d)
+		a.NilError(err)
+
+		decoded, err := consumer.Decode(msg)
+		a.NilError(err)
+		a.Equal(decoded, d)
+	}
+
+	errCases := []string{
+		``,
+		`{"a":1}`,
+		`<json`,
+		`<json>{"a":1}`,
+		`<json:x>{"a":1}`,
+		`<yaml:1>a: 1`,
+		`<upper:1>{"a":1}`,
+		`<json:1>[1]`,
+	}
+
+	for i, c := range errCases {
+		a.Use(&i, &c)
+		_, err := (&Codec{}).Decode([]byte(c))
+		a.NonNilError(err)
+	}
+
+	_, err := (&Codec{}).Decode([]byte(`<yaml:1>a: 1`))
+	a.Assert(errors.Is(err, ErrInvalidFormat))
+
+	// 没有配置的内置格式也不会被解析。
+	msg, err := (&Codec{Format: FormatBinary}).Encode(d)
+	a.NilError(err)
+	_, err = (&Codec{}).Decode(msg)
+	a.Assert(errors.Is(err, ErrInvalidFormat))
+	_, err = (&Codec{}).Decode([]byte{'<', 'b', 'i', 'n', 'a', 'r', 'y', ':', '1', '>', 2, 1, 1, 'a', 9, 9, 2, 1, 6, 1, 1, 'x'})
+	a.Assert(errors.Is(err, ErrInvalidFormat))
+}
+
+func TestNATSEncoder(t *testing.T) {
+	type Event struct {
+		Name  string `data:"name"`
+		Count int    `data:"count"`
+	}
+
+	a := assert.New(t)
+	e := &NATSEncoder{
+		Codec: Codec{SchemaVersion: 1},
+	}
+	msg, err := e.Encode("events", &Event{Name: "click", Count: 2})
+	a.NilError(err)
+	a.Equal(string(msg), `<json:1>{"count":2,"name":"click"}`)
+
+	var ev Event
+	a.NilError(e.Decode("events", msg, &ev))
+	a.Equal(ev, Event{Name: "click", Count: 2})
+
+	d := Make(RawData{"a": 1})
+	msg, err = e.Encode("events", &d)
+	a.NilError(err)
+
+	var decoded Data
+	a.NilError(e.Decode("events", msg, &decoded))
+	a.Equal(decoded, d)
+}
@@ -0,0 +1,163 @@
+package data
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Conflicts 检查 p1 和 p2 是否以不兼容的方式修改了同一个值，返回所有冲突的路径，路径格式与 `Data#Query` 相同。
+// 这适合在审核流程中发现对同一个文档的并发修改，避免后应用的 patch 悄悄覆盖先应用的 patch。
+//
+// 两个 patch 修改的路径相同，或者一个是另一个的上级时，认为它们修改了同一个值，除非是以下几种兼容的情况：
+//     - 都删除了这个值；
+//     - 都将这个值设置成了相同的值，包括 updates 中相同的叶子节点和 replaces 中相同的值；
+//     - 都通过 updates 向同一个数组追加元素，或者都通过 `Patch#DeleteMatched` 删除同一个数组中的元素。
+// updates 中的 object 会被展开到叶子节点，因此两个 patch 更新同一个 object 中不同的 key 不会冲突。
+//
+// 冲突的路径是两个修改中较短的路径，按照字典序排列，如果没有冲突返回 nil。
+func Conflicts(p1, p2 *Patch) []string {
+	if p1 == nil || p2 == nil {
+		return nil
+	}
+
+	writes1 := patchWrites(p1)
+	writes2 := patchWrites(p2)
+	conflicts := map[string]struct{}{}
+
+	for _, w1 := range writes1 {
+		for _, w2 := range writes2 {
+			path, overlapped := overlappedPath(w1.path, w2.path)
+
+			if !overlapped || w1.compatible(w2) {
+				continue
+			}
+
+			conflicts[JoinQuery(path...)] = struct{}{}
+		}
+	}
+
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	paths := make([]string, 0, len(conflicts))
+
+	for p := range conflicts {
+		paths = append(paths, p)
+	}
+
+	sort.Strings(paths)
+	return paths
+}
+
+type patchWriteKind int
+
+const (
+	patchWriteDelete patchWriteKind = iota
+	patchWriteDeleteMatched
+	patchWriteReplace
+	patchWriteSet
+	patchWriteAppend
+)
+
+// patchWrite 是 patch 对一个路径的修改。
+type patchWrite struct {
+	path  []string
+	kind  patchWriteKind
+	value interface{}
+}
+
+func (w patchWrite) compatible(other patchWrite) bool {
+	if w.kind != other.kind {
+		return false
+	}
+
+	switch w.kind {
+	case patchWriteDelete:
+		return true
+	case patchWriteDeleteMatched, patchWriteAppend:
+		return len(w.path) == len(other.path)
+	}
+
+	return len(w.path) == len(other.path) && reflect.DeepEqual(w.value, other.value)
+}
+
+// patchWrites 返回 patch 中所有 action 的修改。
+func patchWrites(patch *Patch) []patchWrite {
+	var writes []patchWrite
+
+	for _, action := range patch.actions {
+		for _, query := range action.Deletes {
+			writes = append(writes, patchWrite{
+				path: queryFields(query),
+				kind: patchWriteDelete,
+			})
+		}
+
+		for query := range action.DeleteMatches {
+			writes = append(writes, patchWrite{
+				path: queryFields(query),
+				kind: patchWriteDeleteMatched,
+			})
+		}
+
+		for query, d := range action.Replaces {
+			writes = append(writes, patchWrite{
+				path:  queryFields(query),
+				kind:  patchWriteReplace,
+				value: d.data,
+			})
+		}
+
+		for query, d := range action.Updates {
+			writes = appendUpdateWrites(writes, queryFields(query), d.data)
+		}
+	}
+
+	return writes
+}
+
+// appendUpdateWrites 将 updates 中的 d 展开成叶子节点的修改，合并时数组会被追加，其他值会被覆盖。
+func appendUpdateWrites(writes []patchWrite, path []string, d RawData) []patchWrite {
+	for k, v := range d {
+		p := append(path[:len(path):len(path)], k)
+
+		if inner, ok := v.(Data); ok {
+			v = inner.data
+		}
+
+		if m, ok := v.(RawData); ok {
+			writes = appendUpdateWrites(writes, p, m)
+			continue
+		}
+
+		kind := patchWriteSet
+
+		if v != nil && reflect.TypeOf(v).Kind() == reflect.Slice {
+			kind = patchWriteAppend
+		}
+
+		writes = append(writes, patchWrite{
+			path:  p,
+			kind:  kind,
+			value: v,
+		})
+	}
+
+	return writes
+}
+
+// overlappedPath 判断 p1 和 p2 是否相同或者一个是另一个的上级，如果是，返回较短的那个。
+func overlappedPath(p1, p2 []string) ([]string, bool) {
+	if len(p1) > len(p2) {
+		p1, p2 = p2, p1
+	}
+
+	for i, f := range p1 {
+		if p2[i] != f {
+			return nil, false
+		}
+	}
+
+	return p1, true
+}
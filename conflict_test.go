@@ -0,0 +1,109 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestConflicts(t *testing.T) {
+	type patchFunc func(p *Patch)
+	update := func(query string, d RawData) patchFunc {
+		return func(p *Patch) {
+			p.Add(nil, map[string]Data{query: Make(d)})
+		}
+	}
+	replace := func(query string, d RawData) patchFunc {
+		return func(p *Patch) {
+			p.Replace(query, Make(d))
+		}
+	}
+	del := func(queries ...string) patchFunc {
+		return func(p *Patch) {
+			p.Add(queries, nil)
+		}
+	}
+	deleteMatched := func(query string, cond RawData) patchFunc {
+		return func(p *Patch) {
+			p.DeleteMatched(query, Make(cond))
+		}
+	}
+
+	cases := []struct {
+		P1, P2   []patchFunc
+		Expected []string
+	}{
+		{ // 更新同一个 object 中不同的 key。
+			[]patchFunc{update("server", RawData{"addr": ":80"})},
+			[]patchFunc{update("", RawData{"server": RawData{"workers": 4}})},
+			nil,
+		},
+		{ // 更新同一个 key。
+			[]patchFunc{update("server", RawData{"addr": ":80", "tls": true})},
+			[]patchFunc{update("", RawData{"server": RawData{"addr": ":8080", "tls": true}})},
+			[]string{"server.addr"},
+		},
+		{ // 设置成相同的值。
+			[]patchFunc{update("server", RawData{"addr": ":80"}), replace("db", RawData{"host": "a"})},
+			[]patchFunc{update("server", RawData{"addr": ":80"}), replace("db", RawData{"host": "a"})},
+			nil,
+		},
+		{ // 删除或者替换上级。
+			[]patchFunc{update("server", RawData{"addr": ":80"}), update("db", RawData{"host": "a"})},
+			[]patchFunc{del("server"), replace("db", RawData{"host": "a"})},
+			[]string{"db", "server"},
+		},
+		{ // 都删除。
+			[]patchFunc{del("server", "db.host")},
+			[]patchFunc{del("server.addr", "db")},
+			nil,
+		},
+		{ // 追加和删除数组元素。
+			[]patchFunc{update("", RawData{"tags": []string{"a"}}), deleteMatched("hosts", RawData{"": "x"})},
+			[]patchFunc{update("", RawData{"tags": []string{"b"}}), deleteMatched("hosts", RawData{"": "y"})},
+			nil,
+		},
+		{
+			[]patchFunc{update("", RawData{"tags": []string{"a"}})},
+			[]patchFunc{deleteMatched("tags", RawData{"": "a"}), update("", RawData{"hosts": []string{"b"}})},
+			[]string{"tags"},
+		},
+		{ // 一个设置标量，一个设置下级。
+			[]patchFunc{update("", RawData{"a": 1})},
+			[]patchFunc{update("a", RawData{"b": 1})},
+			[]string{"a"},
+		},
+		{ // 替换整个 Data 与所有修改都冲突。
+			[]patchFunc{replace("", RawData{"a": 1})},
+			[]patchFunc{update("x", RawData{"y": 1}), del("z")},
+			[]string{""},
+		},
+		{ // 转义的 key。
+			[]patchFunc{update("hosts", RawData{"example.com": 1})},
+			[]patchFunc{update(`hosts.example\.com`, RawData{"weight": 2})},
+			[]string{`hosts.example\.com`},
+		},
+	}
+
+	for i, c := range cases {
+		a := assert.New(t)
+		a.Use(&i, &c)
+
+		p1 := NewPatch()
+		p2 := NewPatch()
+
+		for _, fn := range c.P1 {
+			fn(p1)
+		}
+
+		for _, fn := range c.P2 {
+			fn(p2)
+		}
+
+		a.Equal(Conflicts(p1, p2), c.Expected)
+		a.Equal(Conflicts(p2, p1), c.Expected)
+	}
+
+	a := assert.New(t)
+	a.Assert(Conflicts(nil, NewPatch()) == nil)
+}
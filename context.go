@@ -0,0 +1,45 @@
+package data
+
+import "context"
+
+// contextCheckInterval 是每隔多少次遍历检查一次 ctx 是否已经结束。
+const contextCheckInterval = 256
+
+// contextChecker 用于在深度遍历过程中定期检查 ctx 是否已经结束。
+// nil 的 contextChecker 永远不会报错，这样不需要 ctx 的代码可以直接传 nil。
+type contextChecker struct {
+	ctx   context.Context
+	count int
+	err   error
+}
+
+func newContextChecker(ctx context.Context) *contextChecker {
+	return &contextChecker{
+		ctx: ctx,
+		err: ctx.Err(),
+	}
+}
+
+// check 返回 ctx 的错误。为了减少开销，只有每隔 contextCheckInterval 次调用才会真正检查 ctx。
+// 一旦发现 ctx 已经结束，之后所有的调用都会返回同样的错误。
+func (c *contextChecker) check() error {
+	if c == nil || c.err != nil {
+		return c.error()
+	}
+
+	c.count++
+
+	if c.count%contextCheckInterval == 0 {
+		c.err = c.ctx.Err()
+	}
+
+	return c.err
+}
+
+func (c *contextChecker) error() error {
+	if c == nil {
+		return nil
+	}
+
+	return c.err
+}
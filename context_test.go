@@ -0,0 +1,131 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/huandu/go-assert"
+)
+
+// cancelAfterContext 在 Err 被调用 n 次之后返回 context.Canceled，用来模拟遍历过程中 ctx 被取消。
+type cancelAfterContext struct {
+	context.Context
+	n int
+}
+
+func (ctx *cancelAfterContext) Err() error {
+	if ctx.n <= 0 {
+		return context.Canceled
+	}
+
+	ctx.n--
+	return nil
+}
+
+func makeHugeJSON(n int) string {
+	items := make([]string, 0, n)
+
+	for i := 0; i < n; i++ {
+		items = append(items, `{"a":1,"b":["x","y"]}`)
+	}
+
+	return `{"list":[` + strings.Join(items, ",") + `]}`
+}
+
+func TestParseJSONContext(t *testing.T) {
+	a := assert.New(t)
+	str := makeHugeJSON(contextCheckInterval * 4)
+
+	d, err := ParseJSONContext(context.Background(), str)
+	a.NilError(err)
+	a.Equal(len(d.Query("list").([]RawData)), contextCheckInterval*4)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = ParseJSONContext(ctx, str)
+	a.Assert(errors.Is(err, context.Canceled))
+
+	// 解析过程中被取消。
+	_, err = ParseJSONContext(&cancelAfterContext{Context: context.Background(), n: 1}, str)
+	a.Assert(errors.Is(err, context.Canceled))
+}
+
+func TestDecodeContext(t *testing.T) {
+	a := assert.New(t)
+	d, err := ParseJSON(makeHugeJSON(contextCheckInterval * 4))
+	a.NilError(err)
+
+	var v struct {
+		List []struct {
+			A int      `data:"a"`
+			B []string `data:"b"`
+		} `data:"list"`
+	}
+	dec := &Decoder{}
+	a.NilError(dec.DecodeContext(context.Background(), d, &v))
+	a.Equal(len(v.List), contextCheckInterval*4)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = dec.DecodeContext(ctx, d, &v)
+	a.Assert(errors.Is(err, context.Canceled))
+
+	err = dec.DecodeContext(&cancelAfterContext{Context: context.Background(), n: 1}, d, &v)
+	a.Assert(errors.Is(err, context.Canceled))
+
+	// DecodeContext 不能影响 dec 本身。
+	a.Equal(dec.checker, (*contextChecker)(nil))
+}
+
+func TestPatchApplyToContext(t *testing.T) {
+	a := assert.New(t)
+	patch := NewPatch()
+
+	for i := 0; i < contextCheckInterval*2; i++ {
+		patch.Replace("v", Make(RawData{"i": i}))
+	}
+
+	d := Make(RawData{"v": 0})
+	a.NilError(patch.ApplyToContext(context.Background(), &d))
+	a.Equal(d.Query("v.i"), int64(contextCheckInterval*2-1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	d = Make(RawData{"v": 0})
+	err := patch.ApplyToContext(ctx, &d)
+	a.Assert(errors.Is(err, context.Canceled))
+	a.Equal(d.Query("v"), int64(0))
+
+	// 应用过程中被取消，target 只应用了部分变更。
+	d = Make(RawData{"v": 0})
+	err = patch.ApplyToContext(&cancelAfterContext{Context: context.Background(), n: 1}, &d)
+	a.Assert(errors.Is(err, context.Canceled))
+	a.Assert(d.Query("v.i").(int64) < contextCheckInterval*2-1)
+}
+
+func TestPatchApplyToContextDeepMerge(t *testing.T) {
+	a := assert.New(t)
+	update := RawData{}
+
+	for i := 0; i < contextCheckInterval*4; i++ {
+		update[strconv.Itoa(i)] = i
+	}
+
+	patch := NewPatch()
+	patch.Add(nil, map[string]Data{
+		"v": Make(RawData{"m": update}),
+	})
+
+	d := Make(RawData{"v": RawData{}})
+	a.NilError(patch.ApplyToContext(context.Background(), &d))
+	a.Equal(len(d.Get("v", "m").(RawData)), contextCheckInterval*4)
+
+	// 合并一个很大的 Update 的过程中被取消。
+	d = Make(RawData{"v": RawData{}})
+	err := patch.ApplyToContext(&cancelAfterContext{Context: context.Background(), n: 1}, &d)
+	a.Assert(errors.Is(err, context.Canceled))
+	a.Assert(len(d.Get("v", "m").(RawData)) < contextCheckInterval*4)
+}
@@ -2,13 +2,15 @@ package data
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"math"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 	"unsafe"
 
 	"github.com/tidwall/gjson"
@@ -29,7 +31,8 @@ var emptyData Data
 //
 // Data 里面的数据不允许随意修改，只能通过 `MergeTo`、`Patch#ApplyTo` 等方法修改。
 type Data struct {
-	data RawData
+	data  RawData
+	order *keyOrder
 }
 
 var (
@@ -60,60 +63,198 @@ func Make(m map[string]interface{}) Data {
 // 例如：
 //     <json>{"hello":"world!"}
 func Parse(str string) (d Data, err error) {
-	if !strings.HasPrefix(str, dataMetaBegin) {
-		err = errors.New("go-data: invalid data string format")
+	p := Parser{}
+	return p.Parse(str)
+}
+
+// ParseJSON 解析 JSON 字符串并且生成 Data，如果解析过程出现任何错误则返回错误。
+// 由于 Data 是一个 map，所以 JSON 必须是一个 object，如果不是则返回错误。
+func ParseJSON(str string) (d Data, err error) {
+	p := Parser{}
+	return p.ParseJSON(str)
+}
+
+// ParseJSONContext 与 ParseJSON 相同，但在解析过程中会定期检查 ctx，
+// 如果 ctx 已经结束则放弃解析并返回 ctx.Err()。
+func ParseJSONContext(ctx context.Context, str string) (d Data, err error) {
+	p := Parser{}
+	return p.ParseJSONContext(ctx, str)
+}
+
+// Parser 用来解析字符串并生成 Data，可以通过设置各个字段来定制解析行为。
+// Parser 的零值与 Parse/ParseJSON 的行为完全相同。
+type Parser struct {
+	// KeepOrder 为 true 时，Parser 会记录 JSON object 中 key 出现的顺序，
+	// 在输出 JSON 时按照这个顺序输出，而不是按照字典序。
+	// 这个顺序会在 Merge、MergeTo 和 Clone 时保留，合并进来的新 key 会追加在已有 key 的后面。
+	KeepOrder bool
+
+	// ZeroCopy 为 true 时，ParseJSONBytes 不会复制输入的 []byte，
+	// 解析得到的 Data 中的字符串可能直接引用输入的内存，
+	// 调用者必须保证在 Data 不再被使用之前不会修改输入的内容，否则 Data 的内容会被破坏。
+	ZeroCopy bool
+
+	// Headerless 为 true 时，Parse 允许 str 不包含 `<json>` 这样的头，
+	// 如果 str 不以头的开始标记开头，则直接当做 JSON 解析。
+	Headerless bool
+
+	// MetaBegin 和 MetaEnd 是头的开始和结束标记，默认分别是 `<` 和 `>`。
+	MetaBegin string
+	MetaEnd   string
+
+	// Instrumentation 用来观测解析过程，如果为 nil 则使用 `SetInstrumentation` 设置的全局值。
+	Instrumentation Instrumentation
+
+	// Unsigned 为 true 时，所有非负整数都会解析成 uint64，负整数依然解析成 int64。
+	// 这适合 Data 中的整数主要由 Encoder 从无符号类型编码而来的场景，可以保证序列化再解析之后类型不变。
+	// 需要注意，同时包含负数和非负整数的数组会被解析成 []interface{}。
+	//
+	// 设置 Unsigned 之后，超过 int64 范围的正整数也会精确的解析成 uint64，否则会解析成 float64。
+	// 合并时可以使用 `MergeOptions` 的 Unsigned 选项，让 []int64 和 []uint64 按照同样的规则合并。
+	Unsigned bool
+
+	// Arena 不为 nil 时，解析过程中的 RawData 会从 Arena 中分配，详见 `Arena` 文档。
+	// 设置了 Arena 的 Parser 不能并发使用。
+	Arena *Arena
+}
+
+// Parse 从 str 中解析 Data，str 的格式详见 `Parse` 文档。
+func (p *Parser) Parse(str string) (d Data, err error) {
+	metaBegin, metaEnd := metaMarks(p.MetaBegin, p.MetaEnd)
+
+	if !strings.HasPrefix(str, metaBegin) {
+		if p.Headerless {
+			return p.ParseJSON(str)
+		}
+
+		err = ErrInvalidFormat
 		return
 	}
 
-	str = str[len(dataMetaBegin):]
-	idx := strings.Index(str, dataMetaEnd)
+	str = str[len(metaBegin):]
+	idx := strings.Index(str, metaEnd)
 
 	if idx < 0 {
-		err = errors.New("go-data: invalid data string format")
+		err = ErrInvalidFormat
 		return
 	}
 
 	typeName := str[:idx]
-	str = str[idx+len(dataMetaEnd):]
+	str = str[idx+len(metaEnd):]
 
 	switch typeName {
 	case dataTypeJSON:
-		d, err = ParseJSON(str)
+		d, err = p.ParseJSON(str)
 	default:
-		err = fmt.Errorf("go-data: invalid data type '%v'", typeName)
+		err = fmt.Errorf("%w: unknown data type '%v'", ErrInvalidFormat, typeName)
 	}
 
 	return
 }
 
+// ParseJSONBytes 解析 JSON 并且生成 Data，规则与 ParseJSON 相同。
+// 默认情况下会复制一份 src，如果设置了 ZeroCopy 则直接使用 src 的内存。
+func (p *Parser) ParseJSONBytes(src []byte) (d Data, err error) {
+	var str string
+
+	if p.ZeroCopy {
+		str = *(*string)(unsafe.Pointer(&src))
+	} else {
+		str = string(src)
+	}
+
+	return p.ParseJSON(str)
+}
+
 // ParseJSON 解析 JSON 字符串并且生成 Data，如果解析过程出现任何错误则返回错误。
 // 由于 Data 是一个 map，所以 JSON 必须是一个 object，如果不是则返回错误。
-func ParseJSON(str string) (d Data, err error) {
+func (p *Parser) ParseJSON(str string) (d Data, err error) {
+	return p.parseJSON(str, nil)
+}
+
+// ParseJSONContext 与 ParseJSON 相同，但在解析过程中会定期检查 ctx，
+// 如果 ctx 已经结束则放弃解析并返回 ctx.Err()。
+func (p *Parser) ParseJSONContext(ctx context.Context, str string) (d Data, err error) {
+	return p.parseJSON(str, newContextChecker(ctx))
+}
+
+func (p *Parser) parseJSON(str string, checker *contextChecker) (d Data, err error) {
+	if inst := currentInstrumentation(p.Instrumentation); inst != nil {
+		start := time.Now()
+		defer func() {
+			inst.OnParse(len(str), time.Since(start), err)
+		}()
+	}
+
+	if err = checker.error(); err != nil {
+		return
+	}
+
 	if !gjson.Valid(str) {
-		err = errors.New("go-data: invalid JSON string")
+		err = ErrInvalidJSON
 		return
 	}
 
 	res := gjson.Parse(str)
 
 	if !res.IsObject() {
-		err = errors.New("go-data: JSON must be an object")
+		err = ErrNotObject
 		return
 	}
 
-	raw := RawData{}
-	parseJSONToRawData(raw, res)
+	jp := &jsonParser{
+		checker:  checker,
+		arena:    p.Arena,
+		unsigned: p.Unsigned,
+	}
+	raw := jp.newRawData()
+	jp.parseObject(raw, res)
+
+	if err = checker.error(); err != nil {
+		return
+	}
 
 	if len(raw) != 0 {
 		d = Data{
 			data: raw,
 		}
+
+		if p.KeepOrder {
+			d.order = parseJSONOrder(res)
+		}
 	}
 
 	return
 }
 
-func parseJSONValue(res gjson.Result) (v interface{}, t reflect.Type) {
+func metaMarks(begin, end string) (string, string) {
+	if begin == "" {
+		begin = dataMetaBegin
+	}
+
+	if end == "" {
+		end = dataMetaEnd
+	}
+
+	return begin, end
+}
+
+// jsonParser 保存解析 JSON 过程中需要用到的状态。
+type jsonParser struct {
+	checker  *contextChecker
+	arena    *Arena
+	unsigned bool
+}
+
+func (jp *jsonParser) newRawData() RawData {
+	if jp.arena == nil {
+		return RawData{}
+	}
+
+	return jp.arena.newRawData()
+}
+
+func (jp *jsonParser) parseValue(res gjson.Result) (v interface{}, t reflect.Type) {
 	switch res.Type {
 	case gjson.True:
 		v = true
@@ -124,9 +265,38 @@ func parseJSONValue(res gjson.Result) (v interface{}, t reflect.Type) {
 		t = typeOfBool
 		return
 	case gjson.Number:
+		// 先尝试精确的解析整数，避免大整数经过 float64 转换后丢失精度。
+		if i, err := strconv.ParseInt(res.Raw, 10, 64); err == nil {
+			if jp.unsigned && i >= 0 {
+				v = uint64(i)
+				t = typeOfUint64
+				return
+			}
+
+			v = i
+			t = typeOfInt64
+			return
+		}
+
+		// 超过 int64 范围的正整数只能用 uint64 表示，默认与之前一样解析成 float64。
+		if jp.unsigned {
+			if u, err := strconv.ParseUint(res.Raw, 10, 64); err == nil {
+				v = u
+				t = typeOfUint64
+				return
+			}
+		}
+
 		f := res.Float()
 
-		if f >= math.MinInt64 && f <= math.MaxInt64 && math.Round(f) == f {
+		// float64(math.MaxInt64) 等于 2^63，已经超出了 int64 的范围，所以这里不能使用 <=。
+		if f >= math.MinInt64 && f < math.MaxInt64 && math.Round(f) == f {
+			if jp.unsigned && f >= 0 {
+				v = uint64(f)
+				t = typeOfUint64
+				return
+			}
+
 			v = int64(f)
 			t = typeOfInt64
 			return
@@ -141,8 +311,8 @@ func parseJSONValue(res gjson.Result) (v interface{}, t reflect.Type) {
 		return
 	case gjson.JSON:
 		if res.IsObject() {
-			d := RawData{}
-			parseJSONToRawData(d, res)
+			d := jp.newRawData()
+			jp.parseObject(d, res)
 			v = d
 			t = typeOfObject
 			return
@@ -151,27 +321,51 @@ func parseJSONValue(res gjson.Result) (v interface{}, t reflect.Type) {
 		// 对于数组来说，需要根据数组元素的类型来决定 slice 的类型。
 		// 假如 slice 所有元素类型一致，那么需要尽可能的生成这个类型的 slice。
 		// 例如，如果里面都是整数，则 slice 类型是 []int64。
-		v, t = parseJSONArray(res.Array())
+		v, t = jp.parseArray(res.Array())
 		return
 	}
 
 	return
 }
 
-func parseJSONToRawData(d RawData, res gjson.Result) {
+func (jp *jsonParser) parseObject(d RawData, res gjson.Result) {
 	res.ForEach(func(key, value gjson.Result) bool {
-		v, _ := parseJSONValue(value)
+		if jp.checker.check() != nil {
+			return false
+		}
+
+		v, _ := jp.parseValue(value)
 		d[key.Str] = v
 		return true
 	})
 }
 
-func parseJSONArray(res []gjson.Result) (v interface{}, t reflect.Type) {
-	vals := make([]reflect.Value, 0, len(res))
+func (jp *jsonParser) parseArray(res []gjson.Result) (v interface{}, t reflect.Type) {
+	var vals []reflect.Value
 	var elemType reflect.Type
+	start := 0
+
+	if jp.arena == nil {
+		vals = make([]reflect.Value, 0, len(res))
+	} else {
+		// 使用 arena 中的临时缓冲，嵌套的数组会在缓冲后面继续追加，用完后截断归还，
+		// 这样不需要为每个数组都分配一次临时的 vals。
+		start = len(jp.arena.vals)
+	}
 
 	for _, r := range res {
-		val, vt := parseJSONValue(r)
+		if jp.checker.check() != nil {
+			break
+		}
+
+		val, vt := jp.parseValue(r)
+		rv := reflect.ValueOf(val)
+
+		// null 只能放在 []interface{} 里。
+		if vt == nil {
+			vt = typeOfInterface
+			rv = reflect.Zero(typeOfInterface)
+		}
 
 		if elemType == nil {
 			elemType = vt
@@ -179,7 +373,16 @@ func parseJSONArray(res []gjson.Result) (v interface{}, t reflect.Type) {
 			elemType = typeOfInterface
 		}
 
-		vals = append(vals, reflect.ValueOf(val))
+		if jp.arena == nil {
+			vals = append(vals, rv)
+		} else {
+			jp.arena.vals = append(jp.arena.vals, rv)
+		}
+	}
+
+	if jp.arena != nil {
+		vals = jp.arena.vals[start:]
+		defer jp.arena.releaseValues(start)
 	}
 
 	if elemType == nil {
@@ -194,18 +397,64 @@ func parseJSONArray(res []gjson.Result) (v interface{}, t reflect.Type) {
 }
 
 // MarshalJSON 将 d 序列化成 JSON。
+//
+// 序列化使用的缓冲区来自内部的对象池，返回值只会分配一次恰好够用的内存，
+// 这样 Data 嵌入在大结构中被 `json.Marshal` 序列化时可以减少内存分配。
 func (d Data) MarshalJSON() ([]byte, error) {
-	buf := &bytes.Buffer{}
-	d.json(buf, false)
+	buf := acquireJSONBuffer()
+	defer releaseJSONBuffer(buf)
+
+	if err := defaultFormatter.json(buf, d, false); err != nil {
+		return nil, err
+	}
+
+	return append([]byte(nil), buf.Bytes()...), nil
+}
+
+// AppendJSON 将 d 序列化成 JSON 并追加到 dst 后面，返回追加后的 slice。
+// 如果 dst 的容量足够，序列化过程不会分配新的内存，适合复用缓冲区输出大量 Data 的场景。
+//
+// 如果出错，返回的 slice 与 dst 相同。
+func (d Data) AppendJSON(dst []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(dst)
+
+	if err := defaultFormatter.json(buf, d, false); err != nil {
+		return dst, err
+	}
+
 	return buf.Bytes(), nil
 }
 
+const maxPooledJSONBufferSize = 64 * 1024
+
+var jsonBufferPool = sync.Pool{
+	New: func() interface{} {
+		return &bytes.Buffer{}
+	},
+}
+
+func acquireJSONBuffer() *bytes.Buffer {
+	return jsonBufferPool.Get().(*bytes.Buffer)
+}
+
+// releaseJSONBuffer 将 buf 放回对象池，过大的 buf 会被丢弃，避免长期占用内存。
+func releaseJSONBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledJSONBufferSize {
+		return
+	}
+
+	buf.Reset()
+	jsonBufferPool.Put(buf)
+}
+
 // UnmarshalJSON 解析 JSON 字符串并设置 d 的值。
 // 这里不直接使用 `json.Unmarshal` 来反序列化的原因是，`Data` 内部要求统一所有的数据类型，
 // 但 `json.Marshal` 无法满足这个要求。
+//
+// 解析过程会复制一份 src，调用者在解析完成后可以随意复用 src。
 func (d *Data) UnmarshalJSON(src []byte) error {
-	str := *(*string)(unsafe.Pointer(&src))
-	data, err := ParseJSON(str)
+	p := Parser{}
+	data, err := p.ParseJSONBytes(src)
 
 	if err != nil {
 		return err
@@ -219,6 +468,7 @@ func (d *Data) UnmarshalJSON(src []byte) error {
 //
 // 其中，query 的格式是以“.”分隔的字段，例如 a.b.c 代表访问 d["a"]["b"]["c"]。
 // 如果希望访问数组元素，可以直接写数组下标数字，比如 a.0.c 代表访问 d["a"][0]["c"]。
+// 如果 key 本身包含“.”，需要转义成“\.”，比如 a\.b.c 代表访问 d["a.b"]["c"]，详见 `SplitQuery`。
 func (d Data) Query(query string) interface{} {
 	return d.data.Query(query)
 }
@@ -227,15 +477,117 @@ func (d Data) Query(query string) interface{} {
 //
 // 其中，query 的格式是以“.”分隔的字段，例如 a.b.c 代表访问 d["a"]["b"]["c"]。
 // 如果希望访问数组元素，可以直接写数组下标数字，比如 a.0.c 代表访问 d["a"][0]["c"]。
+// 如果 key 本身包含“.”，需要转义成“\.”，比如 a\.b.c 代表访问 d["a.b"]["c"]，详见 `SplitQuery`。
 func (d RawData) Query(query string) interface{} {
 	if query == "" {
 		return d
 	}
 
-	fields := strings.Split(query, ".")
+	fields := SplitQuery(query)
 	return d.Get(fields...)
 }
 
+// QueryFirst 按顺序使用 queries 查询 d，返回第一个不为 nil 的值，如果都找不到则返回 nil。
+// 这适合用于兼容不同版本的文档，比如 `d.QueryFirst("new_name", "legacy_name")`。
+//
+// 其中，query 的格式详见 `Data#Query` 文档。
+func (d Data) QueryFirst(queries ...string) interface{} {
+	return d.data.QueryFirst(queries...)
+}
+
+// QueryFirst 按顺序使用 queries 查询 d，返回第一个不为 nil 的值，如果都找不到则返回 nil。
+//
+// 其中，query 的格式详见 `RawData#Query` 文档。
+func (d RawData) QueryFirst(queries ...string) interface{} {
+	for _, query := range queries {
+		if v := d.Query(query); v != nil {
+			return v
+		}
+	}
+
+	return nil
+}
+
+// Coalesce 按顺序在 ds 中查询 query，返回第一个不为 nil 的值，如果都找不到则返回 nil。
+//
+// 这适合用于分层配置之类的场景，比如 `Coalesce("timeout", userConf, appConf, defaultConf)`，
+// 与先 Merge 再 Query 相比，Coalesce 不需要复制任何数据。
+// 需要注意，Coalesce 返回的是某一个 Data 中的值，并不会合并多个 Data 中同一个 query 的值。
+func Coalesce(query string, ds ...Data) interface{} {
+	for _, d := range ds {
+		if d.Len() == 0 {
+			continue
+		}
+
+		if v := d.Query(query); v != nil {
+			return v
+		}
+	}
+
+	return nil
+}
+
+// Slice 返回 query 对应的数组中从 offset 开始、最多 limit 个元素组成的 slice，以及数组的总长度，
+// 这适合在 API 中对 Data 中很大的数组做分页。如果 limit 小于 0，返回 offset 之后的所有元素。
+//
+// 返回的 slice 与数组共享内存，不会复制任何元素，类型与数组的类型相同，比如 []RawData 或 []int64。
+// 因此不应该修改返回的 slice 中的元素，但 append 返回的 slice 不会影响 d。
+//
+// 如果 query 对应的值不存在或者不是数组，window 为 nil，total 为 0；
+// 如果 offset 超出范围，window 是一个空 slice。
+func (d Data) Slice(query string, offset, limit int) (window interface{}, total int) {
+	v := reflect.ValueOf(d.Query(query))
+
+	if v.Kind() != reflect.Slice {
+		return
+	}
+
+	total = v.Len()
+
+	if offset < 0 {
+		offset = 0
+	}
+
+	if offset > total {
+		offset = total
+	}
+
+	end := total
+
+	if limit >= 0 && limit < total-offset {
+		end = offset + limit
+	}
+
+	window = v.Slice3(offset, end, end).Interface()
+	return
+}
+
+// QueryGJSON 使用 gjson 的 path 语法查询 d，返回查询结果，如果找不到则返回 nil。
+// 与 Query 不同，path 支持 gjson 的全部语法，包括通配符、条件查询、modifier 和 multipath 等，
+// 比如 `d.QueryGJSON("friends.#(age>45)#.name")`，语法详见 https://github.com/tidwall/gjson。
+//
+// QueryGJSON 会先将 d 序列化成 JSON 再进行查询，查询结果会被转化成 Data 支持的类型，
+// 其中 object 会被转化成 RawData，修改返回值不会影响 d 的内容。
+// 如果 d 中存在无法序列化的值，返回错误。
+func (d Data) QueryGJSON(path string) (interface{}, error) {
+	buf := acquireJSONBuffer()
+	defer releaseJSONBuffer(buf)
+
+	if err := defaultFormatter.json(buf, d, false); err != nil {
+		return nil, err
+	}
+
+	res := gjson.Get(buf.String(), path)
+
+	if !res.Exists() {
+		return nil, nil
+	}
+
+	jp := &jsonParser{}
+	v, _ := jp.parseValue(res)
+	return v, nil
+}
+
 // Get 通过 fields 找到对应的值并且返回，如果找不到则返回 nil。
 //
 // 其中，field 是一个数组，例如 []string{"a", "b", "c"} 代表访问 d["a"]["b"]["c"]。
@@ -248,11 +600,17 @@ func (d Data) Get(fields ...string) interface{} {
 //
 // 其中，field 是一个数组，例如 []string{"a", "b", "c"} 代表访问 d["a"]["b"]["c"]。
 // 如果希望访问数组元素，可以直接写数组下标数字，比如 []string{"a", "0", "c"} 代表访问 d["a"][0]["c"]。
+//
+// 如果 d 中嵌入了其他 Data，Get 会直接访问这个 Data 内部的数据，就像访问 RawData 一样。
 func (d RawData) Get(fields ...string) interface{} {
 	if len(fields) == 0 {
 		return d
 	}
 
+	if tracer := currentQueryTracer(); tracer != nil {
+		return d.getTraced(fields, tracer)
+	}
+
 	v := d.get(fields, nil)
 
 	if !v.IsValid() {
@@ -309,6 +667,9 @@ func (d RawData) get(fields []string, modifier func(v reflect.Value) reflect.Val
 			val = val.Elem()
 		}
 
+		// 如果遇到嵌在树中的 Data，直接遍历它内部的 RawData。
+		val = unwrapData(val)
+
 		switch val.Kind() {
 		case reflect.Map:
 			t := val.Type()
@@ -437,6 +798,19 @@ func (d RawData) get(fields []string, modifier func(v reflect.Value) reflect.Val
 	return
 }
 
+func isData(val reflect.Value) bool {
+	return val.IsValid() && val.Type() == typeOfData
+}
+
+// unwrapData 如果 val 是一个 Data，返回它内部的 RawData，否则直接返回 val。
+func unwrapData(val reflect.Value) reflect.Value {
+	if isData(val) {
+		return reflect.ValueOf(val.Interface().(Data).data)
+	}
+
+	return val
+}
+
 // Delete 将 query 中查询到的值删除。
 // 如果 query 为空字符串，则清空 d。
 func (d *RawData) Delete(queries ...string) {
@@ -453,8 +827,20 @@ func (d *RawData) Delete(queries ...string) {
 	}
 }
 
+// DeletePath 将 paths 中每个路径对应的值删除，规则与 `RawData#Delete` 相同。
+// 如果某个 path 为空，则清空 d。
+func (d *RawData) DeletePath(paths ...Path) {
+	queries := make([]string, 0, len(paths))
+
+	for _, p := range paths {
+		queries = append(queries, p.String())
+	}
+
+	d.Delete(queries...)
+}
+
 func (d RawData) delete(query string) {
-	fields := strings.Split(query, ".")
+	fields := SplitQuery(query)
 	target := fields[len(fields)-1] // 最后一个 key 是目标 key。
 	fields = fields[:len(fields)-1]
 
@@ -469,6 +855,15 @@ func (d RawData) delete(query string) {
 
 		v = val
 
+		// 对于嵌在树中的 Data，直接删除它内部 RawData 的 key，返回值依然是原来的 Data。
+		if isData(val) {
+			if inner := unwrapData(val); !inner.IsNil() {
+				inner.SetMapIndex(reflect.ValueOf(target), reflect.Value{})
+			}
+
+			return
+		}
+
 		switch val.Kind() {
 		case reflect.Map:
 			if val.Type().AssignableTo(typeOfObject) {
@@ -504,52 +899,168 @@ func (d RawData) delete(query string) {
 	})
 }
 
-// JSON 返回 d 对应的 JSON 字符串。
-// 如果 pretty 为 true，会为打印优化输出格式。
-func (d Data) JSON(pretty bool) string {
-	buf := &bytes.Buffer{}
-	d.json(buf, pretty)
-	return buf.String()
-}
-
-func (d Data) json(buf *bytes.Buffer, pretty bool) {
-	if d.Len() == 0 {
-		buf.WriteString("{}")
+// deleteMatched 删除 query 对应数组中所有符合 cond 的元素，cond 的规则详见 `Patch#DeleteMatched`。
+func (d RawData) deleteMatched(query string, cond Data) {
+	if len(d) == 0 || cond.Len() == 0 {
 		return
 	}
 
-	enc := json.NewEncoder(buf)
-	enc.SetEscapeHTML(false)
+	var fields []string
+
+	if query != "" {
+		fields = SplitQuery(query)
+	}
+
+	d.get(fields, func(val reflect.Value) reflect.Value {
+		slice := val
+
+		for slice.Kind() == reflect.Interface {
+			slice = slice.Elem()
+		}
+
+		if slice.Kind() != reflect.Slice {
+			return val
+		}
+
+		l := slice.Len()
+		filtered := reflect.MakeSlice(slice.Type(), 0, l)
+
+		for i := 0; i < l; i++ {
+			elem := slice.Index(i)
+
+			if !matchCond(elem.Interface(), cond.data) {
+				filtered = reflect.Append(filtered, elem)
+			}
+		}
 
-	if pretty {
-		enc.SetIndent("", "\t")
+		if filtered.Len() == l {
+			return val
+		}
+
+		return filtered
+	})
+}
+
+func matchCond(v interface{}, cond RawData) bool {
+	var elem RawData
+
+	switch val := v.(type) {
+	case RawData:
+		elem = val
+	case Data:
+		elem = val.data
 	}
 
-	enc.Encode(d.data)
+	for k, expected := range cond {
+		var actual interface{}
 
-	// 干掉最后多余的那个 \n
-	data := buf.Bytes()
+		if k == "" {
+			actual = v
+		} else if elem != nil {
+			actual = elem.Query(k)
+		} else {
+			return false
+		}
 
-	if len(data) > 0 && data[len(data)-1] == '\n' {
-		buf.Truncate(buf.Len() - 1)
+		if !reflect.DeepEqual(actual, expected) {
+			return false
+		}
 	}
+
+	return true
+}
+
+// set 将 query 对应的值设置为 v，如果 query 对应的值不存在则新建一个。
+// 如果 query 的上一级不存在，或者上一级是一个 slice 但下标越界、类型不匹配，返回 false。
+func (d RawData) set(query string, v interface{}) (ok bool) {
+	fields := SplitQuery(query)
+	target := fields[len(fields)-1] // 最后一个 key 是目标 key。
+	fields = fields[:len(fields)-1]
+
+	d.get(fields, func(val reflect.Value) reflect.Value {
+		parent := val
+
+		for val.Kind() == reflect.Interface {
+			val = val.Elem()
+		}
+
+		val = unwrapData(val)
+
+		switch val.Kind() {
+		case reflect.Map:
+			if val.IsNil() || !val.Type().AssignableTo(typeOfObject) {
+				return parent
+			}
+
+			if v == nil {
+				val.SetMapIndex(reflect.ValueOf(target), reflect.Zero(typeOfInterface))
+			} else {
+				val.SetMapIndex(reflect.ValueOf(target), reflect.ValueOf(v))
+			}
+
+			ok = true
+
+		case reflect.Slice:
+			idx, err := strconv.ParseInt(target, 10, 64)
+
+			if err != nil {
+				return parent
+			}
+
+			i := int(idx)
+
+			if i < 0 || i >= val.Len() {
+				return parent
+			}
+
+			elem := val.Index(i)
+
+			if v == nil {
+				elem.Set(reflect.Zero(elem.Type()))
+				ok = true
+				return parent
+			}
+
+			rv := reflect.ValueOf(v)
+
+			if !rv.Type().AssignableTo(elem.Type()) {
+				return parent
+			}
+
+			elem.Set(rv)
+			ok = true
+		}
+
+		return parent
+	})
+	return
+}
+
+// JSON 返回 d 对应的 JSON 字符串。
+// 如果 pretty 为 true，会为打印优化输出格式。
+func (d Data) JSON(pretty bool) string {
+	return defaultFormatter.JSON(d, pretty)
+}
+
+// JSONE 返回 d 对应的 JSON 字符串，如果 d 中存在无法序列化的值则返回错误。
+// 如果 pretty 为 true，会为打印优化输出格式。
+func (d Data) JSONE(pretty bool) (string, error) {
+	return defaultFormatter.JSONE(d, pretty)
 }
 
 // PrettyString 输出用于打印输出的存储格式。
 func (d Data) PrettyString() string {
-	buf := &bytes.Buffer{}
-	buf.WriteString(dataMetaBegin + dataTypeJSON + dataMetaEnd)
-	buf.WriteRune('\n')
-	d.json(buf, true)
-	return buf.String()
+	return defaultFormatter.PrettyString(d)
 }
 
 // String 返回 d 的可存储格式，这个格式可以用 Parse 解析并还原成 Data 结构。
 func (d Data) String() string {
-	buf := &bytes.Buffer{}
-	buf.WriteString(dataMetaBegin + dataTypeJSON + dataMetaEnd)
-	d.json(buf, false)
-	return buf.String()
+	return defaultFormatter.String(d)
+}
+
+// StringE 返回 d 的可存储格式，如果 d 中存在无法序列化的值则返回错误。
+func (d Data) StringE() (string, error) {
+	return defaultFormatter.StringE(d)
 }
 
 // Len 返回 d 的数据个数。
@@ -557,7 +1068,95 @@ func (d Data) Len() int {
 	return len(d.data)
 }
 
+// Keys 返回 d 顶层所有的 key。
+// 如果 d 是由设置了 KeepOrder 的 Parser 解析得到的，key 按照出现的顺序返回，否则按照字典序返回。
+func (d Data) Keys() []string {
+	if d.Len() == 0 {
+		return nil
+	}
+
+	return d.order.sortedKeys(d.data)
+}
+
 // Clone 复制一份 d 的内容。
 func (d Data) Clone() Data {
 	return Merge(d)
 }
+
+// Merge 将 others 从左至右合并到 d 的副本中并返回，d 本身不会被修改，
+// 等价于 `Merge(append([]Data{d}, others...)...)`，合并规则详见 `Merge` 文档。
+//
+// 这适合用链式调用构造文档，比如 `d.Merge(defaults).Merge(overrides)`。
+func (d Data) Merge(others ...Data) Data {
+	data := make([]Data, 0, len(others)+1)
+	data = append(data, d)
+	data = append(data, others...)
+	return Merge(data...)
+}
+
+// MergeInPlace 将 others 从左至右直接合并到 d 中，等价于 `MergeTo(d, others...)`，合并规则详见 `Merge` 文档。
+func (d *Data) MergeInPlace(others ...Data) {
+	MergeTo(d, others...)
+}
+
+// ToMap 返回 d 的深拷贝，其中所有的 RawData 和嵌入的 Data 都会被转化成 map[string]interface{}，
+// 元素是 RawData 或 Data 的 slice 会被转化成 []interface{}，其他 slice 会被复制一份。
+// 这适合将 Data 交给模板引擎、校验器等只认识普通 map 的第三方库使用。
+//
+// 修改返回值不会影响 d 的内容。如果 d 为空，返回一个空 map 而不是 nil。
+func (d Data) ToMap() map[string]interface{} {
+	return toPlainMap(d.data)
+}
+
+func toPlainMap(d RawData) map[string]interface{} {
+	m := make(map[string]interface{}, len(d))
+
+	for k, v := range d {
+		m[k] = toPlainValue(v)
+	}
+
+	return m
+}
+
+func toPlainValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case RawData:
+		if val == nil {
+			return nil
+		}
+
+		return toPlainMap(val)
+	case Data:
+		if val.data == nil {
+			return nil
+		}
+
+		return toPlainMap(val.data)
+	}
+
+	rv := reflect.ValueOf(v)
+
+	if rv.Kind() != reflect.Slice {
+		return cloneValue(v)
+	}
+
+	if rv.IsNil() {
+		return v
+	}
+
+	switch rv.Type().Elem() {
+	case typeOfObject, typeOfData, typeOfInterface:
+		l := rv.Len()
+		s := make([]interface{}, l)
+
+		for i := 0; i < l; i++ {
+			s[i] = toPlainValue(rv.Index(i).Interface())
+		}
+
+		return s
+	}
+
+	return cloneValue(v)
+}
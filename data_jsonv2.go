@@ -0,0 +1,19 @@
+//go:build goexperiment.jsonv2 && go1.27
+// +build goexperiment.jsonv2,go1.27
+
+package data
+
+import "encoding/json/jsontext"
+
+// MarshalJSONTo 实现了 encoding/json/v2 的 MarshalerTo 接口，
+// 直接将 d 的序列化结果写入 enc，不需要像 MarshalJSON 那样为返回值分配内存。
+func (d Data) MarshalJSONTo(enc *jsontext.Encoder) error {
+	buf := acquireJSONBuffer()
+	defer releaseJSONBuffer(buf)
+
+	if err := defaultFormatter.json(buf, d, false); err != nil {
+		return err
+	}
+
+	return enc.WriteValue(jsontext.Value(buf.Bytes()))
+}
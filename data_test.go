@@ -3,6 +3,7 @@ package data
 import (
 	"bytes"
 	"encoding/json"
+	"math"
 	"reflect"
 	"strings"
 	"testing"
@@ -84,6 +85,162 @@ loop:
 	}
 }
 
+func TestDataQueryFirst(t *testing.T) {
+	cases := []struct {
+		Data    Data
+		Queries []string
+		Result  interface{}
+	}{
+		{ // 没有 query。
+			fullData,
+			nil,
+			nil,
+		},
+		{ // 第一个 query 就能找到。
+			Make(RawData{
+				"new_name": 1,
+				"legacy":   RawData{"name": 2},
+			}),
+			[]string{"new_name", "legacy.name"},
+			int64(1),
+		},
+		{ // 回退到后面的 query。
+			Make(RawData{
+				"legacy": RawData{"name": 2},
+			}),
+			[]string{"new_name", "legacy.name"},
+			int64(2),
+		},
+		{ // 零值不是 nil，不应该回退。
+			Make(RawData{
+				"new_name": "",
+				"legacy":   RawData{"name": 2},
+			}),
+			[]string{"new_name", "legacy.name"},
+			"",
+		},
+		{ // 都找不到。
+			Make(RawData{
+				"other": 1,
+			}),
+			[]string{"new_name", "legacy.name"},
+			nil,
+		},
+	}
+	a := assert.New(t)
+
+	for i, c := range cases {
+		a.Use(&i, &c)
+		a.Equal(c.Data.QueryFirst(c.Queries...), c.Result)
+	}
+}
+
+func TestCoalesce(t *testing.T) {
+	a := assert.New(t)
+	user := Make(RawData{
+		"timeout": 10,
+	})
+	app := Make(RawData{
+		"timeout": 20,
+		"server": RawData{
+			"host": "app",
+		},
+	})
+	defaults := Make(RawData{
+		"timeout": 30,
+		"server": RawData{
+			"host": "localhost",
+			"port": 80,
+		},
+	})
+
+	a.Equal(Coalesce("timeout", user, app, defaults), int64(10))
+	a.Equal(Coalesce("timeout", Data{}, app, defaults), int64(20))
+	a.Equal(Coalesce("server.host", user, app, defaults), "app")
+	a.Equal(Coalesce("server.port", user, app, defaults), int64(80))
+	a.Equal(Coalesce("server", user, app, defaults), RawData{"host": "app"})
+	a.Equal(Coalesce("not_exist", user, app, defaults), nil)
+	a.Equal(Coalesce("", Data{}, user), RawData(user.data))
+	a.Equal(Coalesce("timeout"), nil)
+}
+
+func TestDataSlice(t *testing.T) {
+	d := Make(RawData{
+		"list": []int{1, 2, 3, 4, 5},
+		"objs": []RawData{{"a": 1}, {"b": 2}},
+		"name": "name",
+	})
+	cases := []struct {
+		Query  string
+		Offset int
+		Limit  int
+		Window interface{}
+		Total  int
+	}{
+		{"list", 0, 2, []int64{1, 2}, 5},
+		{"list", 2, 2, []int64{3, 4}, 5},
+		{"list", 4, 2, []int64{5}, 5},
+		{"list", 5, 2, []int64{}, 5},
+		{"list", 10, 2, []int64{}, 5},
+		{"list", -1, 1, []int64{1}, 5},
+		{"list", 1, -1, []int64{2, 3, 4, 5}, 5},
+		{"list", 1, 0, []int64{}, 5},
+		{"objs", 1, 10, []RawData{{"b": int64(2)}}, 2},
+		{"name", 0, 1, nil, 0},
+		{"not_exist", 0, 1, nil, 0},
+	}
+	a := assert.New(t)
+
+	for i, c := range cases {
+		a.Use(&i, &c)
+		window, total := d.Slice(c.Query, c.Offset, c.Limit)
+		a.Equal(window, c.Window)
+		a.Equal(total, c.Total)
+	}
+
+	// append 返回值不会影响 d。
+	window, _ := d.Slice("list", 0, 2)
+	_ = append(window.([]int64), 100)
+	a.Equal(d.Get("list"), []int64{1, 2, 3, 4, 5})
+}
+
+func TestDataQueryGJSON(t *testing.T) {
+	d := Make(RawData{
+		"name": "go-data",
+		"friends": []RawData{
+			{"name": "alice", "age": 44},
+			{"name": "bob", "age": 68},
+			{"name": "carol", "age": 47},
+		},
+		"tags": []string{"a", "b"},
+	})
+	cases := []struct {
+		Path   string
+		Result interface{}
+	}{
+		{"name", "go-data"},
+		{"friends.1.age", int64(68)},
+		{"friends.#", int64(3)},
+		{"friends.#.name", []string{"alice", "bob", "carol"}},
+		{"friends.#(age>45)#.name", []string{"bob", "carol"}},
+		{"friends.#(name==alice)", RawData{"name": "alice", "age": int64(44)}},
+		{"{name,tags}", RawData{"name": "go-data", "tags": []string{"a", "b"}}},
+		{"tags|@reverse", []string{"b", "a"}},
+		{"not_exist", nil},
+	}
+	a := assert.New(t)
+
+	for i, c := range cases {
+		a.Use(&i, &c)
+		v, err := d.QueryGJSON(c.Path)
+		a.NilError(err)
+		a.Equal(v, c.Result)
+	}
+
+	_, err := Make(RawData{"nan": math.NaN()}).QueryGJSON("nan")
+	a.NonNilError(err)
+}
+
 var (
 	complexData = Make(RawData{
 		"int":    123,
@@ -234,6 +391,76 @@ func TestDataParse(t *testing.T) {
 	}
 }
 
+func TestDataParseUnsigned(t *testing.T) {
+	a := assert.New(t)
+	str := `{"big":9007199254740993,"huge":18446744073709551615,"neg":-1,"float":2.5,"exp":1e3,"list":[1,2]}`
+
+	d, err := ParseJSON(str)
+	a.NilError(err)
+	a.Equal(d.data, RawData{
+		"big":   int64(9007199254740993),
+		"huge":  float64(18446744073709551615),
+		"neg":   int64(-1),
+		"float": 2.5,
+		"exp":   int64(1000),
+		"list":  []int64{1, 2},
+	})
+
+	p := &Parser{
+		Unsigned: true,
+	}
+	d, err = p.ParseJSON(str)
+	a.NilError(err)
+	a.Equal(d.data, RawData{
+		"big":   uint64(9007199254740993),
+		"huge":  uint64(18446744073709551615),
+		"neg":   int64(-1),
+		"float": 2.5,
+		"exp":   uint64(1000),
+		"list":  []uint64{1, 2},
+	})
+
+	// 设置 Unsigned 之后，Encoder 编码的无符号整数在序列化之后类型不变。
+	encoded := Make(RawData{
+		"id":  uint32(123),
+		"ids": []uint{1, 2},
+	})
+	d, err = p.ParseJSON(encoded.JSON(false))
+	a.NilError(err)
+	a.Equal(d, encoded)
+
+	var v struct {
+		Big  int64   `data:"big"`
+		Huge uint64  `data:"huge"`
+		Neg  int     `data:"neg"`
+		List []int   `data:"list"`
+		Exp  float64 `data:"exp"`
+	}
+	d, err = p.ParseJSON(str)
+	a.NilError(err)
+	dec := &Decoder{}
+	a.NilError(dec.Decode(d, &v))
+	a.Equal(v.Big, int64(9007199254740993))
+	a.Equal(v.Huge, uint64(18446744073709551615))
+	a.Equal(v.Neg, -1)
+	a.Equal(v.List, []int{1, 2})
+	a.Equal(v.Exp, 1000.0)
+}
+
+func TestDataParseNullElement(t *testing.T) {
+	a := assert.New(t)
+	d, err := ParseJSON(`{"a":[null,1],"b":[null],"c":[[null,"x"],["y"]]}`)
+	a.NilError(err)
+
+	// null 元素只能放在 []interface{} 里。
+	a.Equal(d.data, RawData{
+		"a": []interface{}{nil, int64(1)},
+		"b": []interface{}{nil},
+		"c": []interface{}{[]interface{}{nil, "x"}, []string{"y"}},
+	})
+	a.NilError(d.validate())
+}
+
 func TestDataJSONUnmarshal(t *testing.T) {
 	cases := []struct {
 		JSON     string
@@ -280,3 +507,276 @@ func TestDataJSONUnmarshal(t *testing.T) {
 		a.Equal(c.Value, actual.Interface())
 	}
 }
+
+func TestDataJSONError(t *testing.T) {
+	a := assert.New(t)
+	d := Make(RawData{
+		"nan": math.NaN(),
+	})
+
+	_, err := d.JSONE(false)
+	a.NonNilError(err)
+	_, err = d.JSONE(true)
+	a.NonNilError(err)
+	_, err = d.StringE()
+	a.NonNilError(err)
+	_, err = d.MarshalJSON()
+	a.NonNilError(err)
+	_, err = json.Marshal(d)
+	a.NonNilError(err)
+
+	// 定制输出的时候也需要报错。
+	f := &Formatter{
+		FloatKeepPoint: true,
+	}
+	_, err = f.JSONE(Make(RawData{"nan": []float64{math.NaN()}}), false)
+	a.NonNilError(err)
+
+	str, err := complexData.StringE()
+	a.NilError(err)
+	a.Equal(str, complexData.String())
+	str, err = complexData.JSONE(true)
+	a.NilError(err)
+	a.Equal(str, complexDataJSON)
+}
+
+func TestDataAppendJSON(t *testing.T) {
+	a := assert.New(t)
+	d := Make(RawData{
+		"foo": 123,
+		"bar": []string{"a", "<b>"},
+	})
+	expected := `{"bar":["a","<b>"],"foo":123}`
+
+	buf := make([]byte, 0, 64)
+	buf = append(buf, "prefix:"...)
+	out, err := d.AppendJSON(buf)
+	a.NilError(err)
+	a.Equal(string(out), "prefix:"+expected)
+	a.Assert(&out[0] == &buf[:1][0])
+
+	out, err = Make(RawData{"nan": math.NaN()}).AppendJSON(buf)
+	a.NonNilError(err)
+	a.Equal(string(out), "prefix:")
+
+	// 多次调用 MarshalJSON 不会相互影响。
+	b1, err := d.MarshalJSON()
+	a.NilError(err)
+	b2, err := Make(RawData{"a": 1}).MarshalJSON()
+	a.NilError(err)
+	a.Equal(string(b1), expected)
+	a.Equal(string(b2), `{"a":1}`)
+
+	// Data 嵌入在其他结构中时也可以正常序列化。
+	var v struct {
+		Name string `json:"name"`
+		Data Data   `json:"data"`
+	}
+	v.Name = "embedded"
+	v.Data = Make(RawData{"foo": 123})
+	out, err = json.Marshal(v)
+	a.NilError(err)
+	a.Equal(string(out), `{"name":"embedded","data":{"foo":123}}`)
+}
+
+func TestDataJSONComplex(t *testing.T) {
+	a := assert.New(t)
+	d := Make(RawData{
+		"c":    complex(1, -2.5),
+		"list": []complex64{complex(0, 1)},
+		"map": RawData{
+			"c": complex(3, 0),
+		},
+	})
+	str, err := d.JSONE(false)
+	a.NilError(err)
+	a.Equal(str, `{"c":{"re":1,"im":-2.5},"list":[{"re":0,"im":1}],"map":{"c":{"re":3,"im":0}}}`)
+
+	pretty, err := d.JSONE(true)
+	a.NilError(err)
+	a.Equal(pretty, `{
+	"c": {
+		"re": 1,
+		"im": -2.5
+	},
+	"list": [
+		{
+			"re": 0,
+			"im": 1
+		}
+	],
+	"map": {
+		"c": {
+			"re": 3,
+			"im": 0
+		}
+	}
+}`)
+
+	s, err := d.StringE()
+	a.NilError(err)
+	a.Equal(s, "<json>"+str)
+
+	f := &Formatter{
+		FloatKeepPoint: true,
+	}
+	a.Equal(f.JSON(d, false), `{"c":{"re":1.0,"im":-2.5},"list":[{"re":0.0,"im":1.0}],"map":{"c":{"re":3.0,"im":0.0}}}`)
+
+	// 解析之后可以通过 Decoder 还原成复数。
+	parsed, err := Parse(s)
+	a.NilError(err)
+
+	var v struct {
+		C    complex128            `data:"c"`
+		List []complex64           `data:"list"`
+		Map  map[string]complex128 `data:"map"`
+	}
+	dec := &Decoder{}
+	a.NilError(dec.Decode(parsed, &v))
+	a.Equal(v.C, complex(1, -2.5))
+	a.Equal(v.List, []complex64{complex(0, 1)})
+	a.Equal(v.Map, map[string]complex128{"c": complex(3, 0)})
+
+	// key 不是字符串的 map 不能解析成复数。
+	var c struct {
+		C complex128 `data:"c"`
+	}
+	a.NonNilError(dec.Decode(Make(RawData{"c": map[int]float64{1: 2}}), &c))
+
+	type key string
+	a.NilError(dec.Decode(Make(RawData{"c": map[key]float64{"re": 2, "im": 3}}), &c))
+	a.Equal(c.C, complex(2, 3))
+}
+
+func TestDataJSONUnmarshalCopy(t *testing.T) {
+	a := assert.New(t)
+	src := []byte(`{"key":"value","arr":["s1"]}`)
+	expected := Make(RawData{
+		"key": "value",
+		"arr": []string{"s1"},
+	})
+
+	var d Data
+	a.NilError(d.UnmarshalJSON(src))
+	a.Equal(d, expected)
+
+	// 修改 src 不能影响已经解析好的 d。
+	copy(src, bytes.Repeat([]byte("x"), len(src)))
+	a.Equal(d, expected)
+
+	p := &Parser{
+		ZeroCopy: true,
+	}
+	src = []byte(`{"key":"value"}`)
+	d, err := p.ParseJSONBytes(src)
+	a.NilError(err)
+	a.Equal(d, Make(RawData{
+		"key": "value",
+	}))
+}
+
+func TestDataEmbeddedData(t *testing.T) {
+	a := assert.New(t)
+	makeData := func() Data {
+		return Data{
+			data: RawData{
+				"server": Make(RawData{
+					"host": "localhost",
+					"port": 8080,
+					"tags": []string{"a", "b", "c"},
+				}),
+				"list": []Data{
+					Make(RawData{"name": "first"}),
+					{},
+				},
+			},
+		}
+	}
+	d := makeData()
+
+	a.Equal(d.Query("server.host"), "localhost")
+	a.Equal(d.Get("server", "port"), int64(8080))
+	a.Equal(d.Query("server.tags.1"), "b")
+	a.Equal(d.Query("list.0.name"), "first")
+	a.Equal(d.Query("list.1.name"), nil)
+	a.Equal(d.Query("server.not_exist"), nil)
+
+	d.data.Delete("server.port", "server.tags.0", "list.1.name")
+	a.Equal(d.Query("server"), Make(RawData{
+		"host": "localhost",
+		"tags": []string{"b", "c"},
+	}))
+
+	patch := NewPatch()
+	patch.Add(nil, map[string]Data{
+		"server": Make(RawData{
+			"port": 9090,
+		}),
+	})
+	patched, err := patch.Apply(makeData())
+	a.NilError(err)
+	a.Equal(patched.Query("server.port"), int64(9090))
+
+	// 空 Data 无法被 patch。
+	patch = NewPatch()
+	patch.Add(nil, map[string]Data{
+		"list.1": Make(RawData{
+			"name": "second",
+		}),
+	})
+	_, err = patch.Apply(makeData())
+	a.NonNilError(err)
+
+	var v struct {
+		Server struct {
+			Host string   `data:"host"`
+			Port int      `data:"port"`
+			Tags []string `data:"tags"`
+		} `data:"server"`
+		List []struct {
+			Name string `data:"name"`
+		} `data:"list"`
+	}
+	dec := &Decoder{}
+	a.NilError(dec.Decode(makeData(), &v))
+	a.Equal(v.Server.Host, "localhost")
+	a.Equal(v.Server.Port, 8080)
+	a.Equal(v.Server.Tags, []string{"a", "b", "c"})
+	a.Equal(len(v.List), 2)
+	a.Equal(v.List[0].Name, "first")
+}
+
+func TestDataToMap(t *testing.T) {
+	a := assert.New(t)
+	d := Data{
+		data: RawData{
+			"int":  int64(1),
+			"ints": []int64{1, 2},
+			"map": RawData{
+				"list": []RawData{{"a": "b"}},
+			},
+			"data": Make(RawData{"c": true}),
+			"any":  []interface{}{RawData{"d": 1.5}, "s"},
+			"nil":  RawData(nil),
+		},
+	}
+	m := d.ToMap()
+	a.Equal(m, map[string]interface{}{
+		"int":  int64(1),
+		"ints": []int64{1, 2},
+		"map": map[string]interface{}{
+			"list": []interface{}{map[string]interface{}{"a": "b"}},
+		},
+		"data": map[string]interface{}{"c": true},
+		"any":  []interface{}{map[string]interface{}{"d": 1.5}, "s"},
+		"nil":  nil,
+	})
+
+	// 修改返回值不会影响 d。
+	m["ints"].([]int64)[0] = 100
+	m["map"].(map[string]interface{})["list"].([]interface{})[0].(map[string]interface{})["a"] = "changed"
+	a.Equal(d.Query("ints.0"), int64(1))
+	a.Equal(d.Query("map.list.0.a"), "b")
+
+	a.Equal(Data{}.ToMap(), map[string]interface{}{})
+}
@@ -0,0 +1,19 @@
+module github.com/altstory/go-data/datahttp
+
+go 1.26.0
+
+require (
+	github.com/altstory/go-data v0.0.0-20261015150310-648e7655041d
+	github.com/huandu/go-assert v1.1.5
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/huandu/go-clone v1.1.0 // indirect
+	github.com/tidwall/gjson v1.4.0 // indirect
+	github.com/tidwall/match v1.0.1 // indirect
+	github.com/tidwall/pretty v1.0.0 // indirect
+	golang.org/x/text v0.42.0 // indirect
+)
+
+replace github.com/altstory/go-data => ../
@@ -0,0 +1,51 @@
+package datahttp
+
+import (
+	"net/http"
+
+	data "github.com/altstory/go-data"
+)
+
+// Handler 是一个用于调试的 http.Handler，类似 expvar，会以格式化的 JSON 输出当前的 Data，
+// 方便运维人员查看正在运行的服务实际生效的配置。
+//
+// Load 可以是 `data.SyncData#Load`、`data.FileSource#Snapshot`、`Source#Snapshot` 等任何返回当前 Data 的函数。
+// 如果设置了 Policy，输出之前会使用 `data.Data#FilterByPolicy` 过滤掉 Role 无权查看的字段，
+// 比如可以用来隐藏密码等敏感配置。
+//
+//     http.Handle("/debug/config", &datahttp.Handler{
+//         Load: source.Snapshot,
+//     })
+type Handler struct {
+	Load   func() data.Data // 返回需要输出的 Data，不能为 nil。
+	Policy data.Policy      // 如果不为 nil，输出前使用 Policy 过滤 Data。
+	Role   string           // 使用 Policy 过滤时的角色。
+}
+
+var _ http.Handler = (*Handler)(nil)
+
+// ServeHTTP 以格式化的 JSON 输出 Load 返回的 Data，只支持 GET 和 HEAD 请求。
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	d := h.Load()
+
+	if h.Policy != nil {
+		d = d.FilterByPolicy(h.Policy, h.Role)
+	}
+
+	str, err := d.JSONE(true)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write([]byte(str))
+}
@@ -0,0 +1,72 @@
+package datahttp
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	data "github.com/altstory/go-data"
+	"github.com/huandu/go-assert"
+)
+
+func TestHandler(t *testing.T) {
+	d := data.Make(data.RawData{
+		"name": "service",
+		"db": data.RawData{
+			"user":     "root",
+			"password": "secret",
+		},
+	})
+	cases := []struct {
+		Handler *Handler
+		Method  string
+		Status  int
+		Body    string
+	}{
+		{
+			&Handler{Load: func() data.Data { return d }},
+			http.MethodGet,
+			http.StatusOK,
+			"{\n\t\"db\": {\n\t\t\"password\": \"secret\",\n\t\t\"user\": \"root\"\n\t},\n\t\"name\": \"service\"\n}",
+		},
+		{
+			&Handler{
+				Load:   func() data.Data { return d },
+				Policy: data.Policy{"ops": {"name", "db.user"}},
+				Role:   "ops",
+			},
+			http.MethodGet,
+			http.StatusOK,
+			"{\n\t\"db\": {\n\t\t\"user\": \"root\"\n\t},\n\t\"name\": \"service\"\n}",
+		},
+		{
+			&Handler{Load: func() data.Data { return d }},
+			http.MethodPost,
+			http.StatusMethodNotAllowed,
+			"Method Not Allowed\n",
+		},
+		{
+			&Handler{Load: func() data.Data { return data.Make(data.RawData{"nan": math.NaN()}) }},
+			http.MethodGet,
+			http.StatusInternalServerError,
+			"",
+		},
+	}
+	a := assert.New(t)
+
+	for i, c := range cases {
+		a.Use(&i, &c)
+		w := httptest.NewRecorder()
+		c.Handler.ServeHTTP(w, httptest.NewRequest(c.Method, "/debug/config", nil))
+		a.Equal(w.Code, c.Status)
+
+		if c.Body != "" {
+			a.Equal(w.Body.String(), c.Body)
+		}
+
+		if c.Status == http.StatusOK {
+			a.Equal(w.Header().Get("Content-Type"), "application/json; charset=utf-8")
+		}
+	}
+}
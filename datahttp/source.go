@@ -0,0 +1,196 @@
+// Package datahttp 提供 go-data 与 HTTP 相关的工具：Source 通过 HTTP 读取 Data 文档，
+// Handler 以 JSON 输出当前的 Data，方便调试。
+//
+// 这个子模块有独立的 `go.mod`，这样只使用 go-data 主模块的程序不会引入 net/http。
+package datahttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	data "github.com/altstory/go-data"
+)
+
+// DefaultMaxBodySize 是 Source 默认允许的文档大小上限。
+const DefaultMaxBodySize = 10 << 20
+
+// ErrBodyTooLarge 是文档超过 `Source#MaxBodySize` 时 Fetch 返回的错误。
+var ErrBodyTooLarge = errors.New("go-data: response body too large")
+
+// Source 定期从 URL 获取一个 Data 文档，适合用来读取远程的功能开关之类的配置。
+//
+// 文档的格式与 `data.Data#String` 的输出相同，比如 `<json>{"a":1}`。
+// Source 会记录服务端返回的 ETag，并在下次请求时通过 If-None-Match 询问文档是否变化，
+// 如果服务端返回 304 Not Modified，则不会重新下载和解析文档。
+type Source struct {
+	URL         string        // 文档的 URL。
+	Interval    time.Duration // 定期获取文档的间隔，如果为 0，使用 data.DefaultPollInterval。
+	Client      *http.Client  // 发送请求的 client，如果为 nil，使用 http.DefaultClient。
+	Parser      *data.Parser  // 用来解析文档的 Parser，如果为 nil，使用 Parser 的零值。
+	MaxBodySize int64         // 文档大小的上限，超过时 Fetch 返回 ErrBodyTooLarge，如果为 0，使用 DefaultMaxBodySize。
+
+	// OnError 在 Watch 过程中请求或解析文档出错时被调用，如果为 nil 则忽略这些错误。
+	OnError func(err error)
+
+	mu       sync.Mutex
+	handlers []func(d data.Data)
+	etag     string
+	hash     uint64
+	loaded   bool
+	snapshot data.SyncData
+}
+
+// NewSource 创建一个从 url 获取文档的 Source。
+func NewSource(url string) *Source {
+	return &Source{
+		URL: url,
+	}
+}
+
+// Fetch 立即请求一次文档，如果文档发生了变化，替换当前的快照并调用所有 OnUpdate 设置的回调。
+// 如果服务端返回 304 或者文档内容没有变化，直接返回 nil。
+//
+// Fetch 不会在请求过程中持有锁，多个 Fetch 可以并发执行。
+func (src *Source) Fetch(ctx context.Context) error {
+	req, err := http.NewRequest(http.MethodGet, src.URL, nil)
+
+	if err != nil {
+		return err
+	}
+
+	req = req.WithContext(ctx)
+
+	src.mu.Lock()
+	etag := src.etag
+	src.mu.Unlock()
+
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	client := src.Client
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("go-data: fail to fetch `%v` with status %v", src.URL, resp.Status)
+	}
+
+	max := src.MaxBodySize
+
+	if max <= 0 {
+		max = DefaultMaxBodySize
+	}
+
+	// 多读一个字节，这样才能知道文档是否超过了上限。
+	body, err := io.ReadAll(io.LimitReader(resp.Body, max+1))
+
+	if err != nil {
+		return err
+	}
+
+	if int64(len(body)) > max {
+		return ErrBodyTooLarge
+	}
+
+	h := fnv.New64a()
+	h.Write(body)
+	hash := h.Sum64()
+
+	src.mu.Lock()
+	unchanged := src.loaded && hash == src.hash
+
+	if unchanged {
+		src.etag = resp.Header.Get("ETag")
+	}
+
+	src.mu.Unlock()
+
+	if unchanged {
+		return nil
+	}
+
+	p := src.Parser
+
+	if p == nil {
+		p = &data.Parser{}
+	}
+
+	d, err := p.Parse(string(body))
+
+	if err != nil {
+		return err
+	}
+
+	src.mu.Lock()
+	src.etag = resp.Header.Get("ETag")
+	src.hash = hash
+	src.loaded = true
+	src.snapshot.Store(d)
+	handlers := src.handlers
+	src.mu.Unlock()
+
+	for _, fn := range handlers {
+		fn(d)
+	}
+
+	return nil
+}
+
+// Snapshot 返回最近一次成功获取的 Data，如果还没有成功获取过则返回空 Data。
+// 调用者不应该修改返回的 Data。
+func (src *Source) Snapshot() data.Data {
+	return src.snapshot.Load()
+}
+
+// OnUpdate 增加一个在文档更新之后调用的回调，d 是新的快照。
+// 回调在调用 Fetch 或 Watch 的 goroutine 中按照增加的顺序调用，不应该在回调中执行耗时的操作。
+func (src *Source) OnUpdate(fn func(d data.Data)) {
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	src.handlers = append(src.handlers, fn)
+}
+
+// Watch 立即获取一次文档，之后每隔 Interval 获取一次，直到 ctx 结束才返回 ctx.Err()。
+func (src *Source) Watch(ctx context.Context) error {
+	interval := src.Interval
+
+	if interval <= 0 {
+		interval = data.DefaultPollInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := src.Fetch(ctx); err != nil && ctx.Err() == nil && src.OnError != nil {
+			src.OnError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
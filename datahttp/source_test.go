@@ -0,0 +1,110 @@
+package datahttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	data "github.com/altstory/go-data"
+	"github.com/huandu/go-assert"
+)
+
+func TestSource(t *testing.T) {
+	a := assert.New(t)
+
+	var mu sync.Mutex
+	doc := `<json>{"flag":true}`
+	etag := `"v1"`
+	status := http.StatusOK
+	requests := 0
+	notModified := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+
+		if r.Header.Get("If-None-Match") == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(doc))
+	}))
+	defer server.Close()
+
+	src := NewSource(server.URL)
+	updates := make(chan data.Data, 10)
+	src.OnUpdate(func(d data.Data) {
+		updates <- d
+	})
+
+	ctx := context.Background()
+	a.Equal(src.Snapshot(), data.Data{})
+	a.NilError(src.Fetch(ctx))
+	a.Equal(src.Snapshot(), data.Make(data.RawData{"flag": true}))
+	a.Equal(<-updates, data.Make(data.RawData{"flag": true}))
+
+	// 第二次请求会带上 ETag，服务端返回 304。
+	a.NilError(src.Fetch(ctx))
+	a.Equal(notModified, 1)
+	a.Equal(len(updates), 0)
+
+	mu.Lock()
+	doc = `<json>{"flag":false}`
+	etag = `"v2"`
+	mu.Unlock()
+	a.NilError(src.Fetch(ctx))
+	a.Equal(<-updates, data.Make(data.RawData{"flag": false}))
+
+	// 出错时保留原有的快照。
+	mu.Lock()
+	status = http.StatusInternalServerError
+	mu.Unlock()
+	a.NonNilError(src.Fetch(ctx))
+	a.Equal(src.Snapshot(), data.Make(data.RawData{"flag": false}))
+
+	mu.Lock()
+	status = http.StatusOK
+	doc = `{"flag":1}`
+	etag = `"v3"`
+	mu.Unlock()
+	a.NonNilError(src.Fetch(ctx))
+	a.Equal(src.Snapshot(), data.Make(data.RawData{"flag": false}))
+
+	// 文档超过大小上限。
+	mu.Lock()
+	doc = `<json>{"flag":"too large"}`
+	mu.Unlock()
+	src.MaxBodySize = int64(len(doc) - 1)
+	a.Equal(src.Fetch(ctx), ErrBodyTooLarge)
+	a.Equal(src.Snapshot(), data.Make(data.RawData{"flag": false}))
+
+	src.MaxBodySize = int64(len(doc))
+	a.NilError(src.Fetch(ctx))
+	a.Equal(<-updates, data.Make(data.RawData{"flag": "too large"}))
+
+	mu.Lock()
+	doc = `<json>{"flag":"watch"}`
+	etag = `"v4"`
+	mu.Unlock()
+	src.Interval = time.Millisecond
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan error)
+	go func() {
+		done <- src.Watch(ctx)
+	}()
+	a.Equal(<-updates, data.Make(data.RawData{"flag": "watch"}))
+	cancel()
+	a.Equal(<-done, context.Canceled)
+}
@@ -0,0 +1,156 @@
+// Package dataotel 为 go-data 提供 OpenTelemetry tracing 支持。
+//
+// Tracer 会将 Parse/Decode/Patch 等操作包装在 span 中，并在 span 上记录数据规模和错误，
+// 方便在链路追踪中观察这些操作的耗时。
+package dataotel
+
+import (
+	"context"
+
+	data "github.com/altstory/go-data"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName 是创建 tracer 时使用的名字。
+const InstrumentationName = "github.com/altstory/go-data/dataotel"
+
+// span 上记录的属性。
+const (
+	AttrByteSize = attribute.Key("data.byte_size") // 输入的字节数。
+	AttrKeyCount = attribute.Key("data.key_count") // Data 顶层 key 的个数，如果操作生成了新的 Data，记录的是结果的个数。
+	AttrQuery    = attribute.Key("data.query")     // DecodeQuery 使用的 query。
+)
+
+// Tracer 将 go-data 的操作包装在 span 中。
+// Tracer 的零值可以直接使用，会使用全局的 TracerProvider 和默认的 Parser。
+type Tracer struct {
+	TracerProvider trace.TracerProvider // 如果为 nil，使用 otel.GetTracerProvider()。
+	Parser         *data.Parser         // 如果为 nil，使用 Parser 的零值。
+}
+
+// Parse 与 `data.Parse` 相同，同时创建名为 go-data.Parse 的 span。
+func (t *Tracer) Parse(ctx context.Context, str string) (d data.Data, err error) {
+	_, span := t.start(ctx, "go-data.Parse", AttrByteSize.Int(len(str)))
+	defer func() {
+		end(span, d, err)
+	}()
+
+	d, err = t.parser().Parse(str)
+	return
+}
+
+// ParseJSON 与 `data.ParseJSONContext` 相同，同时创建名为 go-data.ParseJSON 的 span。
+func (t *Tracer) ParseJSON(ctx context.Context, str string) (d data.Data, err error) {
+	ctx, span := t.start(ctx, "go-data.ParseJSON", AttrByteSize.Int(len(str)))
+	defer func() {
+		end(span, d, err)
+	}()
+
+	d, err = t.parser().ParseJSONContext(ctx, str)
+	return
+}
+
+// Decode 与 `Decoder#DecodeContext` 相同，同时创建名为 go-data.Decode 的 span。
+// 如果 dec 为 nil，使用 Decoder 的零值。
+func (t *Tracer) Decode(ctx context.Context, dec *data.Decoder, d data.Data, v interface{}) (err error) {
+	ctx, span := t.start(ctx, "go-data.Decode", AttrKeyCount.Int(d.Len()))
+	defer func() {
+		end(span, data.Data{}, err)
+	}()
+
+	if dec == nil {
+		dec = &data.Decoder{}
+	}
+
+	err = dec.DecodeContext(ctx, d, v)
+	return
+}
+
+// DecodeQuery 与 `Decoder#DecodeQuery` 相同，同时创建名为 go-data.DecodeQuery 的 span。
+// 如果 dec 为 nil，使用 Decoder 的零值。
+func (t *Tracer) DecodeQuery(ctx context.Context, dec *data.Decoder, d data.Data, query string, v interface{}) (err error) {
+	_, span := t.start(ctx, "go-data.DecodeQuery", AttrKeyCount.Int(d.Len()), AttrQuery.String(query))
+	defer func() {
+		end(span, data.Data{}, err)
+	}()
+
+	if dec == nil {
+		dec = &data.Decoder{}
+	}
+
+	err = dec.DecodeQuery(d, query, v)
+	return
+}
+
+// Apply 与 `Patch#Apply` 相同，同时创建名为 go-data.Patch.Apply 的 span。
+func (t *Tracer) Apply(ctx context.Context, patch *data.Patch, d data.Data) (applied data.Data, err error) {
+	ctx, span := t.start(ctx, "go-data.Patch.Apply", AttrKeyCount.Int(d.Len()))
+	defer func() {
+		end(span, applied, err)
+	}()
+
+	d = d.Clone()
+
+	if err = patch.ApplyToContext(ctx, &d); err != nil {
+		return
+	}
+
+	applied = d
+	return
+}
+
+// ApplyTo 与 `Patch#ApplyToContext` 相同，同时创建名为 go-data.Patch.ApplyTo 的 span。
+func (t *Tracer) ApplyTo(ctx context.Context, patch *data.Patch, target *data.Data) (err error) {
+	var keys int
+
+	if target != nil {
+		keys = target.Len()
+	}
+
+	ctx, span := t.start(ctx, "go-data.Patch.ApplyTo", AttrKeyCount.Int(keys))
+	defer func() {
+		var d data.Data
+
+		if target != nil {
+			d = *target
+		}
+
+		end(span, d, err)
+	}()
+
+	err = patch.ApplyToContext(ctx, target)
+	return
+}
+
+func (t *Tracer) start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	provider := t.TracerProvider
+
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+
+	return provider.Tracer(InstrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+func (t *Tracer) parser() *data.Parser {
+	if t.Parser == nil {
+		return &data.Parser{}
+	}
+
+	return t.Parser
+}
+
+// end 在 span 上记录结果 d 的规模和错误，然后结束 span。
+func end(span trace.Span, d data.Data, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else if d.Len() != 0 {
+		span.SetAttributes(AttrKeyCount.Int(d.Len()))
+	}
+
+	span.End()
+}
@@ -0,0 +1,70 @@
+package dataotel
+
+import (
+	"context"
+	"testing"
+
+	data "github.com/altstory/go-data"
+	"github.com/huandu/go-assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracer(t *testing.T) {
+	a := assert.New(t)
+	recorder := tracetest.NewSpanRecorder()
+	tracer := &Tracer{
+		TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)),
+	}
+	ctx := context.Background()
+	str := `{"a":1,"b":{"c":2}}`
+
+	d, err := tracer.ParseJSON(ctx, str)
+	a.NilError(err)
+	_, err = tracer.Parse(ctx, "bad")
+	a.NonNilError(err)
+
+	var v struct {
+		A int `data:"a"`
+	}
+	a.NilError(tracer.Decode(ctx, nil, d, &v))
+	a.Equal(v.A, 1)
+	a.NilError(tracer.DecodeQuery(ctx, nil, d, "b.c", &v.A))
+	a.Equal(v.A, 2)
+
+	patch := data.NewPatch()
+	patch.Replace("d", data.Make(data.RawData{"e": 3}))
+	applied, err := tracer.Apply(ctx, patch, d)
+	a.NilError(err)
+	a.Equal(applied.Query("d.e"), int64(3))
+	a.NilError(tracer.ApplyTo(ctx, patch, &d))
+	a.Equal(d.Query("d.e"), int64(3))
+
+	spans := recorder.Ended()
+	names := make([]string, 0, len(spans))
+
+	for _, s := range spans {
+		names = append(names, s.Name())
+	}
+
+	a.Equal(names, []string{
+		"go-data.ParseJSON",
+		"go-data.Parse",
+		"go-data.Decode",
+		"go-data.DecodeQuery",
+		"go-data.Patch.Apply",
+		"go-data.Patch.ApplyTo",
+	})
+	a.Equal(spans[0].Attributes(), []attribute.KeyValue{
+		AttrByteSize.Int(len(str)),
+		AttrKeyCount.Int(2),
+	})
+	a.Equal(spans[1].Status().Code, codes.Error)
+	a.Equal(len(spans[1].Events()), 1)
+	// 结果的 key 个数会覆盖输入的 key 个数。
+	a.Equal(spans[5].Attributes(), []attribute.KeyValue{
+		AttrKeyCount.Int(3),
+	})
+}
@@ -0,0 +1,375 @@
+// Package datapb 定义了 Data 和 Patch 的 protobuf 格式（见 patch.proto），并提供与 go-data 中类型的相互转换，
+// 这样 Patch 可以直接作为 protobuf 消息在 gRPC 服务之间传递，而不需要先序列化成 JSON 字符串。
+//
+// 转换时会保留 Data 中所有值的类型，但以下信息会丢失：
+//     - key 的顺序，protobuf 的 map 是无序的；
+//     - slice 的元素类型，还原时使用与 `data.ParseJSON` 相同的规则，
+//       所有元素类型相同时还原成对应类型的 slice，否则还原成 []interface{}。
+//
+// 修改 patch.proto 之后需要使用 `go generate` 重新生成 patch.pb.go。
+package datapb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative patch.proto
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	data "github.com/altstory/go-data"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var typeOfInterface = reflect.TypeOf((*interface{})(nil)).Elem()
+
+// FromPatch 将 p 转换成 protobuf 消息。如果 p 中存在 Data 不支持的值，返回错误。
+func FromPatch(p *data.Patch) (*Patch, error) {
+	pb := &Patch{}
+
+	if p == nil {
+		return pb, nil
+	}
+
+	for i, action := range p.Actions() {
+		a, err := FromPatchAction(action)
+
+		if err != nil {
+			return nil, fmt.Errorf("go-data: fail to convert action %v: %w", i, err)
+		}
+
+		pb.Actions = append(pb.Actions, a)
+	}
+
+	return pb, nil
+}
+
+// ToPatch 将 protobuf 消息还原成 Patch。如果 pb 中存在非法的值，返回错误。
+func ToPatch(pb *Patch) (*data.Patch, error) {
+	p := data.NewPatch()
+
+	for i, a := range pb.GetActions() {
+		action, err := ToPatchAction(a)
+
+		if err != nil {
+			return nil, fmt.Errorf("go-data: fail to convert action %v: %w", i, err)
+		}
+
+		p.AddAction(action)
+	}
+
+	return p, nil
+}
+
+// FromPatchAction 将 action 转换成 protobuf 消息。
+func FromPatchAction(action *data.PatchAction) (*PatchAction, error) {
+	pb := &PatchAction{}
+
+	if action == nil {
+		return pb, nil
+	}
+
+	pb.Deletes = append(pb.Deletes, action.Deletes...)
+	var err error
+
+	if pb.DeleteMatches, err = fromDataMap(action.DeleteMatches); err != nil {
+		return nil, err
+	}
+
+	if pb.Replaces, err = fromDataMap(action.Replaces); err != nil {
+		return nil, err
+	}
+
+	if pb.Updates, err = fromDataMap(action.Updates); err != nil {
+		return nil, err
+	}
+
+	pb.Expires = fromTimeMap(action.Expires)
+	return pb, nil
+}
+
+// ToPatchAction 将 protobuf 消息还原成 PatchAction。
+func ToPatchAction(pb *PatchAction) (*data.PatchAction, error) {
+	action := &data.PatchAction{}
+
+	if len(pb.GetDeletes()) != 0 {
+		action.Deletes = append(action.Deletes, pb.GetDeletes()...)
+	}
+
+	var err error
+
+	if action.DeleteMatches, err = toDataMap(pb.GetDeleteMatches()); err != nil {
+		return nil, err
+	}
+
+	if action.Replaces, err = toDataMap(pb.GetReplaces()); err != nil {
+		return nil, err
+	}
+
+	if action.Updates, err = toDataMap(pb.GetUpdates()); err != nil {
+		return nil, err
+	}
+
+	if action.Expires, err = toTimeMap(pb.GetExpires()); err != nil {
+		return nil, err
+	}
+
+	return action, nil
+}
+
+func fromDataMap(m map[string]data.Data) (map[string]*Data, error) {
+	if m == nil {
+		return nil, nil
+	}
+
+	pb := make(map[string]*Data, len(m))
+
+	for query, d := range m {
+		v, err := FromData(d)
+
+		if err != nil {
+			return nil, fmt.Errorf("go-data: fail to convert data of query `%v`: %w", query, err)
+		}
+
+		pb[query] = v
+	}
+
+	return pb, nil
+}
+
+func toDataMap(pb map[string]*Data) (map[string]data.Data, error) {
+	if pb == nil {
+		return nil, nil
+	}
+
+	m := make(map[string]data.Data, len(pb))
+
+	for query, v := range pb {
+		d, err := ToData(v)
+
+		if err != nil {
+			return nil, fmt.Errorf("go-data: fail to convert data of query `%v`: %w", query, err)
+		}
+
+		m[query] = d
+	}
+
+	return m, nil
+}
+
+func fromTimeMap(m map[string]time.Time) map[string]*timestamppb.Timestamp {
+	if m == nil {
+		return nil
+	}
+
+	pb := make(map[string]*timestamppb.Timestamp, len(m))
+
+	for query, t := range m {
+		pb[query] = timestamppb.New(t)
+	}
+
+	return pb
+}
+
+func toTimeMap(pb map[string]*timestamppb.Timestamp) (map[string]time.Time, error) {
+	if pb == nil {
+		return nil, nil
+	}
+
+	m := make(map[string]time.Time, len(pb))
+
+	for query, ts := range pb {
+		if err := ts.CheckValid(); err != nil {
+			return nil, fmt.Errorf("go-data: invalid expiry time of query `%v`: %w", query, err)
+		}
+
+		m[query] = ts.AsTime()
+	}
+
+	return m, nil
+}
+
+// FromData 将 d 转换成 protobuf 消息。如果 d 中存在 Data 不支持的值，返回错误。
+func FromData(d data.Data) (*Data, error) {
+	return fromMap(d.ToMap(), nil)
+}
+
+// ToData 将 protobuf 消息还原成 Data。如果 pb 中存在非法的值，返回错误。
+func ToData(pb *Data) (data.Data, error) {
+	raw, err := toRawData(pb, nil)
+
+	if err != nil {
+		return data.Data{}, err
+	}
+
+	return data.Make(raw), nil
+}
+
+func fromMap(m map[string]interface{}, path []string) (*Data, error) {
+	pb := &Data{
+		Fields: make(map[string]*Value, len(m)),
+	}
+	keys := make([]string, 0, len(m))
+
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	// 按照字典序转换，保证出错时报告的路径是稳定的。
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v, err := fromValue(m[k], append(path[:len(path):len(path)], k))
+
+		if err != nil {
+			return nil, err
+		}
+
+		pb.Fields[k] = v
+	}
+
+	return pb, nil
+}
+
+func fromValue(v interface{}, path []string) (*Value, error) {
+	switch val := v.(type) {
+	case nil:
+		return &Value{}, nil
+	case int64:
+		return &Value{Kind: &Value_IntValue{IntValue: val}}, nil
+	case uint64:
+		return &Value{Kind: &Value_UintValue{UintValue: val}}, nil
+	case float64:
+		return &Value{Kind: &Value_FloatValue{FloatValue: val}}, nil
+	case string:
+		return &Value{Kind: &Value_StringValue{StringValue: val}}, nil
+	case bool:
+		return &Value{Kind: &Value_BoolValue{BoolValue: val}}, nil
+	case time.Time:
+		return &Value{Kind: &Value_TimeValue{TimeValue: timestamppb.New(val)}}, nil
+	case complex128:
+		return &Value{Kind: &Value_ComplexValue{ComplexValue: &Complex{Real: real(val), Imag: imag(val)}}}, nil
+	case map[string]interface{}:
+		obj, err := fromMap(val, path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &Value{Kind: &Value_ObjectValue{ObjectValue: obj}}, nil
+	}
+
+	rv := reflect.ValueOf(v)
+
+	if rv.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("go-data: unsupported value type %T at `%v`", v, strings.Join(path, "."))
+	}
+
+	list := &List{
+		Values: make([]*Value, 0, rv.Len()),
+	}
+
+	for i := 0; i < rv.Len(); i++ {
+		elem, err := fromValue(rv.Index(i).Interface(), append(path[:len(path):len(path)], strconv.Itoa(i)))
+
+		if err != nil {
+			return nil, err
+		}
+
+		list.Values = append(list.Values, elem)
+	}
+
+	return &Value{Kind: &Value_ListValue{ListValue: list}}, nil
+}
+
+func toRawData(pb *Data, path []string) (data.RawData, error) {
+	fields := pb.GetFields()
+	raw := make(data.RawData, len(fields))
+
+	for k, v := range fields {
+		val, err := toValue(v, append(path[:len(path):len(path)], k))
+
+		if err != nil {
+			return nil, err
+		}
+
+		raw[k] = val
+	}
+
+	return raw, nil
+}
+
+func toValue(pb *Value, path []string) (interface{}, error) {
+	switch kind := pb.GetKind().(type) {
+	case nil:
+		return nil, nil
+	case *Value_IntValue:
+		return kind.IntValue, nil
+	case *Value_UintValue:
+		return kind.UintValue, nil
+	case *Value_FloatValue:
+		return kind.FloatValue, nil
+	case *Value_StringValue:
+		return kind.StringValue, nil
+	case *Value_BoolValue:
+		return kind.BoolValue, nil
+	case *Value_TimeValue:
+		if err := kind.TimeValue.CheckValid(); err != nil {
+			return nil, fmt.Errorf("go-data: invalid time at `%v`: %w", strings.Join(path, "."), err)
+		}
+
+		return kind.TimeValue.AsTime(), nil
+	case *Value_ComplexValue:
+		return complex(kind.ComplexValue.GetReal(), kind.ComplexValue.GetImag()), nil
+	case *Value_ObjectValue:
+		return toRawData(kind.ObjectValue, path)
+	case *Value_ListValue:
+		return toSlice(kind.ListValue, path)
+	}
+
+	return nil, fmt.Errorf("go-data: unsupported value kind %T at `%v`", pb.GetKind(), strings.Join(path, "."))
+}
+
+// toSlice 使用与 `data.ParseJSON` 相同的规则还原 slice：
+// 所有元素类型相同时还原成对应类型的 slice，否则还原成 []interface{}。
+func toSlice(pb *List, path []string) (interface{}, error) {
+	values := pb.GetValues()
+	elems := make([]interface{}, 0, len(values))
+	var elemType reflect.Type
+
+	for i, v := range values {
+		elem, err := toValue(v, append(path[:len(path):len(path)], strconv.Itoa(i)))
+
+		if err != nil {
+			return nil, err
+		}
+
+		t := typeOfInterface
+
+		if elem != nil {
+			t = reflect.TypeOf(elem)
+		}
+
+		if elemType == nil {
+			elemType = t
+		} else if elemType != t {
+			elemType = typeOfInterface
+		}
+
+		elems = append(elems, elem)
+	}
+
+	if elemType == nil || elemType == typeOfInterface {
+		return elems, nil
+	}
+
+	slice := reflect.MakeSlice(reflect.SliceOf(elemType), len(elems), len(elems))
+
+	for i, elem := range elems {
+		slice.Index(i).Set(reflect.ValueOf(elem))
+	}
+
+	return slice.Interface(), nil
+}
@@ -0,0 +1,126 @@
+package datapb
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	data "github.com/altstory/go-data"
+	"github.com/huandu/go-assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestPatchRoundTrip(t *testing.T) {
+	a := assert.New(t)
+	now := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+	p := data.NewPatch()
+	p.Add([]string{"a", "b.0"}, map[string]data.Data{
+		"": data.Make(data.RawData{
+			"int":     -1,
+			"uint":    uint(2),
+			"float":   1.5,
+			"str":     "s",
+			"bool":    true,
+			"time":    now,
+			"complex": complex(1, 2),
+			"nil":     nil,
+			"ints":    []int{1, 2},
+			"nested":  [][]string{{"a"}, {"b", "c"}},
+			"mixed":   []interface{}{1, "a", nil},
+			"objects": []data.RawData{{"k": "v"}},
+			"empty":   []interface{}{},
+			"obj": data.RawData{
+				"x": data.RawData{"y": 1},
+			},
+		}),
+	})
+	p.DeleteMatched("list", data.Make(data.RawData{"disabled": true}))
+	p.Replace("c", data.Make(data.RawData{"new": true}))
+
+	pb, err := FromPatch(p)
+	a.NilError(err)
+
+	buf, err := proto.Marshal(pb)
+	a.NilError(err)
+
+	var decoded Patch
+	a.NilError(proto.Unmarshal(buf, &decoded))
+
+	restored, err := ToPatch(&decoded)
+	a.NilError(err)
+	a.Equal(restored.Actions(), p.Actions())
+
+	d := data.Make(data.RawData{
+		"a":    1,
+		"b":    []int{1, 2},
+		"c":    data.RawData{"old": true},
+		"list": []data.RawData{{"disabled": true}, {"disabled": false}},
+	})
+	expected, err := p.Apply(d)
+	a.NilError(err)
+	actual, err := restored.Apply(d)
+	a.NilError(err)
+	a.Equal(actual, expected)
+}
+
+func TestPatchExpiresRoundTrip(t *testing.T) {
+	a := assert.New(t)
+	at := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+	p := data.NewPatch()
+	p.UpdateUntil("a", data.Make(data.RawData{"b": 1}), at)
+	p.ReplaceUntil("c", data.Make(data.RawData{"d": true}), at.Add(time.Hour))
+
+	pb, err := FromPatch(p)
+	a.NilError(err)
+
+	buf, err := proto.Marshal(pb)
+	a.NilError(err)
+
+	var decoded Patch
+	a.NilError(proto.Unmarshal(buf, &decoded))
+
+	restored, err := ToPatch(&decoded)
+	a.NilError(err)
+	a.Equal(restored.Actions(), p.Actions())
+
+	// 接收方的 TTLData 依然会记录过期时间。
+	d := data.Make(data.RawData{
+		"a": data.RawData{},
+		"c": 1,
+	})
+	expected := data.NewTTLData(d)
+	a.NilError(expected.Apply(p))
+	actual := data.NewTTLData(d)
+	a.NilError(actual.Apply(restored))
+	a.Equal(actual.Expires(), expected.Expires())
+	a.Equal(len(actual.Expires()), 2)
+
+	_, err = ToPatchAction(&PatchAction{
+		Expires: map[string]*timestamppb.Timestamp{
+			"a": {Nanos: -1},
+		},
+	})
+	a.NonNilError(err)
+}
+
+func TestToDataErrors(t *testing.T) {
+	a := assert.New(t)
+	pb := &Data{
+		Fields: map[string]*Value{
+			"a": {Kind: &Value_ListValue{ListValue: &List{
+				Values: []*Value{
+					{Kind: &Value_TimeValue{TimeValue: nil}},
+				},
+			}}},
+		},
+	}
+	_, err := ToData(pb)
+	a.NonNilError(err)
+	// protobuf 会在错误信息中随机使用不同的空白字符，只检查固定的前缀。
+	a.Assert(strings.HasPrefix(err.Error(), "go-data: invalid time at `a.0`: "))
+
+	d, err := ToData(nil)
+	a.NilError(err)
+	a.Equal(d.Len(), 0)
+}
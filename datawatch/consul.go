@@ -0,0 +1,138 @@
+package datawatch
+
+import (
+	"bytes"
+	"context"
+	"sync"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// ConsulSource 使用 Consul KV 的阻塞查询实现 Source。
+//
+// Consul 不会返回具体的变更内容，因此每次阻塞查询返回后，
+// ConsulSource 会比较前后两次查询的结果来生成 Event。
+// 为了知道 rev 对应的值，ConsulSource 会记住每个 prefix 最近一次 List 的结果，
+// Watch 以这个结果作为比较的起点，所以 Watch 的 rev 应该来自同一个 ConsulSource 的 List。
+type ConsulSource struct {
+	Client *api.Client
+
+	mu   sync.Mutex
+	last map[string]consulSnapshot
+}
+
+type consulSnapshot struct {
+	kvs map[string][]byte
+	rev uint64
+}
+
+var _ Source = (*ConsulSource)(nil)
+
+// List 读取 prefix 下所有 key 的值，返回的版本号是 Consul 的 X-Consul-Index。
+func (src *ConsulSource) List(ctx context.Context, prefix string) (kvs map[string][]byte, rev uint64, err error) {
+	pairs, meta, err := src.Client.KV().List(prefix, (&api.QueryOptions{}).WithContext(ctx))
+
+	if err != nil {
+		return
+	}
+
+	kvs = consulPairs(pairs)
+	rev = meta.LastIndex
+
+	src.mu.Lock()
+	defer src.mu.Unlock()
+
+	if src.last == nil {
+		src.last = map[string]consulSnapshot{}
+	}
+
+	src.last[prefix] = consulSnapshot{
+		kvs: consulPairs(pairs),
+		rev: rev,
+	}
+	return
+}
+
+// Watch 使用阻塞查询监听 prefix 下 X-Consul-Index 大于 rev 的所有变更。
+//
+// 如果 rev 不是最近一次 List 返回的版本号，Watch 会重新读取 prefix 下所有 key 的值，
+// 并将这些值作为一批 Event 通知 fn，这种情况下，在此期间被删除的 key 无法被通知。
+func (src *ConsulSource) Watch(ctx context.Context, prefix string, rev uint64, fn func(events []Event)) error {
+	src.mu.Lock()
+	last, ok := src.last[prefix]
+	src.mu.Unlock()
+
+	kvs := last.kvs
+
+	if !ok || last.rev != rev {
+		var err error
+		kvs, rev, err = src.List(ctx, prefix)
+
+		if err != nil {
+			return err
+		}
+
+		events := make([]Event, 0, len(kvs))
+
+		for k, v := range kvs {
+			events = append(events, Event{
+				Key:   k,
+				Value: v,
+			})
+		}
+
+		fn(events)
+	}
+
+	for {
+		opts := &api.QueryOptions{
+			WaitIndex: rev,
+		}
+		pairs, meta, err := src.Client.KV().List(prefix, opts.WithContext(ctx))
+
+		if err != nil {
+			return err
+		}
+
+		// 按照 Consul 文档的建议，index 变小时需要重置。
+		if meta.LastIndex < rev {
+			rev = 0
+		} else {
+			rev = meta.LastIndex
+		}
+
+		latest := consulPairs(pairs)
+		var events []Event
+
+		for k, v := range latest {
+			if old, ok := kvs[k]; !ok || !bytes.Equal(old, v) {
+				events = append(events, Event{
+					Key:   k,
+					Value: v,
+				})
+			}
+		}
+
+		for k := range kvs {
+			if _, ok := latest[k]; !ok {
+				events = append(events, Event{
+					Key:     k,
+					Deleted: true,
+				})
+			}
+		}
+
+		kvs = latest
+		fn(events)
+	}
+}
+
+func consulPairs(pairs api.KVPairs) map[string][]byte {
+	kvs := make(map[string][]byte, len(pairs))
+
+	for _, pair := range pairs {
+		kvs[pair.Key] = pair.Value
+	}
+
+	return kvs
+}
@@ -0,0 +1,154 @@
+package datawatch
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/huandu/go-assert"
+)
+
+// fakeConsul 模拟 Consul KV 的 HTTP 接口，支持阻塞查询。
+type fakeConsul struct {
+	mu      sync.Mutex
+	index   uint64
+	kvs     map[string][]byte
+	changed chan struct{}
+}
+
+func newFakeConsul(kvs map[string][]byte) *fakeConsul {
+	return &fakeConsul{
+		index:   1,
+		kvs:     kvs,
+		changed: make(chan struct{}),
+	}
+}
+
+func (c *fakeConsul) set(kvs map[string][]byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.index++
+	c.kvs = kvs
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+func (c *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	prefix := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+	wait, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64)
+
+	c.mu.Lock()
+
+	for wait > 0 && c.index <= wait {
+		changed := c.changed
+		c.mu.Unlock()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-changed:
+		}
+
+		c.mu.Lock()
+	}
+
+	var pairs api.KVPairs
+
+	for k, v := range c.kvs {
+		if strings.HasPrefix(k, prefix) {
+			pairs = append(pairs, &api.KVPair{Key: k, Value: v})
+		}
+	}
+
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(c.index, 10))
+	c.mu.Unlock()
+
+	if len(pairs) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(pairs)
+}
+
+func sortEvents(events []Event) []Event {
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Key < events[j].Key
+	})
+	return events
+}
+
+func TestConsulSource(t *testing.T) {
+	a := assert.New(t)
+	consul := newFakeConsul(map[string][]byte{
+		"app/a": []byte(`{"a":1}`),
+	})
+	server := httptest.NewServer(consul)
+	defer server.Close()
+
+	client, err := api.NewClient(&api.Config{Address: server.URL})
+	a.NilError(err)
+	src := &ConsulSource{Client: client}
+
+	kvs, rev, err := src.List(context.Background(), "app/")
+	a.NilError(err)
+	a.Equal(kvs, map[string][]byte{"app/a": []byte(`{"a":1}`)})
+	a.Equal(rev, uint64(1))
+
+	// List 和 Watch 之间的变更不能丢失。
+	consul.set(map[string][]byte{
+		"app/a": []byte(`{"a":2}`),
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan []Event)
+	done := make(chan error)
+	go func() {
+		done <- src.Watch(ctx, "app/", rev, func(events []Event) {
+			ch <- sortEvents(events)
+		})
+	}()
+
+	a.Equal(<-ch, []Event{
+		{Key: "app/a", Value: []byte(`{"a":2}`)},
+	})
+
+	consul.set(map[string][]byte{
+		"app/b": []byte(`{"b":1}`),
+	})
+	a.Equal(<-ch, []Event{
+		{Key: "app/a", Deleted: true},
+		{Key: "app/b", Value: []byte(`{"b":1}`)},
+	})
+
+	cancel()
+	a.NonNilError(<-done)
+
+	// rev 不是最近一次 List 的结果时，先通知所有的值。
+	src = &ConsulSource{Client: client}
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		done <- src.Watch(ctx, "app/", 1, func(events []Event) {
+			ch <- sortEvents(events)
+		})
+	}()
+
+	a.Equal(<-ch, []Event{
+		{Key: "app/b", Value: []byte(`{"b":1}`)},
+	})
+
+	consul.set(map[string][]byte{})
+	a.Equal(<-ch, []Event{
+		{Key: "app/b", Deleted: true},
+	})
+
+	cancel()
+	a.NonNilError(<-done)
+}
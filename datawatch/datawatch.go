@@ -0,0 +1,190 @@
+// Package datawatch 监听 etcd、Consul 等 key-value 存储中某个前缀下的所有 key，
+// 将每个 key 的值解析成 Data 并合并成一个快照，在数据变化时通知调用者。
+//
+// 前缀下每个 key 的值都必须是一个 JSON object，所有值按照 key 的字典序依次合并，
+// 合并规则与 `data.Merge` 相同，因此字典序靠后的 key 可以覆盖靠前的 key 中的配置。
+package datawatch
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	data "github.com/altstory/go-data"
+)
+
+// DefaultRetryInterval 是 Watcher 在监听出错后重试的默认间隔。
+const DefaultRetryInterval = time.Second
+
+// Event 是 Source 中一个 key 的变更。
+type Event struct {
+	Key     string // 发生变更的 key。
+	Value   []byte // 变更后的值，如果 key 被删除则为 nil。
+	Deleted bool   // 如果为 true，key 被删除。
+}
+
+// Source 是可以被监听的 key-value 存储，`EtcdSource` 和 `ConsulSource` 分别实现了 etcd 和 Consul 的支持。
+type Source interface {
+	// List 返回 prefix 下所有 key 的当前值，以及这些值对应的版本号，这个版本号会被传给 Watch。
+	List(ctx context.Context, prefix string) (kvs map[string][]byte, rev uint64, err error)
+
+	// Watch 监听 prefix 下版本号 rev 之后的所有变更，每收到一批变更调用一次 fn，
+	// 直到 ctx 结束或者出错才返回。
+	Watch(ctx context.Context, prefix string, rev uint64, fn func(events []Event)) error
+}
+
+// Watcher 监听 Source 中 Prefix 下的所有 key，并维护一个合并后的 Data 快照。
+// 使用前必须设置 Source 和 Prefix，然后调用 Run 开始监听。
+type Watcher struct {
+	Source        Source        // 被监听的 key-value 存储。
+	Prefix        string        // 被监听的 key 前缀。
+	Parser        *data.Parser  // 用来解析值的 Parser，如果为 nil，使用 Parser 的零值。
+	RetryInterval time.Duration // 监听出错后重试的间隔，如果为 0，使用 DefaultRetryInterval。
+
+	// OnError 在监听出错或者某个 key 的值无法解析时被调用，如果为 nil 则忽略这些错误。
+	// 无法解析的 key 不会参与合并。
+	OnError func(err error)
+
+	mu       sync.Mutex
+	docs     map[string]data.Data
+	handlers []func(d data.Data)
+	snapshot data.SyncData
+}
+
+// Load 返回当前合并后的 Data 快照，在 Run 第一次成功读取数据之前返回空 Data。
+// 调用者不应该修改返回的 Data。
+func (w *Watcher) Load() data.Data {
+	return w.snapshot.Load()
+}
+
+// OnChange 增加一个在快照发生变化后调用的回调，d 是新的快照。
+// 回调会在 Run 所在的 goroutine 中按照增加的顺序调用，不应该在回调中执行耗时的操作。
+func (w *Watcher) OnChange(fn func(d data.Data)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.handlers = append(w.handlers, fn)
+}
+
+// Run 读取 Prefix 下所有 key 的值并开始监听变更，直到 ctx 结束才返回 ctx.Err()。
+// 如果读取或者监听出错，会调用 OnError，并在 RetryInterval 之后重新读取全部数据再继续监听。
+func (w *Watcher) Run(ctx context.Context) error {
+	for {
+		if err := w.watch(ctx); err != nil && ctx.Err() == nil {
+			w.reportError(err)
+		}
+
+		retry := w.RetryInterval
+
+		if retry <= 0 {
+			retry = DefaultRetryInterval
+		}
+
+		timer := time.NewTimer(retry)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (w *Watcher) watch(ctx context.Context) error {
+	kvs, rev, err := w.Source.List(ctx, w.Prefix)
+
+	if err != nil {
+		return err
+	}
+
+	docs := make(map[string]data.Data, len(kvs))
+
+	for k, v := range kvs {
+		if d, ok := w.parse(k, v); ok {
+			docs[k] = d
+		}
+	}
+
+	w.mu.Lock()
+	w.docs = docs
+	w.mu.Unlock()
+	w.update()
+
+	return w.Source.Watch(ctx, w.Prefix, rev, w.apply)
+}
+
+func (w *Watcher) apply(events []Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	w.mu.Lock()
+
+	for _, e := range events {
+		if e.Deleted {
+			delete(w.docs, e.Key)
+			continue
+		}
+
+		if d, ok := w.parse(e.Key, e.Value); ok {
+			w.docs[e.Key] = d
+		} else {
+			delete(w.docs, e.Key)
+		}
+	}
+
+	w.mu.Unlock()
+	w.update()
+}
+
+func (w *Watcher) parse(key string, value []byte) (data.Data, bool) {
+	p := w.Parser
+
+	if p == nil {
+		p = &data.Parser{}
+	}
+
+	d, err := p.ParseJSONBytes(value)
+
+	if err != nil {
+		w.reportError(fmt.Errorf("go-data: fail to parse value of key `%v`: %w", key, err))
+		return data.Data{}, false
+	}
+
+	return d, true
+}
+
+// update 重新合并所有的文档，并通知所有的回调。
+func (w *Watcher) update() {
+	w.mu.Lock()
+	keys := make([]string, 0, len(w.docs))
+
+	for k := range w.docs {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	docs := make([]data.Data, 0, len(keys))
+
+	for _, k := range keys {
+		docs = append(docs, w.docs[k])
+	}
+
+	handlers := w.handlers
+	w.mu.Unlock()
+
+	d := data.Merge(docs...)
+	w.snapshot.Store(d)
+
+	for _, fn := range handlers {
+		fn(d)
+	}
+}
+
+func (w *Watcher) reportError(err error) {
+	if w.OnError != nil {
+		w.OnError(err)
+	}
+}
@@ -0,0 +1,119 @@
+package datawatch
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	data "github.com/altstory/go-data"
+	"github.com/huandu/go-assert"
+)
+
+type fakeSource struct {
+	mu     sync.Mutex
+	kvs    map[string][]byte
+	events chan []Event
+	fails  int
+}
+
+func (src *fakeSource) List(ctx context.Context, prefix string) (map[string][]byte, uint64, error) {
+	src.mu.Lock()
+	defer src.mu.Unlock()
+
+	if src.fails > 0 {
+		src.fails--
+		return nil, 0, errors.New("list failed")
+	}
+
+	kvs := make(map[string][]byte, len(src.kvs))
+
+	for k, v := range src.kvs {
+		kvs[k] = v
+	}
+
+	return kvs, 1, nil
+}
+
+func (src *fakeSource) Watch(ctx context.Context, prefix string, rev uint64, fn func(events []Event)) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case events := <-src.events:
+			fn(events)
+		}
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	a := assert.New(t)
+	src := &fakeSource{
+		kvs: map[string][]byte{
+			"/app/1-base":     []byte(`{"port":80,"name":"base"}`),
+			"/app/2-override": []byte(`{"name":"override"}`),
+		},
+		events: make(chan []Event),
+		fails:  1,
+	}
+	w := &Watcher{
+		Source:        src,
+		Prefix:        "/app/",
+		RetryInterval: time.Millisecond,
+	}
+
+	var errs []error
+	var errMu sync.Mutex
+	w.OnError = func(err error) {
+		errMu.Lock()
+		defer errMu.Unlock()
+		errs = append(errs, err)
+	}
+
+	changes := make(chan data.Data, 10)
+	w.OnChange(func(d data.Data) {
+		changes <- d
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- w.Run(ctx)
+	}()
+
+	d := <-changes
+	a.Equal(d, data.Make(data.RawData{
+		"port": 80,
+		"name": "override",
+	}))
+	a.Equal(w.Load(), d)
+
+	src.events <- []Event{
+		{Key: "/app/2-override", Deleted: true},
+		{Key: "/app/3-extra", Value: []byte(`{"debug":true}`)},
+	}
+	d = <-changes
+	a.Equal(d, data.Make(data.RawData{
+		"port":  80,
+		"name":  "base",
+		"debug": true,
+	}))
+
+	// 无法解析的值不参与合并。
+	src.events <- []Event{
+		{Key: "/app/3-extra", Value: []byte(`not json`)},
+	}
+	d = <-changes
+	a.Equal(d, data.Make(data.RawData{
+		"port": 80,
+		"name": "base",
+	}))
+
+	cancel()
+	a.Equal(<-done, context.Canceled)
+
+	errMu.Lock()
+	defer errMu.Unlock()
+	a.Equal(len(errs), 2)
+}
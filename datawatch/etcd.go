@@ -0,0 +1,67 @@
+package datawatch
+
+import (
+	"context"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// EtcdSource 使用 etcd v3 API 实现 Source。
+type EtcdSource struct {
+	Client *clientv3.Client
+}
+
+var _ Source = (*EtcdSource)(nil)
+
+// List 读取 prefix 下所有 key 的值，返回的版本号是 etcd 的 revision。
+func (src *EtcdSource) List(ctx context.Context, prefix string) (kvs map[string][]byte, rev uint64, err error) {
+	resp, err := src.Client.Get(ctx, prefix, clientv3.WithPrefix())
+
+	if err != nil {
+		return
+	}
+
+	kvs = make(map[string][]byte, len(resp.Kvs))
+
+	for _, kv := range resp.Kvs {
+		kvs[string(kv.Key)] = kv.Value
+	}
+
+	rev = uint64(resp.Header.Revision)
+	return
+}
+
+// Watch 监听 prefix 下 revision 大于 rev 的所有变更。
+func (src *EtcdSource) Watch(ctx context.Context, prefix string, rev uint64, fn func(events []Event)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	wch := src.Client.Watch(clientv3.WithRequireLeader(ctx), prefix, clientv3.WithPrefix(), clientv3.WithRev(int64(rev)+1))
+
+	for resp := range wch {
+		if err := resp.Err(); err != nil {
+			return err
+		}
+
+		events := make([]Event, 0, len(resp.Events))
+
+		for _, ev := range resp.Events {
+			e := Event{
+				Key: string(ev.Kv.Key),
+			}
+
+			if ev.Type == mvccpb.DELETE {
+				e.Deleted = true
+			} else {
+				e.Value = ev.Kv.Value
+			}
+
+			events = append(events, e)
+		}
+
+		fn(events)
+	}
+
+	return ctx.Err()
+}
@@ -0,0 +1,81 @@
+package datawatch
+
+import (
+	"context"
+	"testing"
+
+	"github.com/huandu/go-assert"
+	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+type fakeEtcdKV struct {
+	clientv3.KV
+	resp *clientv3.GetResponse
+}
+
+func (kv *fakeEtcdKV) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	return kv.resp, nil
+}
+
+type fakeEtcdWatcher struct {
+	clientv3.Watcher
+	rev int64
+	wch chan clientv3.WatchResponse
+}
+
+func (w *fakeEtcdWatcher) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
+	w.rev = clientv3.OpGet(key, opts...).Rev()
+	return w.wch
+}
+
+func TestEtcdSource(t *testing.T) {
+	a := assert.New(t)
+	kv := &fakeEtcdKV{
+		resp: &clientv3.GetResponse{
+			Header: &etcdserverpb.ResponseHeader{Revision: 5},
+			Kvs: []*mvccpb.KeyValue{
+				{Key: []byte("/app/a"), Value: []byte(`{"a":1}`)},
+			},
+		},
+	}
+	watcher := &fakeEtcdWatcher{
+		wch: make(chan clientv3.WatchResponse, 2),
+	}
+	src := &EtcdSource{
+		Client: &clientv3.Client{
+			KV:      kv,
+			Watcher: watcher,
+		},
+	}
+
+	kvs, rev, err := src.List(context.Background(), "/app/")
+	a.NilError(err)
+	a.Equal(kvs, map[string][]byte{"/app/a": []byte(`{"a":1}`)})
+	a.Equal(rev, uint64(5))
+
+	watcher.wch <- clientv3.WatchResponse{
+		Events: []*clientv3.Event{
+			{Type: mvccpb.PUT, Kv: &mvccpb.KeyValue{Key: []byte("/app/b"), Value: []byte(`{"b":2}`)}},
+			{Type: mvccpb.DELETE, Kv: &mvccpb.KeyValue{Key: []byte("/app/a")}},
+		},
+	}
+	watcher.wch <- clientv3.WatchResponse{
+		CompactRevision: 3,
+	}
+
+	var events [][]Event
+	err = src.Watch(context.Background(), "/app/", rev, func(e []Event) {
+		events = append(events, e)
+	})
+	a.Equal(err, rpctypes.ErrCompacted)
+	a.Equal(watcher.rev, int64(6))
+	a.Equal(events, [][]Event{
+		{
+			{Key: "/app/b", Value: []byte(`{"b":2}`)},
+			{Key: "/app/a", Deleted: true},
+		},
+	})
+}
@@ -1,41 +1,274 @@
 package data
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
 	"reflect"
+	"strconv"
 	"time"
-
-	"github.com/huandu/go-clone"
 )
 
 // Decoder 用来将 Data 设置到指定值里面去。
+//
+// 默认情况下，Decoder 会将 Data 合并到目标值已有的内容上：
+//     - 对于 struct，Data 中不存在的字段会保持原值不变；
+//     - 对于 slice 和 map，只要 Data 中存在对应的值，目标值会被整个替换；
+//     - 对于非 nil 的指针，会直接解析到指针指向的值里，不会重新分配内存；
+//     - 对于 nil 指针，只有 Data 中存在对应的非 nil 值时才会分配内存，否则保持 nil，
+//       如果希望总是分配一个空值，可以在字段 tag 中设置 alloc 选项；
+//     - 空的 slice 和 map（比如 JSON 中的 `[]` 和 `{}`）不是 nil 值，*[]T、*map[K]V 会被分配内存并设置成非 nil 的空值，
+//       而 nil 的 slice 和 map 与不存在的值一样，不会分配内存；**T 等多级指针的每一级都遵循相同的规则；
+//     - 对于设置了 squash 的 struct 指针，总是会分配内存。
+//
+// 如果希望解析结果与目标值原有内容无关，可以设置 ZeroFields。
+//
+// 如果希望只更新 Data 中出现的内容，可以设置 PartialUpdate，这适合用于将 PATCH 请求的数据绑定到已有对象上。
+// 与默认行为不同的是，PartialUpdate 模式下 map 不会被整个替换，而是逐个 key 更新，
+// 已有 key 对应的值也会作为解析的基础，只更新 Data 中存在的字段。
+// 同时设置 ZeroFields 和 PartialUpdate 时，ZeroFields 优先，PartialUpdate 不生效。
 type Decoder struct {
-	TagName string // 在解析 struct 时候使用的 field tag，默认是 data。
+	TagName                string // 在解析 struct 时候使用的 field tag，默认是 data。
+	ZeroFields             bool   // 如果为 true，解析前会先将目标值重置为零值。
+	PartialUpdate          bool   // 如果为 true，只更新 Data 中出现的内容，其他内容保持不变。
+	DetectSquashCollisions bool   // 如果为 true，squash 展开的字段与其他字段 key 相同时报错。
+
+	// DurationFormat 决定如何将数字解析成 time.Duration，应该与编码时 Encoder 的设置相同。
+	// 字符串格式的 time.Duration 总是可以被解析。
+	DurationFormat DurationFormat
+
+	// KeyFolding 决定如何比较 struct 字段的 key 与 Data 中的 key，默认精确匹配。
+	// DecodeQuery、DecodeField 和 DecodeElements 查找 query 时也使用相同的规则。
+	KeyFolding KeyFolding
+
+	// RelativeTime 不为 nil 时，time.Time 和 time.Duration 可以从相对时间表达式中解析，
+	// 比如 "now-24h"、"2d"，详见 `RelativeTime`。
+	RelativeTime *RelativeTime
+
+	// Instrumentation 用来观测解码过程，如果为 nil 则使用 `SetInstrumentation` 设置的全局值。
+	Instrumentation Instrumentation
+
+	interfaces   map[reflect.Type]reflect.Type
+	types        map[reflect.Type]DecodeFunc
+	transformers map[string]Transformer
+	plans        map[reflect.Type]*TypePlan
+	checker      *contextChecker
+}
+
+// RegisterInterface 为接口类型 iface 注册实现类型 impl。
+// 当需要将数据解析到 iface 类型的值时，Decoder 会先将数据解析到一个新的 impl 类型的值里，再将这个值赋给目标。
+//
+// iface 必须是接口类型，且 impl 必须实现了 iface，否则 panic。
+func (dec *Decoder) RegisterInterface(iface reflect.Type, impl reflect.Type) {
+	if iface.Kind() != reflect.Interface {
+		panic(fmt.Errorf("go-data: type %v is not an interface", iface))
+	}
+
+	if !impl.Implements(iface) {
+		panic(fmt.Errorf("go-data: type %v does not implement %v", impl, iface))
+	}
+
+	if dec.interfaces == nil {
+		dec.interfaces = map[reflect.Type]reflect.Type{}
+	}
+
+	dec.interfaces[iface] = impl
+}
+
+// DecodeFunc 将 Data 中的值 v 转化成注册的类型，返回值的类型必须可以赋值给注册的类型。
+type DecodeFunc func(v interface{}) (interface{}, error)
+
+// RegisterType 为类型 t 注册一个解码函数，所有需要解析成 t 的值都会使用 fn 来转化。
+// 这适合用于 decimal.Decimal、uuid.UUID 这类需要特殊处理的类型，
+// 通常需要与 `Encoder#RegisterType` 一同使用。
+func (dec *Decoder) RegisterType(t reflect.Type, fn DecodeFunc) {
+	if dec.types == nil {
+		dec.types = map[reflect.Type]DecodeFunc{}
+	}
+
+	dec.types[t] = fn
+}
+
+// RegisterTransformer 注册一个名为 name 的 Transformer。
+// 如果字段 tag 中设置了 decoder=name，解码时会先用 fn 转化 Data 中的值，再将转化的结果解析到字段中。
+func (dec *Decoder) RegisterTransformer(name string, fn Transformer) {
+	if dec.transformers == nil {
+		dec.transformers = map[string]Transformer{}
+	}
+
+	dec.transformers[name] = fn
 }
 
 // Decode 将 d 解析到 v 中。
 func (dec *Decoder) Decode(d Data, v interface{}) error {
 	from := reflect.ValueOf(d.data)
-	to := reflect.ValueOf(v)
-	return dec.decode(from, to)
+	return dec.decodeTo(from, v)
 }
 
 // DecodeQuery 解析 query 找到对应的值并且解析到 v 中。
 // 其中，query 的格式详见 `Data#Qeury` 文档。
+// 如果解析失败，返回的 `*DecodeError` 中的路径包含 query 本身，比如 `items.1.id`。
 func (dec *Decoder) DecodeQuery(d Data, query string, v interface{}) error {
-	from := reflect.ValueOf(d.Query(query))
-	to := reflect.ValueOf(v)
-	return dec.decode(from, to)
+	from := reflect.ValueOf(d.QueryFold(query, dec.KeyFolding))
+	return prefixDecodeError(queryFields(query), dec.decodeTo(from, v))
 }
 
 // DecodeField 通过 field 找到对应的值并且解析到 v 中。
 // 其中，field 的格式详见 `Data#Get` 文档。
 func (dec *Decoder) DecodeField(d Data, field []string, v interface{}) error {
-	from := reflect.ValueOf(d.Get(field...))
+	from := reflect.ValueOf(dec.KeyFolding.get(d.data, field))
+	return prefixDecodeError(field, dec.decodeTo(from, v))
+}
+
+// DecodeElements 将 query 对应的数组逐个元素解析到 v 中，v 必须是指向 slice 的指针。
+// 与 DecodeQuery 不同，解析失败的元素会被跳过，v 中只包含成功解析的元素，
+// 每个被跳过的元素对应 skipped 中的一个 `*DecodeError`，错误的路径中包含 query 和元素下标。
+// 这适合处理来自外部的列表数据，个别坏数据不应该导致整个列表不可用。
+//
+// 如果 query 对应的值不存在，v 保持不变；如果不是数组，或者 v 不是指向 slice 的指针，返回 err。
+func (dec *Decoder) DecodeElements(d Data, query string, v interface{}) (skipped []*DecodeError, err error) {
 	to := reflect.ValueOf(v)
-	return dec.decode(from, to)
+
+	if to.Kind() != reflect.Ptr || to.IsNil() || to.Elem().Kind() != reflect.Slice {
+		err = fmt.Errorf("go-data: cannot decode elements to a value of type %T which is not a pointer to slice", v)
+		return
+	}
+
+	from := reflect.ValueOf(d.QueryFold(query, dec.KeyFolding))
+
+	for from.Kind() == reflect.Interface {
+		from = from.Elem()
+	}
+
+	if isNilValue(from) {
+		return
+	}
+
+	fields := queryFields(query)
+
+	if from.Kind() != reflect.Slice && from.Kind() != reflect.Array {
+		err = prefixDecodeError(fields, fmt.Errorf("go-data: cannot decode elements from a value of type %v", from.Type()))
+		return
+	}
+
+	l := from.Len()
+	slice := to.Elem()
+	val := reflect.MakeSlice(slice.Type(), 0, l)
+
+	for i := 0; i < l; i++ {
+		elem := reflect.New(slice.Type().Elem())
+
+		if e := dec.decodeTo(from.Index(i), elem.Interface()); e != nil {
+			e = prefixDecodeError(append(fields[:len(fields):len(fields)], strconv.Itoa(i)), e)
+			skipped = append(skipped, e.(*DecodeError))
+			continue
+		}
+
+		val = reflect.Append(val, elem.Elem())
+	}
+
+	slice.Set(val)
+	return
+}
+
+// queryFields 将 query 拆分成字段，空 query 代表顶层的值，返回 nil。
+func queryFields(query string) []string {
+	if query == "" {
+		return nil
+	}
+
+	return SplitQuery(query)
+}
+
+// prefixDecodeError 将 fields 加到 err 的路径最前面，如果 err 不是 `*DecodeError`，会被包装成 `*DecodeError`。
+func prefixDecodeError(fields []string, err error) error {
+	if err == nil || len(fields) == 0 {
+		return err
+	}
+
+	e, ok := err.(*DecodeError)
+
+	if !ok {
+		e = &DecodeError{
+			Err: err,
+		}
+	}
+
+	e.Fields = append(append([]string{}, fields...), e.Fields...)
+	return e
+}
+
+// DecodeFields 只将 d 中 fields 列出的顶层字段解析到 v 中，其他字段会被忽略。
+// 当 d 很大而只需要用到其中少数几个字段时，使用 DecodeFields 可以避免解析整个 d 的开销。
+func (dec *Decoder) DecodeFields(d Data, v interface{}, fields ...string) error {
+	raw := make(RawData, len(fields))
+
+	for _, f := range fields {
+		if val, ok := d.data[f]; ok {
+			raw[f] = val
+		} else if dec.KeyFolding != 0 {
+			if val, ok := dec.KeyFolding.lookup(d.data, f); ok {
+				raw[f] = val
+			}
+		}
+	}
+
+	from := reflect.ValueOf(raw)
+	return dec.decodeTo(from, v)
+}
+
+// DecodeContext 与 Decode 相同，但在解析过程中会定期检查 ctx，
+// 如果 ctx 已经结束则放弃解析并返回错误，这个错误可以用 `errors.Is(err, context.Canceled)` 等方式判断。
+// 放弃解析时 v 可能已经被部分修改。
+func (dec *Decoder) DecodeContext(ctx context.Context, d Data, v interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return &DecodeError{
+			Err: err,
+		}
+	}
+
+	copied := *dec
+	copied.checker = newContextChecker(ctx)
+	from := reflect.ValueOf(d.data)
+	return copied.decodeTo(from, v)
+}
+
+func (dec *Decoder) tagName() string {
+	if dec.TagName == "" {
+		return defaultTagName
+	}
+
+	return dec.TagName
+}
+
+func (dec *Decoder) decodeTo(from reflect.Value, v interface{}) (err error) {
+	if inst := currentInstrumentation(dec.Instrumentation); inst != nil {
+		start := time.Now()
+		defer func() {
+			inst.OnDecode(valueSize(from), time.Since(start), err)
+		}()
+	}
+
+	to := reflect.ValueOf(v)
+
+	if dec.ZeroFields && to.Kind() == reflect.Ptr && !to.IsNil() {
+		elem := to.Elem()
+		elem.Set(reflect.Zero(elem.Type()))
+	}
+
+	if err := dec.decode(from, to); err != nil {
+		if _, ok := err.(*DecodeError); !ok {
+			err = &DecodeError{
+				Err: err,
+			}
+		}
+
+		return err
+	}
+
+	return nil
 }
 
 // decode 将 from 中的内容解析到 to 中去。
@@ -45,6 +278,10 @@ func (dec *Decoder) DecodeField(d Data, field []string, v interface{}) error {
 // 由于 decode 仅在内部使用，这里会假定 from 要么是 Data，要么是已经 Data 里已经解析过的值，
 // 因此 from 不可能是、也不可能包含任何 struct、chan、func、ptr 等不是数据的值。
 func (dec *Decoder) decode(from reflect.Value, to reflect.Value) error {
+	if err := dec.checker.check(); err != nil {
+		return err
+	}
+
 	if to.Kind() == reflect.Ptr {
 		to = to.Elem()
 	}
@@ -57,17 +294,26 @@ func (dec *Decoder) decode(from reflect.Value, to reflect.Value) error {
 		return fmt.Errorf("go-data: cannot decode to a value of type %v which is not settable", to.Type())
 	}
 
-	// 如果 from == nil，那么直接跳过解析过程，同时也不报错。
-	switch from.Kind() {
-	case reflect.Invalid:
+	for from.Kind() == reflect.Interface {
+		from = from.Elem()
+	}
+
+	// 如果 from 是嵌在树中的 Data，使用它内部的 RawData 来解析。
+	if isData(from) {
+		from = unwrapData(from)
+	}
+
+	// 如果 from == nil，那么直接跳过解析过程，同时也不报错，to 中的 nil 指针也不会被分配内存。
+	if isNilValue(from) {
 		return nil
-	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Ptr, reflect.Slice, reflect.Map:
-		if from.IsNil() {
-			return nil
-		}
 	}
 
 	for to.Kind() == reflect.Ptr {
+		// 注册的类型可能是指针类型，比如 *big.Int，这种情况下不需要分配内存。
+		if _, ok := dec.types[to.Type()]; ok {
+			break
+		}
+
 		if to.IsNil() {
 			to.Set(reflect.New(to.Type().Elem()))
 		}
@@ -75,32 +321,71 @@ func (dec *Decoder) decode(from reflect.Value, to reflect.Value) error {
 		to = to.Elem()
 	}
 
-	for from.Kind() == reflect.Interface {
-		from = from.Elem()
+	// 优先使用注册的解码函数。
+	if fn, ok := dec.types[to.Type()]; ok {
+		v, err := fn(from.Interface())
+
+		if err != nil {
+			return err
+		}
+
+		val := reflect.ValueOf(v)
+
+		if !val.IsValid() {
+			to.Set(reflect.Zero(to.Type()))
+			return nil
+		}
+
+		if !val.Type().AssignableTo(to.Type()) {
+			return fmt.Errorf("go-data: decode func of type %v returns a value of type %v", to.Type(), val.Type())
+		}
+
+		to.Set(val)
+		return nil
+	}
+
+	// 除了 interface 以外，目标类型都需要 from 指向的值来解析。
+	if to.Kind() != reflect.Interface {
+		for from.Kind() == reflect.Ptr {
+			from = from.Elem()
+		}
 	}
 
 	// 先处理一些知名类型。
 	switch to.Type() {
 	case typeOfDuration:
-		if from.Kind() != reflect.String {
-			return fmt.Errorf("go-data: cannot decode a value of type %v from %v", to.Type(), from.Type())
-		}
-
-		if str := from.String(); str == "" {
-			to.SetInt(0)
-		} else {
-			dur, err := time.ParseDuration(from.String())
+		if dec.RelativeTime != nil && from.Kind() == reflect.String && from.String() != "" {
+			dur, err := dec.RelativeTime.ParseDuration(from.String())
 
 			if err != nil {
 				return err
 			}
 
 			to.SetInt(int64(dur))
+			return nil
+		}
+
+		dur, err := dec.DurationFormat.decode(from)
+
+		if err != nil {
+			return err
 		}
 
+		to.SetInt(int64(dur))
 		return nil
 
 	case typeOfTime:
+		if dec.RelativeTime != nil && from.Kind() == reflect.String {
+			t, err := dec.RelativeTime.ParseTime(from.String())
+
+			if err != nil {
+				return err
+			}
+
+			to.Set(reflect.ValueOf(t))
+			return nil
+		}
+
 		if from.Type() != typeOfTime {
 			return fmt.Errorf("go-data: cannot decode a value of type %v from %v", to.Type(), from.Type())
 		}
@@ -231,6 +516,39 @@ func (dec *Decoder) decode(from reflect.Value, to reflect.Value) error {
 				return fmt.Errorf("go-data: cannot decode value of type %v from %v due to overflow", to.Type(), cmplx)
 			}
 
+			to.SetComplex(cmplx)
+			return nil
+		case reflect.Map:
+			// 复数序列化成 JSON 之后是 `{"re":1,"im":2}` 的形式，只有 key 是字符串的 map 才可能是这种形式。
+			keyType := from.Type().Key()
+
+			if keyType.Kind() != reflect.String {
+				break
+			}
+
+			re := from.MapIndex(reflect.ValueOf("re").Convert(keyType))
+			im := from.MapIndex(reflect.ValueOf("im").Convert(keyType))
+
+			if !re.IsValid() || !im.IsValid() {
+				break
+			}
+
+			var r, i float64
+
+			if err := dec.decode(re, reflect.ValueOf(&r)); err != nil {
+				return err
+			}
+
+			if err := dec.decode(im, reflect.ValueOf(&i)); err != nil {
+				return err
+			}
+
+			cmplx := complex(r, i)
+
+			if to.OverflowComplex(cmplx) {
+				return fmt.Errorf("go-data: cannot decode value of type %v from %v due to overflow", to.Type(), cmplx)
+			}
+
 			to.SetComplex(cmplx)
 			return nil
 		}
@@ -248,8 +566,8 @@ func (dec *Decoder) decode(from reflect.Value, to reflect.Value) error {
 			for i := 0; i < fromLen; i++ {
 				v := to.Index(i)
 
-				if err := dec.decode(from.Index(i), v); err != nil {
-					return err
+				if err := dec.decode(from.Index(i), v.Addr()); err != nil {
+					return wrapDecodeError(strconv.Itoa(i), err)
 				}
 			}
 
@@ -266,8 +584,9 @@ func (dec *Decoder) decode(from reflect.Value, to reflect.Value) error {
 			for i := 0; i < fromLen; i++ {
 				v := val.Index(i)
 
-				if err := dec.decode(from.Index(i), v); err != nil {
-					return err
+				// 与 map 一样传入元素的地址，保证 []*T、[]*Data 这类指针元素可以被分配内存。
+				if err := dec.decode(from.Index(i), v.Addr()); err != nil {
+					return wrapDecodeError(strconv.Itoa(i), err)
 				}
 			}
 
@@ -282,21 +601,40 @@ func (dec *Decoder) decode(from reflect.Value, to reflect.Value) error {
 			toKeyType := toType.Key()
 			toElemType := toType.Elem()
 
-			if toKeyType.Kind() != reflect.String {
-				return fmt.Errorf("go-data: cannot decode a value of type %v whose key is not string", to.Type())
+			val := reflect.MakeMap(toType)
+			partial := dec.PartialUpdate && !dec.ZeroFields && !to.IsNil()
+
+			if partial {
+				iter := to.MapRange()
+
+				for iter.Next() {
+					val.SetMapIndex(iter.Key(), iter.Value())
+				}
 			}
 
-			val := reflect.MakeMap(toType)
 			iter := from.MapRange()
 
 			for iter.Next() {
+				field := fmt.Sprint(iter.Key().Interface())
+				k := reflect.New(toKeyType).Elem()
+
+				if err := dec.decodeMapKey(iter.Key(), k); err != nil {
+					return wrapDecodeError(field, err)
+				}
+
 				v := reflect.New(toElemType).Elem()
 
+				if partial {
+					if old := val.MapIndex(k); old.IsValid() {
+						v.Set(old)
+					}
+				}
+
 				if err := dec.decode(iter.Value(), v.Addr()); err != nil {
-					return err
+					return wrapDecodeError(field, err)
 				}
 
-				val.SetMapIndex(iter.Key(), v)
+				val.SetMapIndex(k, v)
 			}
 
 			to.Set(val)
@@ -321,61 +659,74 @@ func (dec *Decoder) decode(from reflect.Value, to reflect.Value) error {
 
 		switch from.Kind() {
 		case reflect.Map:
-			numField := to.NumField()
-			toType := to.Type()
+			// 没有任何导出字段的 struct 无法按字段解析，如果它实现了 json.Unmarshaler，交给后面的逻辑处理。
+			if !hasExportedFields(to.Type()) && reflect.PtrTo(to.Type()).Implements(typeOfJSONUnmarshaler) {
+				break
+			}
 
-			for i := 0; i < numField; i++ {
-				f := toType.Field(i)
-				fv := to.Field(i)
+			plan, err := dec.planOf(to.Type())
+
+			if err != nil {
+				return err
+			}
+
+			for i := range plan.fields {
+				fp := &plan.fields[i]
+				fv := to.Field(fp.index)
+				ft := fp.tag
+				k := fp.name
+
+				// 如果需要合并字段，且这个字段类型是一个 Struct 或 Ptr to Struct，那么会使用 from 的值来给 fv 赋值。
+				if fp.squash {
+					if err := dec.decode(from, fv.Addr()); err != nil {
+						return err
+					}
 
-				if !fv.CanSet() || !fv.CanAddr() {
 					continue
 				}
 
-				tagName := dec.TagName
-
-				if tagName == "" {
-					tagName = defaultTagName
+				// 默认情况下，如果 Data 中没有对应的值，指针字段保持 nil；
+				// 如果设置了 alloc 选项，则总是为 nil 指针分配一个空值。
+				if ft.Alloc {
+					allocPtr(fv)
 				}
 
-				tag := f.Tag.Get(tagName)
-				ft := ParseFieldTag(tag)
+				kv := from.MapIndex(fp.key)
+
+				if !kv.IsValid() && dec.KeyFolding != 0 {
+					kv = dec.KeyFolding.mapIndex(from, k)
+				}
 
-				if ft.Skipped {
+				if !kv.IsValid() {
 					continue
 				}
 
-				// 如果需要合并字段，且这个字段类型是一个 Struct 或 Ptr to Struct，那么会使用 from 的值来给 fv 赋值。
-				if ft.Squash {
-					fieldType := f.Type
+				if ft.Decoder != "" {
+					fn, ok := dec.transformers[ft.Decoder]
 
-					for fieldType.Kind() == reflect.Ptr {
-						fieldType = fieldType.Elem()
+					if !ok {
+						return wrapDecodeError(k, fmt.Errorf("go-data: transformer `%v` is not registered", ft.Decoder))
 					}
 
-					if fieldType.Kind() == reflect.Struct {
-						if err := dec.decode(from, fv.Addr()); err != nil {
-							return err
-						}
+					v, err := fn(kv.Interface())
 
-						continue
+					if err != nil {
+						return wrapDecodeError(k, err)
 					}
-				}
 
-				k := f.Name
-
-				if ft.Alias != "" {
-					k = ft.Alias
+					kv = reflect.ValueOf(v)
 				}
 
-				kv := from.MapIndex(reflect.ValueOf(k))
+				if len(ft.Enum) != 0 {
+					var err error
 
-				if !kv.IsValid() {
-					continue
+					if kv, err = decodeEnum(ft, kv, fp.typ); err != nil {
+						return wrapDecodeError(k, err)
+					}
 				}
 
 				if err := dec.decode(kv, fv.Addr()); err != nil {
-					return err
+					return wrapDecodeError(k, err)
 				}
 			}
 
@@ -386,13 +737,205 @@ func (dec *Decoder) decode(from reflect.Value, to reflect.Value) error {
 		fromType := from.Type()
 		toType := to.Type()
 
+		// 优先使用注册的实现类型来解析。
+		if impl, ok := dec.interfaces[toType]; ok {
+			v := reflect.New(impl).Elem()
+
+			if err := dec.decode(from, v.Addr()); err != nil {
+				return err
+			}
+
+			to.Set(v)
+			return nil
+		}
+
+		// 对于 interface{} 来说，任何类型都符合要求，直接深拷贝 from 即可。
 		if !fromType.Implements(toType) {
 			return fmt.Errorf("go-data: cannot decode an interface value of type %v from %v", toType, fromType)
 		}
 
-		to.Set(reflect.ValueOf(clone.Clone(from.Interface())))
+		to.Set(reflect.ValueOf(cloneValue(from.Interface())))
 		return nil
 	}
 
+	// 如果没有其他办法解析，但 to 实现了 json.Unmarshaler，那么将 from 序列化成 JSON 再交给 to 解析。
+	// 很多第三方类型只提供了 JSON 的序列化方法，比如一些 SDK 中的枚举值。
+	if to.CanAddr() {
+		if u, ok := to.Addr().Interface().(json.Unmarshaler); ok {
+			buf, err := json.Marshal(from.Interface())
+
+			if err != nil {
+				return err
+			}
+
+			return u.UnmarshalJSON(buf)
+		}
+	}
+
 	return fmt.Errorf("go-data: cannot decode a value of type %v from %v", to.Type(), from.Type())
 }
+
+// decodeEnum 将 Data 中的枚举名 from 转化成类型为 t 的字段可以解析的值。
+// 如果 from 不是字符串，则认为它已经是枚举值，直接返回。
+func decodeEnum(ft *FieldTag, from reflect.Value, t reflect.Type) (reflect.Value, error) {
+	for from.Kind() == reflect.Interface {
+		from = from.Elem()
+	}
+
+	if from.Kind() != reflect.String {
+		return from, nil
+	}
+
+	name := from.String()
+	value, ok := ft.enumValue(name)
+
+	if !ok {
+		return from, fmt.Errorf("go-data: unknown enum name `%v`", name)
+	}
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var v interface{}
+	var err error
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err = strconv.ParseInt(value, 10, 64)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err = strconv.ParseUint(value, 10, 64)
+	case reflect.Float32, reflect.Float64:
+		v, err = strconv.ParseFloat(value, 64)
+	case reflect.Bool:
+		v, err = strconv.ParseBool(value)
+	default:
+		v = value
+	}
+
+	if err != nil {
+		return from, fmt.Errorf("go-data: invalid enum value `%v` of type %v", value, t)
+	}
+
+	return reflect.ValueOf(v), nil
+}
+
+// isNilValue 判断 from 是否是 nil，对于指针会一直检查到最终指向的值。
+func isNilValue(from reflect.Value) bool {
+	for {
+		switch from.Kind() {
+		case reflect.Invalid:
+			return true
+		case reflect.Ptr:
+			if from.IsNil() {
+				return true
+			}
+
+			from = from.Elem()
+		case reflect.Chan, reflect.Func, reflect.Interface, reflect.Slice, reflect.Map:
+			return from.IsNil()
+		default:
+			return false
+		}
+	}
+}
+
+// hasExportedFields 判断 struct 类型 t 是否有导出字段。
+func hasExportedFields(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath == "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// allocPtr 为 nil 指针 v 分配空值，如果 v 是多级指针，会逐级分配。
+func allocPtr(v reflect.Value) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+
+		v = v.Elem()
+	}
+}
+
+// decodeMapKey 将 map 的 key 从 from 解析到 to 中。
+//
+// Data 中的 key 一般是 string，但也可能是 map[int]T 这样的非 string key，
+// 因此需要支持 string 与整型、浮点型 key 之间的相互转换。
+func (dec *Decoder) decodeMapKey(from reflect.Value, to reflect.Value) error {
+	for from.Kind() == reflect.Interface {
+		from = from.Elem()
+	}
+
+	switch to.Kind() {
+	case reflect.String:
+		switch from.Kind() {
+		case reflect.String:
+			to.SetString(from.String())
+			return nil
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			to.SetString(strconv.FormatInt(from.Int(), 10))
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			to.SetString(strconv.FormatUint(from.Uint(), 10))
+			return nil
+		case reflect.Float32, reflect.Float64:
+			to.SetString(strconv.FormatFloat(from.Float(), 'g', -1, 64))
+			return nil
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if from.Kind() == reflect.String {
+			i, err := strconv.ParseInt(from.String(), 10, 64)
+
+			if err != nil {
+				return fmt.Errorf("go-data: cannot decode map key of type %v from %q", to.Type(), from.String())
+			}
+
+			if to.OverflowInt(i) {
+				return fmt.Errorf("go-data: cannot decode map key of type %v from %v due to overflow", to.Type(), i)
+			}
+
+			to.SetInt(i)
+			return nil
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if from.Kind() == reflect.String {
+			ui, err := strconv.ParseUint(from.String(), 10, 64)
+
+			if err != nil {
+				return fmt.Errorf("go-data: cannot decode map key of type %v from %q", to.Type(), from.String())
+			}
+
+			if to.OverflowUint(ui) {
+				return fmt.Errorf("go-data: cannot decode map key of type %v from %v due to overflow", to.Type(), ui)
+			}
+
+			to.SetUint(ui)
+			return nil
+		}
+
+	case reflect.Float32, reflect.Float64:
+		if from.Kind() == reflect.String {
+			f, err := strconv.ParseFloat(from.String(), 64)
+
+			if err != nil {
+				return fmt.Errorf("go-data: cannot decode map key of type %v from %q", to.Type(), from.String())
+			}
+
+			if to.OverflowFloat(f) {
+				return fmt.Errorf("go-data: cannot decode map key of type %v from %v due to overflow", to.Type(), f)
+			}
+
+			to.SetFloat(f)
+			return nil
+		}
+	}
+
+	return dec.decode(from, to.Addr())
+}
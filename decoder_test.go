@@ -1,6 +1,8 @@
 package data
 
 import (
+	"encoding/json"
+	"fmt"
 	"reflect"
 	"strings"
 	"testing"
@@ -114,3 +116,856 @@ func TestDecode(t *testing.T) {
 		}
 	}
 }
+
+func TestDecodeMapKey(t *testing.T) {
+	cases := []struct {
+		Data     Data
+		Value    interface{}
+		HasError bool
+	}{
+		{ // string key 转成整型 key
+			Make(RawData{
+				"1":  "one",
+				"-2": "minus two",
+			}),
+			map[int]string{
+				1:  "one",
+				-2: "minus two",
+			},
+			false,
+		},
+		{ // string key 转成无符号整型和浮点 key
+			Make(RawData{
+				"3": 0.5,
+			}),
+			map[uint8]float64{
+				3: 0.5,
+			},
+			false,
+		},
+		{ // string key 转成浮点 key
+			Make(RawData{
+				"0.5": true,
+			}),
+			map[float32]bool{
+				0.5: true,
+			},
+			false,
+		},
+		{ // 非法的 key
+			Make(RawData{
+				"abc": 1,
+			}),
+			map[int]int{},
+			true,
+		},
+		{ // 溢出
+			Make(RawData{
+				"256": 1,
+			}),
+			map[uint8]int{},
+			true,
+		},
+	}
+	a := assert.New(t)
+	dec := &Decoder{}
+
+	for i, c := range cases {
+		a.Use(&i, &c)
+
+		vt := reflect.ValueOf(c.Value).Type()
+		actual := reflect.New(vt)
+		err := dec.Decode(c.Data, actual.Interface())
+
+		if c.HasError {
+			a.NonNilError(err)
+			continue
+		}
+
+		a.NilError(err)
+		a.Equal(c.Value, actual.Elem().Interface())
+	}
+
+	// Data 中也可能存在非 string key 的 map。
+	d := Make(RawData{
+		"m": map[int]interface{}{
+			123: "foo",
+		},
+	})
+	var m map[string]map[int64]string
+	a.NilError(dec.Decode(d, &m))
+	a.Equal(m, map[string]map[int64]string{
+		"m": {123: "foo"},
+	})
+
+	var ms map[string]map[string]string
+	a.NilError(dec.Decode(d, &ms))
+	a.Equal(ms, map[string]map[string]string{
+		"m": {"123": "foo"},
+	})
+}
+
+type ServiceConfig struct {
+	Host string `data:"host"`
+	Port int    `data:"port"`
+}
+
+func TestDecodeMapOfStructs(t *testing.T) {
+	a := assert.New(t)
+	dec := &Decoder{}
+	d := Make(RawData{
+		"services": RawData{
+			"api": RawData{
+				"host": "api.local",
+				"port": 8080,
+			},
+			"web": RawData{
+				"host": "web.local",
+			},
+		},
+	})
+
+	var values map[string]map[string]ServiceConfig
+	a.NilError(dec.Decode(d, &values))
+	a.Equal(values, map[string]map[string]ServiceConfig{
+		"services": {
+			"api": {Host: "api.local", Port: 8080},
+			"web": {Host: "web.local"},
+		},
+	})
+
+	var ptrs struct {
+		Services map[string]*ServiceConfig `data:"services"`
+	}
+	a.NilError(dec.Decode(d, &ptrs))
+	a.Equal(ptrs.Services, map[string]*ServiceConfig{
+		"api": {Host: "api.local", Port: 8080},
+		"web": {Host: "web.local"},
+	})
+
+	// 出错时需要在错误信息中标明出错值的路径。
+	bad := Make(RawData{
+		"services": RawData{
+			"api": RawData{
+				"port": "8080",
+			},
+		},
+	})
+	err := dec.Decode(bad, &ptrs)
+	a.NonNilError(err)
+	a.Assert(strings.Contains(err.Error(), "`services.api.port`"))
+
+	bad = Make(RawData{
+		"list": []RawData{
+			{"port": 1},
+			{"port": true},
+		},
+	})
+	var list struct {
+		List []ServiceConfig `data:"list"`
+	}
+	err = dec.Decode(bad, &list)
+	a.NonNilError(err)
+	a.Assert(strings.Contains(err.Error(), "`list.1.port`"))
+}
+
+func TestDecodeZeroFields(t *testing.T) {
+	a := assert.New(t)
+	d := Make(RawData{
+		"sub_type": RawData{
+			"int8": 8,
+		},
+	})
+	value := &AllValue{
+		Int:    123,
+		String: "old",
+		SubType: SubType{
+			Int16:   16,
+			Strings: []string{"old"},
+		},
+	}
+
+	// 默认情况下，不存在的字段保持原值。
+	dec := &Decoder{
+		TagName: "test",
+	}
+	merged := *value
+	a.NilError(dec.Decode(d, &merged))
+	a.Equal(merged, AllValue{
+		Int:    123,
+		String: "old",
+		SubType: SubType{
+			Int8:    8,
+			Int16:   16,
+			Strings: []string{"old"},
+		},
+		SquashType: &SquashType{},
+	})
+
+	// 设置 ZeroFields 之后，目标值会先被清空。
+	dec.ZeroFields = true
+	zeroed := *value
+	a.NilError(dec.Decode(d, &zeroed))
+	a.Equal(zeroed, AllValue{
+		SubType: SubType{
+			Int8: 8,
+		},
+		SquashType: &SquashType{},
+	})
+
+	// 即使 Data 为空也会清空目标值。
+	zeroed = *value
+	a.NilError(dec.Decode(Data{}, &zeroed))
+	a.Equal(zeroed, AllValue{})
+}
+
+func TestDecodePartialUpdate(t *testing.T) {
+	type Entity struct {
+		Name     string                    `data:"name"`
+		Tags     []string                  `data:"tags"`
+		Labels   map[string]string         `data:"labels"`
+		Services map[string]*ServiceConfig `data:"services"`
+	}
+
+	a := assert.New(t)
+	d := Make(RawData{
+		"labels": RawData{
+			"env": "prod",
+		},
+		"services": RawData{
+			"api": RawData{
+				"port": 9090,
+			},
+			"db": RawData{
+				"host": "db.local",
+			},
+		},
+	})
+	makeEntity := func() *Entity {
+		return &Entity{
+			Name: "old",
+			Tags: []string{"t1"},
+			Labels: map[string]string{
+				"env":  "dev",
+				"team": "core",
+			},
+			Services: map[string]*ServiceConfig{
+				"api": {Host: "api.local", Port: 8080},
+			},
+		}
+	}
+
+	// 默认模式会整个替换 map。
+	dec := &Decoder{}
+	entity := makeEntity()
+	a.NilError(dec.Decode(d, entity))
+	a.Equal(entity, &Entity{
+		Name:   "old",
+		Tags:   []string{"t1"},
+		Labels: map[string]string{"env": "prod"},
+		Services: map[string]*ServiceConfig{
+			"api": {Port: 9090},
+			"db":  {Host: "db.local"},
+		},
+	})
+
+	// PartialUpdate 模式只更新 Data 中存在的值。
+	dec.PartialUpdate = true
+	entity = makeEntity()
+	a.NilError(dec.Decode(d, entity))
+	a.Equal(entity, &Entity{
+		Name: "old",
+		Tags: []string{"t1"},
+		Labels: map[string]string{
+			"env":  "prod",
+			"team": "core",
+		},
+		Services: map[string]*ServiceConfig{
+			"api": {Host: "api.local", Port: 9090},
+			"db":  {Host: "db.local"},
+		},
+	})
+
+	// ZeroFields 优先。
+	dec.ZeroFields = true
+	entity = makeEntity()
+	a.NilError(dec.Decode(d, entity))
+	a.Equal(entity, &Entity{
+		Labels: map[string]string{"env": "prod"},
+		Services: map[string]*ServiceConfig{
+			"api": {Port: 9090},
+			"db":  {Host: "db.local"},
+		},
+	})
+}
+
+func TestDecodeFields(t *testing.T) {
+	cases := []struct {
+		Fields []string
+		Value  *AllValue
+	}{
+		{ // 没有任何字段
+			nil,
+			&AllValue{
+				SquashType: &SquashType{},
+			},
+		},
+		{ // 部分字段
+			[]string{"int", "string", "not_exist"},
+			&AllValue{
+				Int:        -123,
+				String:     "abcd",
+				SquashType: &SquashType{},
+			},
+		},
+		{ // squash 字段
+			[]string{"sub_type", "uint8"},
+			&AllValue{
+				SubType: allValues.SubType,
+				SquashType: &SquashType{
+					Uint8: 8,
+				},
+			},
+		},
+	}
+	a := assert.New(t)
+	dec := &Decoder{
+		TagName: "test",
+	}
+
+	for i, c := range cases {
+		a.Use(&i, &c)
+
+		actual := &AllValue{}
+		a.NilError(dec.DecodeFields(fullData, actual, c.Fields...))
+		a.Equal(c.Value, actual)
+	}
+}
+
+func TestDecodeSquashCollisions(t *testing.T) {
+	a := assert.New(t)
+	d := Make(RawData{
+		"name": "inner",
+		"port": 1,
+	})
+
+	dec := &Decoder{}
+	var v collisionOuter
+	a.NilError(dec.Decode(d, &v))
+	a.Equal(v, collisionOuter{
+		Inner: &collisionInner{
+			Name: "inner",
+			Port: 1,
+		},
+		Port: 1,
+	})
+
+	dec.DetectSquashCollisions = true
+	a.NonNilError(dec.Decode(d, &v))
+}
+
+type Shape interface {
+	Area() float64
+}
+
+type Rect struct {
+	Width  float64 `data:"width"`
+	Height float64 `data:"height"`
+}
+
+func (r *Rect) Area() float64 {
+	return r.Width * r.Height
+}
+
+func TestDecodeInterface(t *testing.T) {
+	type Value struct {
+		Any    interface{} `data:"any"`
+		Shape  Shape       `data:"shape"`
+		Shapes []Shape     `data:"shapes"`
+	}
+
+	a := assert.New(t)
+	raw := RawData{
+		"foo": []int64{1, 2},
+	}
+	d := Make(RawData{
+		"any": raw,
+		"shape": RawData{
+			"width":  2,
+			"height": 3,
+		},
+		"shapes": []RawData{
+			{"width": 1, "height": 1},
+		},
+	})
+
+	// 没有注册实现类型时，无法解析到具名接口。
+	dec := &Decoder{}
+	var v Value
+	a.NonNilError(dec.Decode(d, &v))
+
+	dec.RegisterInterface(reflect.TypeOf((*Shape)(nil)).Elem(), reflect.TypeOf(&Rect{}))
+	v = Value{}
+	a.NilError(dec.Decode(d, &v))
+	a.Equal(v, Value{
+		Any:    raw,
+		Shape:  &Rect{Width: 2, Height: 3},
+		Shapes: []Shape{&Rect{Width: 1, Height: 1}},
+	})
+	a.Equal(v.Shape.Area(), 6.0)
+
+	// interface{} 中的值是深拷贝出来的。
+	v.Any.(RawData)["foo"].([]int64)[0] = 100
+	a.Equal(raw["foo"], []int64{1, 2})
+
+	a.Equal(func() (err error) {
+		defer func() {
+			err = recover().(error)
+		}()
+		dec.RegisterInterface(reflect.TypeOf((*Shape)(nil)).Elem(), reflect.TypeOf(Rect{}))
+		return
+	}().Error(), "go-data: type data.Rect does not implement data.Shape")
+}
+
+func TestDecodeNilPointer(t *testing.T) {
+	type CacheConfig struct {
+		Size int `data:"size"`
+	}
+	type Config struct {
+		Cache      *CacheConfig  `data:"cache"`
+		AllocCache *CacheConfig  `data:"alloc_cache,alloc"`
+		NullCache  **CacheConfig `data:"null_cache,alloc"`
+	}
+
+	cases := []struct {
+		Data   Data
+		Config Config
+	}{
+		{ // 没有任何数据
+			Data{},
+			Config{},
+		},
+		{ // 字段不存在
+			Make(RawData{
+				"foo": 1,
+			}),
+			Config{
+				AllocCache: &CacheConfig{},
+				NullCache:  func() **CacheConfig { c := &CacheConfig{}; return &c }(),
+			},
+		},
+		{ // 字段存在
+			Make(RawData{
+				"cache": RawData{
+					"size": 1,
+				},
+				"alloc_cache": RawData{
+					"size": 2,
+				},
+				"null_cache": nil,
+			}),
+			Config{
+				Cache:      &CacheConfig{Size: 1},
+				AllocCache: &CacheConfig{Size: 2},
+				NullCache:  func() **CacheConfig { c := &CacheConfig{}; return &c }(),
+			},
+		},
+	}
+	a := assert.New(t)
+	dec := &Decoder{}
+
+	for i, c := range cases {
+		a.Use(&i, &c)
+
+		var config Config
+		a.NilError(dec.Decode(c.Data, &config))
+		a.Equal(c.Config, config)
+	}
+}
+
+type Level int
+
+const (
+	LevelLow Level = iota + 1
+	LevelHigh
+)
+
+func (l *Level) UnmarshalJSON(data []byte) error {
+	var str string
+
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+
+	switch str {
+	case "low":
+		*l = LevelLow
+	case "high":
+		*l = LevelHigh
+	default:
+		return fmt.Errorf("unknown level %v", str)
+	}
+
+	return nil
+}
+
+type Money struct {
+	cents int64
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Units int64 `json:"units"`
+		Cents int64 `json:"cents"`
+	}
+
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	m.cents = v.Units*100 + v.Cents
+	return nil
+}
+
+func TestDecodeJSONUnmarshaler(t *testing.T) {
+	type Value struct {
+		Level  Level   `data:"level"`
+		Levels []Level `data:"levels"`
+		Int    Level   `data:"int"`
+		Price  *Money  `data:"price"`
+	}
+	a := assert.New(t)
+	d := Make(RawData{
+		"level":  "high",
+		"levels": []string{"low", "high"},
+		"int":    2,
+		"price": RawData{
+			"units": 3,
+			"cents": 45,
+		},
+	})
+	var v Value
+	dec := &Decoder{}
+	a.NilError(dec.Decode(d, &v))
+
+	// 可以直接解析的值不会使用 json.Unmarshaler。
+	a.Equal(v.Int, LevelHigh)
+	a.Equal(v.Level, LevelHigh)
+	a.Equal(v.Levels, []Level{LevelLow, LevelHigh})
+
+	// Money 没有任何导出字段，只能通过 json.Unmarshaler 解析。
+	a.Equal(v.Price.cents, int64(345))
+
+	err := dec.Decode(Make(RawData{"level": "unknown"}), &v)
+	a.NonNilError(err)
+	a.Equal(err.Error(), "go-data: fail to decode `level`: unknown level unknown")
+}
+
+func TestDecodeEnum(t *testing.T) {
+	type Value struct {
+		Status  int     `data:"status,enum=active:1|inactive:0"`
+		Level   *uint8  `data:"level,enum=low:1|high:2"`
+		Kind    string  `data:"kind,enum=a:alpha|b:beta"`
+		Enabled bool    `data:"enabled,enum=on:true|off:false"`
+		Ratio   float64 `data:"ratio,enum=half:0.5"`
+	}
+	cases := []struct {
+		Data     Data
+		Value    Value
+		HasError bool
+	}{
+		{ // 所有枚举值。
+			Make(RawData{
+				"status":  "active",
+				"level":   "high",
+				"kind":    "b",
+				"enabled": "on",
+				"ratio":   "half",
+			}),
+			Value{
+				Status:  1,
+				Level:   func() *uint8 { v := uint8(2); return &v }(),
+				Kind:    "beta",
+				Enabled: true,
+				Ratio:   0.5,
+			},
+			false,
+		},
+		{ // 不是字符串的值直接解析。
+			Make(RawData{
+				"status": 0,
+			}),
+			Value{},
+			false,
+		},
+		{ // 不认识的枚举名。
+			Make(RawData{
+				"status": "deleted",
+			}),
+			Value{},
+			true,
+		},
+	}
+	a := assert.New(t)
+	dec := &Decoder{}
+
+	for i, c := range cases {
+		a.Use(&i, &c)
+		var v Value
+		err := dec.Decode(c.Data, &v)
+
+		if c.HasError {
+			a.NonNilError(err)
+			continue
+		}
+
+		a.NilError(err)
+		a.Equal(v, c.Value)
+	}
+}
+
+func TestDecoderCompile(t *testing.T) {
+	type Item struct {
+		ID   int    `data:"id"`
+		Name string `data:"name"`
+	}
+	type Value struct {
+		Title   string `data:"title"`
+		Items   []Item `data:"items"`
+		Skipped int    `data:"-"`
+		private int
+	}
+	a := assert.New(t)
+	dec := &Decoder{}
+	plan := dec.Compile(reflect.TypeOf(&Value{}))
+	a.Equal(plan.Type(), reflect.TypeOf(Value{}))
+	a.Equal(plan.NumField(), 2)
+	a.Assert(dec.plans[reflect.TypeOf(Item{})] != nil)
+	a.Assert(dec.Compile(reflect.TypeOf(Value{})) == plan)
+
+	d := Make(RawData{
+		"title": "list",
+		"items": []RawData{
+			{"id": 1, "name": "first"},
+			{"id": 2, "name": "second"},
+		},
+		"Skipped": 3,
+	})
+
+	for i := 0; i < 2; i++ {
+		a.Use(&i)
+		var v Value
+		a.NilError(dec.Decode(d, &v))
+		a.Equal(v, Value{
+			Title: "list",
+			Items: []Item{
+				{ID: 1, Name: "first"},
+				{ID: 2, Name: "second"},
+			},
+		})
+	}
+
+	// 修改 TagName 之后，已编译的 plan 不再适用。
+	dec.TagName = "other"
+	var v Value
+	a.NilError(dec.Decode(Make(RawData{"Title": "other"}), &v))
+	a.Equal(v.Title, "other")
+
+	// 编译过的 plan 同样可以检查 squash 字段冲突。
+	dec = &Decoder{
+		DetectSquashCollisions: true,
+	}
+	dec.Compile(reflect.TypeOf(collisionOuter{}))
+	var outer collisionOuter
+	a.NonNilError(dec.Decode(Make(RawData{"port": 1}), &outer))
+}
+
+func TestDecodeDataElements(t *testing.T) {
+	a := assert.New(t)
+	d, err := ParseJSON(`{
+		"list": [{"a": 1}, null, {}],
+		"ptrs": [{"b": 2}, null, {}],
+		"map": {"x": {"c": 3}, "y": null, "z": {}},
+		"items": [{"id": 1}, {"id": 2}]
+	}`)
+	a.NilError(err)
+
+	type Item struct {
+		ID int `data:"id"`
+	}
+	var v struct {
+		List  []Data          `data:"list"`
+		Ptrs  []*Data         `data:"ptrs"`
+		Array [3]*Data        `data:"ptrs"`
+		Map   map[string]Data `data:"map"`
+		Items []*Item         `data:"items"`
+	}
+	dec := &Decoder{}
+	a.NilError(dec.Decode(d, &v))
+
+	a.Equal(v.List, []Data{Make(RawData{"a": 1}), emptyData, emptyData})
+	a.Equal(v.Ptrs, []*Data{{data: RawData{"b": int64(2)}}, nil, &emptyData})
+	a.Equal(v.Array, [3]*Data{{data: RawData{"b": int64(2)}}, nil, &emptyData})
+	a.Equal(v.Map, map[string]Data{
+		"x": Make(RawData{"c": 3}),
+		"y": emptyData,
+		"z": emptyData,
+	})
+	a.Equal(v.Items, []*Item{{ID: 1}, {ID: 2}})
+
+	var list []*Data
+	a.NilError(dec.DecodeQuery(d, "list", &list))
+	a.Equal(len(list), 3)
+	a.Equal(list[0].Get("a"), int64(1))
+	a.Assert(list[1] == nil)
+}
+
+func TestDecodePointerCollections(t *testing.T) {
+	type Item struct {
+		ID int `data:"id"`
+	}
+	type Model struct {
+		Slice *[]int           `data:"slice"`
+		Map   *map[string]int  `data:"map"`
+		Item  **Item           `data:"item"`
+		Items *[]*Item         `data:"items"`
+		Refs  *map[string]Item `data:"refs"`
+	}
+	emptyItem := &Item{}
+	oneItem := &Item{ID: 1}
+
+	cases := []struct {
+		Data  RawData
+		Model Model
+	}{
+		{ // 字段不存在
+			RawData{},
+			Model{},
+		},
+		{ // 值为 nil
+			RawData{
+				"slice": nil,
+				"map":   nil,
+				"item":  nil,
+				"items": nil,
+				"refs":  nil,
+			},
+			Model{},
+		},
+		{ // 值为 nil 的 slice、map 和指针
+			RawData{
+				"slice": []int(nil),
+				"map":   map[string]int(nil),
+				"item":  (*Item)(nil),
+				"items": []RawData(nil),
+				"refs":  RawData(nil),
+			},
+			Model{},
+		},
+		{ // 值为 nil 的 Data
+			RawData{
+				"item": Data{},
+				"refs": Data{},
+			},
+			Model{},
+		},
+		{ // 空值
+			RawData{
+				"slice": []interface{}{},
+				"map":   RawData{},
+				"item":  RawData{},
+				"items": []interface{}{},
+				"refs":  RawData{},
+			},
+			Model{
+				Slice: &[]int{},
+				Map:   &map[string]int{},
+				Item:  &emptyItem,
+				Items: &[]*Item{},
+				Refs:  &map[string]Item{},
+			},
+		},
+		{ // 非空值
+			RawData{
+				"slice": []int64{1},
+				"map":   RawData{"a": 1},
+				"item":  &RawData{"id": 1},
+				"items": []interface{}{RawData{"id": 1}, nil},
+				"refs":  RawData{"a": Data{data: RawData{"id": 1}}},
+			},
+			Model{
+				Slice: &[]int{1},
+				Map:   &map[string]int{"a": 1},
+				Item:  &oneItem,
+				Items: &[]*Item{oneItem, nil},
+				Refs:  &map[string]Item{"a": {ID: 1}},
+			},
+		},
+	}
+	a := assert.New(t)
+	dec := &Decoder{}
+
+	for i, c := range cases {
+		a.Use(&i, &c)
+
+		var m Model
+		a.NilError(dec.Decode(Data{data: c.Data}, &m))
+		a.Equal(m, c.Model)
+
+		if c.Model.Slice != nil {
+			a.Assert(*m.Slice != nil)
+			a.Assert(*m.Map != nil)
+			a.Assert(*m.Items != nil)
+			a.Assert(*m.Refs != nil)
+		}
+	}
+}
+
+func TestDecodeElements(t *testing.T) {
+	type Item struct {
+		ID int `data:"id"`
+	}
+
+	a := assert.New(t)
+	d, err := ParseJSON(`{
+		"a": {
+			"items": [{"id": 1}, {"id": "x"}, {"id": 3}, "bad"],
+			"str": "str"
+		}
+	}`)
+	a.NilError(err)
+	dec := &Decoder{}
+
+	// DecodeQuery 的错误中包含完整的路径。
+	var items []Item
+	err = dec.DecodeQuery(d, "a.items", &items)
+	a.Equal(err.Error(), "go-data: fail to decode `a.items.1.id`: cannot decode a value of type int from string")
+	err = dec.DecodeField(d, []string{"a", "items"}, &items)
+	a.Equal(err.Error(), "go-data: fail to decode `a.items.1.id`: cannot decode a value of type int from string")
+
+	items = nil
+	skipped, err := dec.DecodeElements(d, "a.items", &items)
+	a.NilError(err)
+	a.Equal(items, []Item{{ID: 1}, {ID: 3}})
+	a.Equal(len(skipped), 2)
+	a.Equal(skipped[0].Fields, []string{"a", "items", "1", "id"})
+	a.Equal(skipped[1].Fields, []string{"a", "items", "3"})
+	a.Equal(skipped[1].Error(), "go-data: fail to decode `a.items.3`: cannot decode a value of type data.Item from string")
+
+	ptrs := []*Item{{ID: 100}}
+	skipped, err = dec.DecodeElements(d, "a.items", &ptrs)
+	a.NilError(err)
+	a.Equal(ptrs, []*Item{{ID: 1}, {ID: 3}})
+	a.Equal(len(skipped), 2)
+
+	// 值不存在时 v 保持不变。
+	skipped, err = dec.DecodeElements(d, "a.not_exist", &ptrs)
+	a.NilError(err)
+	a.Equal(len(skipped), 0)
+	a.Equal(len(ptrs), 2)
+
+	_, err = dec.DecodeElements(d, "a.str", &items)
+	a.Equal(err.Error(), "go-data: fail to decode `a.str`: cannot decode elements from a value of type string")
+	_, err = dec.DecodeElements(d, "a.items", items)
+	a.NonNilError(err)
+}
@@ -0,0 +1,170 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DiffOp 是 DiffChange 的变更类型。
+type DiffOp int
+
+// 所有的变更类型。
+const (
+	DiffAdded   DiffOp = iota + 1 // 新增了值。
+	DiffRemoved                   // 删除了值。
+	DiffChanged                   // 修改了值。
+)
+
+func (op DiffOp) String() string {
+	switch op {
+	case DiffAdded:
+		return "added"
+	case DiffRemoved:
+		return "removed"
+	case DiffChanged:
+		return "changed"
+	}
+
+	return "DiffOp(" + strconv.Itoa(int(op)) + ")"
+}
+
+// DiffChange 记录了一处变更。
+type DiffChange struct {
+	Op   DiffOp      // 变更类型。
+	Path string      // 变更的值的路径，格式与 `Data#Query` 相同。
+	Old  interface{} // 变更前的值，新增时为 nil。
+	New  interface{} // 变更后的值，删除时为 nil。
+}
+
+// DiffReport 记录了两个 Data 之间的所有变更。
+type DiffReport struct {
+	Changes []DiffChange // 所有变更，同一个 object 中的变更按照 key 的字典序排列。
+}
+
+// Diff 比较 before 和 after，返回从 before 变成 after 的所有变更。
+//
+// object 会逐个 key 深度比较；两个 slice 会逐个下标比较，多出来的元素记为新增或删除；
+// 其他情况下，如果值不同，记为修改了整个值。
+func Diff(before, after Data) *DiffReport {
+	report := &DiffReport{}
+	report.diffObject(nil, before.data, after.data)
+	return report
+}
+
+// Len 返回变更的个数。
+func (report *DiffReport) Len() int {
+	return len(report.Changes)
+}
+
+// String 返回便于阅读的变更列表，每行一个变更，
+// 分别用 `+`、`-`、`~` 表示新增、删除和修改，值使用 JSON 格式输出。
+func (report *DiffReport) String() string {
+	buf := &strings.Builder{}
+
+	for _, c := range report.Changes {
+		switch c.Op {
+		case DiffAdded:
+			fmt.Fprintf(buf, "+ %v: %v\n", c.Path, diffValueString(c.New))
+		case DiffRemoved:
+			fmt.Fprintf(buf, "- %v: %v\n", c.Path, diffValueString(c.Old))
+		case DiffChanged:
+			fmt.Fprintf(buf, "~ %v: %v -> %v\n", c.Path, diffValueString(c.Old), diffValueString(c.New))
+		}
+	}
+
+	return buf.String()
+}
+
+func diffValueString(v interface{}) string {
+	if buf, err := json.Marshal(v); err == nil {
+		return string(buf)
+	}
+
+	return fmt.Sprint(v)
+}
+
+func (report *DiffReport) add(op DiffOp, path []string, from, to interface{}) {
+	report.Changes = append(report.Changes, DiffChange{
+		Op:   op,
+		Path: JoinQuery(path...),
+		Old:  from,
+		New:  to,
+	})
+}
+
+func (report *DiffReport) diffObject(path []string, before, after RawData) {
+	keys := make([]string, 0, len(before)+len(after))
+
+	for k := range before {
+		keys = append(keys, k)
+	}
+
+	for k := range after {
+		if _, ok := before[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		from, inBefore := before[k]
+		to, inAfter := after[k]
+		p := append(path[:len(path):len(path)], k)
+
+		switch {
+		case !inAfter:
+			report.add(DiffRemoved, p, from, nil)
+		case !inBefore:
+			report.add(DiffAdded, p, nil, to)
+		default:
+			report.diffValue(p, from, to)
+		}
+	}
+}
+
+func (report *DiffReport) diffValue(path []string, before, after interface{}) {
+	if d, ok := before.(Data); ok {
+		before = d.data
+	}
+
+	if d, ok := after.(Data); ok {
+		after = d.data
+	}
+
+	bm, bok := before.(RawData)
+	am, aok := after.(RawData)
+
+	if bok && aok {
+		report.diffObject(path, bm, am)
+		return
+	}
+
+	be, bok := sliceElems(before)
+	ae, aok := sliceElems(after)
+
+	if bok && aok {
+		for i := 0; i < len(be) || i < len(ae); i++ {
+			p := append(path[:len(path):len(path)], strconv.Itoa(i))
+
+			switch {
+			case i >= len(ae):
+				report.add(DiffRemoved, p, be[i], nil)
+			case i >= len(be):
+				report.add(DiffAdded, p, nil, ae[i])
+			default:
+				report.diffValue(p, be[i], ae[i])
+			}
+		}
+
+		return
+	}
+
+	if !reflect.DeepEqual(before, after) {
+		report.add(DiffChanged, path, before, after)
+	}
+}
@@ -0,0 +1,67 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestDiff(t *testing.T) {
+	cases := []struct {
+		Before  Data
+		After   Data
+		Changes []DiffChange
+	}{
+		{
+			Data{},
+			Data{},
+			nil,
+		},
+		{
+			Make(RawData{"a": 1, "b": "b", "c": true}),
+			Make(RawData{"a": 2, "b": "b", "d": 1.5}),
+			[]DiffChange{
+				{DiffChanged, "a", int64(1), int64(2)},
+				{DiffRemoved, "c", true, nil},
+				{DiffAdded, "d", nil, 1.5},
+			},
+		},
+		{
+			Make(RawData{"obj": RawData{"x": 1, "y": RawData{"z": 1}}}),
+			Make(RawData{"obj": RawData{"x": 1, "y": RawData{"z": "1"}}}),
+			[]DiffChange{
+				{DiffChanged, "obj.y.z", int64(1), "1"},
+			},
+		},
+		{
+			Make(RawData{"list": []int{1, 2, 3}, "objs": []RawData{{"a": 1}}}),
+			Make(RawData{"list": []int{1, 4}, "objs": []RawData{{"a": 1}, {"b": 2}}}),
+			[]DiffChange{
+				{DiffChanged, "list.1", int64(2), int64(4)},
+				{DiffRemoved, "list.2", int64(3), nil},
+				{DiffAdded, "objs.1", nil, RawData{"b": int64(2)}},
+			},
+		},
+		{ // 类型不同的值整个替换。
+			Make(RawData{"a": RawData{"b": 1}}),
+			Make(RawData{"a": []int{1}}),
+			[]DiffChange{
+				{DiffChanged, "a", RawData{"b": int64(1)}, []int64{1}},
+			},
+		},
+	}
+	a := assert.New(t)
+
+	for i, c := range cases {
+		a.Use(&i, &c)
+		report := Diff(c.Before, c.After)
+		a.Equal(report.Changes, c.Changes)
+		a.Equal(report.Len(), len(c.Changes))
+	}
+
+	report := Diff(Make(RawData{"a": 1, "b": "old", "c": true}), Make(RawData{"a": 1, "b": "new", "d": []string{"x"}}))
+	a.Equal(report.String(), `~ b: "old" -> "new"
+- c: true
++ d: ["x"]
+`)
+}
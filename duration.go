@@ -0,0 +1,113 @@
+package data
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+)
+
+// DurationFormat 是 time.Duration 在 Data 中的表示方式。
+type DurationFormat int
+
+// 所有支持的 DurationFormat。
+const (
+	DurationString       DurationFormat = iota // 字符串，比如 "1h13m20s"，零值是空字符串。
+	DurationMilliseconds                       // int64 毫秒数，不足 1 毫秒的部分会被舍去。
+	DurationSeconds                            // int64 秒数，不足 1 秒的部分会被舍去。
+	DurationFloatSeconds                       // float64 秒数。
+)
+
+// encode 将 dur 转化成 Data 中的值。
+func (f DurationFormat) encode(dur time.Duration) interface{} {
+	switch f {
+	case DurationMilliseconds:
+		return int64(dur / time.Millisecond)
+	case DurationSeconds:
+		return int64(dur / time.Second)
+	case DurationFloatSeconds:
+		return dur.Seconds()
+	}
+
+	if dur == 0 {
+		return ""
+	}
+
+	return dur.String()
+}
+
+// valueType 返回 encode 结果的类型。
+func (f DurationFormat) valueType() reflect.Type {
+	switch f {
+	case DurationMilliseconds, DurationSeconds:
+		return typeOfInt64
+	case DurationFloatSeconds:
+		return typeOfFloat64
+	}
+
+	return typeOfString
+}
+
+// decode 将 Data 中的值 from 转化成 time.Duration。
+// 无论 f 是什么，字符串总是可以被解析；数字则按照 f 的单位解析，如果 f 是 DurationString 则报错。
+func (f DurationFormat) decode(from reflect.Value) (dur time.Duration, err error) {
+	var unit time.Duration
+
+	switch f {
+	case DurationMilliseconds:
+		unit = time.Millisecond
+	case DurationSeconds, DurationFloatSeconds:
+		unit = time.Second
+	}
+
+	switch from.Kind() {
+	case reflect.String:
+		if str := from.String(); str != "" {
+			dur, err = time.ParseDuration(str)
+		}
+
+		return
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if unit != 0 {
+			i := from.Int()
+
+			if i > math.MaxInt64/int64(unit) || i < math.MinInt64/int64(unit) {
+				err = fmt.Errorf("go-data: cannot decode a value of type %v from %v due to overflow", typeOfDuration, i)
+				return
+			}
+
+			dur = time.Duration(i) * unit
+			return
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if unit != 0 {
+			u := from.Uint()
+
+			if u > uint64(math.MaxInt64/int64(unit)) {
+				err = fmt.Errorf("go-data: cannot decode a value of type %v from %v due to overflow", typeOfDuration, u)
+				return
+			}
+
+			dur = time.Duration(u) * unit
+			return
+		}
+
+	case reflect.Float32, reflect.Float64:
+		if unit != 0 {
+			fl := from.Float() * float64(unit)
+
+			if fl > math.MaxInt64 || fl < math.MinInt64 {
+				err = fmt.Errorf("go-data: cannot decode a value of type %v from %v due to overflow", typeOfDuration, from.Float())
+				return
+			}
+
+			dur = time.Duration(math.Round(fl))
+			return
+		}
+	}
+
+	err = fmt.Errorf("go-data: cannot decode a value of type %v from %v", typeOfDuration, from.Type())
+	return
+}
@@ -4,12 +4,78 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"time"
+
+	"github.com/tidwall/gjson"
 )
 
 // Encoder 用来将数据转化成 Data。
 type Encoder struct {
-	TagName   string // 在解析 struct 时候使用的 field tag，默认是 data。
-	OmitEmpty bool   // 如果为 true，则默认所有字段都会忽略空值。
+	TagName                string // 在解析 struct 时候使用的 field tag，默认是 data。
+	OmitEmpty              bool   // 如果为 true，则默认所有字段都会忽略空值。
+	DetectSquashCollisions bool   // 如果为 true，squash 展开的字段与其他字段 key 相同时报错。
+	KeepEmpty              bool   // 如果为 true，omitempty 不会忽略非 nil 的空 map 和 slice。
+
+	// DurationFormat 是 time.Duration 的编码格式，默认是 DurationString。
+	// 使用其他格式编码的 Data 需要使用相同设置的 Decoder 来解码。
+	DurationFormat DurationFormat
+
+	// TimeLocation 不为 nil 时，所有 time.Time 都会先转化成这个时区的时间再保存，比如 time.UTC，
+	// 这样序列化的结果不会包含服务器本地的时区，不同地区生成的 Data 也可以直接比较。
+	// 转化不会改变时间点本身，但会去掉 time.Time 中的单调时钟读数。
+	TimeLocation *time.Location
+
+	// KeepNumericKinds 为 true 时，数字类型会保留原始的 kind，比如 int32 依然是 int32，而不会变成 int64。
+	// 类型别名依然会被消除，比如 `type MyInt int32` 会变成 int32；uintptr 不是 Data 支持的类型，会变成 uint64。
+	//
+	// 需要注意，这个选项只影响 Encoder 生成的 Data。JSON 和二进制格式都不记录数字的 kind，
+	// 序列化再解析之后依然会变成 int64/uint64/float64/complex128；
+	// 此外，Merge 只会深度合并类型相同的 slice，比如 []int32 与 []int64 合并时后者会覆盖前者。
+	KeepNumericKinds bool
+
+	emptyCheckers map[reflect.Type]func(v interface{}) bool
+	transformers  map[string]Transformer
+	types         map[reflect.Type]EncodeFunc
+}
+
+// EncodeFunc 将注册类型的值 v 转化成 Data 支持的值，返回值会再经过 Encoder 的标准化处理。
+type EncodeFunc func(v interface{}) (interface{}, error)
+
+// RegisterType 为类型 t 注册一个编码函数，所有 t 类型的值都会使用 fn 来转化，
+// 这个函数的优先级高于 json.Marshaler 以及 time.Time 等内置的特殊处理。
+// fn 不能返回 t 类型的值，否则编码时会报错。
+func (enc *Encoder) RegisterType(t reflect.Type, fn EncodeFunc) {
+	if enc.types == nil {
+		enc.types = map[reflect.Type]EncodeFunc{}
+	}
+
+	enc.types[t] = fn
+}
+
+// RegisterTransformer 注册一个名为 name 的 Transformer。
+// 如果字段 tag 中设置了 encoder=name，编码时会先用 fn 转化字段值，再将转化的结果编码到 Data 中。
+func (enc *Encoder) RegisterTransformer(name string, fn Transformer) {
+	if enc.transformers == nil {
+		enc.transformers = map[string]Transformer{}
+	}
+
+	enc.transformers[name] = fn
+}
+
+// EmptyChecker 用来定制 omitempty 的行为。
+// 如果一个类型实现了 EmptyChecker，Encoder 会使用 IsEmptyData 的结果来判断这个值是否为空，而不是判断它是否是零值。
+type EmptyChecker interface {
+	IsEmptyData() bool
+}
+
+// RegisterEmptyChecker 为类型 t 注册一个判断空值的函数，用于 omitempty。
+// 这适合无法为其实现 EmptyChecker 的第三方类型，注册的函数优先于 EmptyChecker 使用。
+func (enc *Encoder) RegisterEmptyChecker(t reflect.Type, fn func(v interface{}) bool) {
+	if enc.emptyCheckers == nil {
+		enc.emptyCheckers = map[reflect.Type]func(v interface{}) bool{}
+	}
+
+	enc.emptyCheckers[t] = fn
 }
 
 // Encode 将任意的 Go 类型转化成 Data。
@@ -17,9 +83,22 @@ type Encoder struct {
 // 需要注意，只有以下类型可以成功转化成 Data，如果 v 不是这些类型，Encode 会返回 nil。
 //     - Go struct 和 struct 指针；
 //     - 任意的 map[string]T 类型，T 可以是任意的类型。
+//
+// 如果某个值实现了 json.Marshaler，Encode 会解析 MarshalJSON 的结果作为这个值，而不是通过反射读取它的字段。
+// 与 encoding/json 相同，嵌入了 time.Time 等类型的 struct 也会通过嵌入字段的 MarshalJSON 转化，
+// 其他字段会被忽略，如果不希望这样，需要给这个 struct 实现自己的 MarshalJSON 或者不使用嵌入字段。
+//
+// 如果转化过程出错，Encode 返回空 Data，可以使用 EncodeE 获得具体的错误。
 func (enc *Encoder) Encode(v interface{}) Data {
+	d, _ := enc.EncodeE(v)
+	return d
+}
+
+// EncodeE 将任意的 Go 类型转化成 Data，如果转化过程出错则返回错误。
+// 支持转化的类型与 Encode 相同。
+func (enc *Encoder) EncodeE(v interface{}) (d Data, err error) {
 	if v == nil {
-		return emptyData
+		return
 	}
 
 	val := reflect.ValueOf(v)
@@ -30,13 +109,31 @@ func (enc *Encoder) Encode(v interface{}) Data {
 		val = val.Elem()
 	}
 
-	d := enc.encodeValue(val)
-	return Data{
-		data: d,
+	raw, err := enc.encodeValue(val)
+
+	if err != nil {
+		return
+	}
+
+	d = Data{
+		data: raw,
 	}
+	return
 }
 
-func (enc *Encoder) encodeValue(val reflect.Value) RawData {
+func (enc *Encoder) encodeValue(val reflect.Value) (RawData, error) {
+	if m, ok := jsonMarshaler(val); ok {
+		v, err := encodeJSONMarshaler(m)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// 只有 JSON object 才能转化成 Data。
+		d, _ := v.(RawData)
+		return d, nil
+	}
+
 	switch val.Kind() {
 	case reflect.Map:
 		return enc.encodeMap(val)
@@ -44,57 +141,74 @@ func (enc *Encoder) encodeValue(val reflect.Value) RawData {
 		return enc.encodeStruct(val)
 	}
 
-	return nil
+	return nil, nil
 }
 
-func (enc *Encoder) encodeMap(val reflect.Value) RawData {
+func (enc *Encoder) encodeMap(val reflect.Value) (RawData, error) {
 	t := val.Type()
 
 	if t.Key().Kind() != reflect.String {
-		return nil
+		return nil, nil
 	}
 
 	d := RawData{}
 
 	if val.Len() == 0 {
-		return nil
+		return nil, nil
 	}
 
 	iter := val.MapRange()
 
 	for iter.Next() {
 		k := iter.Key()
-		v := iter.Value()
+		v, err := enc.encodeMapValue(iter.Value())
 
-		d[k.String()] = enc.encodeMapValue(v)
+		if err != nil {
+			return nil, err
+		}
+
+		d[k.String()] = v
 	}
 
-	return d
+	return d, nil
 }
 
-func (enc *Encoder) encodeStruct(val reflect.Value) RawData {
+func (enc *Encoder) encodeStruct(val reflect.Value) (RawData, error) {
 	d := RawData{}
-	enc.encodeStructToData(val, d)
-	return d
+
+	if err := enc.encodeStructToData(val, d); err != nil {
+		return nil, err
+	}
+
+	return d, nil
 }
 
-func (enc *Encoder) encodeStructToData(val reflect.Value, d RawData) {
+func (enc *Encoder) tagName() string {
+	if enc.TagName == "" {
+		return defaultTagName
+	}
+
+	return enc.TagName
+}
+
+func (enc *Encoder) encodeStructToData(val reflect.Value, d RawData) error {
 	if val.Type().AssignableTo(typeOfData) {
 		merge(reflect.ValueOf(d), val.Convert(typeOfData).Interface().(Data).data)
-		return
+		return nil
 	}
 
 	t := val.Type()
 	l := t.NumField()
+	tagName := enc.tagName()
 
-	for i := 0; i < l; i++ {
-		f := t.Field(i)
-		tagName := enc.TagName
-
-		if tagName == "" {
-			tagName = defaultTagName
+	if enc.DetectSquashCollisions {
+		if err := checkSquashCollisions(t, tagName); err != nil {
+			return err
 		}
+	}
 
+	for i := 0; i < l; i++ {
+		f := t.Field(i)
 		tag := f.Tag.Get(tagName)
 		ft := ParseFieldTag(tag)
 
@@ -109,12 +223,26 @@ func (enc *Encoder) encodeStructToData(val reflect.Value, d RawData) {
 		}
 
 		fv := val.Field(i)
-		v := enc.encodeMapValue(fv)
+		v, err := enc.encodeField(fv, f, ft)
+
+		if err != nil {
+			return err
+		}
 
-		if (ft.OmitEmpty || enc.OmitEmpty) && isEmpty(v) {
+		if (ft.OmitEmpty || enc.OmitEmpty) && enc.isEmpty(fv, v) {
 			continue
 		}
 
+		if len(ft.Enum) != 0 && v != nil {
+			name, ok := ft.enumName(fmt.Sprint(v))
+
+			if !ok {
+				return fmt.Errorf("go-data: value `%v` of field `%v` is not a valid enum value", v, f.Name)
+			}
+
+			v = name
+		}
+
 		// 如果需要合并字段，且 v 是一个 Data，那么会将 v 内容浅拷贝到 d 里面。
 		if ft.Squash {
 			if data, ok := v.(RawData); ok {
@@ -128,6 +256,108 @@ func (enc *Encoder) encodeStructToData(val reflect.Value, d RawData) {
 
 		d[k] = v
 	}
+
+	return nil
+}
+
+// encodeField 编码 struct 字段 fv，如果设置了 encoder 选项，先使用对应的 Transformer 转化 fv。
+func (enc *Encoder) encodeField(fv reflect.Value, f reflect.StructField, ft *FieldTag) (interface{}, error) {
+	if ft.Encoder == "" {
+		return enc.encodeMapValue(fv)
+	}
+
+	fn, ok := enc.transformers[ft.Encoder]
+
+	if !ok {
+		return nil, fmt.Errorf("go-data: transformer `%v` of field `%v` is not registered", ft.Encoder, f.Name)
+	}
+
+	if !fv.CanInterface() {
+		return nil, fmt.Errorf("go-data: cannot transform unexported field `%v`", f.Name)
+	}
+
+	v, err := fn(fv.Interface())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return enc.encodeMapValue(reflect.ValueOf(v))
+}
+
+// isEmpty 判断 fv 编码后的值 v 是否为空。
+// 如果 fv 的类型注册了判断空值的函数或者实现了 EmptyChecker，优先使用它们的结果。
+// 如果设置了 KeepEmpty，非 nil 的空 map 和 slice 不会被当做空值，
+// 这样可以保证 `{}` 和 `[]` 在编码和序列化之后依然存在。
+func (enc *Encoder) isEmpty(fv reflect.Value, v interface{}) bool {
+	if empty, ok := enc.checkEmpty(fv); ok {
+		return empty
+	}
+
+	if enc.KeepEmpty {
+		for fv.Kind() == reflect.Ptr || fv.Kind() == reflect.Interface {
+			if fv.IsNil() {
+				break
+			}
+
+			fv = fv.Elem()
+		}
+
+		switch fv.Kind() {
+		case reflect.Map, reflect.Slice:
+			if !fv.IsNil() {
+				return false
+			}
+		}
+	}
+
+	return isEmpty(v)
+}
+
+// checkEmpty 使用注册的函数或 EmptyChecker 判断 fv 是否为空，如果都没有则 ok 为 false。
+// 如果 fv 是指针，会逐级检查指针指向的值；对于 nil 指针，总是返回 ok 为 false，由默认规则处理。
+func (enc *Encoder) checkEmpty(fv reflect.Value) (empty bool, ok bool) {
+	if len(enc.emptyCheckers) != 0 {
+		for v := fv; v.IsValid() && v.CanInterface(); v = v.Elem() {
+			if fn, found := enc.emptyCheckers[v.Type()]; found {
+				return fn(v.Interface()), true
+			}
+
+			if !isNonNilRef(v) {
+				break
+			}
+		}
+	}
+
+	for v := fv; v.IsValid() && v.CanInterface(); v = v.Elem() {
+		kind := v.Kind()
+
+		if (kind == reflect.Ptr || kind == reflect.Interface) && v.IsNil() {
+			break
+		}
+
+		if checker, found := v.Interface().(EmptyChecker); found {
+			return checker.IsEmptyData(), true
+		}
+
+		if !isNonNilRef(v) {
+			if v.CanAddr() {
+				if checker, found := v.Addr().Interface().(EmptyChecker); found {
+					return checker.IsEmptyData(), true
+				}
+			}
+
+			break
+		}
+	}
+
+	return
+}
+
+// isNonNilRef 判断 v 是否是非 nil 的指针或 interface。
+func isNonNilRef(v reflect.Value) bool {
+	kind := v.Kind()
+	return (kind == reflect.Ptr || kind == reflect.Interface) && !v.IsNil()
 }
 
 func isEmpty(v interface{}) bool {
@@ -149,20 +379,47 @@ func isEmpty(v interface{}) bool {
 	return false
 }
 
-func (enc *Encoder) encodeMapValue(val reflect.Value) interface{} {
+func (enc *Encoder) encodeMapValue(val reflect.Value) (interface{}, error) {
 	if !val.IsValid() {
-		return nil
+		return nil, nil
+	}
+
+	if fn, ok := enc.types[val.Type()]; ok && val.CanInterface() {
+		v, err := fn(val.Interface())
+
+		if err != nil {
+			return nil, err
+		}
+
+		if reflect.TypeOf(v) == val.Type() {
+			return nil, fmt.Errorf("go-data: encode func of type %v returns a value of the same type", val.Type())
+		}
+
+		return enc.encodeMapValue(reflect.ValueOf(v))
 	}
 
 	switch val.Type() {
 	case typeOfTime:
-		return val.Interface()
-	case typeOfDuration:
-		if val.Int() == 0 {
-			return ""
+		t := val.Interface().(time.Time)
+
+		if enc.TimeLocation != nil {
+			t = t.In(enc.TimeLocation)
 		}
 
-		return val.Interface().(fmt.Stringer).String()
+		return t, nil
+	case typeOfDuration:
+		return enc.DurationFormat.encode(time.Duration(val.Int())), nil
+	}
+
+	// 对于实现了 json.Marshaler 的类型，使用 MarshalJSON 的结果，而不是通过反射读取它的字段。
+	if m, ok := jsonMarshaler(val); ok {
+		return encodeJSONMarshaler(m)
+	}
+
+	if enc.KeepNumericKinds {
+		if v, ok := keepNumericKind(val); ok {
+			return v, nil
+		}
 	}
 
 	switch val.Kind() {
@@ -170,19 +427,19 @@ func (enc *Encoder) encodeMapValue(val reflect.Value) interface{} {
 	// 例如所有的 int* 都变成 int64。
 
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return val.Int()
+		return val.Int(), nil
 
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return val.Uint()
+		return val.Uint(), nil
 
 	case reflect.Float32, reflect.Float64:
-		return val.Float()
+		return val.Float(), nil
 
 	case reflect.Complex64, reflect.Complex128:
-		return val.Complex()
+		return val.Complex(), nil
 
 	case reflect.Invalid:
-		return nil
+		return nil, nil
 
 	case reflect.String:
 		// 需要特别的支持 json.Number，将这种字符串变成数字。
@@ -190,30 +447,40 @@ func (enc *Encoder) encodeMapValue(val reflect.Value) interface{} {
 			i64, err := num.Int64()
 
 			if err == nil {
-				return i64
+				return i64, nil
 			}
 
 			f64, err := num.Float64()
 
 			if err == nil {
-				return f64
+				return f64, nil
 			}
 
 			// 如果不是合法的数字，将这个类型还原成普通的 string。
-			return string(num)
+			return string(num), nil
 		}
 
 	case reflect.Array, reflect.Slice:
 		l := val.Len()
-		sliceType := reflect.SliceOf(toLargestType(val.Type().Elem()))
+		sliceType := reflect.SliceOf(enc.sliceElemType(val.Type().Elem()))
 		values := reflect.MakeSlice(sliceType, l, l)
 
 		for i := 0; i < l; i++ {
-			v := enc.encodeMapValue(val.Index(i))
+			v, err := enc.encodeMapValue(val.Index(i))
+
+			if err != nil {
+				return nil, err
+			}
+
+			// nil 元素保持零值即可。
+			if v == nil {
+				continue
+			}
+
 			values.Index(i).Set(reflect.ValueOf(v))
 		}
 
-		return values.Interface()
+		return values.Interface(), nil
 
 	case reflect.Interface, reflect.Ptr:
 		val = val.Elem()
@@ -224,38 +491,147 @@ func (enc *Encoder) encodeMapValue(val reflect.Value) interface{} {
 		kt := t.Key()
 
 		if k := kt.Kind(); k != reflect.String {
-			return val.Interface()
+			return val.Interface(), nil
 		}
 
 		d := RawData{}
 
 		if val.Len() == 0 {
-			return d
+			return d, nil
 		}
 
 		iter := val.MapRange()
 
 		for iter.Next() {
 			k := iter.Key()
-			v := iter.Value()
+			v, err := enc.encodeMapValue(iter.Value())
 
-			d[k.String()] = enc.encodeMapValue(v)
+			if err != nil {
+				return nil, err
+			}
+
+			d[k.String()] = v
 		}
 
-		return d
+		return d, nil
 
 	case reflect.Struct:
 		return enc.encodeStruct(val)
 
 	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
 		// 这些类型不是数据。
-		return nil
+		return nil, nil
 	}
 
-	return val.Interface()
+	return val.Interface(), nil
+}
+
+// jsonMarshaler 判断 val 是否需要通过 json.Marshaler 转化，如果需要则返回对应的 json.Marshaler。
+// time.Time 和 Data 虽然也实现了 json.Marshaler，但它们本身就是 Data 支持的类型，不需要转化。
+func jsonMarshaler(val reflect.Value) (json.Marshaler, bool) {
+	if !val.IsValid() || val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		return nil, false
+	}
+
+	if !isJSONMarshalerType(val.Type()) {
+		return nil, false
+	}
+
+	if m, ok := val.Interface().(json.Marshaler); ok {
+		return m, true
+	}
+
+	if val.CanAddr() {
+		if m, ok := val.Addr().Interface().(json.Marshaler); ok {
+			return m, true
+		}
+	}
+
+	return nil, false
+}
+
+// isJSONMarshalerType 判断 t 或者 *t 是否实现了 json.Marshaler，其中 time.Time 和 Data 除外。
+func isJSONMarshalerType(t reflect.Type) bool {
+	if t == typeOfTime || t.AssignableTo(typeOfData) || t.Kind() == reflect.Ptr || t.Kind() == reflect.Interface {
+		return false
+	}
+
+	return t.Implements(typeOfJSONMarshaler) || reflect.PtrTo(t).Implements(typeOfJSONMarshaler)
+}
+
+// encodeJSONMarshaler 调用 m.MarshalJSON 并将结果解析成 Data 支持的类型。
+func encodeJSONMarshaler(m json.Marshaler) (interface{}, error) {
+	buf, err := m.MarshalJSON()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !gjson.ValidBytes(buf) {
+		return nil, fmt.Errorf("%w: MarshalJSON of type %T returns %q", ErrInvalidJSON, m, buf)
+	}
+
+	jp := &jsonParser{}
+	v, _ := jp.parseValue(gjson.ParseBytes(buf))
+	return v, nil
+}
+
+// keepNumericKind 将数字 val 转化成同样 kind 的基础类型，如果 val 不是数字则 ok 为 false。
+func keepNumericKind(val reflect.Value) (v interface{}, ok bool) {
+	t, ok := numericTypes[val.Kind()]
+
+	if !ok {
+		return
+	}
+
+	nv := reflect.New(t).Elem()
+
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		nv.SetInt(val.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		nv.SetUint(val.Uint())
+	case reflect.Float32, reflect.Float64:
+		nv.SetFloat(val.Float())
+	case reflect.Complex64, reflect.Complex128:
+		nv.SetComplex(val.Complex())
+	}
+
+	v = nv.Interface()
+	return
+}
+
+// sliceElemType 返回编码后 slice 的元素类型。
+func (enc *Encoder) sliceElemType(t reflect.Type) reflect.Type {
+	// 注册类型转化后的类型无法预知。
+	if _, ok := enc.types[t]; ok {
+		return typeOfInterface
+	}
+
+	if t == typeOfDuration {
+		return enc.DurationFormat.valueType()
+	}
+
+	if enc.KeepNumericKinds && !isJSONMarshalerType(t) {
+		if nt, ok := numericTypes[t.Kind()]; ok {
+			return nt
+		}
+	}
+
+	// 嵌套的 slice 也需要逐层标准化元素类型。
+	if k := t.Kind(); (k == reflect.Slice || k == reflect.Array) && !isJSONMarshalerType(t) {
+		return reflect.SliceOf(enc.sliceElemType(t.Elem()))
+	}
+
+	return toLargestType(t)
 }
 
 func toLargestType(t reflect.Type) reflect.Type {
+	// 实现了 json.Marshaler 的类型转化后的类型无法预知。
+	if isJSONMarshalerType(t) {
+		return typeOfInterface
+	}
+
 	switch t.Kind() {
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		return typeOfInt64
@@ -266,6 +642,11 @@ func toLargestType(t reflect.Type) reflect.Type {
 	case reflect.Complex64, reflect.Complex128:
 		return typeOfComplex128
 	case reflect.Struct:
+		// time.Time 是 Data 支持的类型，不会被转化成 RawData。
+		if t == typeOfTime {
+			return t
+		}
+
 		return typeOfObject
 	}
 
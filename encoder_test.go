@@ -1,7 +1,14 @@
 package data
 
 import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"reflect"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/huandu/go-assert"
 )
@@ -44,3 +51,565 @@ func TestEncoder(t *testing.T) {
 		a.Equal(c.Data, enc.Encode(c.Value))
 	}
 }
+
+func TestEncoderSquashCollisions(t *testing.T) {
+	a := assert.New(t)
+	v := &collisionOuter{
+		Inner: &collisionInner{
+			Name: "inner",
+			Port: 1,
+		},
+		Host: "host",
+		Port: 2,
+	}
+
+	enc := &Encoder{}
+	d, err := enc.EncodeE(v)
+	a.NilError(err)
+	a.Equal(d, Make(RawData{
+		"name": "inner",
+		"host": "host",
+		"port": 2,
+	}))
+
+	enc.DetectSquashCollisions = true
+	d, err = enc.EncodeE(v)
+	a.NonNilError(err)
+	a.Equal(d, Data{})
+	a.Equal(enc.Encode(v), Data{})
+}
+
+func TestEncoderKeepEmpty(t *testing.T) {
+	type T struct {
+		Map      map[string]int `data:"map,omitempty"`
+		Slice    []int          `data:"slice,omitempty"`
+		NilMap   map[string]int `data:"nil_map,omitempty"`
+		NilSlice []int          `data:"nil_slice,omitempty"`
+		PtrMap   *RawData       `data:"ptr_map,omitempty"`
+		Int      int            `data:"int,omitempty"`
+	}
+
+	a := assert.New(t)
+	v := &T{
+		Map:    map[string]int{},
+		Slice:  []int{},
+		PtrMap: &RawData{},
+	}
+
+	enc := &Encoder{}
+	d := enc.Encode(v)
+	a.Equal(d.JSON(false), `{}`)
+
+	enc.KeepEmpty = true
+	d = enc.Encode(v)
+	a.Equal(d.JSON(false), `{"map":{},"ptr_map":{},"slice":[]}`)
+
+	parsed, err := Parse(d.String())
+	a.NilError(err)
+	a.Equal(parsed.JSON(false), d.JSON(false))
+}
+
+func TestEncoderTimeSlice(t *testing.T) {
+	a := assert.New(t)
+	now := time.Now()
+	d := Make(RawData{
+		"times": []time.Time{now},
+	})
+	a.Equal(d.Get("times"), []time.Time{now})
+	a.NilError(d.validate())
+}
+
+func TestEncoderNestedSlice(t *testing.T) {
+	a := assert.New(t)
+	d := Make(RawData{
+		"nested": [][]int{{1, 2}, nil, {3}},
+		"deep":   [][][]uint8{{{1}}},
+		"array":  [2][]float32{{1.5}, nil},
+		"mixed":  []interface{}{nil, 1, []int{2}},
+	})
+
+	// 每一层的元素类型都会被标准化，nil 元素编码成空 slice。
+	a.Equal(d.Get("nested"), [][]int64{{1, 2}, {}, {3}})
+	a.Equal(d.Get("deep"), [][][]uint64{{{1}}})
+	a.Equal(d.Get("array"), [][]float64{{1.5}, {}})
+
+	// []interface{} 中的 nil 保持不变。
+	a.Equal(d.Get("mixed"), []interface{}{nil, int64(1), []int64{2}})
+	a.NilError(d.validate())
+}
+
+func TestEncoderTimeLocation(t *testing.T) {
+	type Event struct {
+		At    time.Time   `data:"at"`
+		Ptr   *time.Time  `data:"ptr"`
+		Times []time.Time `data:"times"`
+	}
+
+	a := assert.New(t)
+	shanghai := time.FixedZone("Asia/Shanghai", 8*3600)
+	at := time.Date(2020, 1, 2, 8, 4, 5, 0, shanghai)
+	utc := time.Date(2020, 1, 2, 0, 4, 5, 0, time.UTC)
+	event := &Event{
+		At:    at,
+		Ptr:   &at,
+		Times: []time.Time{at, {}},
+	}
+	enc := &Encoder{
+		TimeLocation: time.UTC,
+	}
+	d := enc.Encode(event)
+	a.Equal(d.Get("at"), utc)
+	a.Equal(d.Get("ptr"), utc)
+	a.Equal(d.Get("times"), []time.Time{utc, time.Time{}.In(time.UTC)})
+	a.Equal(enc.Encode(RawData{"at": at}).Get("at"), utc)
+	a.Equal(event.At, at)
+
+	// 转化后的时间点不变，再解析回来的值与原值相等。
+	var decoded Event
+	a.NilError((&Decoder{}).Decode(d, &decoded))
+	a.Assert(decoded.At.Equal(at))
+	a.Equal(decoded.At.Location(), time.UTC)
+
+	// 默认保留原来的时区。
+	a.Equal((&Encoder{}).Encode(event).Get("at"), at)
+}
+
+type Color int
+
+func (c Color) MarshalJSON() ([]byte, error) {
+	switch c {
+	case 1:
+		return []byte(`"red"`), nil
+	case 2:
+		return []byte(`"green"`), nil
+	}
+
+	return []byte(`"unknown"`), nil
+}
+
+type Secret struct {
+	value string
+}
+
+func (s *Secret) MarshalJSON() ([]byte, error) {
+	return []byte(`{"masked":` + strconv.Quote(s.value[:1]+"***") + `}`), nil
+}
+
+type BrokenMarshaler struct{}
+
+func (BrokenMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte(`{`), nil
+}
+
+func TestEncoderJSONMarshaler(t *testing.T) {
+	a := assert.New(t)
+	enc := &Encoder{}
+	now := time.Now()
+
+	d, err := enc.EncodeE(&struct {
+		Color  Color           `data:"color"`
+		Colors []Color         `data:"colors"`
+		Secret *Secret         `data:"secret"`
+		Raw    json.RawMessage `data:"raw"`
+		Time   time.Time       `data:"time"`
+		Data   Data            `data:"data"`
+	}{
+		Color:  1,
+		Colors: []Color{2, 3},
+		Secret: &Secret{value: "password"},
+		Raw:    json.RawMessage(`[1,2,3]`),
+		Time:   now,
+		Data:   Make(RawData{"a": 1}),
+	})
+	a.NilError(err)
+	a.Equal(d, Make(RawData{
+		"color":  "red",
+		"colors": []interface{}{"green", "unknown"},
+		"secret": RawData{"masked": "p***"},
+		"raw":    []int64{1, 2, 3},
+		"time":   now,
+		"data":   RawData{"a": 1},
+	}))
+
+	// 顶层的值也可以是 json.Marshaler。
+	d, err = enc.EncodeE(&Secret{value: "token"})
+	a.NilError(err)
+	a.Equal(d, Make(RawData{"masked": "t***"}))
+
+	_, err = enc.EncodeE(map[string]interface{}{
+		"broken": BrokenMarshaler{},
+	})
+	a.Assert(errors.Is(err, ErrInvalidJSON))
+
+	// 嵌入 time.Time 的 struct 与 encoding/json 一样使用 time.Time 的 MarshalJSON。
+	type Event struct {
+		time.Time
+		Name string `data:"name"`
+	}
+	at := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	event := Event{Time: at, Name: "start"}
+	buf, err := json.Marshal(event)
+	a.NilError(err)
+	a.Equal(string(buf), `"2020-01-02T03:04:05Z"`)
+
+	d, err = enc.EncodeE(map[string]interface{}{
+		"event":  event,
+		"events": []Event{event},
+	})
+	a.NilError(err)
+	a.Equal(d, Make(RawData{
+		"event":  "2020-01-02T03:04:05Z",
+		"events": []interface{}{"2020-01-02T03:04:05Z"},
+	}))
+}
+
+func TestEncoderEnum(t *testing.T) {
+	type Value struct {
+		Status int    `data:"status,enum=active:1|inactive:0"`
+		Level  *uint8 `data:"level,enum=low:1|high:2"`
+		Kind   string `data:"kind,omitempty,enum=a:alpha|b:beta"`
+	}
+	a := assert.New(t)
+	enc := &Encoder{}
+	level := uint8(1)
+
+	d, err := enc.EncodeE(&Value{
+		Status: 0,
+		Level:  &level,
+	})
+	a.NilError(err)
+	a.Equal(d, Make(RawData{
+		"status": "inactive",
+		"level":  "low",
+	}))
+
+	// 编码解码之后值不变。
+	var v Value
+	dec := &Decoder{}
+	a.NilError(dec.Decode(d, &v))
+	a.Equal(v, Value{Level: &level})
+
+	d, err = enc.EncodeE(&Value{
+		Status: 2,
+	})
+	a.NonNilError(err)
+	a.Equal(err.Error(), "go-data: value `2` of field `Status` is not a valid enum value")
+}
+
+type OptionalInt struct {
+	Valid bool
+	Value int
+}
+
+func (o OptionalInt) IsEmptyData() bool {
+	return !o.Valid
+}
+
+type Name string
+
+func (n *Name) IsEmptyData() bool {
+	return *n == "" || *n == "anonymous"
+}
+
+func TestEncoderEmptyChecker(t *testing.T) {
+	type Value struct {
+		Opt      OptionalInt  `data:"opt,omitempty"`
+		ValidOpt OptionalInt  `data:"valid_opt,omitempty"`
+		OptPtr   *OptionalInt `data:"opt_ptr,omitempty"`
+		Name     Name         `data:"name,omitempty"`
+		Time     *time.Time   `data:"time,omitempty"`
+		Ignored  time.Time    `data:"ignored,omitempty"`
+		hidden   int          `data:"hidden,omitempty"`
+	}
+	a := assert.New(t)
+	enc := &Encoder{}
+	v := &Value{
+		ValidOpt: OptionalInt{Valid: true},
+		OptPtr:   &OptionalInt{Value: 1},
+		Name:     "anonymous",
+		Time:     &time.Time{},
+	}
+
+	d, err := enc.EncodeE(v)
+	a.NilError(err)
+	a.Equal(d.Keys(), []string{"valid_opt"})
+
+	// 注册的函数优先。
+	enc.RegisterEmptyChecker(reflect.TypeOf(time.Time{}), func(v interface{}) bool {
+		return v.(time.Time).IsZero()
+	})
+	enc.RegisterEmptyChecker(reflect.TypeOf(OptionalInt{}), func(v interface{}) bool {
+		return v.(OptionalInt).Value == 0
+	})
+	d, err = enc.EncodeE(v)
+	a.NilError(err)
+	a.Equal(d.Keys(), []string{"opt_ptr"})
+}
+
+func TestEncoderTransformer(t *testing.T) {
+	type Value struct {
+		Price   int64 `data:"price,encoder=cents"`
+		Unknown int   `data:"unknown,encoder=not_exist"`
+	}
+	type Product struct {
+		Price int64 `data:"price,encoder=cents,decoder=cents"`
+	}
+	a := assert.New(t)
+	enc := &Encoder{}
+	enc.RegisterTransformer("cents", func(v interface{}) (interface{}, error) {
+		return float64(v.(int64)) / 100, nil
+	})
+	dec := &Decoder{}
+	dec.RegisterTransformer("cents", func(v interface{}) (interface{}, error) {
+		f, ok := v.(float64)
+
+		if !ok {
+			return nil, errors.New("price must be a float")
+		}
+
+		return int64(f*100 + 0.5), nil
+	})
+
+	d, err := enc.EncodeE(&Product{Price: 1234})
+	a.NilError(err)
+	a.Equal(d, Make(RawData{"price": 12.34}))
+
+	var p Product
+	a.NilError(dec.Decode(d, &p))
+	a.Equal(p.Price, int64(1234))
+
+	err = dec.Decode(Make(RawData{"price": "12"}), &p)
+	a.Equal(err.Error(), "go-data: fail to decode `price`: price must be a float")
+
+	_, err = enc.EncodeE(&Value{})
+	a.Equal(err.Error(), "go-data: transformer `not_exist` of field `Unknown` is not registered")
+	err = (&Decoder{}).Decode(d, &p)
+	a.Equal(err.Error(), "go-data: fail to decode `price`: transformer `cents` is not registered")
+}
+
+func TestEncoderKeepNumericKinds(t *testing.T) {
+	type MyInt int32
+	type Value struct {
+		Int32   int32     `data:"int32"`
+		Uint16  uint16    `data:"uint16"`
+		Float32 float32   `data:"float32"`
+		MyInt   MyInt     `data:"my_int"`
+		Int8s   []int8    `data:"int8s"`
+		MyInts  []MyInt   `data:"my_ints"`
+		Int     int       `data:"int"`
+		Ptr     *uint8    `data:"ptr"`
+		Any     []float32 `data:"any"`
+	}
+	a := assert.New(t)
+	u8 := uint8(8)
+	v := &Value{
+		Int32:   -32,
+		Uint16:  16,
+		Float32: 1.5,
+		MyInt:   7,
+		Int8s:   []int8{1, -1},
+		MyInts:  []MyInt{1, 2},
+		Int:     64,
+		Ptr:     &u8,
+		Any:     []float32{0.5},
+	}
+	enc := &Encoder{
+		KeepNumericKinds: true,
+	}
+	d, err := enc.EncodeE(v)
+	a.NilError(err)
+	a.Equal(d.data, RawData{
+		"int32":   int32(-32),
+		"uint16":  uint16(16),
+		"float32": float32(1.5),
+		"my_int":  int32(7),
+		"int8s":   []int8{1, -1},
+		"my_ints": []int32{1, 2},
+		"int":     int(64),
+		"ptr":     uint8(8),
+		"any":     []float32{0.5},
+	})
+	a.Equal(d.JSON(false), `{"any":[0.5],"float32":1.5,"int":64,"int32":-32,"int8s":[1,-1],"my_int":7,"my_ints":[1,2],"ptr":8,"uint16":16}`)
+
+	var decoded Value
+	dec := &Decoder{}
+	a.NilError(dec.Decode(d, &decoded))
+	a.Equal(&decoded, v)
+
+	// uintptr 会变成 uint64，这样才能序列化成二进制格式。
+	d, err = enc.EncodeE(map[string]interface{}{
+		"uintptr": uintptr(9),
+		"ptrs":    []uintptr{10},
+	})
+	a.NilError(err)
+	a.Equal(d.data, RawData{
+		"uintptr": uint64(9),
+		"ptrs":    []uint64{10},
+	})
+	_, err = d.MarshalBinary()
+	a.NilError(err)
+
+	// 默认情况下依然会统一成最大的类型。
+	d, err = (&Encoder{}).EncodeE(v)
+	a.NilError(err)
+	a.Equal(d.Query("int32"), int64(-32))
+	a.Equal(d.Query("my_ints"), []int64{1, 2})
+}
+
+func TestEncoderDurationFormat(t *testing.T) {
+	type Value struct {
+		Timeout time.Duration   `data:"timeout"`
+		Zero    time.Duration   `data:"zero"`
+		Retries []time.Duration `data:"retries"`
+	}
+	cases := []struct {
+		Format DurationFormat
+		Data   Data
+	}{
+		{
+			DurationString,
+			Make(RawData{
+				"timeout": "1m30.5s",
+				"zero":    "",
+				"retries": []string{"1s", "2ms"},
+			}),
+		},
+		{
+			DurationMilliseconds,
+			Make(RawData{
+				"timeout": 90500,
+				"zero":    0,
+				"retries": []int64{1000, 2},
+			}),
+		},
+		{
+			DurationSeconds,
+			Make(RawData{
+				"timeout": 90,
+				"zero":    0,
+				"retries": []int64{1, 0},
+			}),
+		},
+		{
+			DurationFloatSeconds,
+			Make(RawData{
+				"timeout": 90.5,
+				"zero":    0.0,
+				"retries": []float64{1, 0.002},
+			}),
+		},
+	}
+	a := assert.New(t)
+	v := &Value{
+		Timeout: 90*time.Second + 500*time.Millisecond,
+		Retries: []time.Duration{time.Second, 2 * time.Millisecond},
+	}
+
+	for i, c := range cases {
+		a.Use(&i, &c)
+		enc := &Encoder{
+			DurationFormat: c.Format,
+		}
+		d, err := enc.EncodeE(v)
+		a.NilError(err)
+		a.Equal(d, c.Data)
+
+		// 经过 JSON 序列化之后依然可以解析。
+		d, err = ParseJSON(d.JSON(false))
+		a.NilError(err)
+
+		var decoded Value
+		dec := &Decoder{
+			DurationFormat: c.Format,
+		}
+		a.NilError(dec.Decode(d, &decoded))
+
+		switch c.Format {
+		case DurationSeconds:
+			a.Equal(decoded.Timeout, 90*time.Second)
+		default:
+			a.Equal(decoded.Timeout, v.Timeout)
+		}
+	}
+
+	// 默认的 Decoder 不能从数字解析 time.Duration，但任何 Decoder 都能解析字符串。
+	var decoded Value
+	a.NonNilError((&Decoder{}).Decode(Make(RawData{"timeout": 1}), &decoded))
+	a.NilError((&Decoder{DurationFormat: DurationSeconds}).Decode(Make(RawData{"timeout": "2s"}), &decoded))
+	a.Equal(decoded.Timeout, 2*time.Second)
+}
+
+type testUUID [4]byte
+
+func TestEncoderRegisterType(t *testing.T) {
+	type Value struct {
+		ID    testUUID   `data:"id"`
+		IDs   []testUUID `data:"ids"`
+		Big   *big.Int   `data:"big"`
+		NoBig *big.Int   `data:"no_big"`
+	}
+	a := assert.New(t)
+	enc := &Encoder{}
+	enc.RegisterType(reflect.TypeOf(testUUID{}), func(v interface{}) (interface{}, error) {
+		id := v.(testUUID)
+		return hex.EncodeToString(id[:]), nil
+	})
+	enc.RegisterType(reflect.TypeOf(&big.Int{}), func(v interface{}) (interface{}, error) {
+		if b := v.(*big.Int); b != nil {
+			return b.String(), nil
+		}
+
+		return nil, nil
+	})
+	dec := &Decoder{}
+	dec.RegisterType(reflect.TypeOf(testUUID{}), func(v interface{}) (interface{}, error) {
+		var id testUUID
+		str, _ := v.(string)
+		buf, err := hex.DecodeString(str)
+
+		if err != nil || len(buf) != len(id) {
+			return nil, errors.New("invalid uuid")
+		}
+
+		copy(id[:], buf)
+		return id, nil
+	})
+	dec.RegisterType(reflect.TypeOf(&big.Int{}), func(v interface{}) (interface{}, error) {
+		b, ok := new(big.Int).SetString(v.(string), 10)
+
+		if !ok {
+			return nil, errors.New("invalid big int")
+		}
+
+		return b, nil
+	})
+
+	huge, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	v := &Value{
+		ID:  testUUID{1, 2, 3, 4},
+		IDs: []testUUID{{0xa, 0xb, 0xc, 0xd}},
+		Big: huge,
+	}
+	d, err := enc.EncodeE(v)
+	a.NilError(err)
+	a.Equal(d, Make(RawData{
+		"id":     "01020304",
+		"ids":    []interface{}{"0a0b0c0d"},
+		"big":    "123456789012345678901234567890",
+		"no_big": nil,
+	}))
+
+	var decoded Value
+	a.NilError(dec.Decode(d, &decoded))
+	a.Equal(&decoded, v)
+
+	err = dec.Decode(Make(RawData{"id": "xyz"}), &decoded)
+	a.Equal(err.Error(), "go-data: fail to decode `id`: invalid uuid")
+
+	// 编码函数不能返回同样的类型。
+	enc.RegisterType(reflect.TypeOf(testUUID{}), func(v interface{}) (interface{}, error) {
+		return v, nil
+	})
+	_, err = enc.EncodeE(v)
+	a.NonNilError(err)
+}
@@ -0,0 +1,209 @@
+package data
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+)
+
+// envelopeVersion 是 Envelope 序列化格式的版本号。
+const envelopeVersion = 1
+
+const envelopeChecksumPrefix = "sha256:"
+
+// Envelope 是一个带有 schema 版本号、修订号、校验和以及可选签名的 Data 封装，
+// 可以直接用来保存或者传递文档，不需要每个业务自己设计一套包装格式。
+//
+// Envelope 通过 `Envelope#Seal` 序列化，通过 `OpenEnvelope` 解析并校验。
+// 序列化的结果本身是一个使用二进制格式（见 `Data#MarshalBinary`）序列化的 Data，
+// 其中 payload 是 Data 使用 Format 序列化的结果，checksum 是 payload 的 SHA-256 校验和，
+// 签名覆盖了 schema 版本号、修订号、格式和校验和，因此这些信息都无法在不破坏签名的情况下被修改。
+type Envelope struct {
+	Data          Data
+	SchemaVersion int   // 文档的 schema 版本号，读取方可以据此处理不同版本的文档。
+	Revision      int64 // 文档的修订号，比如 `Journal` 的修订号。
+
+	// Checksum 是 payload 的校验和，格式为 "sha256:" 加上十六进制的哈希值。
+	// Seal 时会自动计算，不需要手动设置。
+	Checksum string
+
+	// Signature 是 Signer 生成的签名，Seal 时如果 Signer 为 nil 则为空。
+	Signature []byte
+
+	// Format 是 Data 的序列化格式，为 nil 时使用 FormatBinary。
+	Format Format
+}
+
+// Signer 为 Envelope 生成和校验签名，可以实现这个接口来使用 ed25519 等非对称签名算法。
+type Signer interface {
+	// Sign 返回 msg 的签名。
+	Sign(msg []byte) ([]byte, error)
+
+	// Verify 校验 sig 是否是 msg 的合法签名，如果不合法返回错误。
+	Verify(msg, sig []byte) error
+}
+
+// HMACSigner 返回一个使用 HMAC-SHA256 生成和校验签名的 Signer。
+func HMACSigner(key []byte) Signer {
+	return hmacSigner(append([]byte{}, key...))
+}
+
+type hmacSigner []byte
+
+func (key hmacSigner) Sign(msg []byte) ([]byte, error) {
+	h := hmac.New(sha256.New, key)
+	h.Write(msg)
+	return h.Sum(nil), nil
+}
+
+func (key hmacSigner) Verify(msg, sig []byte) error {
+	expected, _ := key.Sign(msg)
+
+	if !hmac.Equal(expected, sig) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// Seal 将 env 序列化，如果 signer 不为 nil，同时使用 signer 签名。
+// Seal 会更新 env 的 Checksum 和 Signature。
+func (env *Envelope) Seal(signer Signer) ([]byte, error) {
+	format := env.Format
+
+	if format == nil {
+		format = FormatBinary
+	}
+
+	payload, err := format.Marshal(env.Data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(payload)
+	checksum := envelopeChecksumPrefix + hex.EncodeToString(sum[:])
+	var sig []byte
+
+	if signer != nil {
+		if sig, err = signer.Sign(envelopeMessage(env.SchemaVersion, env.Revision, format.Name(), checksum)); err != nil {
+			return nil, err
+		}
+	}
+
+	raw := RawData{
+		"envelope":       int64(envelopeVersion),
+		"schema_version": int64(env.SchemaVersion),
+		"revision":       env.Revision,
+		"format":         format.Name(),
+		"checksum":       checksum,
+		"payload":        string(payload),
+	}
+
+	if sig != nil {
+		raw["signature"] = string(sig)
+	}
+
+	buf, err := Data{data: raw}.MarshalBinary()
+
+	if err != nil {
+		return nil, err
+	}
+
+	env.Checksum = checksum
+	env.Signature = sig
+	return buf, nil
+}
+
+// OpenEnvelope 解析 `Envelope#Seal` 生成的数据，并校验校验和与签名。
+// src 是不可信的输入，由于签名保存在 src 内部，校验签名之前需要先解析 src 的外层结构，
+// 任何无法解析的 src 都会返回 ErrInvalidEnvelope，不会 panic。
+// 如果 src 不合法或者校验和不匹配，返回的错误可以通过 `errors.Is(err, ErrInvalidEnvelope)` 判断；
+// 如果 signer 不为 nil 而 Envelope 没有签名或者签名不合法，返回的错误可以通过 `errors.Is(err, ErrInvalidSignature)` 判断。
+// 如果 signer 为 nil，签名不会被校验，但依然会设置到返回值的 Signature 中。
+//
+// 除了内置的 FormatJSON 和 FormatBinary 以外，formats 中的格式也可以被解析。
+func OpenEnvelope(src []byte, signer Signer, formats ...Format) (*Envelope, error) {
+	var outer Data
+
+	if err := outer.UnmarshalBinary(src); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEnvelope, err)
+	}
+
+	if v, ok := outer.QueryInt64("envelope"); !ok || v != envelopeVersion {
+		return nil, fmt.Errorf("%w: unsupported envelope version %v", ErrInvalidEnvelope, outer.Query("envelope"))
+	}
+
+	schemaVersion, ok1 := outer.QueryInt64("schema_version")
+	revision, ok2 := outer.QueryInt64("revision")
+	name, ok3 := outer.QueryString("format")
+	checksum, ok4 := outer.QueryString("checksum")
+	payload, ok5 := outer.QueryString("payload")
+
+	if !ok1 || !ok2 || !ok3 || !ok4 || !ok5 {
+		return nil, fmt.Errorf("%w: missing required fields", ErrInvalidEnvelope)
+	}
+
+	sum := sha256.Sum256([]byte(payload))
+
+	if checksum != envelopeChecksumPrefix+hex.EncodeToString(sum[:]) {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrInvalidEnvelope)
+	}
+
+	var sig []byte
+
+	if s, ok := outer.QueryString("signature"); ok {
+		sig = []byte(s)
+	}
+
+	if signer != nil {
+		if sig == nil {
+			return nil, fmt.Errorf("%w: envelope is not signed", ErrInvalidSignature)
+		}
+
+		if err := signer.Verify(envelopeMessage(int(schemaVersion), revision, name, checksum), sig); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+		}
+	}
+
+	codec := &Codec{
+		Format:  FormatBinary,
+		Formats: append([]Format{FormatJSON}, formats...),
+	}
+	format := codec.lookup(name)
+
+	if format == nil {
+		return nil, fmt.Errorf("%w: unknown data type '%v'", ErrInvalidEnvelope, name)
+	}
+
+	d, err := format.Unmarshal([]byte(payload))
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEnvelope, err)
+	}
+
+	return &Envelope{
+		Data:          d,
+		SchemaVersion: int(schemaVersion),
+		Revision:      revision,
+		Checksum:      checksum,
+		Signature:     sig,
+		Format:        format,
+	}, nil
+}
+
+// envelopeMessage 返回需要签名的内容。
+func envelopeMessage(schemaVersion int, revision int64, format, checksum string) []byte {
+	msg := make([]byte, 0, 64+len(format)+len(checksum))
+	msg = append(msg, "go-data-envelope:"...)
+	msg = strconv.AppendInt(msg, int64(schemaVersion), 10)
+	msg = append(msg, ':')
+	msg = strconv.AppendInt(msg, revision, 10)
+	msg = append(msg, ':')
+	msg = append(msg, format...)
+	msg = append(msg, ':')
+	msg = append(msg, checksum...)
+	return msg
+}
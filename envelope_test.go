@@ -0,0 +1,123 @@
+package data
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestEnvelope(t *testing.T) {
+	d := Make(RawData{
+		"name": "config",
+		"list": []int{1, 2, 3},
+	})
+	signer := HMACSigner([]byte("secret"))
+	cases := []struct {
+		Format  Format
+		Formats []Format
+		Signer  Signer
+	}{
+		{nil, nil, nil},
+		{FormatJSON, nil, signer},
+		{upperFormat{}, []Format{upperFormat{}}, signer},
+	}
+
+	for i, c := range cases {
+		a := assert.New(t)
+		a.Use(&i, &c)
+
+		env := &Envelope{
+			Data:          d,
+			SchemaVersion: 3,
+			Revision:      42,
+			Format:        c.Format,
+		}
+		sealed, err := env.Seal(c.Signer)
+		a.NilError(err)
+		a.Assert(len(env.Checksum) == len("sha256:")+64)
+		a.Equal(env.Signature != nil, c.Signer != nil)
+
+		opened, err := OpenEnvelope(sealed, c.Signer, c.Formats...)
+		a.NilError(err)
+		a.Equal(opened.Data, d)
+		a.Equal(opened.SchemaVersion, 3)
+		a.Equal(opened.Revision, int64(42))
+		a.Equal(opened.Checksum, env.Checksum)
+		a.Equal(opened.Signature, env.Signature)
+
+		// 不校验签名时也可以打开。
+		_, err = OpenEnvelope(sealed, nil, c.Formats...)
+		a.NilError(err)
+	}
+}
+
+func TestEnvelopeTampered(t *testing.T) {
+	a := assert.New(t)
+	signer := HMACSigner([]byte("secret"))
+	env := &Envelope{
+		Data:     Make(RawData{"a": 1}),
+		Revision: 1,
+	}
+	sealed, err := env.Seal(signer)
+	a.NilError(err)
+
+	tamper := func(fn func(raw RawData)) []byte {
+		var outer Data
+		a.NilError(outer.UnmarshalBinary(sealed))
+		fn(outer.data)
+		buf, err := outer.MarshalBinary()
+		a.NilError(err)
+		return buf
+	}
+	payload, err := FormatBinary.Marshal(Make(RawData{"a": 2}))
+	a.NilError(err)
+	other := &Envelope{
+		Data:     Make(RawData{"a": 2}),
+		Revision: 1,
+	}
+	_, err = other.Seal(nil)
+	a.NilError(err)
+	deep := string(deepBinary(maxBinaryDepth*10, []byte{1, 0, 8}, []byte{0}))
+	deepSum := sha256.Sum256([]byte(deep))
+
+	cases := []struct {
+		Src    []byte
+		Signer Signer
+		Err    error
+	}{
+		{nil, nil, ErrInvalidEnvelope},
+		{[]byte("not an envelope"), nil, ErrInvalidEnvelope},
+		{tamper(func(raw RawData) { raw["envelope"] = int64(2) }), nil, ErrInvalidEnvelope},
+		{tamper(func(raw RawData) { delete(raw, "checksum") }), nil, ErrInvalidEnvelope},
+		{tamper(func(raw RawData) { raw["payload"] = string(payload) }), nil, ErrInvalidEnvelope},
+		{tamper(func(raw RawData) { raw["format"] = "unknown" }), nil, ErrInvalidEnvelope},
+		{tamper(func(raw RawData) { raw["revision"] = int64(2) }), signer, ErrInvalidSignature},
+		{tamper(func(raw RawData) {
+			raw["payload"] = string(payload)
+			raw["checksum"] = other.Checksum
+		}), signer, ErrInvalidSignature},
+		{tamper(func(raw RawData) { delete(raw, "signature") }), signer, ErrInvalidSignature},
+		{sealed, HMACSigner([]byte("wrong")), ErrInvalidSignature},
+
+		// 精心构造的二进制数据在校验签名之前就无法解析，不能 panic。
+		{[]byte{2, 1, 1, 'a', 9, 9, 2, 1, 6, 1, 1, 'x'}, signer, ErrInvalidEnvelope},
+
+		// 嵌套过深的数据不能导致栈溢出。
+		{deepBinary(maxBinaryDepth*10, []byte{1, 0, 8}, []byte{0}), signer, ErrInvalidEnvelope},
+		{tamper(func(raw RawData) {
+			raw["payload"] = deep
+			raw["checksum"] = envelopeChecksumPrefix + hex.EncodeToString(deepSum[:])
+		}), nil, ErrInvalidEnvelope},
+	}
+
+	for i, c := range cases {
+		a := assert.New(t)
+		a.Use(&i, &c)
+
+		_, err := OpenEnvelope(c.Src, c.Signer)
+		a.Assert(errors.Is(err, c.Err))
+	}
+}
@@ -0,0 +1,315 @@
+package data
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// EqualOptions 决定 `EqualOptions#Equal` 如何比较两个 Data，适合在测试中比较由不确定的系统生成的 Data。
+//
+// 无论如何设置，以下规则总是生效：
+//     - 值为 nil 的 key 与不存在的 key 相同，nil 与空的 object、数组相同；
+//     - int64、uint64 和 float64 之间按照数值比较，比如 int64(1) 与 float64(1) 相等；
+//     - time.Time 使用 time.Time#Equal 比较，时区不同但时间点相同的值相等；
+//     - 数组只比较元素，不比较 slice 的类型，比如 []int64{1} 与 []interface{}{int64(1)} 相等。
+type EqualOptions struct {
+	IgnoreArrayOrder bool    // 如果为 true，数组中的元素只要一一对应即可，不需要顺序相同。
+	Epsilon          float64 // 两个数字之差的绝对值不超过 Epsilon 时认为相等。
+
+	// IgnorePaths 中的 query 对应的值以及它下面的所有值都不会被比较，query 的格式与 `Data#Query` 相同，
+	// 其中 "*" 可以匹配任意一个 key 或者数组下标，比如 "items.*.updated_at"。
+	IgnorePaths []string
+}
+
+// Equal 使用默认的 `EqualOptions` 比较 d1 和 d2 是否相等。
+func Equal(d1, d2 Data) bool {
+	opts := &EqualOptions{}
+	return opts.Equal(d1, d2)
+}
+
+// Equal 比较 d1 和 d2 是否相等，规则详见 `EqualOptions`。
+func (opts *EqualOptions) Equal(d1, d2 Data) bool {
+	_, equal := opts.Compare(d1, d2)
+	return equal
+}
+
+// Compare 比较 d1 和 d2 是否相等，如果不相等，query 是第一个不相等的值的路径，格式与 `Data#Query` 相同，
+// 这可以用来在测试失败时输出更有用的信息。
+// 路径按照 key 的字典序查找，数组元素使用 d1 中的下标；如果 d1 和 d2 本身就不相等，比如都是数组但长度不同，query 是这个数组的路径。
+func (opts *EqualOptions) Compare(d1, d2 Data) (query string, equal bool) {
+	ec := &equalComparer{
+		opts: opts,
+	}
+
+	for _, p := range opts.IgnorePaths {
+		ec.ignores = append(ec.ignores, queryFields(p))
+	}
+
+	if ec.equal(d1.data, d2.data, nil) {
+		equal = true
+		return
+	}
+
+	query = JoinQuery(ec.mismatch...)
+	return
+}
+
+type equalComparer struct {
+	opts     *EqualOptions
+	ignores  [][]string
+	mismatch []string
+}
+
+func (ec *equalComparer) ignored(path []string) bool {
+	for _, pattern := range ec.ignores {
+		if len(pattern) != len(path) {
+			continue
+		}
+
+		matched := true
+
+		for i, f := range pattern {
+			if f != "*" && f != path[i] {
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// equal 比较 v1 和 v2，如果不相等，将第一个不相等的路径记录在 ec.mismatch 中。
+func (ec *equalComparer) equal(v1, v2 interface{}, path []string) bool {
+	if len(path) != 0 && ec.ignored(path) {
+		return true
+	}
+
+	if ec.equalValue(v1, v2, path) {
+		return true
+	}
+
+	if ec.mismatch == nil {
+		ec.mismatch = append([]string{}, path...)
+	}
+
+	return false
+}
+
+func (ec *equalComparer) equalValue(v1, v2 interface{}, path []string) bool {
+	if d, ok := v1.(Data); ok {
+		v1 = d.data
+	}
+
+	if d, ok := v2.(Data); ok {
+		v2 = d.data
+	}
+
+	m1, isMap1 := v1.(RawData)
+	m2, isMap2 := v2.(RawData)
+
+	if isMap1 || isMap2 {
+		if (!isMap1 && v1 != nil) || (!isMap2 && v2 != nil) {
+			return false
+		}
+
+		return ec.equalObject(m1, m2, path)
+	}
+
+	elems1, isSlice1 := sliceElems(v1)
+	elems2, isSlice2 := sliceElems(v2)
+
+	if isSlice1 || isSlice2 {
+		if (!isSlice1 && v1 != nil) || (!isSlice2 && v2 != nil) {
+			return false
+		}
+
+		if len(elems1) != len(elems2) {
+			return false
+		}
+
+		if ec.opts.IgnoreArrayOrder {
+			return ec.equalUnordered(elems1, elems2, path)
+		}
+
+		for i := range elems1 {
+			if !ec.equal(elems1[i], elems2[i], appendPath(path, i)) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	if f1, ok := numberValue(v1); ok {
+		f2, ok := numberValue(v2)
+		return ok && ec.equalNumber(v1, v2, f1, f2)
+	}
+
+	if t1, ok := v1.(time.Time); ok {
+		t2, ok := v2.(time.Time)
+		return ok && t1.Equal(t2)
+	}
+
+	return reflect.DeepEqual(v1, v2)
+}
+
+func (ec *equalComparer) equalObject(m1, m2 RawData, path []string) bool {
+	keys := make([]string, 0, len(m1)+len(m2))
+
+	for k := range m1 {
+		keys = append(keys, k)
+	}
+
+	for k := range m2 {
+		if _, ok := m1[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if !ec.equal(m1[k], m2[k], append(path[:len(path):len(path)], k)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// equalUnordered 判断 elems1 和 elems2 中的元素是否可以一一对应。
+// 由于 Epsilon 让“相等”不再具有传递性，贪心匹配可能会错过存在的对应关系，
+// 所以这里使用二分图的增广路算法寻找完美匹配，第一个无法匹配的 elems1 元素就是不相等的路径。
+func (ec *equalComparer) equalUnordered(elems1, elems2 []interface{}, path []string) bool {
+	// 尝试匹配时不记录不相等的路径。
+	sub := &equalComparer{
+		opts:    ec.opts,
+		ignores: ec.ignores,
+	}
+	candidates := make([][]int, len(elems1))
+
+	for i, e1 := range elems1 {
+		p := appendPath(path, i)
+
+		if ec.ignored(p) {
+			continue
+		}
+
+		for j, e2 := range elems2 {
+			if sub.equal(e1, e2, p) {
+				candidates[i] = append(candidates[i], j)
+			}
+		}
+	}
+
+	matched := make([]int, len(elems2))
+
+	for j := range matched {
+		matched[j] = -1
+	}
+
+	for i := range elems1 {
+		p := appendPath(path, i)
+
+		if ec.ignored(p) {
+			continue
+		}
+
+		if !augment(candidates, matched, make([]bool, len(elems2)), i) {
+			if ec.mismatch == nil {
+				ec.mismatch = p
+			}
+
+			return false
+		}
+	}
+
+	return true
+}
+
+// augment 从 i 出发寻找增广路，找到时更新 matched 并返回 true。
+// matched[j] 是与 elems2 中第 j 个元素匹配的 elems1 下标，-1 代表还没有匹配。
+func augment(candidates [][]int, matched []int, visited []bool, i int) bool {
+	for _, j := range candidates[i] {
+		if visited[j] {
+			continue
+		}
+
+		visited[j] = true
+
+		if matched[j] < 0 || augment(candidates, matched, visited, matched[j]) {
+			matched[j] = i
+			return true
+		}
+	}
+
+	return false
+}
+
+func (ec *equalComparer) equalNumber(v1, v2 interface{}, f1, f2 float64) bool {
+	// 整数之间直接计算差值，避免转化成 float64 损失精度。
+	if neg1, abs1, ok := integerValue(v1); ok {
+		if neg2, abs2, ok := integerValue(v2); ok {
+			var diff uint64
+
+			switch {
+			case neg1 != neg2:
+				// 符号不同时差值至少为 1，超过 uint64 范围的差值不需要精确计算。
+				return float64(abs1)+float64(abs2) <= ec.opts.Epsilon
+			case abs1 > abs2:
+				diff = abs1 - abs2
+			default:
+				diff = abs2 - abs1
+			}
+
+			return diff == 0 || float64(diff) <= ec.opts.Epsilon
+		}
+	}
+
+	if f1 == f2 {
+		return true
+	}
+
+	return math.Abs(f1-f2) <= ec.opts.Epsilon
+}
+
+// integerValue 返回整数 v 的符号和绝对值，如果 v 不是 int64 或者 uint64，ok 为 false。
+func integerValue(v interface{}) (neg bool, abs uint64, ok bool) {
+	switch n := v.(type) {
+	case int64:
+		if n < 0 {
+			return true, uint64(-n), true
+		}
+
+		return false, uint64(n), true
+	case uint64:
+		return false, n, true
+	}
+
+	return
+}
+
+func numberValue(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+
+	return 0, false
+}
+
+func appendPath(path []string, i int) []string {
+	return append(path[:len(path):len(path)], strconv.Itoa(i))
+}
@@ -0,0 +1,175 @@
+package data
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestEqualOptions(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		Options  EqualOptions
+		D1       RawData
+		D2       RawData
+		Query    string
+		Expected bool
+	}{
+		{
+			EqualOptions{},
+			RawData{"a": 1, "b": RawData{"c": []string{"x"}}},
+			RawData{"b": RawData{"c": []interface{}{"x"}}, "a": 1.0},
+			"", true,
+		},
+		{ // nil 与不存在的 key、空 object 和空数组相同。
+			EqualOptions{},
+			RawData{"a": nil, "b": RawData{}, "c": []int{}},
+			RawData{"b": nil, "c": nil},
+			"", true,
+		},
+		{ // 时区不同但时间点相同。
+			EqualOptions{},
+			RawData{"t": now},
+			RawData{"t": now.UTC()},
+			"", true,
+		},
+		{
+			EqualOptions{},
+			RawData{"a": RawData{"b": []int{1, 2, 3}}},
+			RawData{"a": RawData{"b": []int{1, 3, 2}}},
+			"a.b.1", false,
+		},
+		{
+			EqualOptions{IgnoreArrayOrder: true},
+			RawData{"a": RawData{"b": []int{1, 2, 3}}},
+			RawData{"a": RawData{"b": []int{3, 1, 2}}},
+			"", true,
+		},
+		{
+			EqualOptions{IgnoreArrayOrder: true},
+			RawData{"list": []RawData{{"id": 1}, {"id": 1}, {"id": 2}}},
+			RawData{"list": []RawData{{"id": 2}, {"id": 1}, {"id": 2}}},
+			"list.1", false,
+		},
+		{
+			EqualOptions{},
+			RawData{"list": []int{1, 2}},
+			RawData{"list": []int{1, 2, 3}},
+			"list", false,
+		},
+		{ // 贪心匹配会把 1.0 和 1.4 匹配在一起，导致 1.4 找不到对应的元素。
+			EqualOptions{IgnoreArrayOrder: true, Epsilon: 0.5},
+			RawData{"list": []float64{1.0, 1.4}},
+			RawData{"list": []float64{1.4, 0.6}},
+			"", true,
+		},
+		{
+			EqualOptions{IgnoreArrayOrder: true, Epsilon: 0.5},
+			RawData{"list": []float64{1.0, 1.4, 3}},
+			RawData{"list": []float64{1.4, 0.6, 1.2}},
+			"list.2", false,
+		},
+		{
+			EqualOptions{},
+			RawData{"f": 0.30000000000000004},
+			RawData{"f": 0.3},
+			"f", false,
+		},
+		{
+			EqualOptions{Epsilon: 1e-9},
+			RawData{"f": 0.30000000000000004, "i": 100},
+			RawData{"f": 0.3, "i": 100.0000000001},
+			"", true,
+		},
+		{
+			EqualOptions{Epsilon: 0.5},
+			RawData{"i": 1},
+			RawData{"i": 2},
+			"i", false,
+		},
+		{
+			EqualOptions{},
+			RawData{"u": uint64(1<<63 + 1)},
+			RawData{"u": uint64(1<<63 + 2)},
+			"u", false,
+		},
+		{ // 不同类型的整数也需要精确比较。
+			EqualOptions{},
+			RawData{"i": int64(math.MaxInt64), "j": int64(-1)},
+			RawData{"i": uint64(1 << 63), "j": uint64(1<<64 - 1)},
+			"i", false,
+		},
+		{
+			EqualOptions{},
+			RawData{"i": int64(math.MaxInt64), "j": int64(0)},
+			RawData{"i": uint64(math.MaxInt64), "j": uint64(0)},
+			"", true,
+		},
+		{
+			EqualOptions{Epsilon: 1},
+			RawData{"i": int64(math.MaxInt64), "j": int64(-1), "k": int64(math.MinInt64)},
+			RawData{"i": uint64(1 << 63), "j": uint64(0), "k": uint64(0)},
+			"k", false,
+		},
+		{
+			EqualOptions{IgnorePaths: []string{"meta.request_id", "items.*.updated_at"}},
+			RawData{
+				"meta":  RawData{"request_id": "a", "v": 1},
+				"items": []RawData{{"id": 1, "updated_at": now}},
+			},
+			RawData{
+				"meta":  RawData{"request_id": "b", "v": 1},
+				"items": []RawData{{"id": 1, "updated_at": now.Add(time.Second)}},
+			},
+			"", true,
+		},
+		{
+			EqualOptions{IgnorePaths: []string{"meta.request_id"}},
+			RawData{"meta": RawData{"request_id": "a", "v": 1}},
+			RawData{"meta": RawData{"request_id": "b", "v": 2}},
+			"meta.v", false,
+		},
+		{
+			EqualOptions{IgnorePaths: []string{`a\.b`}},
+			RawData{"a.b": 1, "a": RawData{"b": 1}},
+			RawData{"a.b": 2, "a": RawData{"b": 1}},
+			"", true,
+		},
+		{
+			EqualOptions{},
+			RawData{"a": RawData{"b": 1}},
+			RawData{"a": "b"},
+			"a", false,
+		},
+		{
+			EqualOptions{},
+			RawData{"a": []int{1}},
+			RawData{"a": 1},
+			"a", false,
+		},
+		{
+			EqualOptions{},
+			RawData{"a": true, "b": "str", "c": complex(1, 2)},
+			RawData{"a": true, "b": "str", "c": complex(1, 2)},
+			"", true,
+		},
+	}
+
+	for i, c := range cases {
+		a := assert.New(t)
+		a.Use(&i, &c)
+
+		d1 := Make(c.D1)
+		d2 := Make(c.D2)
+		query, equal := c.Options.Compare(d1, d2)
+		a.Equal(equal, c.Expected)
+		a.Equal(query, c.Query)
+		a.Equal(c.Options.Equal(d2, d1), c.Expected)
+	}
+
+	a := assert.New(t)
+	a.Assert(Equal(Data{}, Make(RawData{})))
+	a.Assert(!Equal(Data{}, Make(RawData{"a": 1})))
+}
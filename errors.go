@@ -0,0 +1,136 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 所有可以通过 errors.Is 判断的错误。
+var (
+	ErrInvalidFormat  = errors.New("go-data: invalid data string format") // Data 序列化格式不合法。
+	ErrInvalidJSON    = errors.New("go-data: invalid JSON string")        // JSON 字符串不合法。
+	ErrInvalidBinary  = errors.New("go-data: invalid binary data")        // 二进制数据不合法。
+	ErrNotObject      = errors.New("go-data: value is not an object")     // 值不是一个 object。
+	ErrQueryNotFound  = errors.New("go-data: query not found")            // query 找不到对应的值。
+	ErrDocNotFound    = errors.New("go-data: document not found")         // Transaction 中的文档不存在。
+	ErrMaxDepth       = errors.New("go-data: max depth exceeded")         // 数据嵌套深度超过限制。
+	ErrMaxNodes       = errors.New("go-data: max nodes exceeded")         // 数据节点总数超过限制。
+	ErrHeaderTooLarge = errors.New("go-data: header size exceeded")       // 编码后的 header 超过大小限制。
+	ErrInvalidJournal = errors.New("go-data: invalid journal")            // Journal 中的记录不合法。
+	ErrNotArray       = errors.New("go-data: value is not an array")      // 值不是一个数组。
+
+	ErrInvalidEnvelope  = errors.New("go-data: invalid envelope")           // Envelope 格式不合法或者校验和不匹配。
+	ErrInvalidSignature = errors.New("go-data: invalid envelope signature") // Envelope 的签名不合法。
+
+	ErrUnlockedShard = errors.New("go-data: patch modified an unlocked shard") // patch 修改了 ShardedData 中没有被锁住的分片。
+)
+
+// DecodeError 是 Decoder 解析失败时返回的错误。
+type DecodeError struct {
+	Fields []string // 出错的值在 Data 中的路径，如果为空则代表出错的是顶层的值。
+	Err    error    // 具体的错误原因。
+}
+
+func (e *DecodeError) Error() string {
+	if len(e.Fields) == 0 {
+		return e.Err.Error()
+	}
+
+	msg := strings.TrimPrefix(e.Err.Error(), "go-data: ")
+	return fmt.Sprintf("go-data: fail to decode `%v`: %v", JoinQuery(e.Fields...), msg)
+}
+
+// Unwrap 返回具体的错误原因。
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// wrapDecodeError 将 field 加到 err 的路径最前面。
+func wrapDecodeError(field string, err error) error {
+	if e, ok := err.(*DecodeError); ok {
+		e.Fields = append([]string{field}, e.Fields...)
+		return e
+	}
+
+	return &DecodeError{
+		Fields: []string{field},
+		Err:    err,
+	}
+}
+
+// PatchError 是 Patch 应用失败时返回的错误。
+type PatchError struct {
+	Op    string // 出错时正在执行的操作，比如 "updating"。
+	Query string // 出错的 query。
+	Err   error  // 具体的错误原因。
+}
+
+func (e *PatchError) Error() string {
+	msg := strings.TrimPrefix(e.Err.Error(), "go-data: ")
+	return fmt.Sprintf("go-data: fail to apply patch when %v `%v`: %v", e.Op, e.Query, msg)
+}
+
+// Unwrap 返回具体的错误原因。
+func (e *PatchError) Unwrap() error {
+	return e.Err
+}
+
+// TransactionError 是 Transaction 应用失败时返回的错误。
+type TransactionError struct {
+	Name string // 出错的文档名。
+	Err  error  // 具体的错误原因。
+}
+
+func (e *TransactionError) Error() string {
+	msg := strings.TrimPrefix(e.Err.Error(), "go-data: ")
+	return fmt.Sprintf("go-data: fail to apply transaction to document `%v`: %v", e.Name, msg)
+}
+
+// Unwrap 返回具体的错误原因。
+func (e *TransactionError) Unwrap() error {
+	return e.Err
+}
+
+// MergeError 是 MergeOptions 合并失败时返回的错误。
+type MergeError struct {
+	Index int    // 出错的数据在参数中的下标。
+	Path  string // 出错的值在数据中的 query。
+	Err   error  // 具体的错误原因。
+}
+
+func (e *MergeError) Error() string {
+	msg := strings.TrimPrefix(e.Err.Error(), "go-data: ")
+	return fmt.Sprintf("go-data: fail to merge data #%v at `%v`: %v", e.Index, e.Path, msg)
+}
+
+// Unwrap 返回具体的错误原因。
+func (e *MergeError) Unwrap() error {
+	return e.Err
+}
+
+// RoundTripError 是 CheckRoundTrip 发现某些字段在编解码之后发生了变化时返回的错误。
+type RoundTripError struct {
+	Fields []string // 所有发生变化的字段，每个字段是以“.”分隔的 Go 字段名路径，比如 "Server.Port"。
+}
+
+func (e *RoundTripError) Error() string {
+	return fmt.Sprintf("go-data: fields changed after round trip: `%v`", strings.Join(e.Fields, "`, `"))
+}
+
+// BatchError 是 BatchApply 应用某个 patch 失败时返回的错误。
+type BatchError struct {
+	Index   int   // 出错的 patch 在参数中的下标。
+	Applied int   // 已经应用到 target 上的 patch 个数。
+	Err     error // 具体的错误原因。
+}
+
+func (e *BatchError) Error() string {
+	msg := strings.TrimPrefix(e.Err.Error(), "go-data: ")
+	return fmt.Sprintf("go-data: fail to apply patch #%v in batch: %v", e.Index, msg)
+}
+
+// Unwrap 返回具体的错误原因。
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,108 @@
+package data
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestSentinelErrors(t *testing.T) {
+	cases := []struct {
+		Err    error
+		Target error
+	}{
+		{
+			func() error { _, err := Parse(`{"a":1}`); return err }(),
+			ErrInvalidFormat,
+		},
+		{
+			func() error { _, err := Parse(`<bson>{"a":1}`); return err }(),
+			ErrInvalidFormat,
+		},
+		{
+			func() error { _, err := Parse(`<json>{"a":1,}`); return err }(),
+			ErrInvalidJSON,
+		},
+		{
+			func() error { _, err := ParseJSON(`[1]`); return err }(),
+			ErrNotObject,
+		},
+		{
+			func() error { var d Data; return d.UnmarshalBinary([]byte{1}) }(),
+			ErrInvalidBinary,
+		},
+		{
+			func() error {
+				p := NewPatch()
+				p.Add(nil, map[string]Data{"foo": Make(RawData{"a": 1})})
+				_, err := p.Apply(Make(RawData{"bar": 1}))
+				return err
+			}(),
+			ErrQueryNotFound,
+		},
+		{
+			func() error {
+				p := NewPatch()
+				p.Add(nil, map[string]Data{"foo": Make(RawData{"a": 1})})
+				_, err := p.Apply(Make(RawData{"foo": 1}))
+				return err
+			}(),
+			ErrNotObject,
+		},
+		{
+			func() error {
+				p := NewPatch()
+				p.Replace("foo.bar", Make(RawData{"a": 1}))
+				_, err := p.Apply(Make(RawData{"bar": 1}))
+				return err
+			}(),
+			ErrQueryNotFound,
+		},
+	}
+	a := assert.New(t)
+
+	for i, c := range cases {
+		a.Use(&i, &c)
+		a.Assert(errors.Is(c.Err, c.Target))
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	a := assert.New(t)
+	dec := &Decoder{}
+
+	var i int
+	err := dec.Decode(Make(RawData{"a": 1}), i)
+	var decodeErr *DecodeError
+	a.Assert(errors.As(err, &decodeErr))
+	a.Equal(len(decodeErr.Fields), 0)
+
+	var v struct {
+		List []struct {
+			Port uint8 `data:"port"`
+		} `data:"list"`
+	}
+	err = dec.Decode(Make(RawData{
+		"list": []RawData{
+			{"port": 1},
+			{"port": 256},
+		},
+	}), &v)
+	a.Assert(errors.As(err, &decodeErr))
+	a.Equal(decodeErr.Fields, []string{"list", "1", "port"})
+	a.Equal(err.Error(), "go-data: fail to decode `list.1.port`: cannot decode value of type uint8 from 256 due to overflow")
+}
+
+func TestPatchError(t *testing.T) {
+	a := assert.New(t)
+	p := NewPatch()
+	p.Add(nil, map[string]Data{"foo.bar": Make(RawData{"a": 1})})
+	_, err := p.Apply(Make(RawData{"foo": 1}))
+
+	var patchErr *PatchError
+	a.Assert(errors.As(err, &patchErr))
+	a.Equal(patchErr.Op, "updating")
+	a.Equal(patchErr.Query, "foo.bar")
+	a.Equal(err.Error(), "go-data: fail to apply patch when updating `foo.bar`: query not found")
+}
@@ -0,0 +1,165 @@
+package data
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+)
+
+// Experiments 根据 Data 中定义的实验为每个用户确定性的选择一个分组，这适合将 A/B 实验的配置保存在 Data 中。
+//
+// Data 中每个顶层 key 是一个实验，格式如下：
+//     {
+//         "new_checkout": {
+//             "salt": "2020-01",                        // 可选，修改 salt 会让所有用户重新分组。
+//             "variants": [
+//                 {"name": "control", "weight": 90},
+//                 {"name": "treatment", "weight": 10, "value": {"button_color": "red"}}
+//             ]
+//         }
+//     }
+//
+// 同一个 subject 在同一个实验中总是得到同一个分组，不同实验之间的分组互相独立。
+// 分组的概率与 weight 成正比，weight 为 0 的分组不会被选中，调整 weight 时只有部分用户会改变分组。
+//
+// Experiments 创建之后不会被修改，可以被多个 goroutine 并发使用。
+type Experiments struct {
+	experiments map[string]*Experiment
+}
+
+// Experiment 是一个实验的定义。
+type Experiment struct {
+	Salt     string     `data:"salt"`
+	Variants []*Variant `data:"variants"`
+
+	totalWeight float64
+}
+
+// Variant 是实验中的一个分组。
+type Variant struct {
+	Name   string  `data:"name"`
+	Weight float64 `data:"weight"`
+	Value  Data    `data:"value"` // 分组对应的配置，可以为空。
+}
+
+// NewExperiments 解析 d 中所有的实验。
+// 如果某个实验的格式不正确、没有分组、有 weight 为负数的分组或者所有分组的 weight 都是 0，返回错误。
+func NewExperiments(d Data) (*Experiments, error) {
+	dec := &Decoder{}
+	experiments := make(map[string]*Experiment, d.Len())
+
+	for key := range d.data {
+		exp := &Experiment{}
+
+		if err := dec.DecodeField(d, []string{key}, exp); err != nil {
+			return nil, err
+		}
+
+		for i, v := range exp.Variants {
+			if v == nil {
+				return nil, fmt.Errorf("go-data: variant #%v of experiment `%v` is nil", i, key)
+			}
+
+			if v.Weight < 0 {
+				return nil, fmt.Errorf("go-data: variant `%v` of experiment `%v` has negative weight %v", v.Name, key, v.Weight)
+			}
+
+			exp.totalWeight += v.Weight
+		}
+
+		if exp.totalWeight <= 0 {
+			return nil, fmt.Errorf("go-data: experiment `%v` has no variant with positive weight", key)
+		}
+
+		experiments[key] = exp
+	}
+
+	return &Experiments{
+		experiments: experiments,
+	}, nil
+}
+
+// Keys 返回所有实验的 key，按照字典序排列。
+func (e *Experiments) Keys() []string {
+	keys := make([]string, 0, len(e.experiments))
+
+	for key := range e.experiments {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// Get 返回 key 对应的实验，如果不存在则返回 nil。调用者不应该修改返回的值。
+func (e *Experiments) Get(key string) *Experiment {
+	return e.experiments[key]
+}
+
+// Resolve 返回 subject 在 key 对应实验中的分组，如果实验不存在则返回 nil。调用者不应该修改返回的值。
+func (e *Experiments) Resolve(key, subject string) *Variant {
+	exp := e.experiments[key]
+
+	if exp == nil {
+		return nil
+	}
+
+	return exp.resolve(key, subject)
+}
+
+// ResolveAll 返回 subject 在所有实验中的分组，key 是实验的 key。
+func (e *Experiments) ResolveAll(subject string) map[string]*Variant {
+	variants := make(map[string]*Variant, len(e.experiments))
+
+	for key, exp := range e.experiments {
+		variants[key] = exp.resolve(key, subject)
+	}
+
+	return variants
+}
+
+func (exp *Experiment) resolve(key, subject string) *Variant {
+	point := hashUnit(key, exp.Salt, subject) * exp.totalWeight
+	var last *Variant
+
+	for _, v := range exp.Variants {
+		if v.Weight <= 0 {
+			continue
+		}
+
+		if point < v.Weight {
+			return v
+		}
+
+		point -= v.Weight
+		last = v
+	}
+
+	// 浮点数误差可能导致 point 略大于所有 weight 之和。
+	return last
+}
+
+// hashUnit 将 parts 哈希成 [0, 1) 之间均匀分布的数，相同的 parts 总是得到相同的结果。
+func hashUnit(parts ...string) float64 {
+	h := fnv.New64a()
+
+	for i, part := range parts {
+		if i > 0 {
+			h.Write([]byte{0})
+		}
+
+		h.Write([]byte(part))
+	}
+
+	// FNV 的高位在 parts 只有末尾几个字符不同时分布很不均匀，需要再混合一次，
+	// 这里使用 MurmurHash3 的 fmix64。
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+
+	// 使用哈希值的高 53 位。
+	return float64(x>>11) / (1 << 53)
+}
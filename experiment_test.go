@@ -0,0 +1,112 @@
+package data
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestExperiments(t *testing.T) {
+	a := assert.New(t)
+	d := Make(RawData{
+		"checkout": RawData{
+			"variants": []RawData{
+				{"name": "control", "weight": 90},
+				{"name": "treatment", "weight": 10, "value": RawData{"color": "red"}},
+			},
+		},
+		"banner": RawData{
+			"salt": "v2",
+			"variants": []RawData{
+				{"name": "off", "weight": 0},
+				{"name": "a", "weight": 1.5},
+				{"name": "b", "weight": 1.5},
+			},
+		},
+	})
+	e, err := NewExperiments(d)
+	a.NilError(err)
+	a.Equal(e.Keys(), []string{"banner", "checkout"})
+	a.Equal(e.Get("banner").Salt, "v2")
+	a.Assert(e.Get("not_exist") == nil)
+	a.Assert(e.Resolve("not_exist", "user") == nil)
+
+	counts := map[string]int{}
+	const n = 10000
+
+	for i := 0; i < n; i++ {
+		subject := "user-" + strconv.Itoa(i)
+		v := e.Resolve("checkout", subject)
+		counts["checkout."+v.Name]++
+		counts["banner."+e.Resolve("banner", subject).Name]++
+
+		// 结果是确定的。
+		a.Equal(e.Resolve("checkout", subject), v)
+		a.Equal(e.ResolveAll(subject)["checkout"], v)
+	}
+
+	a.Equal(counts["banner.off"], 0)
+	a.Assert(counts["checkout.treatment"] > n*8/100 && counts["checkout.treatment"] < n*12/100)
+	a.Assert(counts["banner.a"] > n*45/100 && counts["banner.a"] < n*55/100)
+	a.Equal(counts["checkout.control"]+counts["checkout.treatment"], n)
+
+	for i := 0; ; i++ {
+		if v := e.Resolve("checkout", "user-"+strconv.Itoa(i)); v.Name == "treatment" {
+			a.Equal(v.Value.Get("color"), "red")
+			break
+		}
+	}
+}
+
+func TestExperimentsStable(t *testing.T) {
+	a := assert.New(t)
+	define := func(control, treatment float64) *Experiments {
+		e, err := NewExperiments(Make(RawData{
+			"exp": RawData{
+				"variants": []RawData{
+					{"name": "control", "weight": control},
+					{"name": "treatment", "weight": treatment},
+				},
+			},
+		}))
+		a.NilError(err)
+		return e
+	}
+
+	// 扩大实验组时，原来在实验组中的用户不会改变分组。
+	small := define(90, 10)
+	large := define(80, 20)
+
+	for i := 0; i < 1000; i++ {
+		subject := strconv.Itoa(i)
+
+		if small.Resolve("exp", subject).Name == "treatment" {
+			a.Equal(large.Resolve("exp", subject).Name, "treatment")
+		}
+	}
+}
+
+func TestExperimentsError(t *testing.T) {
+	cases := []RawData{
+		{"exp": "not an object"},
+		{"exp": RawData{}},
+		{"exp": RawData{"variants": []RawData{{"name": "a", "weight": 0}}}},
+		{"exp": RawData{"variants": []RawData{{"name": "a", "weight": -1}, {"name": "b", "weight": 2}}}},
+		{"exp": RawData{"variants": []interface{}{nil}}},
+		{"exp": RawData{"variants": []RawData{{"name": "a", "weight": "heavy"}}}},
+	}
+
+	for i, c := range cases {
+		a := assert.New(t)
+		a.Use(&i, &c)
+
+		_, err := NewExperiments(Make(c))
+		a.NonNilError(err)
+	}
+
+	a := assert.New(t)
+	e, err := NewExperiments(Data{})
+	a.NilError(err)
+	a.Equal(e.Keys(), []string{})
+}
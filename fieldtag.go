@@ -1,6 +1,10 @@
 package data
 
-import "strings"
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
 
 // FieldTag 是一个解析完成的字段 tag。
 //
@@ -10,6 +14,11 @@ import "strings"
 // 当前支持以下选项：
 //     - omitempty：忽略空值
 //     - squash：将一个字段的内容展开到当前 struct
+//     - alloc：解析时，即使 Data 中没有对应的值，也为 nil 指针字段分配一个空值
+//     - enum=name1:value1|name2:value2：枚举值映射，Data 中的 name 与 struct 中的 value 相互转化，
+//       比如 `data:"status,enum=active:1|inactive:0"` 会将 Data 中的 "active" 解析成字段值 1，反之亦然
+//     - encoder=name：编码时使用 `Encoder#RegisterTransformer` 注册的名为 name 的 Transformer 转化字段值
+//     - decoder=name：解码时使用 `Decoder#RegisterTransformer` 注册的名为 name 的 Transformer 转化 Data 中的值
 //
 // 当 alias 为“-”时，当前字段会被跳过。
 type FieldTag struct {
@@ -17,6 +26,20 @@ type FieldTag struct {
 	Skipped   bool   // 字段别名为“-”时，跳过这个字段。
 	OmitEmpty bool   // 忽略空值。
 	Squash    bool   // 是否展开。
+	Alloc     bool   // 解析时是否总是为 nil 指针分配空值。
+
+	Enum    []EnumItem // 枚举值映射。
+	Encoder string     // 编码时使用的 Transformer 名字。
+	Decoder string     // 解码时使用的 Transformer 名字。
+}
+
+// Transformer 用来在编码或解码时转化单个字段的值，比如在不同的单位之间转换。
+type Transformer func(v interface{}) (interface{}, error)
+
+// EnumItem 是一个枚举值映射，Name 是 Data 中的值，Value 是 struct 字段值的字符串形式。
+type EnumItem struct {
+	Name  string
+	Value string
 }
 
 // ParseFieldTag 解析 field tag 的 alias 和选项。
@@ -27,6 +50,9 @@ func ParseFieldTag(tag string) *FieldTag {
 	skipped := false
 	omitEmpty := false
 	squash := false
+	alloc := false
+	var enum []EnumItem
+	var encoder, decoder string
 
 	for _, opt := range opts[1:] {
 		switch opt {
@@ -34,6 +60,16 @@ func ParseFieldTag(tag string) *FieldTag {
 			omitEmpty = true
 		case "squash":
 			squash = true
+		case "alloc":
+			alloc = true
+		default:
+			if strings.HasPrefix(opt, "enum=") {
+				enum = parseEnumItems(opt[len("enum="):])
+			} else if strings.HasPrefix(opt, "encoder=") {
+				encoder = strings.TrimSpace(opt[len("encoder="):])
+			} else if strings.HasPrefix(opt, "decoder=") {
+				decoder = strings.TrimSpace(opt[len("decoder="):])
+			}
 		}
 	}
 
@@ -47,5 +83,113 @@ func ParseFieldTag(tag string) *FieldTag {
 		Skipped:   skipped,
 		OmitEmpty: omitEmpty,
 		Squash:    squash,
+		Alloc:     alloc,
+		Enum:      enum,
+		Encoder:   encoder,
+		Decoder:   decoder,
 	}
 }
+
+func parseEnumItems(str string) (items []EnumItem) {
+	for _, item := range strings.Split(str, "|") {
+		idx := strings.Index(item, ":")
+
+		if idx <= 0 {
+			continue
+		}
+
+		items = append(items, EnumItem{
+			Name:  strings.TrimSpace(item[:idx]),
+			Value: strings.TrimSpace(item[idx+1:]),
+		})
+	}
+
+	return
+}
+
+// enumValue 返回 name 对应的枚举值。
+func (ft *FieldTag) enumValue(name string) (string, bool) {
+	for _, item := range ft.Enum {
+		if item.Name == name {
+			return item.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// enumName 返回枚举值 value 对应的 name。
+func (ft *FieldTag) enumName(value string) (string, bool) {
+	for _, item := range ft.Enum {
+		if item.Value == value {
+			return item.Name, true
+		}
+	}
+
+	return "", false
+}
+
+// checkSquashCollisions 检查 struct 类型 t 中通过 squash 展开的字段是否与其他字段使用了相同的 key，
+// 如果有则返回错误，错误信息中包含冲突的两个字段名。
+func checkSquashCollisions(t reflect.Type, tagName string) error {
+	return collectFieldKeys(t, tagName, "", map[string]string{})
+}
+
+func collectFieldKeys(t reflect.Type, tagName string, prefix string, owners map[string]string) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || t.AssignableTo(typeOfData) {
+		return nil
+	}
+
+	l := t.NumField()
+
+	for i := 0; i < l; i++ {
+		f := t.Field(i)
+
+		// 跳过所有私有字段。
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+
+		ft := ParseFieldTag(f.Tag.Get(tagName))
+
+		if ft.Skipped {
+			continue
+		}
+
+		name := prefix + f.Name
+
+		if ft.Squash {
+			fieldType := f.Type
+
+			for fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+
+			if fieldType.Kind() == reflect.Struct && !fieldType.AssignableTo(typeOfData) {
+				if err := collectFieldKeys(fieldType, tagName, name+".", owners); err != nil {
+					return err
+				}
+
+				continue
+			}
+		}
+
+		k := f.Name
+
+		if ft.Alias != "" {
+			k = ft.Alias
+		}
+
+		if other, ok := owners[k]; ok {
+			return fmt.Errorf("go-data: key `%v` of field `%v` collides with field `%v`", k, name, other)
+		}
+
+		owners[k] = name
+	}
+
+	return nil
+}
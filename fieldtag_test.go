@@ -1,6 +1,7 @@
 package data
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/huandu/go-assert"
@@ -34,12 +35,31 @@ func TestParseFieldTag(t *testing.T) {
 				Skipped: true,
 			},
 		},
+		{ // 枚举值，不合法的映射会被忽略。
+			"status,enum=active:1| inactive : 0|bad|:2",
+			&FieldTag{
+				Alias: "status",
+				Enum: []EnumItem{
+					{Name: "active", Value: "1"},
+					{Name: "inactive", Value: "0"},
+				},
+			},
+		},
+		{ // 转化函数。
+			"price,encoder=cents,decoder=cents",
+			&FieldTag{
+				Alias:   "price",
+				Encoder: "cents",
+				Decoder: "cents",
+			},
+		},
 		{ // 所有都包含
-			"a1_b2,squash,omitempty",
+			"a1_b2,squash,omitempty,alloc",
 			&FieldTag{
 				Alias:     "a1_b2",
 				OmitEmpty: true,
 				Squash:    true,
+				Alloc:     true,
 			},
 		},
 	}
@@ -50,3 +70,31 @@ func TestParseFieldTag(t *testing.T) {
 		assert.AssertEqual(t, expected, actual)
 	}
 }
+
+type collisionInner struct {
+	Name string `data:"name"`
+	Port int    `data:"port"`
+}
+
+type collisionOuter struct {
+	Inner *collisionInner `data:",squash"`
+	Host  string          `data:"host"`
+	Port  int             `data:"port"`
+}
+
+type noCollision struct {
+	Inner collisionInner `data:",squash"`
+	Host  string         `data:"host"`
+	Skip  int            `data:"-"`
+}
+
+func TestCheckSquashCollisions(t *testing.T) {
+	a := assert.New(t)
+
+	err := checkSquashCollisions(reflect.TypeOf(&collisionOuter{}), defaultTagName)
+	a.NonNilError(err)
+	a.Equal(err.Error(), "go-data: key `port` of field `Port` collides with field `Inner.Port`")
+
+	a.NilError(checkSquashCollisions(reflect.TypeOf(noCollision{}), defaultTagName))
+	a.NilError(checkSquashCollisions(reflect.TypeOf(AllValue{}), "test"))
+}
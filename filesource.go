@@ -0,0 +1,155 @@
+package data
+
+import (
+	"context"
+	"hash/fnv"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultPollInterval 是 FileSource 检查文件是否变化的默认间隔。
+const DefaultPollInterval = time.Second
+
+// FileSource 从文件中加载 Data，并在文件变化时自动重新加载，适合用来实现配置文件的热更新。
+//
+// FileSource 通过定期检查文件的修改时间和大小来发现变化，文件内容真正发生变化时才会重新解析。
+// 重新解析成功后，新的 Data 会原子的替换原有的快照；如果解析失败，原有的快照保持不变。
+type FileSource struct {
+	Path     string        // 文件路径。
+	Interval time.Duration // 检查文件变化的间隔，如果为 0，使用 DefaultPollInterval。
+
+	// Parse 用来将文件内容解析成 Data，如果为 nil，使用 `Parser#ParseJSONBytes` 按照 JSON 解析。
+	// 如果文件是 TOML、YAML 等格式，可以通过设置 Parse 来支持。
+	Parse func(content []byte) (Data, error)
+
+	// OnError 在 Watch 过程中读取或解析文件出错时被调用，如果为 nil 则忽略这些错误。
+	OnError func(err error)
+
+	mu       sync.Mutex
+	handlers []func(d Data)
+	modTime  time.Time
+	size     int64
+	hash     uint64
+	loaded   bool
+	snapshot SyncData
+}
+
+// NewFileSource 创建一个读取 path 的 FileSource。
+func NewFileSource(path string) *FileSource {
+	return &FileSource{
+		Path: path,
+	}
+}
+
+// Load 立即读取并解析文件，如果成功则替换当前的快照。
+// 如果文件内容与上次加载时相比发生了变化，会调用所有 OnReload 设置的回调。
+func (fs *FileSource) Load() error {
+	return fs.reload(false)
+}
+
+// reload 加载文件，如果 onlyModified 为 true，只有文件的修改时间或者大小变化时才会读取文件。
+// 回调在释放 fs.mu 之后调用，这样回调中可以调用 fs 的任何方法。
+func (fs *FileSource) reload(onlyModified bool) error {
+	fs.mu.Lock()
+	d, changed, err := fs.load(onlyModified)
+	handlers := fs.handlers
+	fs.mu.Unlock()
+
+	if err != nil || !changed {
+		return err
+	}
+
+	for _, fn := range handlers {
+		fn(d)
+	}
+
+	return nil
+}
+
+// load 读取并解析文件，如果文件内容发生了变化，替换当前的快照并返回 true，调用者需要持有 fs.mu。
+func (fs *FileSource) load(onlyModified bool) (d Data, changed bool, err error) {
+	info, err := os.Stat(fs.Path)
+
+	if err != nil {
+		return
+	}
+
+	if onlyModified && fs.loaded && info.ModTime().Equal(fs.modTime) && info.Size() == fs.size {
+		return
+	}
+
+	content, err := ioutil.ReadFile(fs.Path)
+
+	if err != nil {
+		return
+	}
+
+	fs.modTime = info.ModTime()
+	fs.size = info.Size()
+
+	h := fnv.New64a()
+	h.Write(content)
+	hash := h.Sum64()
+
+	if fs.loaded && hash == fs.hash {
+		return
+	}
+
+	parse := fs.Parse
+
+	if parse == nil {
+		p := &Parser{}
+		parse = p.ParseJSONBytes
+	}
+
+	if d, err = parse(content); err != nil {
+		return
+	}
+
+	fs.hash = hash
+	fs.loaded = true
+	fs.snapshot.Store(d)
+	changed = true
+	return
+}
+
+// Snapshot 返回最近一次成功加载的 Data，如果还没有成功加载过则返回空 Data。
+// 调用者不应该修改返回的 Data。
+func (fs *FileSource) Snapshot() Data {
+	return fs.snapshot.Load()
+}
+
+// OnReload 增加一个在文件成功重新加载之后调用的回调，d 是新的快照。
+// 回调在调用 Load 或 Watch 的 goroutine 中按照增加的顺序调用，不应该在回调中执行耗时的操作。
+func (fs *FileSource) OnReload(fn func(d Data)) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.handlers = append(fs.handlers, fn)
+}
+
+// Watch 定期检查文件是否变化，如果变化则重新加载，直到 ctx 结束才返回 ctx.Err()。
+// 如果还没有成功加载过文件，Watch 会先尝试加载一次。
+func (fs *FileSource) Watch(ctx context.Context) error {
+	interval := fs.Interval
+
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := fs.reload(true); err != nil && fs.OnError != nil {
+			fs.OnError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
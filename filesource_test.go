@@ -0,0 +1,84 @@
+package data
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestFileSource(t *testing.T) {
+	a := assert.New(t)
+	dir, err := ioutil.TempDir("", "go-data")
+	a.NilError(err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.json")
+	write := func(content string, mtime time.Time) {
+		a.NilError(ioutil.WriteFile(path, []byte(content), 0644))
+		a.NilError(os.Chtimes(path, mtime, mtime))
+	}
+	now := time.Now()
+	write(`{"port":80}`, now)
+
+	fs := NewFileSource(path)
+	fs.Interval = time.Millisecond
+	reloaded := make(chan Data, 10)
+	fs.OnReload(func(d Data) {
+		reloaded <- d
+	})
+	errs := make(chan error, 10)
+	fs.OnError = func(err error) {
+		errs <- err
+	}
+
+	a.Equal(fs.Snapshot(), Data{})
+	a.NilError(fs.Load())
+	a.Equal(fs.Snapshot(), Make(RawData{"port": 80}))
+	a.Equal(<-reloaded, Make(RawData{"port": 80}))
+
+	// 内容没有变化时不会触发回调。
+	a.NilError(fs.Load())
+	a.Equal(len(reloaded), 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- fs.Watch(ctx)
+	}()
+
+	write(`{"port":8080}`, now.Add(time.Second))
+	a.Equal(<-reloaded, Make(RawData{"port": 8080}))
+	a.Equal(fs.Snapshot(), Make(RawData{"port": 8080}))
+
+	// 解析失败时保留原有的快照。
+	write(`{"port":`, now.Add(2*time.Second))
+	a.NonNilError(<-errs)
+	a.Equal(fs.Snapshot(), Make(RawData{"port": 8080}))
+
+	cancel()
+	a.Equal(<-done, context.Canceled)
+
+	// 自定义解析函数。
+	fs = &FileSource{
+		Path: path,
+		Parse: func(content []byte) (Data, error) {
+			return Make(RawData{"raw": string(content)}), nil
+		},
+	}
+	// 回调中可以调用 fs 的方法，不会死锁。
+	var reloadErr error
+	fs.OnReload(func(d Data) {
+		reloadErr = fs.Load()
+	})
+	a.NilError(fs.Load())
+	a.NilError(reloadErr)
+	a.Equal(fs.Snapshot(), Make(RawData{"raw": `{"port":`}))
+
+	fs = NewFileSource(filepath.Join(dir, "not_exist.json"))
+	a.NonNilError(fs.Load())
+}
@@ -0,0 +1,79 @@
+package data
+
+import (
+	"reflect"
+	"strconv"
+)
+
+// FindKey 在 d 中深度查找所有名为 name 的 key，返回这些 key 的路径，路径格式与 `Data#Query` 相同。
+// 数组中的 object 也会被查找，比如 `list.1.name`。
+//
+// 路径按照深度优先的顺序返回，同一个 object 中的 key 按照 `Data#Keys` 的顺序遍历。
+// 如果找不到，返回 nil。
+func (d Data) FindKey(name string) []string {
+	var paths []string
+
+	walkValues(d.data, d.order, nil, func(path []string, isKey bool, v interface{}) {
+		if isKey && path[len(path)-1] == name {
+			paths = append(paths, JoinQuery(path...))
+		}
+	})
+
+	return paths
+}
+
+// FindValue 在 d 中深度查找所有满足 pred 的叶子节点，返回这些值的路径，路径格式与 `Data#Query` 相同。
+// 叶子节点是除了 object 和数组以外的值，数组中的元素也会被逐个检查。
+// 比如，可以用来找到所有包含旧域名的字符串，再通过 `Patch` 批量修改。
+//
+// 路径的顺序与 `Data#FindKey` 相同。如果找不到，返回 nil。
+func (d Data) FindValue(pred func(v interface{}) bool) []string {
+	var paths []string
+
+	walkValues(d.data, d.order, nil, func(path []string, isKey bool, v interface{}) {
+		if isLeafValue(v) && pred(v) {
+			paths = append(paths, JoinQuery(path...))
+		}
+	})
+
+	return paths
+}
+
+func isLeafValue(v interface{}) bool {
+	switch v.(type) {
+	case RawData, Data:
+		return false
+	}
+
+	return v == nil || reflect.TypeOf(v).Kind() != reflect.Slice
+}
+
+// walkValues 深度优先遍历 v 中的所有值，对每个值调用 fn。
+// path 是值的路径，如果值是 object 中的一个 key 对应的值，isKey 为 true，如果是数组元素则为 false。
+func walkValues(v interface{}, order *keyOrder, path []string, fn func(path []string, isKey bool, v interface{})) {
+	if d, ok := v.(Data); ok {
+		v = d.data
+	}
+
+	if m, ok := v.(RawData); ok {
+		for _, k := range order.sortedKeys(m) {
+			p := append(path[:len(path):len(path)], k)
+			fn(p, true, m[k])
+			walkValues(m[k], order.child(k), p, fn)
+		}
+
+		return
+	}
+
+	elems, ok := sliceElems(v)
+
+	if !ok {
+		return
+	}
+
+	for i, elem := range elems {
+		p := append(path[:len(path):len(path)], strconv.Itoa(i))
+		fn(p, false, elem)
+		walkValues(elem, order.elem(i), p, fn)
+	}
+}
@@ -0,0 +1,81 @@
+package data
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestDataFindKey(t *testing.T) {
+	d := Make(RawData{
+		"name": "root",
+		"server": RawData{
+			"name": "server",
+			"port": 80,
+			"tls": RawData{
+				"port": 443,
+			},
+		},
+		"list": []RawData{
+			{"name": "first"},
+			{"port": 8080},
+			{"name": "third"},
+		},
+		"ports": []int{1, 2},
+	})
+	cases := []struct {
+		Name  string
+		Paths []string
+	}{
+		{"name", []string{"list.0.name", "list.2.name", "name", "server.name"}},
+		{"port", []string{"list.1.port", "server.port", "server.tls.port"}},
+		{"tls", []string{"server.tls"}},
+		{"0", nil},
+		{"not_exist", nil},
+	}
+	a := assert.New(t)
+
+	for i, c := range cases {
+		a.Use(&i, &c)
+		a.Equal(d.FindKey(c.Name), c.Paths)
+	}
+
+	p := &Parser{
+		KeepOrder: true,
+	}
+	d, err := p.ParseJSON(`{"z":{"id":1},"a":{"id":2}}`)
+	a.NilError(err)
+	a.Equal(d.FindKey("id"), []string{"z.id", "a.id"})
+}
+
+func TestDataFindValue(t *testing.T) {
+	d := Make(RawData{
+		"host": "old.example.com",
+		"server": RawData{
+			"url":  "https://old.example.com/api",
+			"port": 80,
+		},
+		"mirrors": []string{"new.example.com", "old.example.com"},
+		"list": []RawData{
+			{"host": "old.example.com"},
+		},
+		"empty": nil,
+	})
+	a := assert.New(t)
+
+	a.Equal(d.FindValue(func(v interface{}) bool {
+		s, ok := v.(string)
+		return ok && strings.Contains(s, "old.example.com")
+	}), []string{"host", "list.0.host", "mirrors.1", "server.url"})
+	a.Equal(d.FindValue(func(v interface{}) bool {
+		return v == int64(80)
+	}), []string{"server.port"})
+	a.Equal(d.FindValue(func(v interface{}) bool {
+		return v == nil
+	}), []string{"empty"})
+	a.Equal(d.FindValue(func(v interface{}) bool {
+		_, ok := v.(RawData)
+		return ok
+	}), nil)
+}
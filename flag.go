@@ -0,0 +1,163 @@
+package data
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// defaultFlagBucketBy 是百分比灰度默认使用的 ctx 属性。
+const defaultFlagBucketBy = "id"
+
+// Enabled 判断 flagPath 对应的功能开关对于 ctx 描述的请求是否打开，flagPath 的格式与 `Data#Query` 相同。
+// ctx 是请求的属性，比如用户 id、国家、套餐等，可以是多层嵌套的 map。
+//
+// 功能开关可以是一个 bool，也可以是一个规则对象，格式如下：
+//     {
+//         "enabled": true,                     // 总开关，默认为 true，为 false 时总是关闭。
+//         "rules": [                           // 按顺序匹配，使用第一个匹配的规则的结果。
+//             {"match": {"country": ["CN", "US"], "plan": "pro"}},
+//             {"match": {"beta": true}, "percentage": 20, "bucket_by": "user.id"},
+//             {"match": {"country": "FR"}, "enabled": false}
+//         ],
+//         "default": false                     // 没有规则匹配时的结果，默认为 false。
+//     }
+// 每个规则包含以下字段，所有字段都是可选的：
+//     - match：ctx 中每个 query 对应的值都等于期望值时匹配，期望值是数组时只要等于其中任意一个即可，
+//       为空时总是匹配；
+//     - percentage：匹配的请求中打开的百分比，取值范围是 0 到 100，默认为 100；
+//     - bucket_by：计算百分比时使用的 ctx 属性，格式与 `Data#Query` 相同，默认为 "id"，
+//       同一个属性值总是得到相同的结果，如果 ctx 中没有这个属性，百分比小于 100 的规则结果为关闭；
+//     - enabled：规则匹配时的结果，默认为 true，为 false 时即使在百分比内也是关闭。
+// 如果规则对象没有 rules，那么这个对象本身会被当作唯一的规则，比如 {"percentage": 10} 代表对 10% 的请求打开，
+// 这个规则不匹配时同样使用 default 作为结果。
+//
+// 如果 flagPath 不存在，或者规则的格式不正确，返回 false。
+func (d Data) Enabled(flagPath string, ctx map[string]interface{}) bool {
+	var flag Data
+
+	switch v := d.Query(flagPath).(type) {
+	case bool:
+		return v
+	case RawData:
+		flag = Data{data: v}
+	case Data:
+		flag = v
+	default:
+		return false
+	}
+
+	if enabled, ok := flag.QueryBool("enabled"); ok && !enabled {
+		return false
+	}
+
+	attrs := Make(ctx)
+	rules := flag.Query("rules")
+
+	if rules == nil {
+		if enabled, matched := evalFlagRule(flagPath, flag, attrs); matched {
+			return enabled
+		}
+	} else {
+		elems, ok := sliceElems(rules)
+
+		if !ok {
+			return false
+		}
+
+		for _, elem := range elems {
+			var rule Data
+
+			switch v := elem.(type) {
+			case RawData:
+				rule = Data{data: v}
+			case Data:
+				rule = v
+			default:
+				return false
+			}
+
+			if enabled, matched := evalFlagRule(flagPath, rule, attrs); matched {
+				return enabled
+			}
+		}
+	}
+
+	defaultValue, _ := flag.QueryBool("default")
+	return defaultValue
+}
+
+// evalFlagRule 判断 rule 是否匹配 attrs，以及匹配时开关是否打开。
+func evalFlagRule(flagPath string, rule, attrs Data) (enabled, matched bool) {
+	switch match := rule.Query("match").(type) {
+	case nil:
+	case RawData:
+		if !matchFlagAttrs(match, attrs) {
+			return
+		}
+	case Data:
+		if !matchFlagAttrs(match.data, attrs) {
+			return
+		}
+	default:
+		return
+	}
+
+	matched = true
+
+	if on, ok := rule.QueryBool("enabled"); ok && !on {
+		return
+	}
+
+	percentage, ok := rule.QueryFloat64("percentage")
+
+	if !ok || percentage >= 100 {
+		enabled = true
+		return
+	}
+
+	bucketBy, ok := rule.QueryString("bucket_by")
+
+	if !ok {
+		bucketBy = defaultFlagBucketBy
+	}
+
+	subject := attrs.Query(bucketBy)
+
+	if subject == nil {
+		return
+	}
+
+	enabled = hashUnit(flagPath, fmt.Sprint(subject))*100 < percentage
+	return
+}
+
+func matchFlagAttrs(match RawData, attrs Data) bool {
+	for query, expected := range match {
+		actual := attrs.Query(query)
+
+		if reflect.DeepEqual(actual, expected) {
+			continue
+		}
+
+		elems, ok := sliceElems(expected)
+
+		if !ok {
+			return false
+		}
+
+		found := false
+
+		for _, elem := range elems {
+			if reflect.DeepEqual(actual, elem) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,107 @@
+package data
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestDataEnabled(t *testing.T) {
+	d := Make(RawData{
+		"flags": RawData{
+			"on":       true,
+			"off":      false,
+			"killed":   RawData{"enabled": false, "percentage": 100},
+			"everyone": RawData{},
+			"pro": RawData{
+				"match": RawData{"plan": "pro"},
+			},
+			"cn_default": RawData{
+				"match":   RawData{"country": "CN"},
+				"default": true,
+			},
+			"regions": RawData{
+				"rules": []RawData{
+					{"match": RawData{"country": "FR"}, "enabled": false},
+					{"match": RawData{"country": []string{"CN", "US"}, "user.level": 3}},
+					{"match": RawData{"beta": true}, "percentage": 0},
+				},
+				"default": true,
+			},
+			"invalid_rules": RawData{"rules": "oops"},
+			"invalid_match": RawData{"match": 1},
+			"not_flag":      "yes",
+		},
+	})
+	cases := []struct {
+		Flag     string
+		Ctx      map[string]interface{}
+		Expected bool
+	}{
+		{"flags.on", nil, true},
+		{"flags.off", nil, false},
+		{"flags.killed", nil, false},
+		{"flags.everyone", nil, true},
+		{"flags.pro", map[string]interface{}{"plan": "pro"}, true},
+		{"flags.pro", map[string]interface{}{"plan": "free"}, false},
+		{"flags.pro", nil, false},
+		{"flags.cn_default", map[string]interface{}{"country": "CN"}, true},
+		{"flags.cn_default", map[string]interface{}{"country": "US"}, true},
+		{"flags.regions", map[string]interface{}{"country": "FR"}, false},
+		{"flags.regions", map[string]interface{}{"country": "US", "user": map[string]int{"level": 3}}, true},
+		{"flags.regions", map[string]interface{}{"country": "US", "beta": true}, false},
+		{"flags.regions", map[string]interface{}{"country": "JP"}, true},
+		{"flags.invalid_rules", nil, false},
+		{"flags.invalid_match", nil, false},
+		{"flags.not_flag", nil, false},
+		{"flags.not_exist", nil, false},
+	}
+
+	for i, c := range cases {
+		a := assert.New(t)
+		a.Use(&i, &c)
+
+		a.Equal(d.Enabled(c.Flag, c.Ctx), c.Expected)
+	}
+}
+
+func TestDataEnabledPercentage(t *testing.T) {
+	a := assert.New(t)
+	d := Make(RawData{
+		"rollout": RawData{"percentage": 25},
+		"by_user": RawData{"percentage": 50, "bucket_by": "user.id"},
+		"other":   RawData{"percentage": 25},
+	})
+	const n = 10000
+	enabled := 0
+	same := 0
+
+	for i := 0; i < n; i++ {
+		ctx := map[string]interface{}{"id": i}
+		on := d.Enabled("rollout", ctx)
+
+		if on {
+			enabled++
+		}
+
+		// 结果是确定的，不同的开关之间互相独立。
+		a.Equal(d.Enabled("rollout", ctx), on)
+
+		if d.Enabled("other", ctx) == on {
+			same++
+		}
+	}
+
+	a.Assert(enabled > n*22/100 && enabled < n*28/100)
+	a.Assert(same < n*70/100)
+
+	// 没有 bucket_by 对应的属性时，百分比灰度总是关闭。
+	a.Assert(!d.Enabled("by_user", map[string]interface{}{"id": 1}))
+
+	for i := 0; ; i++ {
+		if d.Enabled("by_user", map[string]interface{}{"user": map[string]interface{}{"id": strconv.Itoa(i)}}) {
+			break
+		}
+	}
+}
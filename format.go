@@ -0,0 +1,372 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var defaultFormatter Formatter
+
+// Formatter 用来将 Data 格式化成字符串，可以通过设置各个字段来定制输出格式。
+// Formatter 的零值与 `Data#JSON`、`Data#String` 等方法的输出完全相同。
+//
+// 需要注意，Formatter 只影响输出格式，不影响 Parse 的解析结果，
+// 比如即使输出的是 `2.0`，Parse 依然会将它解析成整数 2。
+//
+// JSON 不支持复数，复数会输出成 `{"re":1,"im":2}` 这样的 object，
+// Parse 之后得到的是一个 RawData，但可以使用 Decoder 将它还原成复数。
+type Formatter struct {
+	// FloatFormat 是浮点数的输出格式，支持以下值：
+	//     - 0：与 encoding/json 一致，数字特别大或特别小时使用科学计数法；
+	//     - 'f'：从不使用科学计数法；
+	//     - 'e'：总是使用科学计数法。
+	FloatFormat byte
+
+	// FloatPrecision 是浮点数小数点后的位数，为 0 时使用能够精确表达这个数的最少位数。
+	FloatPrecision int
+
+	// FloatKeepPoint 为 true 时，整数值的浮点数也总是会输出小数点，比如 2.0 输出成 `2.0` 而不是 `2`。
+	FloatKeepPoint bool
+
+	// Headerless 为 true 时，String 和 PrettyString 不输出 `<json>` 这样的头，只输出 JSON。
+	// 这样的输出需要使用设置了 Headerless 的 Parser 来解析。
+	Headerless bool
+
+	// MetaBegin 和 MetaEnd 是头的开始和结束标记，默认分别是 `<` 和 `>`。
+	// 使用自定义标记输出的内容需要使用相同设置的 Parser 来解析。
+	MetaBegin string
+	MetaEnd   string
+
+	// KeyPriority 是优先输出的 key 列表，每个 object 中出现在列表里的 key 都会按照列表的顺序排在最前面，
+	// 其他 key 依然按照原来的规则排列。比如设置为 `[]string{"name", "id"}` 时，
+	// 每个 object 都会先输出 "name"，再输出 "id"，然后才是其他按字典序排列的 key。
+	// 这可以让序列化后的文档在 code review 时更容易阅读。
+	KeyPriority []string
+}
+
+// JSON 返回 d 对应的 JSON 字符串。
+// 如果 pretty 为 true，会为打印优化输出格式。
+//
+// 如果 d 中存在无法序列化的值，返回的字符串可能不完整，可以使用 JSONE 获得具体的错误。
+func (f *Formatter) JSON(d Data, pretty bool) string {
+	str, _ := f.JSONE(d, pretty)
+	return str
+}
+
+// JSONE 返回 d 对应的 JSON 字符串，如果 d 中存在无法序列化的值则返回错误。
+// 如果 pretty 为 true，会为打印优化输出格式。
+func (f *Formatter) JSONE(d Data, pretty bool) (string, error) {
+	buf := &bytes.Buffer{}
+
+	if err := f.json(buf, d, pretty); err != nil {
+		return buf.String(), err
+	}
+
+	return buf.String(), nil
+}
+
+// PrettyString 输出用于打印输出的存储格式。
+func (f *Formatter) PrettyString(d Data) string {
+	buf := &bytes.Buffer{}
+
+	if !f.Headerless {
+		f.writeHeader(buf)
+		buf.WriteRune('\n')
+	}
+
+	f.json(buf, d, true)
+	return buf.String()
+}
+
+// String 返回 d 的可存储格式，这个格式可以用 Parse 解析并还原成 Data 结构。
+//
+// 如果 d 中存在无法序列化的值，返回的字符串可能不完整，可以使用 StringE 获得具体的错误。
+func (f *Formatter) String(d Data) string {
+	str, _ := f.StringE(d)
+	return str
+}
+
+// StringE 返回 d 的可存储格式，如果 d 中存在无法序列化的值则返回错误。
+func (f *Formatter) StringE(d Data) (string, error) {
+	buf := &bytes.Buffer{}
+
+	if !f.Headerless {
+		f.writeHeader(buf)
+	}
+
+	if err := f.json(buf, d, false); err != nil {
+		return buf.String(), err
+	}
+
+	return buf.String(), nil
+}
+
+func (f *Formatter) writeHeader(buf *bytes.Buffer) {
+	metaBegin, metaEnd := metaMarks(f.MetaBegin, f.MetaEnd)
+	buf.WriteString(metaBegin)
+	buf.WriteString(dataTypeJSON)
+	buf.WriteString(metaEnd)
+}
+
+func (f *Formatter) json(buf *bytes.Buffer, d Data, pretty bool) error {
+	if d.Len() == 0 {
+		buf.WriteString("{}")
+		return nil
+	}
+
+	// 只有需要定制输出时才逐个节点输出，否则直接使用 encoding/json，这样会更快一些。
+	if d.order != nil || f.customFloat() || len(f.KeyPriority) != 0 {
+		return f.customJSON(buf, d, pretty)
+	}
+
+	start := buf.Len()
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+
+	if pretty {
+		enc.SetIndent("", "\t")
+	}
+
+	if err := enc.Encode(d.data); err != nil {
+		// encoding/json 不支持复数，这种情况下需要逐个节点输出。
+		if e, ok := err.(*json.UnsupportedTypeError); ok && isComplexKind(e.Type.Kind()) {
+			buf.Truncate(start)
+			return f.customJSON(buf, d, pretty)
+		}
+
+		return err
+	}
+
+	// 干掉最后多余的那个 \n
+	data := buf.Bytes()
+
+	if len(data) > 0 && data[len(data)-1] == '\n' {
+		buf.Truncate(buf.Len() - 1)
+	}
+
+	return nil
+}
+
+func (f *Formatter) customFloat() bool {
+	return f.FloatFormat != 0 || f.FloatPrecision != 0 || f.FloatKeepPoint
+}
+
+// prioritizeKeys 将 keys 中出现在 KeyPriority 中的 key 按照 KeyPriority 的顺序移到最前面，
+// 其他 key 保持原有的相对顺序。
+func (f *Formatter) prioritizeKeys(keys []string) []string {
+	if len(f.KeyPriority) == 0 || len(keys) == 0 {
+		return keys
+	}
+
+	rest := make(map[string]bool, len(keys))
+
+	for _, k := range keys {
+		rest[k] = true
+	}
+
+	prioritized := make([]string, 0, len(keys))
+
+	for _, k := range f.KeyPriority {
+		if rest[k] {
+			prioritized = append(prioritized, k)
+			delete(rest, k)
+		}
+	}
+
+	for _, k := range keys {
+		if rest[k] {
+			prioritized = append(prioritized, k)
+		}
+	}
+
+	return prioritized
+}
+
+func (f *Formatter) customJSON(buf *bytes.Buffer, d Data, pretty bool) error {
+	w := &jsonWriter{
+		formatter: f,
+	}
+
+	if !pretty {
+		w.buf = buf
+		return w.write(d.data, d.order)
+	}
+
+	w.buf = &bytes.Buffer{}
+
+	if err := w.write(d.data, d.order); err != nil {
+		return err
+	}
+
+	return json.Indent(buf, w.buf.Bytes(), "", "\t")
+}
+
+// jsonWriter 逐个节点的将 Data 中的值写成紧凑的 JSON，用于支持 key 顺序、浮点格式等定制输出。
+type jsonWriter struct {
+	buf       *bytes.Buffer
+	formatter *Formatter
+	unordered bool // 忽略嵌在树中的 Data 记录的 key 顺序，总是按字典序输出。
+}
+
+func (w *jsonWriter) write(v interface{}, order *keyOrder) error {
+	switch val := v.(type) {
+	case RawData:
+		if val == nil {
+			return w.writeValue(v)
+		}
+
+		w.buf.WriteByte('{')
+
+		for i, k := range w.formatter.prioritizeKeys(order.sortedKeys(val)) {
+			if i != 0 {
+				w.buf.WriteByte(',')
+			}
+
+			if err := w.writeValue(k); err != nil {
+				return err
+			}
+
+			w.buf.WriteByte(':')
+
+			if err := w.write(val[k], order.child(k)); err != nil {
+				return err
+			}
+		}
+
+		w.buf.WriteByte('}')
+		return nil
+
+	case Data:
+		// 嵌在树中的 Data 与 RawData 一样逐个节点输出，这样也会使用同样的 formatter。
+		if val.Len() == 0 {
+			w.buf.WriteString("{}")
+			return nil
+		}
+
+		if w.unordered {
+			return w.write(val.data, nil)
+		}
+
+		return w.write(val.data, val.order)
+
+	case float64:
+		return w.writeFloat(val, 64)
+
+	case float32:
+		return w.writeFloat(float64(val), 32)
+
+	case complex128:
+		return w.writeComplex(real(val), imag(val), 64)
+
+	case complex64:
+		return w.writeComplex(float64(real(val)), float64(imag(val)), 32)
+	}
+
+	rv := reflect.ValueOf(v)
+
+	// []byte 需要按照 encoding/json 的规则输出成 base64 字符串。
+	if rv.Kind() != reflect.Slice || rv.IsNil() || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return w.writeValue(v)
+	}
+
+	w.buf.WriteByte('[')
+
+	for i := 0; i < rv.Len(); i++ {
+		if i != 0 {
+			w.buf.WriteByte(',')
+		}
+
+		if err := w.write(rv.Index(i).Interface(), order.elem(i)); err != nil {
+			return err
+		}
+	}
+
+	w.buf.WriteByte(']')
+	return nil
+}
+
+func (w *jsonWriter) writeValue(v interface{}) error {
+	enc := json.NewEncoder(w.buf)
+	enc.SetEscapeHTML(false)
+
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+
+	// 干掉最后多余的那个 \n
+	w.buf.Truncate(w.buf.Len() - 1)
+	return nil
+}
+
+// writeComplex 将复数输出成 `{"re":1,"im":2}` 的形式，Decoder 可以将这种形式的 object 解析成复数。
+func (w *jsonWriter) writeComplex(re, im float64, bits int) error {
+	w.buf.WriteString(`{"re":`)
+
+	if err := w.writeFloat(re, bits); err != nil {
+		return err
+	}
+
+	w.buf.WriteString(`,"im":`)
+
+	if err := w.writeFloat(im, bits); err != nil {
+		return err
+	}
+
+	w.buf.WriteByte('}')
+	return nil
+}
+
+func isComplexKind(kind reflect.Kind) bool {
+	return kind == reflect.Complex64 || kind == reflect.Complex128
+}
+
+func (w *jsonWriter) writeFloat(f float64, bits int) error {
+	if !w.formatter.customFloat() {
+		return w.writeValue(f)
+	}
+
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return fmt.Errorf("go-data: unsupported float value %v", f)
+	}
+
+	prec := w.formatter.FloatPrecision
+
+	if prec <= 0 {
+		prec = -1
+	}
+
+	var str string
+
+	switch w.formatter.FloatFormat {
+	case 'f', 'e':
+		str = strconv.FormatFloat(f, w.formatter.FloatFormat, prec, bits)
+	default:
+		// 与 encoding/json 的规则保持一致。
+		format := byte('f')
+
+		if abs := math.Abs(f); abs != 0 && (bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21)) {
+			format = 'e'
+		}
+
+		str = strconv.FormatFloat(f, format, prec, bits)
+
+		if format == 'e' {
+			// 将 e-09 变成 e-9。
+			if n := len(str); n >= 4 && str[n-4] == 'e' && str[n-3] == '-' && str[n-2] == '0' {
+				str = str[:n-2] + str[n-1:]
+			}
+		}
+	}
+
+	if w.formatter.FloatKeepPoint && !strings.ContainsAny(str, ".eE") {
+		str += ".0"
+	}
+
+	w.buf.WriteString(str)
+	return nil
+}
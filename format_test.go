@@ -0,0 +1,175 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestFormatterFloat(t *testing.T) {
+	d := Make(RawData{
+		"int":    1,
+		"float":  2.0,
+		"frac":   1.25,
+		"small":  0.0000001,
+		"large":  1e22,
+		"floats": []float64{1, 2.5},
+		"nested": RawData{
+			"f": 3.0,
+		},
+	})
+	cases := []struct {
+		Formatter Formatter
+		JSON      string
+	}{
+		{ // 默认格式
+			Formatter{},
+			`{"float":2,"floats":[1,2.5],"frac":1.25,"int":1,"large":1e+22,"nested":{"f":3},"small":1e-7}`,
+		},
+		{ // 总是保留小数点
+			Formatter{
+				FloatKeepPoint: true,
+			},
+			`{"float":2.0,"floats":[1.0,2.5],"frac":1.25,"int":1,"large":1e+22,"nested":{"f":3.0},"small":1e-7}`,
+		},
+		{ // 不使用科学计数法
+			Formatter{
+				FloatFormat:    'f',
+				FloatKeepPoint: true,
+			},
+			`{"float":2.0,"floats":[1.0,2.5],"frac":1.25,"int":1,"large":10000000000000000000000.0,"nested":{"f":3.0},"small":0.0000001}`,
+		},
+		{ // 固定小数位数
+			Formatter{
+				FloatFormat:    'f',
+				FloatPrecision: 2,
+			},
+			`{"float":2.00,"floats":[1.00,2.50],"frac":1.25,"int":1,"large":10000000000000000000000.00,"nested":{"f":3.00},"small":0.00}`,
+		},
+		{ // 科学计数法
+			Formatter{
+				FloatFormat: 'e',
+			},
+			`{"float":2e+00,"floats":[1e+00,2.5e+00],"frac":1.25e+00,"int":1,"large":1e+22,"nested":{"f":3e+00},"small":1e-07}`,
+		},
+	}
+	a := assert.New(t)
+
+	for i, c := range cases {
+		a.Use(&i, &c)
+
+		a.Equal(c.Formatter.JSON(d, false), c.JSON)
+		a.Equal(c.Formatter.String(d), "<json>"+c.JSON)
+	}
+
+	f := &Formatter{
+		FloatKeepPoint: true,
+	}
+	a.Equal(f.PrettyString(Make(RawData{"f": 1.0})), "<json>\n{\n\t\"f\": 1.0\n}")
+	a.Equal(f.JSON(Data{}, false), "{}")
+}
+
+func TestFormatterHeader(t *testing.T) {
+	d := Make(RawData{
+		"a": 1,
+	})
+	cases := []struct {
+		Formatter Formatter
+		Parser    Parser
+		Str       string
+	}{
+		{ // 默认格式
+			Formatter{},
+			Parser{},
+			`<json>{"a":1}`,
+		},
+		{ // 没有头
+			Formatter{
+				Headerless: true,
+			},
+			Parser{
+				Headerless: true,
+			},
+			`{"a":1}`,
+		},
+		{ // 自定义头
+			Formatter{
+				MetaBegin: "#!",
+				MetaEnd:   "\n",
+			},
+			Parser{
+				MetaBegin: "#!",
+				MetaEnd:   "\n",
+			},
+			"#!json\n{\"a\":1}",
+		},
+	}
+	a := assert.New(t)
+
+	for i, c := range cases {
+		a.Use(&i, &c)
+
+		str := c.Formatter.String(d)
+		a.Equal(str, c.Str)
+
+		parsed, err := c.Parser.Parse(str)
+		a.NilError(err)
+		a.Equal(parsed, d)
+
+		parsed, err = c.Parser.Parse(c.Formatter.PrettyString(d))
+		a.NilError(err)
+		a.Equal(parsed, d)
+	}
+
+	// Headerless 的 Parser 依然可以解析带头的格式。
+	p := &Parser{
+		Headerless: true,
+	}
+	parsed, err := p.Parse(`<json>{"a":1}`)
+	a.NilError(err)
+	a.Equal(parsed, d)
+
+	// 默认的 Parser 不能解析没有头的格式。
+	_, err = Parse(`{"a":1}`)
+	a.NonNilError(err)
+}
+
+func TestFormatterKeyPriority(t *testing.T) {
+	d := Make(RawData{
+		"b":  1,
+		"id": 2,
+		"list": []RawData{
+			{"z": 1, "name": "x"},
+		},
+		"name": "top",
+	})
+	cases := []struct {
+		Priority []string
+		JSON     string
+	}{
+		{nil, `{"b":1,"id":2,"list":[{"name":"x","z":1}],"name":"top"}`},
+		{[]string{"name", "id"}, `{"name":"top","id":2,"b":1,"list":[{"name":"x","z":1}]}`},
+		{[]string{"z", "missing"}, `{"b":1,"id":2,"list":[{"z":1,"name":"x"}],"name":"top"}`},
+	}
+	a := assert.New(t)
+
+	for i, c := range cases {
+		a.Use(&i, &c)
+		f := Formatter{
+			KeyPriority: c.Priority,
+		}
+		a.Equal(f.JSON(d, false), c.JSON)
+
+		parsed, err := Parse(f.PrettyString(d))
+		a.NilError(err)
+		a.Equal(parsed, d)
+	}
+
+	// 记录了 key 顺序时，其他 key 保持记录的顺序。
+	p := Parser{KeepOrder: true}
+	ordered, err := p.ParseJSON(`{"z":1,"id":2,"a":3}`)
+	a.NilError(err)
+	f := Formatter{KeyPriority: []string{"id"}}
+	a.Equal(f.JSON(ordered, false), `{"id":2,"z":1,"a":3}`)
+	a.Equal(f.PrettyString(ordered), "<json>\n{\n\t\"id\": 2,\n\t\"z\": 1,\n\t\"a\": 3\n}")
+}
@@ -0,0 +1,89 @@
+//go:build go1.18
+// +build go1.18
+
+package data
+
+import (
+	"testing"
+)
+
+func FuzzParseJSON(f *testing.F) {
+	f.Add(`{}`)
+	f.Add(`{"a":1,"b":"str","c":[1,2,3],"d":{"e":null}}`)
+	f.Add(`{"a":[1,"a",null,{"b":[]}],"b":1.5e10,"c":-0}`)
+	f.Add(`{"a":18446744073709551615,"b":9223372036854775808}`)
+
+	f.Fuzz(func(t *testing.T, str string) {
+		d, err := ParseJSON(str)
+
+		if err != nil {
+			return
+		}
+
+		if err := d.validate(); err != nil {
+			t.Fatalf("ParseJSON(%q) returns non-canonical data: %v", str, err)
+		}
+
+		if err := Merge(d, d).validate(); err != nil {
+			t.Fatalf("Merge of ParseJSON(%q) returns non-canonical data: %v", str, err)
+		}
+
+		s1, err := d.StringE()
+
+		if err != nil {
+			return
+		}
+
+		parsed, err := Parse(s1)
+
+		if err != nil {
+			t.Fatalf("fail to parse %q serialized from %q: %v", s1, str, err)
+		}
+
+		if s2 := parsed.String(); s1 != s2 {
+			t.Fatalf("serialization of %q is not stable: %q != %q", str, s1, s2)
+		}
+	})
+}
+
+func FuzzUnmarshalBinary(f *testing.F) {
+	for _, d := range []Data{
+		{},
+		Make(RawData{"a": 1, "b": "str", "c": []int{1, 2, 3}, "d": RawData{"e": nil}}),
+		Make(RawData{"a": []interface{}{1, "a", nil, RawData{"b": []string{}}}, "b": [][]string{{"x"}}}),
+	} {
+		buf, err := d.MarshalBinary()
+
+		if err != nil {
+			f.Fatal(err)
+		}
+
+		f.Add(buf)
+	}
+
+	f.Add([]byte{2, 1, 1, 'a', 9, 9, 2, 1, 6, 1, 1, 'x'})
+
+	f.Fuzz(func(t *testing.T, src []byte) {
+		var d Data
+
+		if err := d.UnmarshalBinary(src); err != nil {
+			return
+		}
+
+		if err := d.validate(); err != nil {
+			t.Fatalf("UnmarshalBinary(%q) returns non-canonical data: %v", src, err)
+		}
+
+		buf, err := d.MarshalBinary()
+
+		if err != nil {
+			t.Fatalf("fail to marshal data unmarshaled from %q: %v", src, err)
+		}
+
+		var parsed Data
+
+		if err := parsed.UnmarshalBinary(buf); err != nil {
+			t.Fatalf("fail to unmarshal %q marshaled from %q: %v", buf, src, err)
+		}
+	})
+}
@@ -1,9 +1,16 @@
 module github.com/altstory/go-data
 
-go 1.13
+go 1.26.0
 
 require (
 	github.com/huandu/go-assert v1.1.5
 	github.com/huandu/go-clone v1.1.0
 	github.com/tidwall/gjson v1.4.0
+	golang.org/x/text v0.42.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/tidwall/match v1.0.1 // indirect
+	github.com/tidwall/pretty v1.0.0 // indirect
 )
@@ -0,0 +1,65 @@
+package data
+
+import (
+	"bytes"
+	"hash/fnv"
+)
+
+// SubtreeHashes 计算 d 中每个深度为 depth 的子树的哈希值，key 是子树的路径，路径格式与 `Data#Query` 相同。
+// 这适合在数据同步时快速发现哪些部分发生了变化，只传输变化的子树，而不需要比较整个文档。
+//
+// depth 为 1 时计算顶层每个 key 对应的值的哈希，为 2 时计算第二层的，以此类推。
+// 如果某个值在到达 depth 之前就已经不是 object 了，比如是数组或者字符串，则直接计算这个值的哈希。
+// 如果 depth 小于等于 0，返回的 map 中只有一个 key 为空字符串的哈希，代表整个 d 的哈希。
+//
+// 哈希值只与数据内容有关，与 key 的顺序无关，同样的内容在任何时候都会得到同样的哈希值。
+func (d Data) SubtreeHashes(depth int) map[string]uint64 {
+	hashes := map[string]uint64{}
+	buf := &bytes.Buffer{}
+
+	if depth <= 0 {
+		hashes[""] = hashValue(buf, d.data)
+		return hashes
+	}
+
+	hashSubtrees(hashes, buf, d.data, nil, depth)
+	return hashes
+}
+
+func hashSubtrees(hashes map[string]uint64, buf *bytes.Buffer, d RawData, path []string, depth int) {
+	for k, v := range d {
+		p := append(path[:len(path):len(path)], k)
+
+		if depth > 1 {
+			if child, ok := v.(RawData); ok && len(child) != 0 {
+				hashSubtrees(hashes, buf, child, p, depth-1)
+				continue
+			}
+
+			if child, ok := v.(Data); ok && child.Len() != 0 {
+				hashSubtrees(hashes, buf, child.data, p, depth-1)
+				continue
+			}
+		}
+
+		hashes[JoinQuery(p...)] = hashValue(buf, v)
+	}
+}
+
+// hashValue 计算 v 的 FNV-1a 哈希，v 会先被序列化成 key 按字典序排列的 JSON，
+// 嵌在 v 中的 Data 记录的 key 顺序也会被忽略。
+func hashValue(buf *bytes.Buffer, v interface{}) uint64 {
+	buf.Reset()
+	w := &jsonWriter{
+		buf:       buf,
+		formatter: &defaultFormatter,
+		unordered: true,
+	}
+
+	// 无法序列化的值，比如 NaN，会导致输出不完整，但对于同样的数据结果依然是稳定的。
+	w.write(v, nil)
+
+	h := fnv.New64a()
+	h.Write(buf.Bytes())
+	return h.Sum64()
+}
@@ -0,0 +1,69 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestDataSubtreeHashes(t *testing.T) {
+	a := assert.New(t)
+	d := Make(RawData{
+		"app": RawData{
+			"name": "go-data",
+			"server": RawData{
+				"port": 80,
+			},
+		},
+		"db": RawData{
+			"host": "localhost",
+		},
+		"tags":  []string{"a", "b"},
+		"empty": RawData{},
+	})
+
+	h1 := d.SubtreeHashes(1)
+	a.Equal(len(h1), 4)
+	a.Assert(h1["app"] != h1["db"])
+
+	h2 := d.SubtreeHashes(2)
+	a.Equal(len(h2), 5)
+	a.Equal(h2["tags"], h1["tags"])
+	a.Equal(h2["empty"], h1["empty"])
+	a.Assert(h2["app.server"] != 0)
+	a.Assert(h2["db.host"] != 0)
+
+	h0 := d.SubtreeHashes(0)
+	a.Equal(len(h0), 1)
+
+	// 修改某个子树只会改变这个子树的哈希。
+	changed := d.Clone()
+	changed.data["db"].(RawData)["host"] = "remote"
+	c1 := changed.SubtreeHashes(1)
+	a.Equal(c1["app"], h1["app"])
+	a.Equal(c1["tags"], h1["tags"])
+	a.Assert(c1["db"] != h1["db"])
+	a.Assert(changed.SubtreeHashes(0)[""] != h0[""])
+
+	// 哈希与 key 的顺序无关。
+	p := &Parser{
+		KeepOrder: true,
+	}
+	o1, err := p.ParseJSON(`{"s":{"b":1,"a":2}}`)
+	a.NilError(err)
+	o2, err := p.ParseJSON(`{"s":{"a":2,"b":1}}`)
+	a.NilError(err)
+	a.Equal(o1.SubtreeHashes(1), o2.SubtreeHashes(1))
+
+	// 嵌在树中的 Data 的 key 顺序也不影响哈希。
+	s1, err := p.ParseJSON(`{"b":1,"a":2}`)
+	a.NilError(err)
+	s2, err := p.ParseJSON(`{"a":2,"b":1}`)
+	a.NilError(err)
+	e1 := Data{data: RawData{"s": s1}}
+	e2 := Data{data: RawData{"s": s2}}
+	a.Assert(Equal(e1, e2))
+	a.Equal(e1.SubtreeHashes(0), e2.SubtreeHashes(0))
+	a.Equal(e1.SubtreeHashes(1), e2.SubtreeHashes(1))
+	a.Equal(e1.SubtreeHashes(0), o1.SubtreeHashes(0))
+}
@@ -0,0 +1,262 @@
+package data
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 默认的 header 编码参数。
+const (
+	DefaultHeaderPrefix  = "x-data-" // 默认的 header 名前缀。
+	DefaultMaxHeaderSize = 4096      // 默认的所有 header 名和值的总长度上限。
+)
+
+// headerBase64Prefix 是 base64 编码的值的前缀，JSON 值不可能以 `b` 开头，所以不会产生歧义。
+const headerBase64Prefix = "b64:"
+
+// HeaderCodec 将一个小的 Data 编码成一组 HTTP header 或 gRPC metadata，或者反过来解码，
+// 适合在服务之间传递动态的上下文信息（baggage）。
+//
+// 编码时 Data 会被展开，每个非 object 的值对应一个 header，header 名是前缀加上值的路径，
+// 比如 `{"user":{"id":1}}` 会变成 `x-data-user.id: 1`。值使用 JSON 格式编码，
+// 如果 JSON 中有非 ASCII 或者不可打印的字符，则使用 base64 编码并加上 `b64:` 前缀。
+//
+// 由于 header 名不区分大小写，Data 中的 key 只能由小写字母、数字、`-` 和 `_` 组成，否则编码时报错。
+// 空的 object 会在编码时被丢弃，time.Time 等 JSON 不支持的类型在解码后会变成字符串。
+type HeaderCodec struct {
+	Prefix  string // header 名的前缀，为空时使用 DefaultHeaderPrefix，解码时不区分大小写。
+	MaxSize int    // 所有 header 名和值的总长度上限，为 0 时使用 DefaultMaxHeaderSize，为负数时不限制。
+}
+
+// EncodeHeader 使用默认参数将 d 编码成 header，详见 `HeaderCodec#Encode`。
+func EncodeHeader(d Data) (map[string]string, error) {
+	codec := HeaderCodec{}
+	return codec.Encode(d)
+}
+
+// DecodeHeader 使用默认参数从 header 中解码 Data，详见 `HeaderCodec#Decode`。
+func DecodeHeader(header map[string][]string) (Data, error) {
+	codec := HeaderCodec{}
+	return codec.Decode(header)
+}
+
+// Encode 将 d 编码成 header，返回的 header 名都是小写的，可以直接用于 gRPC metadata，
+// 也可以用 `http.Header#Set` 设置到 HTTP 请求中。
+//
+// 如果 d 中的 key 无法用作 header 名，返回错误；如果编码后的总长度超过 MaxSize，返回 ErrHeaderTooLarge。
+func (codec *HeaderCodec) Encode(d Data) (map[string]string, error) {
+	enc := &headerEncoder{
+		prefix: codec.prefix(),
+		header: map[string]string{},
+	}
+
+	if err := enc.encode(d.data, nil); err != nil {
+		return nil, err
+	}
+
+	if max := codec.maxSize(); max > 0 && enc.size > max {
+		return nil, ErrHeaderTooLarge
+	}
+
+	return enc.header, nil
+}
+
+type headerEncoder struct {
+	prefix string
+	header map[string]string
+	size   int
+}
+
+func (enc *headerEncoder) encode(d RawData, path []string) error {
+	var order *keyOrder
+
+	for _, k := range order.sortedKeys(d) {
+		p := append(path[:len(path):len(path)], k)
+
+		if !isHeaderKey(k) {
+			return fmt.Errorf("go-data: key `%v` cannot be used in header", strings.Join(p, "."))
+		}
+
+		v := d[k]
+
+		if nested, ok := v.(Data); ok {
+			v = nested.data
+		}
+
+		if m, ok := v.(RawData); ok {
+			if err := enc.encode(m, p); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		value, err := encodeHeaderValue(v)
+
+		if err != nil {
+			return err
+		}
+
+		name := enc.prefix + strings.Join(p, ".")
+		enc.header[name] = value
+		enc.size += len(name) + len(value)
+	}
+
+	return nil
+}
+
+// Decode 从 header 中解码 Data，header 可以是 http.Header 或者 gRPC 的 metadata.MD。
+// 只有名字以 Prefix 开头的 header 会被解码，同名 header 有多个值时只使用第一个值。
+//
+// 如果 header 的值不合法，或者多个 header 的路径冲突，比如同时出现 `x-data-a` 和 `x-data-a.b`，返回错误；
+// 如果这些 header 的总长度超过 MaxSize，返回 ErrHeaderTooLarge。
+func (codec *HeaderCodec) Decode(header map[string][]string) (Data, error) {
+	prefix := codec.prefix()
+	values := map[string]string{}
+	names := make([]string, 0, len(header))
+	size := 0
+
+	for name, vals := range header {
+		lower := strings.ToLower(name)
+
+		if !strings.HasPrefix(lower, prefix) || len(lower) == len(prefix) || len(vals) == 0 {
+			continue
+		}
+
+		path := lower[len(prefix):]
+		values[path] = vals[0]
+		names = append(names, path)
+		size += len(name) + len(vals[0])
+	}
+
+	if max := codec.maxSize(); max > 0 && size > max {
+		return emptyData, ErrHeaderTooLarge
+	}
+
+	sort.Strings(names)
+	d := RawData{}
+
+	for _, path := range names {
+		v, err := decodeHeaderValue(values[path])
+
+		if err != nil {
+			return emptyData, fmt.Errorf("go-data: fail to decode header value of `%v`: %w", path, err)
+		}
+
+		if !setHeaderValue(d, strings.Split(path, "."), v) {
+			return emptyData, fmt.Errorf("go-data: header path `%v` conflicts with other headers", path)
+		}
+	}
+
+	return Data{
+		data: d,
+	}, nil
+}
+
+func (codec *HeaderCodec) prefix() string {
+	if codec.Prefix == "" {
+		return DefaultHeaderPrefix
+	}
+
+	return strings.ToLower(codec.Prefix)
+}
+
+func (codec *HeaderCodec) maxSize() int {
+	if codec.MaxSize == 0 {
+		return DefaultMaxHeaderSize
+	}
+
+	return codec.MaxSize
+}
+
+func isHeaderKey(key string) bool {
+	if key == "" {
+		return false
+	}
+
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+
+		if !('a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+
+	return true
+}
+
+func encodeHeaderValue(v interface{}) (string, error) {
+	buf, err := json.Marshal(v)
+
+	if err != nil {
+		return "", err
+	}
+
+	for _, c := range buf {
+		if c < 0x20 || c > 0x7e {
+			return headerBase64Prefix + base64.StdEncoding.EncodeToString(buf), nil
+		}
+	}
+
+	return string(buf), nil
+}
+
+func decodeHeaderValue(value string) (interface{}, error) {
+	if strings.HasPrefix(value, headerBase64Prefix) {
+		buf, err := base64.StdEncoding.DecodeString(value[len(headerBase64Prefix):])
+
+		if err != nil {
+			return nil, err
+		}
+
+		value = string(buf)
+	}
+
+	// 借用 ParseJSON 的规则将值解析成标准类型。
+	d, err := ParseJSON(`{"v":` + value + `}`)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// value 中不应该包含其他的 key，比如 `1,"w":2`。
+	if d.Len() != 1 {
+		return nil, ErrInvalidJSON
+	}
+
+	return d.data["v"], nil
+}
+
+// setHeaderValue 将 v 设置到 d 中 fields 对应的位置，如果路径上已经有非 object 的值则返回 false。
+func setHeaderValue(d RawData, fields []string, v interface{}) bool {
+	for _, field := range fields[:len(fields)-1] {
+		if field == "" {
+			return false
+		}
+
+		next, ok := d[field]
+
+		if !ok {
+			m := RawData{}
+			d[field] = m
+			d = m
+			continue
+		}
+
+		if d, ok = next.(RawData); !ok {
+			return false
+		}
+	}
+
+	last := fields[len(fields)-1]
+
+	if _, ok := d[last]; ok || last == "" {
+		return false
+	}
+
+	d[last] = v
+	return true
+}
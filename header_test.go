@@ -0,0 +1,106 @@
+package data
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestHeaderCodec(t *testing.T) {
+	a := assert.New(t)
+	d := Make(RawData{
+		"user": RawData{
+			"id":   123,
+			"name": "张三",
+			"tags": []string{"a", "b"},
+		},
+		"trace_id": "abc-123",
+		"sampled":  true,
+		"ratio":    0.5,
+		"empty":    RawData{},
+	})
+
+	header, err := EncodeHeader(d)
+	a.NilError(err)
+	a.Equal(header, map[string]string{
+		"x-data-user.id":   "123",
+		"x-data-user.name": "b64:IuW8oOS4iSI=",
+		"x-data-user.tags": `["a","b"]`,
+		"x-data-trace_id":  `"abc-123"`,
+		"x-data-sampled":   "true",
+		"x-data-ratio":     "0.5",
+	})
+
+	h := http.Header{}
+	h.Set("Content-Type", "text/plain")
+
+	for k, v := range header {
+		h.Set(k, v)
+	}
+
+	decoded, err := DecodeHeader(h)
+	a.NilError(err)
+	a.Equal(decoded.data, RawData{
+		"user": RawData{
+			"id":   int64(123),
+			"name": "张三",
+			"tags": []string{"a", "b"},
+		},
+		"trace_id": "abc-123",
+		"sampled":  true,
+		"ratio":    0.5,
+	})
+
+	// gRPC metadata 的 key 都是小写的。
+	md := map[string][]string{}
+
+	for k, v := range header {
+		md[k] = []string{v}
+	}
+
+	decoded2, err := DecodeHeader(md)
+	a.NilError(err)
+	a.Equal(decoded2, decoded)
+}
+
+func TestHeaderCodecErrors(t *testing.T) {
+	a := assert.New(t)
+
+	_, err := EncodeHeader(Make(RawData{"a": RawData{"Bad Key": 1}}))
+	a.Equal(err.Error(), "go-data: key `a.Bad Key` cannot be used in header")
+
+	codec := HeaderCodec{
+		Prefix:  "X-Ctx-",
+		MaxSize: 32,
+	}
+	header, err := codec.Encode(Make(RawData{"a": 1}))
+	a.NilError(err)
+	a.Equal(header, map[string]string{"x-ctx-a": "1"})
+
+	_, err = codec.Encode(Make(RawData{"a": strings.Repeat("x", 32)}))
+	a.Assert(errors.Is(err, ErrHeaderTooLarge))
+
+	_, err = codec.Decode(map[string][]string{"X-Ctx-A": {strings.Repeat("1", 32)}})
+	a.Assert(errors.Is(err, ErrHeaderTooLarge))
+
+	cases := []map[string][]string{
+		{"x-data-a": {"1"}, "x-data-a.b": {"2"}},
+		{"x-data-a": {"1"}, "X-Data-A": {"2"}},
+		{"x-data-a": {`1,"w":2`}},
+		{"x-data-a": {"b64:!!"}},
+		{"x-data-a..b": {"1"}},
+	}
+
+	for i, c := range cases {
+		a.Use(&i, &c)
+		_, err := DecodeHeader(c)
+		a.NonNilError(err)
+	}
+
+	d, err := DecodeHeader(map[string][]string{"x-other": {"1"}, "x-data-": {"1"}})
+	a.NilError(err)
+	a.Equal(d.Len(), 0)
+}
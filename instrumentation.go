@@ -0,0 +1,57 @@
+package data
+
+import (
+	"reflect"
+	"sync/atomic"
+	"time"
+)
+
+// Instrumentation 用来观测 Data 的解析、合并和解码等操作，可以用于统计耗时和数据规模等指标。
+//
+// 实现者需要保证所有方法是并发安全的，并且应该尽快返回，避免影响正常业务逻辑。
+type Instrumentation interface {
+	// OnParse 在解析 JSON 结束后调用，size 是 JSON 的字节数，err 是解析的错误。
+	OnParse(size int, duration time.Duration, err error)
+
+	// OnMerge 在 Merge/MergeTo 结束后调用，count 是参与合并的 Data 个数。
+	OnMerge(count int, duration time.Duration)
+
+	// OnDecode 在 Decoder 解码结束后调用，size 是被解码的值的元素个数，
+	// 对于 map 和 slice 是其长度，对于其他值是 1，err 是解码的错误。
+	OnDecode(size int, duration time.Duration, err error)
+}
+
+type instrumentationHolder struct {
+	inst Instrumentation
+}
+
+var defaultInstrumentation atomic.Value
+
+// SetInstrumentation 设置全局的 Instrumentation，设置为 nil 则关闭观测。
+// Parser 或 Decoder 如果设置了自己的 Instrumentation，则会优先使用自己的。
+func SetInstrumentation(inst Instrumentation) {
+	defaultInstrumentation.Store(instrumentationHolder{
+		inst: inst,
+	})
+}
+
+func currentInstrumentation(inst Instrumentation) Instrumentation {
+	if inst != nil {
+		return inst
+	}
+
+	holder, _ := defaultInstrumentation.Load().(instrumentationHolder)
+	return holder.inst
+}
+
+// valueSize 返回 v 的元素个数，用于 Instrumentation 统计数据规模。
+func valueSize(v reflect.Value) int {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return 0
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return v.Len()
+	}
+
+	return 1
+}
@@ -0,0 +1,96 @@
+package data
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/huandu/go-assert"
+)
+
+type testInstrumentation struct {
+	mu      sync.Mutex
+	parses  []int
+	merges  []int
+	decodes []int
+	errors  int
+}
+
+func (inst *testInstrumentation) OnParse(size int, duration time.Duration, err error) {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+
+	inst.parses = append(inst.parses, size)
+
+	if err != nil {
+		inst.errors++
+	}
+}
+
+func (inst *testInstrumentation) OnMerge(count int, duration time.Duration) {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+
+	inst.merges = append(inst.merges, count)
+}
+
+func (inst *testInstrumentation) OnDecode(size int, duration time.Duration, err error) {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+
+	inst.decodes = append(inst.decodes, size)
+
+	if err != nil {
+		inst.errors++
+	}
+}
+
+func TestInstrumentation(t *testing.T) {
+	a := assert.New(t)
+	global := &testInstrumentation{}
+	SetInstrumentation(global)
+	defer SetInstrumentation(nil)
+
+	d, err := Parse(`<json>{"a":1,"b":[1,2]}`)
+	a.NilError(err)
+	_, err = ParseJSON(`{"a":`)
+	a.NonNilError(err)
+
+	Merge(d, d, d)
+	MergeTo(&d, d)
+
+	var v struct {
+		A int `data:"a"`
+	}
+	dec := &Decoder{}
+	a.NilError(dec.Decode(d, &v))
+	a.NilError(dec.DecodeQuery(d, "b", &[]int{}))
+
+	a.Equal(global.parses, []int{len(`{"a":1,"b":[1,2]}`), len(`{"a":`)})
+	a.Equal(global.merges, []int{3, 1})
+	a.Equal(global.decodes, []int{2, 4})
+	a.Equal(global.errors, 1)
+
+	// Parser 和 Decoder 上设置的 Instrumentation 优先。
+	local := &testInstrumentation{}
+	p := &Parser{
+		Instrumentation: local,
+	}
+	_, err = p.ParseJSON(`{}`)
+	a.NilError(err)
+	dec = &Decoder{
+		Instrumentation: local,
+	}
+	a.NonNilError(dec.Decode(d, v))
+
+	a.Equal(local.parses, []int{2})
+	a.Equal(local.decodes, []int{2})
+	a.Equal(local.errors, 1)
+	a.Equal(len(global.parses), 2)
+	a.Equal(len(global.decodes), 2)
+
+	// 关闭之后不再调用。
+	SetInstrumentation(nil)
+	Merge(d)
+	a.Equal(len(global.merges), 2)
+}
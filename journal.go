@@ -0,0 +1,405 @@
+package data
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"sync"
+)
+
+// Journal 将 Patch 逐个追加写入 io.Writer，配合 `ReplayJournal` 可以实现基于事件溯源的持久化：
+// 文档只需要保存一个初始值以及之后所有的 Patch，任何时候都可以通过重放得到最新的文档。
+//
+// 每个 Patch 都有一个从 1 开始连续递增的修订号（revision），journal 中每条记录的格式如下：
+//     record := uvarint(rev) uvarint(len) payload crc32
+// 其中，payload 是 Patch 的所有 action 使用 `Data#MarshalBinary` 序列化后的数据，
+// crc32 是 rev、len 和 payload 的 IEEE CRC32 校验和，使用 4 字节小端序编码。
+//
+// journal 中也可以包含快照记录（见 `Journal#Snapshot`），快照记录保存了某个修订号时完整的文档，
+// 重放时遇到快照会直接使用快照中的文档，这样配合 `CompactJournal` 可以限制重放所需的时间。
+//
+// Journal 可以被多个 goroutine 并发使用。
+type Journal struct {
+	mu  sync.Mutex
+	w   io.Writer
+	rev int64
+}
+
+// maxJournalRecordSize 是一条记录的最大长度，避免损坏的记录导致分配过多内存。
+const maxJournalRecordSize = 64 << 20
+
+// journalRecord 是 journal 中一个 Patch 的序列化格式。
+type journalRecord struct {
+	Actions []PatchAction `data:"actions"`
+}
+
+// NewJournal 创建一个写入 w 的 Journal。
+// rev 是 w 中已经写入的最后一个修订号，对于新的 journal 应该是 0，
+// 对于已有的 journal，可以使用 `ReplayJournal` 返回的修订号。
+func NewJournal(w io.Writer, rev int64) *Journal {
+	return &Journal{
+		w:   w,
+		rev: rev,
+	}
+}
+
+// Rev 返回最后一个成功写入的修订号。
+func (j *Journal) Rev() int64 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.rev
+}
+
+// Append 将 patch 写入 journal，返回这个 patch 的修订号。
+// 每条记录只会调用一次 w.Write，如果写入失败，修订号不会增加。
+func (j *Journal) Append(patch *Patch) (rev int64, err error) {
+	record := &journalRecord{
+		Actions: make([]PatchAction, 0, len(patch.actions)),
+	}
+
+	for _, action := range patch.actions {
+		record.Actions = append(record.Actions, *action)
+	}
+
+	enc := Encoder{}
+	d, err := enc.EncodeE(record)
+
+	if err != nil {
+		return
+	}
+
+	payload, err := d.MarshalBinary()
+
+	if err != nil {
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	rev = j.rev + 1
+
+	if _, err = j.w.Write(appendJournalRecord(nil, rev, payload)); err != nil {
+		rev = 0
+		return
+	}
+
+	j.rev = rev
+	return
+}
+
+// Snapshot 将 d 作为修订号 upToRev 时的完整文档写入 journal，之后 Append 的修订号从 upToRev+1 开始。
+// 重放时遇到快照会丢弃之前的结果，直接使用快照中的文档。
+//
+// 如果 journal 中已经写入过记录，upToRev 必须等于当前的修订号，即 d 必须是当前最新的文档；
+// 对于新的 journal，upToRev 可以是任意正数，这通常用于压缩 journal，见 `CompactJournal`。
+func (j *Journal) Snapshot(d Data, upToRev int64) error {
+	raw := d.data
+
+	if raw == nil {
+		raw = RawData{}
+	}
+
+	payload, err := Data{
+		data: RawData{
+			journalSnapshotKey: raw,
+		},
+	}.MarshalBinary()
+
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if upToRev <= 0 || j.rev != 0 && upToRev != j.rev {
+		return fmt.Errorf("go-data: cannot snapshot revision %v in journal at revision %v", upToRev, j.rev)
+	}
+
+	if _, err = j.w.Write(appendJournalRecord(nil, upToRev, payload)); err != nil {
+		return err
+	}
+
+	j.rev = upToRev
+	return nil
+}
+
+// CompactJournal 读取 r 中的 journal，将 base 以及修订号不超过 upToRev 的所有记录合并成一个快照写入 w，
+// 然后将之后的记录原样复制到 w 中，返回 w 中最后一个修订号。如果 upToRev 不大于 0，则合并所有记录。
+// 压缩之后的 journal 重放结果与原来相同，但是需要重放的 Patch 更少。
+//
+// 如果 r 中的记录损坏或者 Patch 应用失败，返回错误，这时 w 中可能已经写入了部分数据，不应该再使用。
+func CompactJournal(w io.Writer, r io.Reader, base Data, upToRev int64) (rev int64, err error) {
+	jr := &journalReader{
+		r: bufio.NewReader(r),
+	}
+	replayer := &journalReplayer{
+		d: base,
+	}
+	var snapshot Data
+	var snapshotRev int64
+	var pending [][]byte
+
+	for {
+		var entry *journalEntry
+
+		if entry, err = jr.next(); err != nil {
+			if err != io.EOF {
+				return
+			}
+
+			err = nil
+			break
+		}
+
+		if err = replayer.apply(entry); err != nil {
+			return
+		}
+
+		if upToRev <= 0 || entry.rev <= upToRev {
+			snapshot, snapshotRev = replayer.d, replayer.rev
+			continue
+		}
+
+		pending = append(pending, append([]byte(nil), jr.buf...))
+	}
+
+	if replayer.rev == 0 {
+		return
+	}
+
+	if snapshotRev == 0 {
+		// 没有可以合并的记录，原样复制。
+		for _, record := range pending {
+			if _, err = w.Write(record); err != nil {
+				return
+			}
+		}
+
+		rev = replayer.rev
+		return
+	}
+
+	j := NewJournal(w, 0)
+
+	if err = j.Snapshot(snapshot, snapshotRev); err != nil {
+		return
+	}
+
+	for _, record := range pending {
+		if _, err = w.Write(record); err != nil {
+			return
+		}
+	}
+
+	rev = replayer.rev
+	return
+}
+
+// journalSnapshotKey 是快照记录中保存文档的 key。
+const journalSnapshotKey = "snapshot"
+
+func appendJournalRecord(buf []byte, rev int64, payload []byte) []byte {
+	start := len(buf)
+	buf = appendUvarint(buf, uint64(rev))
+	buf = appendUvarint(buf, uint64(len(payload)))
+	buf = append(buf, payload...)
+
+	var sum [4]byte
+	binary.LittleEndian.PutUint32(sum[:], crc32.ChecksumIEEE(buf[start:]))
+	return append(buf, sum[:]...)
+}
+
+// ReplayJournal 依次读取 r 中的所有 Patch 并应用到 base 的副本上，返回最终的结果以及最后一个修订号，base 本身不会被修改。
+// journal 中的修订号必须是连续的，base 应该是第一条记录之前的文档；如果第一条记录是快照，base 会被忽略。
+//
+// 如果某条记录损坏（比如写入过程中进程崩溃导致最后一条记录不完整、校验和不匹配），
+// 或者 Patch 应用失败，返回错误，同时返回出错之前最后一个成功应用的结果和修订号，
+// 损坏的记录可以通过 `errors.Is(err, ErrInvalidJournal)` 判断。
+func ReplayJournal(r io.Reader, base Data) (d Data, rev int64, err error) {
+	jr := &journalReader{
+		r: bufio.NewReader(r),
+	}
+	replayer := &journalReplayer{
+		d: base,
+	}
+
+	for {
+		var entry *journalEntry
+
+		if entry, err = jr.next(); err != nil {
+			break
+		}
+
+		if err = replayer.apply(entry); err != nil {
+			break
+		}
+	}
+
+	if err == io.EOF {
+		err = nil
+	}
+
+	d, rev = replayer.d, replayer.rev
+
+	// 没有应用过任何记录时，依然需要保证返回值与 base 不共享数据。
+	if rev == 0 {
+		d = base.Clone()
+	}
+
+	return
+}
+
+// journalEntry 是 journal 中的一条记录，patch 和 snapshot 中只有一个不为 nil。
+type journalEntry struct {
+	rev      int64
+	patch    *Patch
+	snapshot *Data
+}
+
+// journalReplayer 记录重放 journal 的中间结果。
+type journalReplayer struct {
+	d   Data
+	rev int64
+}
+
+func (jr *journalReplayer) apply(entry *journalEntry) error {
+	if entry.snapshot != nil {
+		if jr.rev != 0 && entry.rev != jr.rev {
+			return fmt.Errorf("%w: snapshot of revision %v does not match current revision %v", ErrInvalidJournal, entry.rev, jr.rev)
+		}
+
+		jr.d = *entry.snapshot
+		jr.rev = entry.rev
+		return nil
+	}
+
+	if jr.rev != 0 && entry.rev != jr.rev+1 {
+		return fmt.Errorf("%w: expect revision %v but got %v", ErrInvalidJournal, jr.rev+1, entry.rev)
+	}
+
+	applied, err := entry.patch.Apply(jr.d)
+
+	if err != nil {
+		return fmt.Errorf("go-data: fail to replay revision %v: %w", entry.rev, err)
+	}
+
+	jr.d = applied
+	jr.rev = entry.rev
+	return nil
+}
+
+type journalReader struct {
+	r   *bufio.Reader
+	buf []byte // 最后一次读取的完整记录。
+}
+
+// next 读取下一条记录，如果已经没有记录则返回 io.EOF。
+func (jr *journalReader) next() (entry *journalEntry, err error) {
+	if _, err = jr.r.Peek(1); err != nil {
+		return
+	}
+
+	jr.buf = jr.buf[:0]
+	urev, err := jr.readUvarint()
+
+	if err != nil {
+		return
+	}
+
+	size, err := jr.readUvarint()
+
+	if err != nil {
+		return
+	}
+
+	if urev == 0 || urev > math.MaxInt64 || size > maxJournalRecordSize {
+		err = fmt.Errorf("%w: invalid record header", ErrInvalidJournal)
+		return
+	}
+
+	rev := int64(urev)
+	start := len(jr.buf)
+	jr.buf = append(jr.buf, make([]byte, int(size)+4)...)
+
+	if _, err = io.ReadFull(jr.r, jr.buf[start:]); err != nil {
+		err = fmt.Errorf("%w: truncated record of revision %v", ErrInvalidJournal, rev)
+		return
+	}
+
+	body := jr.buf[:len(jr.buf)-4]
+	sum := binary.LittleEndian.Uint32(jr.buf[len(jr.buf)-4:])
+
+	if crc32.ChecksumIEEE(body) != sum {
+		err = fmt.Errorf("%w: checksum mismatch in revision %v", ErrInvalidJournal, rev)
+		return
+	}
+
+	var d Data
+
+	if err = d.UnmarshalBinary(body[start:]); err != nil {
+		err = fmt.Errorf("%w: revision %v: %v", ErrInvalidJournal, rev, err)
+		return
+	}
+
+	entry = &journalEntry{
+		rev: rev,
+	}
+
+	if raw, ok := d.data[journalSnapshotKey].(RawData); ok {
+		entry.snapshot = &Data{
+			data: raw,
+		}
+		return
+	}
+
+	var record journalRecord
+	dec := Decoder{}
+
+	if err = dec.Decode(d, &record); err != nil {
+		err = fmt.Errorf("%w: revision %v: %v", ErrInvalidJournal, rev, err)
+		return
+	}
+
+	entry.patch = NewPatch()
+
+	for i := range record.Actions {
+		entry.patch.AddAction(&record.Actions[i])
+	}
+
+	return
+}
+
+// readUvarint 读取一个 uvarint，并且将读到的原始字节记录在 jr.buf 中用于计算校验和。
+func (jr *journalReader) readUvarint() (uint64, error) {
+	var x uint64
+	var s uint
+
+	for i := 0; i < binary.MaxVarintLen64; i++ {
+		b, err := jr.r.ReadByte()
+
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return 0, fmt.Errorf("%w: truncated record header", ErrInvalidJournal)
+			}
+
+			return 0, err
+		}
+
+		jr.buf = append(jr.buf, b)
+
+		if b < 0x80 {
+			return x | uint64(b)<<s, nil
+		}
+
+		x |= uint64(b&0x7f) << s
+		s += 7
+	}
+
+	return 0, fmt.Errorf("%w: invalid record header", ErrInvalidJournal)
+}
@@ -0,0 +1,213 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestJournal(t *testing.T) {
+	a := assert.New(t)
+	buf := &bytes.Buffer{}
+	j := NewJournal(buf, 0)
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	base := Make(RawData{
+		"a":    1,
+		"list": []RawData{{"on": true}, {"on": false}},
+	})
+
+	p1 := NewPatch()
+	p1.Add([]string{"a"}, map[string]Data{
+		"": Make(RawData{
+			"b":    uint(2),
+			"time": now,
+			"tags": []string{"x"},
+		}),
+	})
+	p2 := NewPatch()
+	p2.DeleteMatched("list", Make(RawData{"on": false}))
+	p2.Replace("b", Make(RawData{"c": 1.5}))
+
+	rev, err := j.Append(p1)
+	a.NilError(err)
+	a.Equal(rev, int64(1))
+	rev, err = j.Append(p2)
+	a.NilError(err)
+	a.Equal(rev, int64(2))
+	a.Equal(j.Rev(), int64(2))
+
+	expected, err := p1.Apply(base)
+	a.NilError(err)
+	expected, err = p2.Apply(expected)
+	a.NilError(err)
+
+	d, rev, err := ReplayJournal(bytes.NewReader(buf.Bytes()), base)
+	a.NilError(err)
+	a.Equal(rev, int64(2))
+	a.Equal(d, expected)
+	a.Equal(base.Get("a"), int64(1))
+
+	// 继续追加到已有的 journal。
+	p3 := NewPatch()
+	p3.Add([]string{"tags"}, nil)
+	j = NewJournal(buf, rev)
+	rev, err = j.Append(p3)
+	a.NilError(err)
+	a.Equal(rev, int64(3))
+
+	d, rev, err = ReplayJournal(bytes.NewReader(buf.Bytes()), base)
+	a.NilError(err)
+	a.Equal(rev, int64(3))
+	a.Equal(d.Get("tags"), nil)
+
+	// 空的 journal。
+	d, rev, err = ReplayJournal(bytes.NewReader(nil), base)
+	a.NilError(err)
+	a.Equal(rev, int64(0))
+	a.Equal(d, base)
+}
+
+func TestJournalCorrupted(t *testing.T) {
+	a := assert.New(t)
+	buf := &bytes.Buffer{}
+	j := NewJournal(buf, 0)
+	base := Make(RawData{"n": 0})
+
+	for i := 1; i <= 3; i++ {
+		p := NewPatch()
+		p.Add(nil, map[string]Data{"": Make(RawData{"n": i})})
+		_, err := j.Append(p)
+		a.NilError(err)
+	}
+
+	data := buf.Bytes()
+
+	// 最后一条记录不完整。
+	d, rev, err := ReplayJournal(bytes.NewReader(data[:len(data)-2]), base)
+	a.Assert(errors.Is(err, ErrInvalidJournal))
+	a.Equal(rev, int64(2))
+	a.Equal(d.Get("n"), int64(2))
+
+	// 校验和不匹配。
+	corrupted := append([]byte(nil), data...)
+	corrupted[len(corrupted)-5] ^= 0xff
+	d, rev, err = ReplayJournal(bytes.NewReader(corrupted), base)
+	a.Assert(errors.Is(err, ErrInvalidJournal))
+	a.Equal(err.Error(), "go-data: invalid journal: checksum mismatch in revision 3")
+	a.Equal(rev, int64(2))
+	a.Equal(d.Get("n"), int64(2))
+
+	// 修订号不连续。
+	other := &bytes.Buffer{}
+	j = NewJournal(other, 5)
+	_, err = j.Append(NewPatch())
+	a.NilError(err)
+	_, rev, err = ReplayJournal(bytes.NewReader(append(append([]byte(nil), data...), other.Bytes()...)), base)
+	a.Equal(err.Error(), "go-data: invalid journal: expect revision 4 but got 6")
+	a.Equal(rev, int64(3))
+
+	// patch 应用失败。
+	bad := &bytes.Buffer{}
+	j = NewJournal(bad, 0)
+	p := NewPatch()
+	p.Add(nil, map[string]Data{"missing": Make(RawData{"a": 1})})
+	_, err = j.Append(p)
+	a.NilError(err)
+	_, rev, err = ReplayJournal(bad, base)
+	a.Assert(errors.Is(err, ErrQueryNotFound))
+	a.Equal(rev, int64(0))
+}
+
+func TestJournalSnapshot(t *testing.T) {
+	a := assert.New(t)
+	buf := &bytes.Buffer{}
+	j := NewJournal(buf, 0)
+	base := Make(RawData{"n": 0})
+	patches := make([]*Patch, 0, 5)
+
+	for i := 1; i <= 5; i++ {
+		p := NewPatch()
+		p.Add(nil, map[string]Data{"": Make(RawData{"n": i, "list": []int{i}})})
+		patches = append(patches, p)
+		_, err := j.Append(p)
+		a.NilError(err)
+	}
+
+	expected, rev, err := ReplayJournal(bytes.NewReader(buf.Bytes()), base)
+	a.NilError(err)
+	a.Equal(rev, int64(5))
+	a.Equal(expected.Get("list"), []int64{1, 2, 3, 4, 5})
+
+	cases := []struct {
+		UpToRev  int64
+		Patches  int
+		Snapshot int64
+	}{
+		{0, 0, 5},
+		{3, 2, 3},
+		{5, 0, 5},
+		{10, 0, 5},
+	}
+
+	for i, c := range cases {
+		a.Use(&i, &c)
+		compacted := &bytes.Buffer{}
+		rev, err := CompactJournal(compacted, bytes.NewReader(buf.Bytes()), base, c.UpToRev)
+		a.NilError(err)
+		a.Equal(rev, int64(5))
+		a.Assert(compacted.Len() < buf.Len())
+
+		// 快照之后的 base 会被忽略。
+		d, rev, err := ReplayJournal(bytes.NewReader(compacted.Bytes()), Make(RawData{"other": true}))
+		a.NilError(err)
+		a.Equal(rev, int64(5))
+		a.Equal(d, expected)
+
+		jr := &journalReader{r: bufio.NewReader(compacted)}
+		entry, err := jr.next()
+		a.NilError(err)
+		a.Equal(entry.rev, c.Snapshot)
+		a.Assert(entry.snapshot != nil)
+
+		for n := 0; n < c.Patches; n++ {
+			entry, err = jr.next()
+			a.NilError(err)
+			a.Assert(entry.patch != nil)
+		}
+
+		_, err = jr.next()
+		a.Equal(err, io.EOF)
+	}
+
+	// 在已有的 journal 中写入快照。
+	a.NonNilError(j.Snapshot(expected, 4))
+	a.NilError(j.Snapshot(expected, 5))
+	p := NewPatch()
+	p.Add(nil, map[string]Data{"": Make(RawData{"n": 6})})
+	rev, err = j.Append(p)
+	a.NilError(err)
+	a.Equal(rev, int64(6))
+
+	d, rev, err := ReplayJournal(bytes.NewReader(buf.Bytes()), base)
+	a.NilError(err)
+	a.Equal(rev, int64(6))
+	a.Equal(d.Get("n"), int64(6))
+
+	// 空的快照以及不匹配的快照。
+	empty := &bytes.Buffer{}
+	j = NewJournal(empty, 0)
+	a.NonNilError(j.Snapshot(Data{}, 0))
+	a.NilError(j.Snapshot(Data{}, 7))
+	d, rev, err = ReplayJournal(bytes.NewReader(empty.Bytes()), base)
+	a.NilError(err)
+	a.Equal(rev, int64(7))
+	a.Equal(d.Len(), 0)
+
+	_, _, err = ReplayJournal(io.MultiReader(bytes.NewReader(buf.Bytes()), bytes.NewReader(empty.Bytes())), base)
+	a.Assert(errors.Is(err, ErrInvalidJournal))
+}
@@ -0,0 +1,136 @@
+package data
+
+import (
+	"reflect"
+	"sync"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// KeyFolding 决定查找 key 时如何比较 key，可以使用 `|` 组合多个选项。
+// 不同系统生成的文档中，同一个 key 可能使用了不同的 Unicode 规范化形式或者大小写，
+// 设置 KeyFolding 之后，这些 key 都可以被找到。
+//
+// 查找时总是先精确匹配，只有找不到时才会比较折叠后的 key；
+// 如果有多个 key 折叠后相同，使用按字典序排列的第一个。
+type KeyFolding int
+
+// 所有支持的 KeyFolding，零值代表精确匹配。
+const (
+	KeyFoldNFC  KeyFolding = 1 << iota // 比较 Unicode NFC 规范化后的 key，比如 "é" 与 "é" 相同。
+	KeyFoldCase                        // 忽略大小写，使用 Unicode case folding 比较 key，比如 "Name" 与 "NAME" 相同。
+)
+
+// foldCaserPool 缓存 case folding 使用的 cases.Caser，避免每次调用 Fold 都创建一个新的。
+// cases.Caser 是有状态的，不能在多个 goroutine 之间共享，所以这里使用 sync.Pool 而不是全局变量。
+var foldCaserPool = sync.Pool{
+	New: func() interface{} {
+		c := cases.Fold()
+		return &c
+	},
+}
+
+// Fold 返回 key 按照 f 折叠后的形式，折叠结果相同的 key 被认为是同一个 key。
+func (f KeyFolding) Fold(key string) string {
+	if f&KeyFoldNFC != 0 {
+		key = norm.NFC.String(key)
+	}
+
+	if f&KeyFoldCase != 0 {
+		c := foldCaserPool.Get().(*cases.Caser)
+		key = c.String(key)
+		foldCaserPool.Put(c)
+
+		// case folding 可能产生非 NFC 的字符串，需要再规范化一次。
+		if f&KeyFoldNFC != 0 {
+			key = norm.NFC.String(key)
+		}
+	}
+
+	return key
+}
+
+// QueryFold 与 `Data#Query` 相同，但是使用 folding 比较 object 中的 key。
+// 数组下标的解析规则不变。
+func (d Data) QueryFold(query string, folding KeyFolding) interface{} {
+	if query == "" {
+		return d.data
+	}
+
+	return folding.get(d.data, SplitQuery(query))
+}
+
+// get 按照 fields 逐级查找 d 中的值，找不到则返回 nil。
+func (f KeyFolding) get(d RawData, fields []string) interface{} {
+	if f == 0 {
+		return d.Get(fields...)
+	}
+
+	var v interface{} = d
+
+	for _, field := range fields {
+		if inner, ok := v.(Data); ok {
+			v = inner.data
+		}
+
+		var ok bool
+
+		if m, isMap := v.(RawData); isMap {
+			v, ok = f.lookup(m, field)
+		} else {
+			v, ok, _ = getField(v, field)
+		}
+
+		if !ok {
+			return nil
+		}
+	}
+
+	return v
+}
+
+// lookup 在 m 中查找 key 对应的值，值为 nil 时认为不存在。
+func (f KeyFolding) lookup(m RawData, key string) (interface{}, bool) {
+	if v := m[key]; v != nil {
+		return v, true
+	}
+
+	folded := f.Fold(key)
+	found := ""
+	var v interface{}
+
+	for k, val := range m {
+		if val == nil || (v != nil && k >= found) || f.Fold(k) != folded {
+			continue
+		}
+
+		found, v = k, val
+	}
+
+	return v, v != nil
+}
+
+// mapIndex 与 lookup 相同，但 m 是任意以 string 为 key 的 map。
+func (f KeyFolding) mapIndex(m reflect.Value, key string) reflect.Value {
+	if m.Type().Key().Kind() != reflect.String {
+		return reflect.Value{}
+	}
+
+	folded := f.Fold(key)
+	found := ""
+	var v reflect.Value
+	iter := m.MapRange()
+
+	for iter.Next() {
+		k := iter.Key().String()
+
+		if (v.IsValid() && k >= found) || f.Fold(k) != folded {
+			continue
+		}
+
+		found, v = k, iter.Value()
+	}
+
+	return v
+}
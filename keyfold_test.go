@@ -0,0 +1,160 @@
+package data
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/huandu/go-assert"
+)
+
+const (
+	nfcName = "caf\u00e9"  // é 是一个字符。
+	nfdName = "cafe\u0301" // e 加上组合重音符。
+)
+
+func TestKeyFoldingFold(t *testing.T) {
+	cases := []struct {
+		Folding  KeyFolding
+		Key      string
+		Expected string
+	}{
+		{0, nfdName, nfdName},
+		{KeyFoldNFC, nfdName, nfcName},
+		{KeyFoldNFC, "Name", "Name"},
+		{KeyFoldCase, "Name", "name"},
+		{KeyFoldCase, "STRASSE", "strasse"},
+		{KeyFoldCase, "Straße", "strasse"},
+		{KeyFoldNFC | KeyFoldCase, "CAFÉ", nfcName},
+	}
+
+	for i, c := range cases {
+		a := assert.New(t)
+		a.Use(&i, &c)
+
+		a.Equal(c.Folding.Fold(c.Key), c.Expected)
+	}
+}
+
+func TestKeyFoldingFoldConcurrent(t *testing.T) {
+	a := assert.New(t)
+	var wg sync.WaitGroup
+	results := make([]string, 16)
+
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				results[i] = KeyFoldCase.Fold("Straße")
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	for _, r := range results {
+		a.Equal(r, "strasse")
+	}
+}
+
+func TestDataQueryFold(t *testing.T) {
+	d := Make(RawData{
+		nfdName: RawData{
+			"Menu": []RawData{{"Price": 12}},
+		},
+		"both": RawData{
+			"KEY": 1,
+			"Key": 2,
+			"key": 3,
+		},
+		"nil": RawData{
+			"Nil": nil,
+			"NIL": 4,
+		},
+		"inner": Make(RawData{"Name": "inner"}),
+	})
+	cases := []struct {
+		Query    string
+		Folding  KeyFolding
+		Expected interface{}
+	}{
+		{nfcName + ".Menu.0.Price", 0, nil},
+		{nfcName + ".Menu.0.Price", KeyFoldNFC, int64(12)},
+		{nfcName + ".menu.0.price", KeyFoldNFC, nil},
+		{nfcName + ".menu.0.price", KeyFoldNFC | KeyFoldCase, int64(12)},
+		{"both.Key", KeyFoldCase, int64(2)}, // 精确匹配优先。
+		{"both.kEY", KeyFoldCase, int64(1)}, // 使用字典序第一个 key。
+		{"nil.nil", KeyFoldCase, int64(4)},  // 值为 nil 的 key 被忽略。
+		{"inner.name", KeyFoldCase, "inner"},
+		{"inner.name.x", KeyFoldCase, nil},
+	}
+
+	for i, c := range cases {
+		a := assert.New(t)
+		a.Use(&i, &c)
+
+		a.Equal(d.QueryFold(c.Query, c.Folding), c.Expected)
+	}
+
+	a := assert.New(t)
+	a.Equal(d.QueryFold("", KeyFoldCase), d.data)
+}
+
+func TestDecoderKeyFolding(t *testing.T) {
+	type Item struct {
+		Name  string `data:"name"`
+		Price int    `data:"price"`
+	}
+	type Menu struct {
+		Title string            `data:"title"`
+		Items []Item            `data:"items"`
+		Tags  map[string]string `data:"tags"`
+	}
+
+	a := assert.New(t)
+	d := Make(RawData{
+		"Menu": RawData{
+			"TITLE": nfdName,
+			"Items": []RawData{
+				{"Name": "tea", "PRICE": 3},
+			},
+			"Tags": RawData{"Lang": "fr"},
+		},
+		nfdName: RawData{
+			"title": "b",
+		},
+	})
+	dec := &Decoder{
+		KeyFolding: KeyFoldNFC | KeyFoldCase,
+	}
+
+	var menu Menu
+	a.NilError(dec.DecodeQuery(d, "menu", &menu))
+	a.Equal(menu, Menu{
+		Title: nfdName, // 值不会被规范化。
+		Items: []Item{{Name: "tea", Price: 3}},
+		Tags:  map[string]string{"Lang": "fr"}, // map 的 key 保持不变。
+	})
+
+	var items []Item
+	skipped, err := dec.DecodeElements(d, "MENU.items", &items)
+	a.NilError(err)
+	a.Equal(len(skipped), 0)
+	a.Equal(items, menu.Items)
+
+	var title string
+	a.NilError(dec.DecodeField(d, []string{nfcName, "Title"}, &title))
+	a.Equal(title, "b")
+
+	var partial struct {
+		Menu Menu `data:"menu"`
+	}
+	a.NilError(dec.DecodeFields(d, &partial, "menu"))
+	a.Equal(partial.Menu, menu)
+
+	// 默认精确匹配。
+	menu = Menu{}
+	a.NilError((&Decoder{}).DecodeQuery(d, "Menu", &menu))
+	a.Equal(menu, Menu{})
+}
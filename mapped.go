@@ -0,0 +1,156 @@
+package data
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/tidwall/gjson"
+)
+
+// MappedData 是一个只读的 Data，序列化的数据保存在磁盘文件中并通过 mmap 映射到内存，
+// 查询时直接在映射的 JSON 上按需解析，只有被查询到的值才会被转化成 Data 的类型，
+// 适合几百 MB 的参考数据集这类不希望在启动时全部解析的场景。
+//
+// MappedData 可以被多个 goroutine 并发使用，使用完毕后需要调用 Close 释放映射。
+// 在不支持 mmap 的平台上，文件会被完整读入内存，但依然是按需解析的。
+//
+// 需要特别注意，映射的文件在 Close 之前一直会被按需读取，如果文件被原地截断或者改写，
+// 之后的查询可能读到不完整的数据，甚至因为 SIGBUS 导致进程崩溃，这是无法通过 recover 恢复的。
+// 更新文件时必须先写入同一个目录下的新文件，再通过 os.Rename 原子的替换旧文件，
+// 已经打开的 MappedData 会继续使用旧文件的内容，重新调用 OpenMapped 才会读到新的内容。
+type MappedData struct {
+	mu      sync.RWMutex
+	src     string
+	release func() error
+}
+
+// OpenMapped 打开 path 对应的文件并映射到内存中。
+// 文件的内容可以是 JSON object，也可以是 `Data#String` 输出的带头的格式，比如 `<json>{"a":1}`。
+//
+// 打开时会检查整个文件是否是合法的 JSON，如果不是则返回错误。
+// 在 MappedData 被关闭之前，不能原地截断或者改写 path 对应的文件，详见 `MappedData` 文档。
+func OpenMapped(path string) (*MappedData, error) {
+	f, err := os.Open(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// 映射建立之后关闭文件不会影响映射的内存。
+	defer f.Close()
+	buf, release, err := mmapFile(f)
+
+	if err != nil {
+		return nil, err
+	}
+
+	src := *(*string)(unsafe.Pointer(&buf))
+	metaBegin, metaEnd := metaMarks("", "")
+
+	if header := metaBegin + dataTypeJSON + metaEnd; strings.HasPrefix(src, header) {
+		src = src[len(header):]
+	}
+
+	if !gjson.Valid(src) {
+		release()
+		return nil, ErrInvalidJSON
+	}
+
+	if !gjson.Parse(src).IsObject() {
+		release()
+		return nil, ErrNotObject
+	}
+
+	return &MappedData{
+		src:     src,
+		release: release,
+	}, nil
+}
+
+// Query 使用与 `Data#Query` 相同的 query 格式查询数据，返回查询结果，如果找不到则返回 nil。
+// 返回值是新解析出来的，与映射的内存无关，修改返回值或者调用 Close 之后依然可以安全的使用。
+func (m *MappedData) Query(query string) interface{} {
+	if query == "" {
+		return m.Get()
+	}
+
+	return m.Get(SplitQuery(query)...)
+}
+
+// Get 使用与 `Data#Get` 相同的规则查询数据，返回查询结果，如果找不到则返回 nil。
+// 如果 fields 为空，返回整个数据，这会解析整个文件，应该尽量避免。
+func (m *MappedData) Get(fields ...string) interface{} {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.release == nil {
+		return nil
+	}
+
+	res := gjson.Parse(m.src)
+
+	if len(fields) != 0 {
+		res = res.Get(gjsonPath(fields))
+	}
+
+	if !res.Exists() || res.Type == gjson.Null {
+		return nil
+	}
+
+	// 复制一份数据再解析，保证结果中的字符串不会引用映射的内存。
+	raw := string(append([]byte(nil), res.Raw...))
+	jp := &jsonParser{}
+	v, _ := jp.parseValue(gjson.Parse(raw))
+	return v
+}
+
+// Data 返回 query 对应的 object 并且转化成 Data，如果找不到或者不是 object 则返回空 Data。
+func (m *MappedData) Data(query string) Data {
+	if d, ok := m.Query(query).(RawData); ok {
+		return Data{
+			data: d,
+		}
+	}
+
+	return emptyData
+}
+
+// Close 释放映射的内存，之后所有的查询都会返回 nil。重复调用 Close 不会报错。
+func (m *MappedData) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.release == nil {
+		return nil
+	}
+
+	err := m.release()
+	m.src = ""
+	m.release = nil
+	return err
+}
+
+// gjsonPath 将 fields 转化成 gjson 的 path，所有 gjson 的特殊字符都会被转义。
+func gjsonPath(fields []string) string {
+	buf := &strings.Builder{}
+
+	for i, f := range fields {
+		if i != 0 {
+			buf.WriteByte('.')
+		}
+
+		for j := 0; j < len(f); j++ {
+			switch c := f[j]; c {
+			case '\\', '.', '*', '?', '|', '#', '@':
+				buf.WriteByte('\\')
+				buf.WriteByte(c)
+			default:
+				buf.WriteByte(c)
+			}
+		}
+	}
+
+	return buf.String()
+}
@@ -0,0 +1,119 @@
+package data
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestMappedData(t *testing.T) {
+	a := assert.New(t)
+	dir, err := ioutil.TempDir("", "go-data-mapped")
+	a.NilError(err)
+	defer os.RemoveAll(dir)
+
+	d := Make(RawData{
+		"a": RawData{
+			"b":    []int{1, 2},
+			"c":    "str",
+			"d":    nil,
+			"e*?#": 1.5,
+			"list": []RawData{{"name": "x"}, {"name": "y"}},
+		},
+		"n": 9223372036854775807,
+	})
+	path := filepath.Join(dir, "data")
+	a.NilError(ioutil.WriteFile(path, []byte(d.String()), 0644))
+
+	m, err := OpenMapped(path)
+	a.NilError(err)
+
+	cases := []string{
+		"a.b",
+		"a.b.1",
+		"a.c",
+		"a.d",
+		"a.e*?#",
+		"a.list.1",
+		"a.list.1.name",
+		"a.list.2",
+		"a.not_exist",
+		"n",
+		"",
+	}
+
+	for i, c := range cases {
+		a.Use(&i, &c)
+		expected := d.Query(c)
+
+		if expected != nil {
+			if v, ok := expected.(Data); ok {
+				expected = v.data
+			}
+		}
+
+		a.Equal(m.Query(c), expected)
+	}
+
+	a.Equal(m.Get("a", "list", "0", "name"), "x")
+	a.Equal(m.Data("a.list.0"), Make(RawData{"name": "x"}))
+	a.Equal(m.Data("a.c"), Data{})
+
+	// Close 之后查询结果依然可以使用。
+	v := m.Query("a.c")
+	a.NilError(m.Close())
+	a.NilError(m.Close())
+	a.Equal(v, "str")
+	a.Equal(m.Query("a.c"), nil)
+
+	// 不带头的 JSON 也可以被打开。
+	a.NilError(ioutil.WriteFile(path, []byte(`{"a":{"b":1}}`), 0644))
+	m, err = OpenMapped(path)
+	a.NilError(err)
+	a.Equal(m.Query("a.b"), int64(1))
+	a.NilError(m.Close())
+
+	errCases := []string{``, `{"a":`, `[1,2]`, `<json>1`}
+
+	for i, c := range errCases {
+		a.Use(&i, &c)
+		a.NilError(ioutil.WriteFile(path, []byte(c), 0644))
+		_, err := OpenMapped(path)
+		a.NonNilError(err)
+	}
+
+	_, err = OpenMapped(filepath.Join(dir, "not_exist"))
+	a.NonNilError(err)
+}
+
+func TestMappedDataReplace(t *testing.T) {
+	a := assert.New(t)
+	dir, err := ioutil.TempDir("", "go-data-mapped")
+	a.NilError(err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "data")
+	a.NilError(ioutil.WriteFile(path, []byte(`{"version":1}`), 0644))
+
+	old, err := OpenMapped(path)
+	a.NilError(err)
+	defer old.Close()
+
+	// 先写入新文件再通过 rename 替换，不能原地改写正在被映射的文件。
+	tmp := filepath.Join(dir, "data.tmp")
+	a.NilError(ioutil.WriteFile(tmp, []byte(`{"version":2,"padding":"`+strings.Repeat("x", 1<<16)+`"}`), 0644))
+	a.NilError(os.Rename(tmp, path))
+
+	// 已经打开的 MappedData 继续使用旧文件的内容。
+	a.Equal(old.Query("version"), int64(1))
+
+	m, err := OpenMapped(path)
+	a.NilError(err)
+	defer m.Close()
+	a.Equal(m.Query("version"), int64(2))
+	a.Equal(old.Query("version"), int64(1))
+}
@@ -1,7 +1,10 @@
 package data
 
 import (
+	"fmt"
 	"reflect"
+	"strconv"
+	"time"
 
 	"github.com/huandu/go-clone"
 )
@@ -13,36 +16,286 @@ import (
 //     - 对于 Data 类型的数值，将相同的 key 进行深度合并；
 //       - 如果出现同名 key 且 value 类型相同，都是 Data 或者 slice，深度合并 value 值；
 //       - 如果出现同名 key 且 value 类型不同，后面出现的 value 覆盖前面的 value。
-//     - 对于 slice 类型的数值，如果两个 slice 类型相同，后面出现的 slice 的值会被 append 进去。
+//     - 对于 slice 类型的数值，如果两个 slice 类型相同，后面出现的 slice 的值会被 append 进去，
+//       合并得到的 slice 总是使用新的底层数组，不会与任何 data 共享；
+//     - 树中 key 为字符串的 map（比如 map[string]string）会被当做 Data 合并，合并后变成 RawData。
+//
+// 如果第一个 data 记录了 key 的顺序（见 `Parser#KeepOrder`），d 也会记录 key 的顺序，
+// 合并进来的新 key 会追加在已有 key 的后面。
 func Merge(data ...Data) (d Data) {
+	return merger{}.mergeData(data)
+}
+
+func (m merger) mergeData(data []Data) (d Data) {
 	if len(data) == 0 {
 		return emptyData
 	}
 
+	if inst := currentInstrumentation(nil); inst != nil {
+		start := time.Now()
+		defer func() {
+			inst.OnMerge(len(data), time.Since(start))
+		}()
+	}
+
 	target := RawData{}
-	merge(reflect.ValueOf(target), data[0].data, data[1:]...)
+
+	if data[0].order == nil {
+		m.merge(reflect.ValueOf(target), data[0].data, data[1:]...)
+		return Data{
+			data: target,
+		}
+	}
+
+	order := newKeyOrder()
+	m.mergeOrdered(target, order, data...)
 	return Data{
-		data: target,
+		data:  target,
+		order: order,
 	}
 }
 
 // MergeTo 将多个 data 从左至右合并到 target 里面，如果有同名的 key 会进行深度遍历进行合并。
 // 如果 target 为 nil，则直接返回，不做任何操作。
 //
+// 如果 target 记录了 key 的顺序（见 `Parser#KeepOrder`），合并进来的新 key 会追加在已有 key 的后面。
+//
 // 具体的合并规则是参考 `Merge` 的文档。
 func MergeTo(target *Data, data ...Data) {
+	merger{}.mergeDataTo(target, data)
+}
+
+func (m merger) mergeDataTo(target *Data, data []Data) {
 	if target == nil || len(data) == 0 {
 		return
 	}
 
-	merge(reflect.ValueOf(target.data), data[0].data, data[1:]...)
+	if inst := currentInstrumentation(nil); inst != nil {
+		start := time.Now()
+		defer func() {
+			inst.OnMerge(len(data), time.Since(start))
+		}()
+	}
+
+	if target.data == nil {
+		target.data = RawData{}
+	}
+
+	if target.order != nil {
+		m.mergeOrdered(target.data, target.order, data...)
+		return
+	}
+
+	m.merge(reflect.ValueOf(target.data), data[0].data, data[1:]...)
+}
+
+// MergeAny 将多个任意类型的 values 从左至右合并到 target 里面。
+// 如果 target 为 nil，则直接返回，不做任何操作。
+//
+// 如果 value 是 Data 或 *Data，则直接合并；否则先使用默认的 Encoder 将 value 转化成 Data 再合并，
+// 转化规则详见 `Encoder#Encode` 文档。如果转化过程出错，返回错误，并且 target 不会被修改。
+// 合并规则详见 `Merge` 文档。
+func MergeAny(target *Data, values ...interface{}) error {
+	if target == nil || len(values) == 0 {
+		return nil
+	}
+
+	enc := Encoder{}
+	data := make([]Data, 0, len(values))
+
+	for _, v := range values {
+		switch val := v.(type) {
+		case Data:
+			data = append(data, val)
+		case *Data:
+			if val != nil {
+				data = append(data, *val)
+			}
+		default:
+			d, err := enc.EncodeE(v)
+
+			if err != nil {
+				return err
+			}
+
+			data = append(data, d)
+		}
+	}
+
+	MergeTo(target, data...)
+	return nil
+}
+
+// ApplyToStruct 将 overrides 从左至右合并到 v 指向的 struct 上，v 必须是 struct 的非 nil 指针。
+// 这相当于先用 `Encoder` 将 v 转化成 Data，再用 `MergeTo` 合并 overrides，最后用 `Decoder` 解析回 v，
+// 适合在默认配置上叠加部分覆盖的配置。合并规则详见 `Merge` 文档，比如 slice 会被追加而不是替换。
+//
+// 没有出现在 overrides 中的字段保持原值，包括私有字段和 tag 为“-”的字段。
+// 如果任何一步出错，返回错误，并且 v 不会被修改。
+// 需要注意，成功之后 v 中的指针、slice 和 map 字段都会指向新的值，不再与原来的值共享内存。
+func ApplyToStruct(v interface{}, overrides ...Data) error {
+	rv := reflect.ValueOf(v)
+
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("go-data: cannot apply data to a value of type %T which is not a pointer to struct", v)
+	}
+
+	enc := Encoder{}
+	d, err := enc.EncodeE(v)
+
+	if err != nil {
+		return err
+	}
+
+	MergeTo(&d, overrides...)
+
+	// 解析到 v 的副本上，出错时 v 不会被修改一半；非 nil 的指针字段会被原地解析，所以需要深拷贝。
+	copied := reflect.ValueOf(clone.Clone(v))
+	dec := Decoder{}
+
+	if err := dec.Decode(d, copied.Interface()); err != nil {
+		return err
+	}
+
+	rv.Elem().Set(copied.Elem())
+	return nil
+}
+
+// MergeOptions 用来限制合并不可信数据时的资源消耗，避免过深的嵌套导致栈溢出，或者过多的节点耗尽内存。
+// 所有限制都会在合并之前检查，如果超出限制则直接返回 `*MergeError`，不会修改任何数据。
+type MergeOptions struct {
+	MaxDepth int // 参与合并的数据最大嵌套深度，顶层的值深度为 1，为 0 时不限制。
+	MaxNodes int // 所有参与合并的数据中值的总个数，为 0 时不限制。
+
+	// Unsigned 为 true 时，元素都是整数的数组即使类型不同也会合并，比如 []int64 与 []uint64，
+	// 合并结果的类型与使用 `Parser#Unsigned` 解析得到的类型相同：
+	// 所有元素都是非负整数时是 []uint64，否则是 []interface{}，其中非负整数是 uint64，负整数是 int64。
+	// 这样，通过 Encoder 编码的无符号整数数组与解析得到的整数数组可以按照同样的规则合并。
+	Unsigned bool
+}
+
+// Merge 检查 data 是否超出限制，如果没有则与 `Merge` 相同。
+func (opts *MergeOptions) Merge(data ...Data) (d Data, err error) {
+	if err = opts.check(data); err != nil {
+		return
+	}
+
+	d = merger{unsigned: opts.Unsigned}.mergeData(data)
+	return
+}
+
+// MergeTo 检查 data 是否超出限制，如果没有则与 `MergeTo` 相同。
+func (opts *MergeOptions) MergeTo(target *Data, data ...Data) error {
+	if err := opts.check(data); err != nil {
+		return err
+	}
+
+	merger{unsigned: opts.Unsigned}.mergeDataTo(target, data)
+	return nil
+}
+
+func (opts *MergeOptions) check(data []Data) error {
+	if opts.MaxDepth <= 0 && opts.MaxNodes <= 0 {
+		return nil
+	}
+
+	budget := &mergeBudget{
+		opts: opts,
+	}
+
+	for i, d := range data {
+		if path, err := budget.walkObject(d.data, 1, nil); err != nil {
+			return &MergeError{
+				Index: i,
+				Path:  JoinQuery(path...),
+				Err:   err,
+			}
+		}
+	}
+
+	return nil
+}
+
+// mergeBudget 记录检查 MergeOptions 限制时已经遍历的节点个数。
+type mergeBudget struct {
+	opts  *MergeOptions
+	nodes int
+}
+
+func (b *mergeBudget) walkObject(d RawData, depth int, path []string) ([]string, error) {
+	var order *keyOrder
+
+	for _, k := range order.sortedKeys(d) {
+		if p, err := b.walk(d[k], depth, append(path, k)); err != nil {
+			return p, err
+		}
+	}
+
+	return nil, nil
+}
+
+func (b *mergeBudget) walk(v interface{}, depth int, path []string) ([]string, error) {
+	b.nodes++
+
+	if b.opts.MaxNodes > 0 && b.nodes > b.opts.MaxNodes {
+		return path, ErrMaxNodes
+	}
+
+	if b.opts.MaxDepth > 0 && depth > b.opts.MaxDepth {
+		return path, ErrMaxDepth
+	}
+
+	switch val := v.(type) {
+	case RawData:
+		return b.walkObject(val, depth+1, path)
+	case Data:
+		return b.walkObject(val.data, depth+1, path)
+	}
+
+	rv := reflect.ValueOf(v)
+
+	if rv.Kind() != reflect.Slice {
+		return nil, nil
+	}
+
+	for i := 0; i < rv.Len(); i++ {
+		if p, err := b.walk(rv.Index(i).Interface(), depth+1, append(path, strconv.Itoa(i))); err != nil {
+			return p, err
+		}
+	}
+
+	return nil, nil
+}
+
+// merger 保存合并规则，零值对应 `Merge` 的默认规则。
+type merger struct {
+	unsigned bool            // 详见 `MergeOptions` 的 Unsigned。
+	checker  *contextChecker // 如果不为 nil，合并过程中 ctx 结束时放弃剩余的 key，调用者需要检查 checker.error()。
+}
+
+// mergeOrdered 将 data 逐个合并到 target 中，同时更新 target 的 key 顺序 order。
+func (m merger) mergeOrdered(target RawData, order *keyOrder, data ...Data) {
+	val := reflect.ValueOf(target)
+
+	for _, d := range data {
+		order.merge(target, d.data, d.order)
+		m.merge(val, d.data)
+	}
 }
 
 func merge(target reflect.Value, data RawData, remaining ...Data) {
+	merger{}.merge(target, data, remaining...)
+}
+
+func (m merger) merge(target reflect.Value, data RawData, remaining ...Data) {
 	for k, v := range data {
+		if m.checker.check() != nil {
+			return
+		}
+
 		key := reflect.ValueOf(k)
 		from := target.MapIndex(key)
-		to := mergeValue(from, v)
+		to := m.mergeValue(from, v)
 
 		target.SetMapIndex(key, to)
 	}
@@ -51,12 +304,13 @@ func merge(target reflect.Value, data RawData, remaining ...Data) {
 		return
 	}
 
-	merge(target, remaining[0].data, remaining[1:]...)
+	m.merge(target, remaining[0].data, remaining[1:]...)
 }
 
-// mergeValue 假定 target 和 v 都是 Data 中的值，因此不会出现 ptr、struct、interface 等特殊类型，
-// 而且所有的 map 类型都是 Data。
-func mergeValue(target reflect.Value, v interface{}) reflect.Value {
+// mergeValue 假定 target 和 v 都是 Data 中的值，因此不会出现 ptr、struct、interface 等特殊类型。
+// 树中的 Data 以及 key 为字符串的 map，比如 map[string]string，都会被当做 RawData 合并，
+// 合并的结果也会转化成 RawData。
+func (m merger) mergeValue(target reflect.Value, v interface{}) reflect.Value {
 	if v == nil {
 		return target
 	}
@@ -67,31 +321,109 @@ func mergeValue(target reflect.Value, v interface{}) reflect.Value {
 		for target.Kind() == reflect.Interface {
 			target = target.Elem()
 		}
+	}
 
-		if target.Type() == data.Type() {
-			switch target.Kind() {
-			case reflect.Map:
-				if target.IsNil() {
-					target = reflect.MakeMap(target.Type())
-				}
+	if raw, ok := toRawData(data); ok {
+		var d RawData
 
-				iter := data.MapRange()
+		if target.IsValid() {
+			d, _ = toRawData(target)
+		}
 
-				for iter.Next() {
-					key := iter.Key()
-					from := target.MapIndex(key)
-					to := mergeValue(from, iter.Value().Interface())
+		if d == nil {
+			d = RawData{}
+		}
 
-					target.SetMapIndex(key, to)
-				}
+		m.merge(reflect.ValueOf(d), raw)
+		return reflect.ValueOf(d)
+	}
+
+	if target.IsValid() && target.Type() == data.Type() && target.Kind() == reflect.Slice {
+		// 总是分配新的底层数组，并且复制 v 的元素，
+		// 否则 target 有多余容量时 append 会写入与其他 Data 共享的数组，v 中的 RawData 元素也会被共享。
+		merged := reflect.MakeSlice(target.Type(), 0, target.Len()+data.Len())
+		merged = reflect.AppendSlice(merged, target)
+		return reflect.AppendSlice(merged, reflect.ValueOf(cloneValue(v)))
+	}
 
-				return target
+	if m.unsigned && target.IsValid() {
+		if merged, ok := mergeIntegers(target.Interface(), v); ok {
+			return reflect.ValueOf(merged)
+		}
+	}
+
+	return reflect.ValueOf(cloneValue(v))
+}
 
-			case reflect.Slice:
-				return reflect.AppendSlice(target, data)
+// mergeIntegers 合并两个元素都是整数的数组，如果任何一个不是这样的数组，ok 为 false。
+// 合并结果的类型规则详见 `MergeOptions` 的 Unsigned。
+func mergeIntegers(target, v interface{}) (merged interface{}, ok bool) {
+	elems1, ok1 := sliceElems(target)
+	elems2, ok2 := sliceElems(v)
+
+	if !ok1 || !ok2 {
+		return
+	}
+
+	elems := make([]interface{}, 0, len(elems1)+len(elems2))
+	unsigned := true
+
+	for _, e := range append(elems1[:len(elems1):len(elems1)], elems2...) {
+		switch n := e.(type) {
+		case uint64:
+			elems = append(elems, n)
+		case int64:
+			if n < 0 {
+				unsigned = false
+				elems = append(elems, n)
+			} else {
+				elems = append(elems, uint64(n))
 			}
+		default:
+			return
 		}
 	}
 
-	return reflect.ValueOf(clone.Clone(v))
+	if !unsigned {
+		return elems, true
+	}
+
+	u := make([]uint64, 0, len(elems))
+
+	for _, e := range elems {
+		u = append(u, e.(uint64))
+	}
+
+	return u, true
+}
+
+// toRawData 将 Data 或者 key 为字符串的 map 转化成 RawData，
+// 如果 val 本身就是 RawData 则直接返回，不会复制。
+func toRawData(val reflect.Value) (RawData, bool) {
+	t := val.Type()
+
+	if t == typeOfObject {
+		return val.Interface().(RawData), true
+	}
+
+	if t == typeOfData {
+		return val.Interface().(Data).data, true
+	}
+
+	if t.Kind() != reflect.Map || t.Key().Kind() != reflect.String {
+		return nil, false
+	}
+
+	if val.IsNil() {
+		return nil, true
+	}
+
+	d := make(RawData, val.Len())
+	iter := val.MapRange()
+
+	for iter.Next() {
+		d[iter.Key().String()] = iter.Value().Interface()
+	}
+
+	return d, true
 }
@@ -1,6 +1,7 @@
 package data
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/huandu/go-assert"
@@ -118,3 +119,355 @@ func BenchmarkMerge(b *testing.B) {
 		Merge(input...)
 	}
 }
+
+func TestMergeAny(t *testing.T) {
+	type Config struct {
+		Name string         `data:"name"`
+		Port int            `data:"port,omitempty"`
+		Tags []string       `data:"tags"`
+		Meta map[string]int `data:"meta,omitempty"`
+	}
+
+	a := assert.New(t)
+	var target Data
+	a.NilError(MergeAny(&target,
+		&Config{
+			Name: "first",
+			Port: 80,
+			Tags: []string{"a"},
+		},
+		map[string]interface{}{
+			"meta": map[string]int{"k": 1},
+		},
+		Make(RawData{
+			"tags": []string{"b"},
+		}),
+		&Config{
+			Name: "second",
+			Tags: []string{"c"},
+		},
+		nil,
+		(*Data)(nil),
+	))
+	a.Equal(target, Make(RawData{
+		"name": "second",
+		"port": 80,
+		"tags": []string{"a", "b", "c"},
+		"meta": RawData{"k": 1},
+	}))
+
+	a.NilError(MergeAny(nil, &Config{}))
+}
+
+func TestDataMerge(t *testing.T) {
+	a := assert.New(t)
+	d := Make(RawData{
+		"name": "base",
+		"tags": []string{"a"},
+	})
+	defaults := Make(RawData{
+		"port": 80,
+	})
+	overrides := Make(RawData{
+		"name": "override",
+		"tags": []string{"b"},
+	})
+
+	merged := d.Merge(defaults).Merge(overrides)
+	a.Equal(merged, Make(RawData{
+		"name": "override",
+		"port": 80,
+		"tags": []string{"a", "b"},
+	}))
+	a.Equal(d, Make(RawData{
+		"name": "base",
+		"tags": []string{"a"},
+	}))
+	a.Equal(d.Merge(), d)
+
+	var target Data
+	target.MergeInPlace(d, defaults)
+	target.MergeInPlace(overrides)
+	a.Equal(target, merged)
+}
+
+func TestMergeStringKeyedMaps(t *testing.T) {
+	a := assert.New(t)
+	d1 := Data{data: RawData{
+		"labels": map[string]string{
+			"app": "web",
+			"env": "dev",
+		},
+		"nested": Make(RawData{
+			"a": 1,
+		}),
+	}}
+	d2 := Data{data: RawData{
+		"labels": map[string]string{
+			"env": "prod",
+		},
+		"nested": RawData{
+			"b": "str",
+		},
+		"extra": map[string]interface{}{
+			"c": map[string]bool{"ok": true},
+		},
+	}}
+	expected := RawData{
+		"labels": RawData{
+			"app": "web",
+			"env": "prod",
+		},
+		"nested": RawData{
+			"a": int64(1),
+			"b": "str",
+		},
+		"extra": RawData{
+			"c": RawData{"ok": true},
+		},
+	}
+
+	a.Equal(Merge(d1, d2).data, expected)
+	a.Equal(d1.data["labels"], map[string]string{
+		"app": "web",
+		"env": "dev",
+	})
+
+	target := Data{data: RawData{
+		"labels": map[string]string{"app": "api"},
+	}}
+	MergeTo(&target, d2)
+	a.Equal(target.data["labels"], RawData{
+		"app": "api",
+		"env": "prod",
+	})
+}
+
+func TestMergeSliceAliasing(t *testing.T) {
+	a := assert.New(t)
+	tags := make([]string, 1, 10)
+	tags[0] = "a"
+	shared := Data{data: RawData{
+		"tags": tags,
+	}}
+	elem := RawData{"v": int64(1)}
+	other := Data{data: RawData{
+		"tags":  []string{"b"},
+		"elems": []interface{}{elem},
+	}}
+
+	var target Data
+	target.data = RawData{"tags": shared.data["tags"]}
+	MergeTo(&target, other)
+	target.MergeInPlace(Data{data: RawData{"tags": []string{"c"}}})
+	a.Equal(target.data["tags"], []string{"a", "b", "c"})
+	a.Equal(tags[:2], []string{"a", ""})
+
+	merged := Merge(other, other)
+	merged.data["elems"].([]interface{})[0].(RawData)["v"] = int64(2)
+	merged.data["elems"].([]interface{})[1].(RawData)["v"] = int64(3)
+	a.Equal(elem["v"], int64(1))
+
+	m1 := Merge(shared, other)
+	m2 := Merge(shared, other)
+	m1.data["tags"].([]string)[1] = "changed"
+	a.Equal(m2.data["tags"], []string{"a", "b"})
+}
+
+func TestMergeOptions(t *testing.T) {
+	deep := Make(RawData{
+		"a": RawData{
+			"b": RawData{
+				"c": []RawData{{"d": 1}},
+			},
+		},
+	})
+	wide := Make(RawData{
+		"list": []int{1, 2, 3, 4},
+		"x":    1,
+	})
+	cases := []struct {
+		Options MergeOptions
+		Data    []Data
+		Index   int
+		Path    string
+		Err     error
+	}{
+		{ // 不限制。
+			MergeOptions{},
+			[]Data{deep, wide},
+			0, "", nil,
+		},
+		{ // 刚好不超过限制。
+			MergeOptions{MaxDepth: 5, MaxNodes: 11},
+			[]Data{deep, wide},
+			0, "", nil,
+		},
+		{ // 深度超过限制。
+			MergeOptions{MaxDepth: 4},
+			[]Data{wide, deep},
+			1, "a.b.c.0.d", ErrMaxDepth,
+		},
+		{ // 节点个数超过限制。
+			MergeOptions{MaxNodes: 10},
+			[]Data{deep, wide},
+			1, "x", ErrMaxNodes,
+		},
+		{ // 嵌入的 Data 也计算在内。
+			MergeOptions{MaxDepth: 2},
+			[]Data{{data: RawData{"a": Make(RawData{"b": RawData{"c": 1}})}}},
+			0, "a.b.c", ErrMaxDepth,
+		},
+	}
+	a := assert.New(t)
+
+	for i, c := range cases {
+		a.Use(&i, &c)
+		target := Make(RawData{"t": 1})
+		expected := target.Clone()
+		merged, err := c.Options.Merge(c.Data...)
+		errTo := c.Options.MergeTo(&target, c.Data...)
+
+		if c.Err == nil {
+			a.NilError(err)
+			a.NilError(errTo)
+			a.Equal(merged, Merge(c.Data...))
+			MergeTo(&expected, c.Data...)
+			a.Equal(target, expected)
+			continue
+		}
+
+		a.Assert(errors.Is(err, c.Err))
+		a.Assert(errors.Is(errTo, c.Err))
+		a.Equal(target, expected)
+
+		var mergeErr *MergeError
+		a.Assert(errors.As(err, &mergeErr))
+		a.Equal(mergeErr.Index, c.Index)
+		a.Equal(mergeErr.Path, c.Path)
+	}
+}
+
+func TestMergeOptionsUnsigned(t *testing.T) {
+	cases := []struct {
+		Target   interface{}
+		Value    interface{}
+		Default  interface{}
+		Unsigned interface{}
+	}{
+		{
+			[]uint64{1, 18446744073709551615},
+			[]int64{2, 3},
+			[]int64{2, 3},
+			[]uint64{1, 18446744073709551615, 2, 3},
+		},
+		{
+			[]int64{-1},
+			[]uint64{2},
+			[]uint64{2},
+			[]interface{}{int64(-1), uint64(2)},
+		},
+		{
+			[]interface{}{int64(1), uint64(18446744073709551615)},
+			[]int64{2},
+			[]int64{2},
+			[]uint64{1, 18446744073709551615, 2},
+		},
+		{ // 同类型的数组与之前一样直接追加。
+			[]int64{-1},
+			[]int64{2},
+			[]int64{-1, 2},
+			[]int64{-1, 2},
+		},
+		{ // 不全是整数的数组不受影响。
+			[]uint64{1},
+			[]string{"a"},
+			[]string{"a"},
+			[]string{"a"},
+		},
+	}
+	a := assert.New(t)
+
+	for i, c := range cases {
+		a.Use(&i, &c)
+		d1 := Make(RawData{"list": c.Target})
+		d2 := Make(RawData{"list": c.Value})
+
+		a.Equal(Merge(d1, d2).Get("list"), c.Default)
+
+		opts := MergeOptions{Unsigned: true}
+		merged, err := opts.Merge(d1, d2)
+		a.NilError(err)
+		a.Equal(merged.Get("list"), c.Unsigned)
+
+		target := d1.Clone()
+		a.NilError(opts.MergeTo(&target, d2))
+		a.Equal(target.Get("list"), c.Unsigned)
+	}
+}
+
+func TestApplyToStruct(t *testing.T) {
+	type Server struct {
+		Addr    string `data:"addr"`
+		Workers int    `data:"workers"`
+	}
+	type Config struct {
+		Name    string            `data:"name"`
+		Server  *Server           `data:"server"`
+		Tags    []string          `data:"tags"`
+		Labels  map[string]string `data:"labels"`
+		Skipped int               `data:"-"`
+		private int
+	}
+
+	a := assert.New(t)
+	server := &Server{
+		Addr:    ":80",
+		Workers: 4,
+	}
+	config := &Config{
+		Name:    "default",
+		Server:  server,
+		Tags:    []string{"a"},
+		Labels:  map[string]string{"env": "dev"},
+		Skipped: 1,
+		private: 2,
+	}
+	a.NilError(ApplyToStruct(config, Make(RawData{
+		"server": RawData{
+			"workers": 8,
+		},
+		"tags": []string{"b"},
+	}), Make(RawData{
+		"labels": RawData{
+			"region": "cn",
+		},
+	})))
+	a.Equal(config, &Config{
+		Name: "default",
+		Server: &Server{
+			Addr:    ":80",
+			Workers: 8,
+		},
+		Tags:    []string{"a", "b"},
+		Labels:  map[string]string{"env": "dev", "region": "cn"},
+		Skipped: 1,
+		private: 2,
+	})
+
+	// 出错时 config 不会被修改，包括指针字段指向的值。
+	server = config.Server
+	err := ApplyToStruct(config, Make(RawData{
+		"server": RawData{
+			"addr":    ":8080",
+			"workers": "many",
+		},
+	}))
+	a.NonNilError(err)
+	a.Equal(config.Server.Addr, ":80")
+	a.Equal(server.Workers, 8)
+
+	a.NonNilError(ApplyToStruct(*config))
+	a.NonNilError(ApplyToStruct((*Config)(nil)))
+	a.NonNilError(ApplyToStruct(&config.Tags))
+}
@@ -0,0 +1,20 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly
+
+package data
+
+import (
+	"io/ioutil"
+	"os"
+)
+
+// mmapFile 在不支持 mmap 的平台上直接将整个文件读入内存。
+func mmapFile(f *os.File) ([]byte, func() error, error) {
+	buf, err := ioutil.ReadAll(f)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return buf, func() error { return nil }, nil
+}
@@ -0,0 +1,36 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+// +build linux darwin freebsd netbsd openbsd dragonfly
+
+package data
+
+import (
+	"os"
+	"syscall"
+)
+
+// mmapFile 将文件以只读方式映射到内存中，返回映射的内存以及释放映射的函数。
+func mmapFile(f *os.File) ([]byte, func() error, error) {
+	info, err := f.Stat()
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	size := info.Size()
+
+	if size == 0 {
+		return nil, func() error { return nil }, nil
+	}
+
+	if int64(int(size)) != size {
+		return nil, nil, syscall.EFBIG
+	}
+
+	buf, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return buf, func() error { return syscall.Munmap(buf) }, nil
+}
@@ -0,0 +1,213 @@
+package data
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/tidwall/gjson"
+)
+
+// keyOrder 记录了 RawData 中 key 出现的顺序。
+//
+// 对于 object，keys 是所有 key 的顺序，children 记录了每个 key 对应值的顺序信息，
+// 如果值中没有任何 object，children 中这个 key 对应的值为 nil。
+// 对于 array，elems 记录了每个元素的顺序信息。
+type keyOrder struct {
+	keys     []string
+	children map[string]*keyOrder
+	elems    []*keyOrder
+}
+
+func newKeyOrder() *keyOrder {
+	return &keyOrder{
+		children: map[string]*keyOrder{},
+	}
+}
+
+func parseJSONOrder(res gjson.Result) *keyOrder {
+	if res.Type != gjson.JSON {
+		return nil
+	}
+
+	if res.IsObject() {
+		order := newKeyOrder()
+
+		res.ForEach(func(key, value gjson.Result) bool {
+			k := key.Str
+
+			if _, ok := order.children[k]; !ok {
+				order.keys = append(order.keys, k)
+			}
+
+			order.children[k] = parseJSONOrder(value)
+			return true
+		})
+
+		return order
+	}
+
+	arr := res.Array()
+	elems := make([]*keyOrder, len(arr))
+	found := false
+
+	for i, r := range arr {
+		elems[i] = parseJSONOrder(r)
+		found = found || elems[i] != nil
+	}
+
+	if !found {
+		return nil
+	}
+
+	return &keyOrder{
+		elems: elems,
+	}
+}
+
+func (order *keyOrder) child(key string) *keyOrder {
+	if order == nil {
+		return nil
+	}
+
+	return order.children[key]
+}
+
+func (order *keyOrder) elem(i int) *keyOrder {
+	if order == nil || i >= len(order.elems) {
+		return nil
+	}
+
+	return order.elems[i]
+}
+
+func (order *keyOrder) add(key string) {
+	if _, ok := order.children[key]; !ok {
+		order.keys = append(order.keys, key)
+		order.children[key] = nil
+	}
+}
+
+func (order *keyOrder) clone() *keyOrder {
+	if order == nil {
+		return nil
+	}
+
+	cloned := &keyOrder{}
+
+	if order.children != nil {
+		cloned.keys = append([]string(nil), order.keys...)
+		cloned.children = make(map[string]*keyOrder, len(order.children))
+
+		for k, child := range order.children {
+			cloned.children[k] = child.clone()
+		}
+	}
+
+	if order.elems != nil {
+		cloned.elems = make([]*keyOrder, len(order.elems))
+
+		for i, elem := range order.elems {
+			cloned.elems[i] = elem.clone()
+		}
+	}
+
+	return cloned
+}
+
+// sortedKeys 返回 d 中所有的 key，先按照 order 中记录的顺序，
+// 然后再按照字典序返回 order 中没有记录的 key。
+func (order *keyOrder) sortedKeys(d RawData) []string {
+	keys := make([]string, 0, len(d))
+	var known map[string]*keyOrder
+
+	if order != nil {
+		known = order.children
+
+		for _, k := range order.keys {
+			if _, ok := d[k]; ok {
+				keys = append(keys, k)
+			}
+		}
+	}
+
+	l := len(keys)
+
+	for k := range d {
+		if _, ok := known[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys[l:])
+	return keys
+}
+
+// merge 按照 mergeValue 的规则，在 src 合并到 target 之前将 src 的顺序信息合并到 order 中。
+func (order *keyOrder) merge(target RawData, src RawData, srcOrder *keyOrder) {
+	for _, k := range srcOrder.sortedKeys(src) {
+		v := src[k]
+
+		if v == nil {
+			continue
+		}
+
+		tv, ok := target[k]
+		order.add(k)
+
+		if !ok || tv == nil {
+			order.children[k] = srcOrder.child(k).clone()
+			continue
+		}
+
+		tval := reflect.ValueOf(tv)
+		sval := reflect.ValueOf(v)
+
+		if tval.Type() != sval.Type() {
+			order.children[k] = srcOrder.child(k).clone()
+			continue
+		}
+
+		switch tval.Kind() {
+		case reflect.Map:
+			td, ok := tv.(RawData)
+
+			if !ok {
+				continue
+			}
+
+			child := order.children[k]
+
+			if child == nil || child.children == nil {
+				child = newKeyOrder()
+				order.children[k] = child
+			}
+
+			child.merge(td, v.(RawData), srcOrder.child(k))
+
+		case reflect.Slice:
+			child := order.children[k]
+			sc := srcOrder.child(k)
+
+			if child == nil && sc == nil {
+				continue
+			}
+
+			elems := make([]*keyOrder, 0, tval.Len()+sval.Len())
+
+			for i := 0; i < tval.Len(); i++ {
+				elems = append(elems, child.elem(i))
+			}
+
+			for i := 0; i < sval.Len(); i++ {
+				elems = append(elems, sc.elem(i).clone())
+			}
+
+			order.children[k] = &keyOrder{
+				elems: elems,
+			}
+
+		default:
+			order.children[k] = srcOrder.child(k).clone()
+		}
+	}
+}
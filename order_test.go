@@ -0,0 +1,68 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestParserKeepOrder(t *testing.T) {
+	a := assert.New(t)
+	p := &Parser{
+		KeepOrder: true,
+	}
+	str := `<json>{"z":1,"a":{"y":true,"b":[{"d":1,"c":2},3]},"m":"m"}`
+	d, err := p.Parse(str)
+	a.NilError(err)
+	a.Equal(d.String(), str)
+	a.Equal(d.Keys(), []string{"z", "a", "m"})
+	a.Equal(d.JSON(true), `{
+	"z": 1,
+	"a": {
+		"y": true,
+		"b": [
+			{
+				"d": 1,
+				"c": 2
+			},
+			3
+		]
+	},
+	"m": "m"
+}`)
+
+	// 默认不记录顺序。
+	unordered, err := Parse(str)
+	a.NilError(err)
+	a.Equal(unordered.String(), `<json>{"a":{"b":[{"c":2,"d":1},3],"y":true},"m":"m","z":1}`)
+	a.Equal(unordered.Keys(), []string{"a", "m", "z"})
+}
+
+func TestMergeKeepOrder(t *testing.T) {
+	a := assert.New(t)
+	p := &Parser{
+		KeepOrder: true,
+	}
+	d1, err := p.ParseJSON(`{"z":1,"a":{"y":true,"x":[{"d":1,"c":2}]}}`)
+	a.NilError(err)
+	d2, err := p.ParseJSON(`{"n":2,"a":{"w":1,"y":false,"x":[{"f":1,"e":2}]}}`)
+	a.NilError(err)
+	d3 := Make(RawData{
+		"k2": 1,
+		"k1": 2,
+		"z":  RawData{"q": 1, "p": 2},
+	})
+
+	merged := Merge(d1, d2, d3)
+	a.Equal(merged.JSON(false), `{"z":{"p":2,"q":1},"a":{"y":false,"x":[{"d":1,"c":2},{"f":1,"e":2}],"w":1},"n":2,"k1":2,"k2":1}`)
+
+	// 合并的结果不会修改原来的值。
+	a.Equal(d1.JSON(false), `{"z":1,"a":{"y":true,"x":[{"d":1,"c":2}]}}`)
+
+	// 第一个 Data 没有顺序，则结果也没有顺序。
+	a.Equal(Merge(d3, d1).JSON(false), `{"a":{"x":[{"c":2,"d":1}],"y":true},"k1":2,"k2":1,"z":1}`)
+
+	cloned := d1.Clone()
+	MergeTo(&cloned, d2)
+	a.Equal(cloned.JSON(false), `{"z":1,"a":{"y":false,"x":[{"d":1,"c":2},{"f":1,"e":2}],"w":1},"n":2}`)
+}
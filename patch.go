@@ -1,20 +1,33 @@
 package data
 
 import (
-	"fmt"
+	"context"
 	"reflect"
 	"sort"
+	"time"
 )
 
 // Patch 代表一系列的对 Data 的修改操作。
 type Patch struct {
 	actions []*PatchAction
+	before  []PatchHook
+	after   []PatchHook
 }
 
+// PatchHook 是应用 action 前后调用的回调函数，target 是当时 Data 的一份快照，修改它不会影响 patch 的结果。
+// 如果 PatchHook 返回错误，patch 会立即中止并且将这个错误返回给调用者。
+type PatchHook func(action *PatchAction, target Data) error
+
 // PatchAction 代表一个 patch 操作。
 type PatchAction struct {
-	Deletes []string        `data:"deletes"`
-	Updates map[string]Data `data:"updates"`
+	Deletes       []string        `data:"deletes"`
+	DeleteMatches map[string]Data `data:"delete_matches,omitempty"`
+	Replaces      map[string]Data `data:"replaces,omitempty"`
+	Updates       map[string]Data `data:"updates"`
+
+	// Expires 是 query 对应的值的过期时间，只有 `TTLData` 会记录这些时间并在过期后删除对应的值，
+	// 直接将 action 应用到 Data 上时会忽略这个字段。
+	Expires map[string]time.Time `data:"expires,omitempty"`
 }
 
 // NewPatch 创建一个新 Patch 对象。
@@ -29,7 +42,7 @@ func NewPatch() *Patch {
 //
 // 需要注意，`Patch#Apply`/`Patch#ApplyTo` 使用 `Merge`/`MergeTo` 来更新 Data，
 // merge 系列函数会深度遍历 map/slice，这导致新值无法简单覆盖老值。
-// 如果希望新值覆盖老值，而不是合并，那么得先用 deletes 删除老值再合并。
+// 如果希望新值覆盖老值，而不是合并，应该使用 `Patch#Replace`。
 func (patch *Patch) Add(deletes []string, updates map[string]Data) {
 	patch.actions = append(patch.actions, &PatchAction{
 		Deletes: deletes,
@@ -37,6 +50,116 @@ func (patch *Patch) Add(deletes []string, updates map[string]Data) {
 	})
 }
 
+// DeleteMatched 增加一个新的 patch 操作，删除 query 对应数组中所有符合 cond 条件的元素。
+// 与使用下标删除数组元素不同，这种删除方式不受 patch 创建之后数组变化的影响。
+//
+// cond 的 key 是数组元素中的 query，value 是期望的值，只有元素中所有 query 的值都与期望值相同时才会被删除。
+// 比如 cond 为 {"disabled": true} 时，会删除所有 "disabled" 为 true 的元素。
+// 如果 cond 的 key 是空字符串，则代表比较元素本身，比如 {"": "foo"} 会删除数组中所有的 "foo"。
+// 如果 cond 为空，不会删除任何元素。
+//
+// 如果 query 对应的值不存在或者不是数组，apply 时会直接忽略，不会报错。
+func (patch *Patch) DeleteMatched(query string, cond Data) {
+	patch.actions = append(patch.actions, &PatchAction{
+		DeleteMatches: map[string]Data{
+			query: cond,
+		},
+	})
+}
+
+// Replace 增加一个新的 patch 操作，将 query 对应的值整个替换成 d，而不是与原值合并。
+// query 对应的原值可以是任意类型，如果原值不存在，会新建这个值；
+// 如果 query 为空字符串，则替换整个 Data。
+//
+// 如果 query 的上一级不存在，或者上一级是一个 slice 但下标越界、元素类型不是 RawData，
+// apply 时会报错。
+func (patch *Patch) Replace(query string, d Data) {
+	patch.actions = append(patch.actions, &PatchAction{
+		Replaces: map[string]Data{
+			query: d,
+		},
+	})
+}
+
+// DeletePath 增加一个新的 patch 操作，删除 paths 对应的值，规则与 `Patch#Add` 中的 deletes 相同。
+func (patch *Patch) DeletePath(paths ...Path) {
+	deletes := make([]string, 0, len(paths))
+
+	for _, p := range paths {
+		deletes = append(deletes, p.String())
+	}
+
+	patch.Add(deletes, nil)
+}
+
+// UpdatePath 增加一个新的 patch 操作，将 d 合并到 p 对应的值上，规则与 `Patch#Add` 中的 updates 相同。
+func (patch *Patch) UpdatePath(p Path, d Data) {
+	patch.Add(nil, map[string]Data{
+		p.String(): d,
+	})
+}
+
+// ReplacePath 增加一个新的 patch 操作，将 p 对应的值整个替换成 d，规则与 `Patch#Replace` 相同。
+func (patch *Patch) ReplacePath(p Path, d Data) {
+	patch.Replace(p.String(), d)
+}
+
+// UpdateUntil 与 `Patch#Add` 中的 updates 相同，将 d 合并到 query 对应的值上，
+// 同时将 d 中每个顶层 key 对应的值标记为在 at 时过期，比如将 {"qps": 200} 合并到 "limits" 上时，
+// "limits.qps" 会在 at 时过期。过期时间只有通过 `TTLData` 应用时才会生效。
+func (patch *Patch) UpdateUntil(query string, d Data, at time.Time) {
+	fields := queryFields(query)
+	expires := make(map[string]time.Time, d.Len())
+
+	for k := range d.data {
+		expires[JoinQuery(append(fields[:len(fields):len(fields)], k)...)] = at
+	}
+
+	patch.actions = append(patch.actions, &PatchAction{
+		Updates: map[string]Data{
+			query: d,
+		},
+		Expires: expires,
+	})
+}
+
+// ReplaceUntil 与 `Patch#Replace` 相同，将 query 对应的值整个替换成 d，同时将这个值标记为在 at 时过期。
+// 过期时间只有通过 `TTLData` 应用时才会生效，query 不能是空字符串。
+func (patch *Patch) ReplaceUntil(query string, d Data, at time.Time) {
+	patch.actions = append(patch.actions, &PatchAction{
+		Replaces: map[string]Data{
+			query: d,
+		},
+		Expires: map[string]time.Time{
+			query: at,
+		},
+	})
+}
+
+// AddAction 增加一个已经构造好的 patch 操作，这适合用来还原从其他格式（比如 protobuf）转换回来的 action。
+// action 的执行规则详见 `PatchAction#ApplyTo`。
+func (patch *Patch) AddAction(action *PatchAction) {
+	if action == nil {
+		return
+	}
+
+	patch.actions = append(patch.actions, action)
+}
+
+// OnBeforeAction 增加一个在应用每个 action 之前调用的回调，可以用来校验变更是否合法。
+// 多个回调按照增加的顺序调用。
+func (patch *Patch) OnBeforeAction(hook PatchHook) {
+	patch.before = append(patch.before, hook)
+}
+
+// OnAfterAction 增加一个在每个 action 成功应用之后调用的回调，可以用来审计变更。
+// 多个回调按照增加的顺序调用。
+//
+// 需要注意，调用 ApplyTo 时，即使回调返回错误，这个 action 也已经应用到 target 上了。
+func (patch *Patch) OnAfterAction(hook PatchHook) {
+	patch.after = append(patch.after, hook)
+}
+
 // Actions 返回所有的 action。
 func (patch *Patch) Actions() []*PatchAction {
 	return patch.actions
@@ -47,7 +170,9 @@ func (patch *Patch) Actions() []*PatchAction {
 //
 // Apply 在如下情况下报错：
 //     * updates 的某个 query 无法找到对应元素；
-//     * updates 的某个 query 查询出的结果并不是一个 RawData。
+//     * updates 的某个 query 查询出的结果并不是一个 RawData；
+//     * replaces 的某个 query 无法设置，具体条件见 `Patch#Replace`；
+//     * `Patch#OnBeforeAction` 或 `Patch#OnAfterAction` 设置的回调返回错误。
 func (patch *Patch) Apply(d Data) (applied Data, err error) {
 	d = d.Clone()
 
@@ -59,16 +184,68 @@ func (patch *Patch) Apply(d Data) (applied Data, err error) {
 	return
 }
 
+// Preview 模拟将所有变更应用在 d 上，返回应用后的结果以及与 d 相比的所有变更，d 本身不会受到任何影响。
+// 这适合在真正应用变更之前向审核者展示这个 patch 具体会做什么。
+//
+// Preview 的出错条件与 Apply 相同，回调也会像 Apply 一样被调用。
+func (patch *Patch) Preview(d Data) (applied Data, report *DiffReport, err error) {
+	if applied, err = patch.Apply(d); err != nil {
+		return
+	}
+
+	report = Diff(d, applied)
+	return
+}
+
 // ApplyTo 将变更直接应用于 target 上，将会修改 target 内部值。
 //
 // ApplyTo 的出错条件与 Apply 相同。
 func (patch *Patch) ApplyTo(target *Data) error {
+	return patch.applyTo(target, nil)
+}
+
+// ApplyToContext 与 ApplyTo 相同，但在应用变更的过程中会定期检查 ctx，
+// 如果 ctx 已经结束则放弃剩余的变更并返回 ctx.Err()，合并 Update 的数据时也会检查 ctx。
+// 需要注意，放弃时 target 可能已经被应用了部分变更，如果需要保证原子性应该使用 Apply 并替换原值。
+func (patch *Patch) ApplyToContext(ctx context.Context, target *Data) error {
+	return patch.applyTo(target, newContextChecker(ctx))
+}
+
+func (patch *Patch) applyTo(target *Data, checker *contextChecker) error {
+	if err := checker.error(); err != nil {
+		return err
+	}
+
 	if target == nil {
 		return nil
 	}
 
 	for _, action := range patch.actions {
-		if err := action.ApplyTo(target); err != nil {
+		if err := runPatchHooks(patch.before, action, *target); err != nil {
+			return err
+		}
+
+		if err := action.applyTo(target, checker); err != nil {
+			return err
+		}
+
+		if err := runPatchHooks(patch.after, action, *target); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func runPatchHooks(hooks []PatchHook, action *PatchAction, target Data) error {
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	snapshot := target.Clone()
+
+	for _, hook := range hooks {
+		if err := hook(action, snapshot); err != nil {
 			return err
 		}
 	}
@@ -77,43 +254,123 @@ func (patch *Patch) ApplyTo(target *Data) error {
 }
 
 // ApplyTo 将一个 action 应用到 target。
+// 执行顺序是先删除 deletes 和 delete_matches，再用 replaces 覆盖，最后用 updates 合并更新。
 func (action *PatchAction) ApplyTo(target *Data) error {
+	return action.applyTo(target, nil)
+}
+
+func (action *PatchAction) applyTo(target *Data, checker *contextChecker) error {
+	if err := checker.check(); err != nil {
+		return err
+	}
+
 	data := target.data
 
 	// 先删除。
 	data.Delete(action.Deletes...)
 	target.data = data // Delete 可能重置 data 内容。
 
-	if len(action.Updates) == 0 {
-		return nil
+	for _, query := range sortedQueries(action.DeleteMatches) {
+		if err := checker.check(); err != nil {
+			return err
+		}
+
+		target.data.deleteMatched(query, action.DeleteMatches[query])
 	}
 
-	// 再更新。
-	queries := make([]string, 0, len(action.Updates))
+	// 再覆盖。
+	for _, query := range sortedQueries(action.Replaces) {
+		if err := checker.check(); err != nil {
+			return err
+		}
 
-	for query := range action.Updates {
-		queries = append(queries, query)
+		v := RawData{}
+
+		if d := action.Replaces[query]; d.Len() != 0 {
+			v = cloneRawData(d.data)
+		}
+
+		if query == "" {
+			target.data = v
+			target.order = nil
+			continue
+		}
+
+		if target.data == nil {
+			target.data = RawData{}
+		}
+
+		if !target.data.set(query, v) {
+			return &PatchError{
+				Op:    "replacing",
+				Query: query,
+				Err:   ErrQueryNotFound,
+			}
+		}
 	}
 
-	// 需要以字典序升序排列，这样处理起来能保证先处理上层数据，再下层。
-	// 比如，同时有更新 "a" 和 "a.b" 时候，保证 "a" 先执行。
-	sort.Strings(queries)
+	data = target.data
+
+	if len(action.Updates) == 0 {
+		return nil
+	}
+
+	// 最后更新。
+	for _, query := range sortedQueries(action.Updates) {
+		if err := checker.check(); err != nil {
+			return err
+		}
 
-	for _, query := range queries {
 		v := data.Query(query)
 
 		if v == nil {
-			return fmt.Errorf("go-data: fail to apply patch due to invalid query `%v` when updating", query)
+			return &PatchError{
+				Op:    "updating",
+				Query: query,
+				Err:   ErrQueryNotFound,
+			}
 		}
 
-		d, ok := v.(RawData)
+		var d RawData
+		ok := false
+
+		switch val := v.(type) {
+		case RawData:
+			d, ok = val, true
+		case Data:
+			// 嵌在树中的空 Data 没有可以合并的 RawData。
+			d, ok = val.data, val.data != nil
+		}
 
 		if !ok {
-			return fmt.Errorf("go-data: fail to apply patch due to query `%v` pointing to a value in unsupported type", query)
+			return &PatchError{
+				Op:    "updating",
+				Query: query,
+				Err:   ErrNotObject,
+			}
 		}
 
-		merge(reflect.ValueOf(d), action.Updates[query].data)
+		m := merger{checker: checker}
+		m.merge(reflect.ValueOf(d), action.Updates[query].data)
+
+		if err := checker.error(); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
+
+// sortedQueries 返回 m 中所有的 query。
+// 这些 query 以字典序升序排列，这样处理起来能保证先处理上层数据，再下层。
+// 比如，同时有更新 "a" 和 "a.b" 时候，保证 "a" 先执行。
+func sortedQueries(m map[string]Data) []string {
+	queries := make([]string, 0, len(m))
+
+	for query := range m {
+		queries = append(queries, query)
+	}
+
+	sort.Strings(queries)
+	return queries
+}
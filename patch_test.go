@@ -1,6 +1,7 @@
 package data
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
@@ -263,3 +264,330 @@ func TestPatch(t *testing.T) {
 
 	}
 }
+
+func TestPatchReplace(t *testing.T) {
+	cases := []struct {
+		Query    string
+		Value    Data
+		Target   Data
+		Result   Data
+		HasError bool
+	}{
+		{ // 替换整个 Data。
+			"",
+			Make(RawData{"a": 1}),
+			fullData,
+			Make(RawData{"a": 1}),
+			false,
+		},
+		{ // 替换不同类型的值。
+			"v1",
+			Make(RawData{"a": 1}),
+			Make(RawData{
+				"v1": []int{1, 2},
+				"v2": 2,
+			}),
+			Make(RawData{
+				"v1": RawData{"a": 1},
+				"v2": 2,
+			}),
+			false,
+		},
+		{ // 替换而不是合并。
+			"v1.map",
+			Make(RawData{"new": true}),
+			Make(RawData{
+				"v1": RawData{
+					"map": RawData{
+						"old": true,
+					},
+				},
+			}),
+			Make(RawData{
+				"v1": RawData{
+					"map": RawData{
+						"new": true,
+					},
+				},
+			}),
+			false,
+		},
+		{ // 新建不存在的值。
+			"v2",
+			Data{},
+			Make(RawData{
+				"v1": 1,
+			}),
+			Make(RawData{
+				"v1": 1,
+				"v2": RawData{},
+			}),
+			false,
+		},
+		{ // 替换数组元素。
+			"arr.1",
+			Make(RawData{"new": 1}),
+			Make(RawData{
+				"arr": []RawData{{"old": 0}, {"old": 1}},
+			}),
+			Make(RawData{
+				"arr": []RawData{{"old": 0}, {"new": 1}},
+			}),
+			false,
+		},
+		{ // 替换空 Data 中的值。
+			"v1",
+			Make(RawData{"a": 1}),
+			Data{},
+			Make(RawData{
+				"v1": RawData{"a": 1},
+			}),
+			false,
+		},
+		{ // 上一级不存在。
+			"foo.bar",
+			Make(RawData{"a": 1}),
+			Make(RawData{
+				"v1": 1,
+			}),
+			Data{},
+			true,
+		},
+		{ // 数组元素类型不匹配。
+			"arr.0",
+			Make(RawData{"a": 1}),
+			Make(RawData{
+				"arr": []int{1, 2},
+			}),
+			Data{},
+			true,
+		},
+		{ // 数组越界。
+			"arr.2",
+			Make(RawData{"a": 1}),
+			Make(RawData{
+				"arr": []RawData{{}, {}},
+			}),
+			Data{},
+			true,
+		},
+	}
+	a := assert.New(t)
+
+	for i, c := range cases {
+		a.Use(&i, &c)
+
+		p := NewPatch()
+		p.Replace(c.Query, c.Value)
+		applied, err := p.Apply(c.Target)
+
+		if c.HasError {
+			a.NonNilError(err)
+			continue
+		}
+
+		a.NilError(err)
+		a.Equal(c.Result, applied)
+
+		// 替换的值需要是一份拷贝。
+		expected := c.Value.JSON(false)
+		applied.Query(c.Query).(RawData)["changed"] = true
+		a.Equal(c.Value.JSON(false), expected)
+	}
+}
+
+func TestPatchDeleteMatched(t *testing.T) {
+	cases := []struct {
+		Query  string
+		Cond   Data
+		Target Data
+		Result Data
+	}{
+		{ // 按字段删除。
+			"list",
+			Make(RawData{"disabled": true}),
+			Make(RawData{
+				"list": []RawData{
+					{"name": "a", "disabled": true},
+					{"name": "b"},
+					{"name": "c", "disabled": false},
+					{"name": "d", "disabled": true},
+				},
+			}),
+			Make(RawData{
+				"list": []RawData{
+					{"name": "b"},
+					{"name": "c", "disabled": false},
+				},
+			}),
+		},
+		{ // 多个条件，且条件是 query。
+			"v1.list",
+			Make(RawData{
+				"meta.env":  "prod",
+				"meta.port": 80,
+			}),
+			Make(RawData{
+				"v1": RawData{
+					"list": []interface{}{
+						RawData{"meta": RawData{"env": "prod", "port": 80}},
+						RawData{"meta": RawData{"env": "prod", "port": 81}},
+						"not-a-map",
+					},
+				},
+			}),
+			Make(RawData{
+				"v1": RawData{
+					"list": []interface{}{
+						RawData{"meta": RawData{"env": "prod", "port": 81}},
+						"not-a-map",
+					},
+				},
+			}),
+		},
+		{ // 比较元素本身。
+			"tags",
+			Make(RawData{"": "old"}),
+			Make(RawData{
+				"tags": []string{"old", "new", "old"},
+			}),
+			Make(RawData{
+				"tags": []string{"new"},
+			}),
+		},
+		{ // 空条件不删除任何元素。
+			"tags",
+			Data{},
+			Make(RawData{
+				"tags": []string{"old"},
+			}),
+			Make(RawData{
+				"tags": []string{"old"},
+			}),
+		},
+		{ // 不是数组，直接忽略。
+			"tags",
+			Make(RawData{"": "old"}),
+			Make(RawData{
+				"tags": "old",
+			}),
+			Make(RawData{
+				"tags": "old",
+			}),
+		},
+		{ // 不存在，直接忽略。
+			"foo.bar",
+			Make(RawData{"": "old"}),
+			Make(RawData{
+				"tags": "old",
+			}),
+			Make(RawData{
+				"tags": "old",
+			}),
+		},
+	}
+	a := assert.New(t)
+
+	for i, c := range cases {
+		a.Use(&i, &c)
+
+		p := NewPatch()
+		p.DeleteMatched(c.Query, c.Cond)
+		target := c.Target.Clone()
+		applied, err := p.Apply(c.Target)
+		a.NilError(err)
+		a.Equal(c.Result, applied)
+
+		// 原值不能被修改。
+		a.Equal(c.Target, target)
+	}
+}
+
+func TestPatchHooks(t *testing.T) {
+	a := assert.New(t)
+	errForbidden := errors.New("forbidden")
+	var logs []string
+
+	patch := NewPatch()
+	patch.Replace("v1", Make(RawData{"a": 1}))
+	patch.Replace("v2", Make(RawData{"b": 2}))
+	patch.Replace("admin", Make(RawData{"c": 3}))
+	patch.OnBeforeAction(func(action *PatchAction, target Data) error {
+		if _, ok := action.Replaces["admin"]; ok {
+			return errForbidden
+		}
+
+		// 修改快照不会影响结果。
+		target.data["snapshot"] = true
+		logs = append(logs, "before:"+target.JSON(false))
+		return nil
+	})
+	patch.OnAfterAction(func(action *PatchAction, target Data) error {
+		logs = append(logs, "after:"+target.JSON(false))
+		return nil
+	})
+
+	d := Make(RawData{"v0": 0})
+	_, err := patch.Apply(d)
+	a.Equal(err, errForbidden)
+
+	err = patch.ApplyTo(&d)
+	a.Equal(err, errForbidden)
+	a.Equal(d, Make(RawData{
+		"v0": 0,
+		"v1": RawData{"a": 1},
+		"v2": RawData{"b": 2},
+	}))
+	a.Equal(logs[4:], []string{
+		`before:{"snapshot":true,"v0":0}`,
+		`after:{"v0":0,"v1":{"a":1}}`,
+		`before:{"snapshot":true,"v0":0,"v1":{"a":1}}`,
+		`after:{"v0":0,"v1":{"a":1},"v2":{"b":2}}`,
+	})
+}
+
+func TestPatchPreview(t *testing.T) {
+	a := assert.New(t)
+	d := Make(RawData{
+		"v1": 1,
+		"v2": RawData{
+			"v2-1": "old",
+		},
+	})
+	patch := NewPatch()
+	patch.Add([]string{"v1"}, map[string]Data{
+		"v2": Make(RawData{
+			"v2-1": "new",
+			"v2-2": true,
+		}),
+	})
+
+	applied, report, err := patch.Preview(d)
+	a.NilError(err)
+	a.Equal(applied, Make(RawData{
+		"v2": RawData{
+			"v2-1": "new",
+			"v2-2": true,
+		},
+	}))
+	a.Equal(report.Changes, []DiffChange{
+		{DiffRemoved, "v1", int64(1), nil},
+		{DiffChanged, "v2.v2-1", "old", "new"},
+		{DiffAdded, "v2.v2-2", nil, true},
+	})
+
+	// d 没有被修改。
+	a.Equal(d, Make(RawData{
+		"v1": 1,
+		"v2": RawData{
+			"v2-1": "old",
+		},
+	}))
+
+	patch.Add(nil, map[string]Data{
+		"not_exist": Make(RawData{"a": 1}),
+	})
+	_, report, err = patch.Preview(d)
+	a.NonNilError(err)
+	a.Assert(report == nil)
+}
@@ -0,0 +1,112 @@
+package data
+
+import (
+	"strings"
+)
+
+const (
+	querySeparator = '.'
+	queryEscape    = '\\'
+)
+
+// SplitQuery 将 query 拆分成字段，拆分的规则与 `Data#Query` 相同。
+//
+// 字段之间以“.”分隔，如果 key 本身包含“.”，需要写成“\.”，key 中的“\”可以写成“\\”，
+// 比如 `a\.b.c` 代表 d["a.b"]["c"]。其他位置的“\”会原样保留。
+// 与 `strings.Split` 一样，空字符串会被拆分成一个空字段。
+func SplitQuery(query string) []string {
+	if strings.IndexByte(query, queryEscape) < 0 {
+		return strings.Split(query, string(querySeparator))
+	}
+
+	var fields []string
+	buf := make([]byte, 0, len(query))
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+
+		switch c {
+		case querySeparator:
+			fields = append(fields, string(buf))
+			buf = buf[:0]
+			continue
+
+		case queryEscape:
+			if i+1 < len(query) {
+				if next := query[i+1]; next == querySeparator || next == queryEscape {
+					buf = append(buf, next)
+					i++
+					continue
+				}
+			}
+		}
+
+		buf = append(buf, c)
+	}
+
+	return append(fields, string(buf))
+}
+
+// JoinQuery 将 fields 拼接成 query，每个字段中的“.”和“\”都会被转义，是 `SplitQuery` 的逆操作。
+// `Diff`、`Data#FindKey` 等返回的路径都使用这个格式，可以直接用于 `Data#Query` 或者 `Patch`。
+func JoinQuery(fields ...string) string {
+	buf := &strings.Builder{}
+
+	for i, f := range fields {
+		if i != 0 {
+			buf.WriteByte(querySeparator)
+		}
+
+		if strings.IndexByte(f, querySeparator) < 0 && strings.IndexByte(f, queryEscape) < 0 {
+			buf.WriteString(f)
+			continue
+		}
+
+		for j := 0; j < len(f); j++ {
+			if c := f[j]; c == querySeparator || c == queryEscape {
+				buf.WriteByte(queryEscape)
+			}
+
+			buf.WriteByte(f[j])
+		}
+	}
+
+	return buf.String()
+}
+
+// Path 是解析过的 query，每个元素是一级 key 或者数组下标，key 中的“.”不需要转义。
+// 在代码中构造路径时应该使用 Path，而不是拼接字符串，这样 key 中的特殊字符总是会被正确处理。
+//
+// 由于 Path 本身就是 []string，可以直接用于 `Data#Get`，比如 `d.Get(p...)`；
+// 需要 query 字符串的地方可以使用 `Path#String`。空的 Path 代表根节点。
+type Path []string
+
+// ParsePath 使用与 `Data#Query` 相同的规则解析 query，空字符串代表根节点。
+func ParsePath(query string) Path {
+	if query == "" {
+		return nil
+	}
+
+	return Path(SplitQuery(query))
+}
+
+// Append 返回在 p 后追加 fields 之后的新 Path，p 本身不会被修改。
+func (p Path) Append(fields ...string) Path {
+	appended := make(Path, 0, len(p)+len(fields))
+	appended = append(appended, p...)
+	return append(appended, fields...)
+}
+
+// Parent 返回 p 的上一级路径，根节点的上一级依然是根节点。
+func (p Path) Parent() Path {
+	if len(p) == 0 {
+		return nil
+	}
+
+	return p[:len(p)-1:len(p)-1]
+}
+
+// String 返回转义后的 query，可以用于 `Data#Query`、`Patch` 等接受 query 字符串的地方。
+func (p Path) String() string {
+	return JoinQuery(p...)
+}
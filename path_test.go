@@ -0,0 +1,131 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestSplitQuery(t *testing.T) {
+	cases := []struct {
+		Query  string
+		Fields []string
+	}{
+		{"", []string{""}},
+		{"a", []string{"a"}},
+		{"a.b.0", []string{"a", "b", "0"}},
+		{`a\.b.c`, []string{"a.b", "c"}},
+		{`a\\.b`, []string{`a\`, "b"}},
+		{`a\\\.b`, []string{`a\.b`}},
+		{`a\b.c\`, []string{`a\b`, `c\`}},
+		{`\.`, []string{"."}},
+		{`a.\.`, []string{"a", "."}},
+	}
+
+	for i, c := range cases {
+		a := assert.New(t)
+		a.Use(&i, &c)
+
+		fields := SplitQuery(c.Query)
+		a.Equal(fields, c.Fields)
+		a.Equal(SplitQuery(JoinQuery(fields...)), c.Fields)
+	}
+
+	a := assert.New(t)
+	a.Equal(JoinQuery("a.b", `c\`, "d"), `a\.b.c\\.d`)
+	a.Equal(JoinQuery(), "")
+}
+
+func TestEscapedQuery(t *testing.T) {
+	a := assert.New(t)
+	before := Make(RawData{
+		"example.com": RawData{
+			"port": 80,
+			"tags": []string{"a"},
+		},
+		"example": RawData{
+			"com": 1,
+		},
+	})
+
+	a.Equal(before.Query(`example\.com.port`), int64(80))
+	a.Equal(before.Query("example.com"), int64(1))
+	a.Equal(before.FindKey("port"), []string{`example\.com.port`})
+
+	p := NewPatch()
+	p.Add([]string{`example\.com.tags`}, map[string]Data{
+		`example\.com`: Make(RawData{"port": 443}),
+	})
+	p.Replace(`example\.com.tls`, Make(RawData{"on": true}))
+	after, err := p.Apply(before)
+	a.NilError(err)
+	a.Equal(after.Query(`example\.com.port`), int64(443))
+	a.Equal(after.Query(`example\.com.tags`), nil)
+	a.Equal(after.Query(`example\.com.tls.on`), true)
+	a.Equal(after.Query("example.com"), int64(1))
+
+	report := Diff(before, after)
+	a.Equal(report.String(), "~ example\\.com.port: 80 -> 443\n- example\\.com.tags: [\"a\"]\n+ example\\.com.tls: {\"on\":true}\n")
+
+	for _, c := range report.Changes {
+		a.Equal(after.Query(c.Path), c.New)
+	}
+
+	sd := NewShardedData(after, 4)
+	a.Equal(sd.Query(`example\.com.port`), int64(443))
+}
+
+func TestPath(t *testing.T) {
+	a := assert.New(t)
+	a.Equal(ParsePath(""), Path(nil))
+	a.Equal(ParsePath(`hosts.example\.com.port`), Path{"hosts", "example.com", "port"})
+
+	p := Path{"hosts"}
+	host := p.Append("example.com")
+	port := host.Append("port")
+	tls := host.Append("tls")
+	a.Equal(p, Path{"hosts"})
+	a.Equal(port.String(), `hosts.example\.com.port`)
+	a.Equal(tls.String(), `hosts.example\.com.tls`)
+	a.Equal(port.Parent(), host)
+	a.Equal(Path{"a"}.Parent(), Path{})
+	a.Equal(Path(nil).Parent(), Path(nil))
+	a.Equal(Path(nil).String(), "")
+
+	// Parent 返回的 Path 追加元素时不会影响原来的 Path。
+	a.Equal(port.Parent().Append("x"), Path{"hosts", "example.com", "x"})
+	a.Equal(port, Path{"hosts", "example.com", "port"})
+
+	d := Make(RawData{
+		"hosts": RawData{},
+	})
+	a.NilError(d.Set(port, 80))
+	a.NilError(d.Set(host.Append("tags"), []string{"a", "b"}))
+	a.Equal(d.Get(port...), int64(80))
+	a.Equal(d.Query(port.String()), int64(80))
+	a.Equal(d.Get(host.Append("tags", "1")...), "b")
+	a.NonNilError(d.Set(port.Append("x"), 1))
+	a.NonNilError(d.Set(nil, 1))
+
+	patch := NewPatch()
+	patch.UpdatePath(host, Make(RawData{"port": 443}))
+	patch.ReplacePath(tls, Make(RawData{"on": true}))
+	patch.DeletePath(host.Append("tags"))
+	applied, err := patch.Apply(d)
+	a.NilError(err)
+	a.Equal(applied, Make(RawData{
+		"hosts": RawData{
+			"example.com": RawData{
+				"port": 443,
+				"tls":  RawData{"on": true},
+			},
+		},
+	}))
+
+	raw := applied.Clone().data
+	raw.DeletePath(tls, Path{"not_exist"})
+	a.Equal(raw.Query(tls.String()), nil)
+	a.Equal(raw.Query(port.String()), int64(443))
+	raw.DeletePath(nil)
+	a.Equal(len(raw), 0)
+}
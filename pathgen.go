@@ -0,0 +1,311 @@
+package data
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"reflect"
+	"strconv"
+)
+
+// PathGenerator 根据 struct 的定义生成类型安全的路径访问代码，
+// 生成的代码可以代替手写的 query 字符串，当 struct 字段改名或者删除时，使用旧路径的代码会直接编译失败。
+//
+// 对于 Config 类型，生成的代码如下：
+//     type ConfigPaths data.Path
+//     var Config ConfigPaths
+//     func (p ConfigPaths) Server() ServerPaths  // struct 字段返回对应类型的 Paths。
+//     func (p ConfigPaths) Timeout() data.Path   // 其他字段直接返回 data.Path。
+//     func (p ConfigPaths) Items() data.Path     // struct 的 slice 或 array 字段。
+//     func (p ConfigPaths) ItemsAt(i int) ItemPaths
+//     func (p ConfigPaths) Refs() data.Path      // 以 string 为 key 的 struct map 字段。
+//     func (p ConfigPaths) RefsAt(key string) RefPaths
+// 使用时可以写成 `data.Path(paths.Config.Server())` 或者 `paths.Config.Server().Addr()`。
+//
+// 字段对应的 key 与 `Decoder` 的规则相同，设置了 squash 的字段会被展开到当前类型中。
+// 生成的代码只依赖 go-data，不会引用 struct 本身。
+// 由于根路径变量与 struct 同名，生成的代码必须放在一个单独的 package 中，比如 struct 所在目录下的 paths 子目录，
+// 否则 `var Config ConfigPaths` 会与 `type Config struct` 冲突而无法编译。
+//
+// 通常的用法是在 struct 所在的 package 中写一个带有 `//go:build ignore` 的小程序调用 Generate，
+// 将结果写入 paths 子目录，再通过 `//go:generate go run gen_paths.go` 生成代码。
+type PathGenerator struct {
+	Package string // 生成代码的 package 名，不能为空，也不能与 struct 放在同一个 package 中。
+	TagName string // 解析 struct 时使用的 field tag，默认是 data。
+}
+
+// pathType 是需要生成代码的一个 struct 类型。
+type pathType struct {
+	name    string
+	typ     reflect.Type
+	methods []pathMethod
+}
+
+// pathMethod 是生成的一个路径访问方法。
+type pathMethod struct {
+	name   string
+	key    string
+	result string // 返回的 Paths 类型名，为空代表返回 data.Path。
+	index  string // 为 "int" 或 "string" 时生成带参数的访问方法，参数分别是数组下标和 map 的 key。
+}
+
+// Generate 为 types 中的每个 struct 类型以及它们引用的所有 struct 类型生成路径访问代码，写入 w。
+// types 中的类型还会各自生成一个同名的变量作为根路径，因此 w 的内容不能与这些类型放在同一个 package 中。
+//
+// 如果 types 中有不是 struct 的类型，或者不同的类型生成了相同的名字、同一个类型中有同名的方法，返回错误。
+func (g *PathGenerator) Generate(w io.Writer, types ...reflect.Type) error {
+	if g.Package == "" {
+		return fmt.Errorf("go-data: package of PathGenerator must not be empty")
+	}
+
+	pg := &pathGen{
+		tagName: g.TagName,
+		names:   map[string]reflect.Type{},
+		seen:    map[reflect.Type]*pathType{},
+	}
+
+	if pg.tagName == "" {
+		pg.tagName = defaultTagName
+	}
+
+	roots := make([]*pathType, 0, len(types))
+
+	for _, t := range types {
+		for t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+
+		if t == nil || !isPathStruct(t) {
+			return fmt.Errorf("go-data: cannot generate paths for type %v which is not a struct", t)
+		}
+
+		if t.Name() == "" {
+			return fmt.Errorf("go-data: cannot generate paths for anonymous struct %v", t)
+		}
+
+		pt, err := pg.add(t, t.Name())
+
+		if err != nil {
+			return err
+		}
+
+		roots = append(roots, pt)
+	}
+
+	for i := 0; i < len(pg.types); i++ {
+		if err := pg.collect(pg.types[i], pg.types[i].typ); err != nil {
+			return err
+		}
+	}
+
+	src, err := format.Source(pg.source(g.Package, roots))
+
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(src)
+	return err
+}
+
+type pathGen struct {
+	tagName string
+	types   []*pathType
+	names   map[string]reflect.Type
+	seen    map[reflect.Type]*pathType
+}
+
+// add 记录需要生成代码的类型 t，如果 t 是匿名 struct，使用 name 作为名字。
+func (pg *pathGen) add(t reflect.Type, name string) (*pathType, error) {
+	if pt, ok := pg.seen[t]; ok {
+		return pt, nil
+	}
+
+	if t.Name() != "" {
+		name = t.Name()
+	}
+
+	if other, ok := pg.names[name]; ok {
+		return nil, fmt.Errorf("go-data: type %v and %v have the same path type name `%vPaths`", other, t, name)
+	}
+
+	pt := &pathType{
+		name: name,
+		typ:  t,
+	}
+	pg.names[name] = t
+	pg.seen[t] = pt
+	pg.types = append(pg.types, pt)
+	return pt, nil
+}
+
+// collect 将 struct 类型 t 的所有字段生成的方法加入 pt，squash 的字段会递归展开。
+func (pg *pathGen) collect(pt *pathType, t reflect.Type) error {
+	plan := newTypePlan(t, pg.tagName)
+
+	for _, fp := range plan.fields {
+		f := t.Field(fp.index)
+		ft := indirectType(fp.typ)
+
+		if fp.squash {
+			if err := pg.collect(pt, ft); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		method := pathMethod{
+			name: f.Name,
+			key:  fp.name,
+		}
+
+		if isPathStruct(ft) {
+			elem, err := pg.add(ft, pt.name+f.Name)
+
+			if err != nil {
+				return err
+			}
+
+			method.result = elem.name + "Paths"
+
+			if err := pt.addMethod(method); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err := pt.addMethod(method); err != nil {
+			return err
+		}
+
+		var index string
+
+		switch ft.Kind() {
+		case reflect.Slice, reflect.Array:
+			index = "int"
+		case reflect.Map:
+			if ft.Key().Kind() == reflect.String {
+				index = "string"
+			}
+		}
+
+		if index == "" {
+			continue
+		}
+
+		et := indirectType(ft.Elem())
+
+		if !isPathStruct(et) {
+			continue
+		}
+
+		elem, err := pg.add(et, pt.name+f.Name+"Elem")
+
+		if err != nil {
+			return err
+		}
+
+		if err := pt.addMethod(pathMethod{
+			name:   f.Name + "At",
+			key:    fp.name,
+			result: elem.name + "Paths",
+			index:  index,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (pt *pathType) addMethod(method pathMethod) error {
+	for _, m := range pt.methods {
+		if m.name == method.name {
+			return fmt.Errorf("go-data: duplicated path method `%v` in type %v", method.name, pt.typ)
+		}
+	}
+
+	pt.methods = append(pt.methods, method)
+	return nil
+}
+
+func (pg *pathGen) source(pkg string, roots []*pathType) []byte {
+	buf := &bytes.Buffer{}
+	needStrconv := false
+
+	for _, pt := range pg.types {
+		for _, m := range pt.methods {
+			if m.index == "int" {
+				needStrconv = true
+			}
+		}
+	}
+
+	fmt.Fprintf(buf, "// Code generated by go-data PathGenerator. DO NOT EDIT.\n\n")
+	fmt.Fprintf(buf, "package %v\n\n", pkg)
+	fmt.Fprintf(buf, "import (\n")
+
+	if needStrconv {
+		fmt.Fprintf(buf, "\"strconv\"\n\n")
+	}
+
+	fmt.Fprintf(buf, "data \"github.com/altstory/go-data\"\n)\n\n")
+
+	if len(roots) != 0 {
+		fmt.Fprintf(buf, "var (\n")
+
+		for _, pt := range roots {
+			fmt.Fprintf(buf, "%v %vPaths // %v 的根路径。\n", pt.name, pt.name, pt.typ)
+		}
+
+		fmt.Fprintf(buf, ")\n\n")
+	}
+
+	for _, pt := range pg.types {
+		typeName := pt.name + "Paths"
+		fmt.Fprintf(buf, "// %v 是 %v 中字段的路径。\n", typeName, pt.typ)
+		fmt.Fprintf(buf, "type %v data.Path\n\n", typeName)
+
+		for _, m := range pt.methods {
+			key := strconv.Quote(m.key)
+
+			switch {
+			case m.index == "int":
+				fmt.Fprintf(buf, "// %v 返回 `%v` 中第 i 个元素的路径。\n", m.name, m.key)
+				fmt.Fprintf(buf, "func (p %v) %v(i int) %v {\n", typeName, m.name, m.result)
+				fmt.Fprintf(buf, "return %v(data.Path(p).Append(%v, strconv.Itoa(i)))\n}\n\n", m.result, key)
+			case m.index == "string":
+				fmt.Fprintf(buf, "// %v 返回 `%v` 中 key 对应的值的路径。\n", m.name, m.key)
+				fmt.Fprintf(buf, "func (p %v) %v(key string) %v {\n", typeName, m.name, m.result)
+				fmt.Fprintf(buf, "return %v(data.Path(p).Append(%v, key))\n}\n\n", m.result, key)
+			case m.result != "":
+				fmt.Fprintf(buf, "// %v 返回 `%v` 的路径。\n", m.name, m.key)
+				fmt.Fprintf(buf, "func (p %v) %v() %v {\n", typeName, m.name, m.result)
+				fmt.Fprintf(buf, "return %v(data.Path(p).Append(%v))\n}\n\n", m.result, key)
+			default:
+				fmt.Fprintf(buf, "// %v 返回 `%v` 的路径。\n", m.name, m.key)
+				fmt.Fprintf(buf, "func (p %v) %v() data.Path {\n", typeName, m.name)
+				fmt.Fprintf(buf, "return data.Path(p).Append(%v)\n}\n\n", key)
+			}
+		}
+	}
+
+	return buf.Bytes()
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t
+}
+
+// isPathStruct 判断 t 是否是一个需要按字段生成路径的 struct，
+// time.Time、Data 以及没有导出字段的 struct 会被当作普通的值。
+func isPathStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != typeOfTime && !t.AssignableTo(typeOfData) && hasExportedFields(t)
+}
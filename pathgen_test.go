@@ -0,0 +1,217 @@
+package data
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/huandu/go-assert"
+)
+
+type pathgenConfig struct {
+	Server  *pathgenServer            `data:"server"`
+	Timeout time.Duration             `data:"timeout"`
+	Items   []pathgenItem             `data:"items"`
+	Refs    map[string]*pathgenItem   `data:"refs"`
+	Tags    []string                  `data:"tags"`
+	Extra   Data                      `data:"extra"`
+	Limits  struct{ Max int }         `data:"limits"`
+	Common  pathgenCommon             `data:",squash"`
+	Ignored int                       `data:"-"`
+	ByID    map[int]pathgenItem       `data:"by_id"`
+	Nested  map[string][]*pathgenItem `data:"nested"`
+}
+
+type pathgenServer struct {
+	Addr    string    `data:"addr"`
+	Started time.Time `data:"started"`
+}
+
+type pathgenItem struct {
+	Name string `data:"name"`
+}
+
+type pathgenCommon struct {
+	Version int `data:"version"`
+}
+
+func TestPathGenerator(t *testing.T) {
+	a := assert.New(t)
+	g := &PathGenerator{
+		Package: "paths",
+	}
+	buf := &strings.Builder{}
+	a.NilError(g.Generate(buf, reflect.TypeOf(&pathgenConfig{})))
+	a.Equal(buf.String(), `// Code generated by go-data PathGenerator. DO NOT EDIT.
+
+package paths
+
+import (
+	"strconv"
+
+	data "github.com/altstory/go-data"
+)
+
+var (
+	pathgenConfig pathgenConfigPaths // data.pathgenConfig 的根路径。
+)
+
+// pathgenConfigPaths 是 data.pathgenConfig 中字段的路径。
+type pathgenConfigPaths data.Path
+
+// Server 返回 `+"`server`"+` 的路径。
+func (p pathgenConfigPaths) Server() pathgenServerPaths {
+	return pathgenServerPaths(data.Path(p).Append("server"))
+}
+
+// Timeout 返回 `+"`timeout`"+` 的路径。
+func (p pathgenConfigPaths) Timeout() data.Path {
+	return data.Path(p).Append("timeout")
+}
+
+// Items 返回 `+"`items`"+` 的路径。
+func (p pathgenConfigPaths) Items() data.Path {
+	return data.Path(p).Append("items")
+}
+
+// ItemsAt 返回 `+"`items`"+` 中第 i 个元素的路径。
+func (p pathgenConfigPaths) ItemsAt(i int) pathgenItemPaths {
+	return pathgenItemPaths(data.Path(p).Append("items", strconv.Itoa(i)))
+}
+
+// Refs 返回 `+"`refs`"+` 的路径。
+func (p pathgenConfigPaths) Refs() data.Path {
+	return data.Path(p).Append("refs")
+}
+
+// RefsAt 返回 `+"`refs`"+` 中 key 对应的值的路径。
+func (p pathgenConfigPaths) RefsAt(key string) pathgenItemPaths {
+	return pathgenItemPaths(data.Path(p).Append("refs", key))
+}
+
+// Tags 返回 `+"`tags`"+` 的路径。
+func (p pathgenConfigPaths) Tags() data.Path {
+	return data.Path(p).Append("tags")
+}
+
+// Extra 返回 `+"`extra`"+` 的路径。
+func (p pathgenConfigPaths) Extra() data.Path {
+	return data.Path(p).Append("extra")
+}
+
+// Limits 返回 `+"`limits`"+` 的路径。
+func (p pathgenConfigPaths) Limits() pathgenConfigLimitsPaths {
+	return pathgenConfigLimitsPaths(data.Path(p).Append("limits"))
+}
+
+// Version 返回 `+"`version`"+` 的路径。
+func (p pathgenConfigPaths) Version() data.Path {
+	return data.Path(p).Append("version")
+}
+
+// ByID 返回 `+"`by_id`"+` 的路径。
+func (p pathgenConfigPaths) ByID() data.Path {
+	return data.Path(p).Append("by_id")
+}
+
+// Nested 返回 `+"`nested`"+` 的路径。
+func (p pathgenConfigPaths) Nested() data.Path {
+	return data.Path(p).Append("nested")
+}
+
+// pathgenServerPaths 是 data.pathgenServer 中字段的路径。
+type pathgenServerPaths data.Path
+
+// Addr 返回 `+"`addr`"+` 的路径。
+func (p pathgenServerPaths) Addr() data.Path {
+	return data.Path(p).Append("addr")
+}
+
+// Started 返回 `+"`started`"+` 的路径。
+func (p pathgenServerPaths) Started() data.Path {
+	return data.Path(p).Append("started")
+}
+
+// pathgenItemPaths 是 data.pathgenItem 中字段的路径。
+type pathgenItemPaths data.Path
+
+// Name 返回 `+"`name`"+` 的路径。
+func (p pathgenItemPaths) Name() data.Path {
+	return data.Path(p).Append("name")
+}
+
+// pathgenConfigLimitsPaths 是 struct { Max int } 中字段的路径。
+type pathgenConfigLimitsPaths data.Path
+
+// Max 返回 `+"`Max`"+` 的路径。
+func (p pathgenConfigLimitsPaths) Max() data.Path {
+	return data.Path(p).Append("Max")
+}
+`)
+}
+
+func TestPathGeneratorTypeCheck(t *testing.T) {
+	a := assert.New(t)
+	g := &PathGenerator{
+		Package: "paths",
+	}
+	buf := &strings.Builder{}
+	a.NilError(g.Generate(buf, reflect.TypeOf(&pathgenConfig{})))
+
+	// 生成的代码放在单独的 package 中，通过 data 包的源码做类型检查。
+	fset := token.NewFileSet()
+	gen, err := parser.ParseFile(fset, "paths.go", buf.String(), 0)
+	a.NilError(err)
+	usage, err := parser.ParseFile(fset, "usage.go", `package paths
+
+import data "github.com/altstory/go-data"
+
+var (
+	_ data.Path = data.Path(pathgenConfig.Server())
+	_ data.Path = pathgenConfig.Server().Addr()
+	_ data.Path = pathgenConfig.ItemsAt(1).Name()
+	_ data.Path = pathgenConfig.RefsAt("k").Name()
+	_ data.Path = pathgenConfig.Limits().Max()
+)
+`, 0)
+	a.NilError(err)
+
+	conf := &types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+	}
+	_, err = conf.Check("paths", fset, []*ast.File{gen, usage}, nil)
+	a.NilError(err)
+}
+
+func TestPathGeneratorErrors(t *testing.T) {
+	type Conflict struct {
+		Items   []pathgenItem `data:"items"`
+		ItemsAt int           `data:"items_at"`
+	}
+
+	cases := []struct {
+		Package string
+		Type    reflect.Type
+	}{
+		{"", reflect.TypeOf(pathgenItem{})},
+		{"paths", reflect.TypeOf(1)},
+		{"paths", reflect.TypeOf(struct{ A int }{})},
+		{"paths", reflect.TypeOf(Conflict{})},
+	}
+
+	for i, c := range cases {
+		a := assert.New(t)
+		a.Use(&i, &c)
+
+		g := &PathGenerator{
+			Package: c.Package,
+		}
+		a.NonNilError(g.Generate(&strings.Builder{}, c.Type))
+	}
+}
@@ -0,0 +1,182 @@
+package data
+
+import (
+	"strconv"
+)
+
+// Policy 记录了每个角色可以访问的字段，key 是角色名，value 是这个角色允许访问的路径模式列表。
+//
+// 路径模式的格式与 `Data#Query` 相同，是以“.”分隔的字段，其中 `*` 可以匹配任意一个 key 或数组下标。
+// 允许访问某个路径就意味着允许访问这个路径下的所有内容，比如 `user` 允许访问 d["user"] 的全部内容，
+// 而 `users.*.name` 只允许访问 d["users"] 中每个元素的 name 字段。
+//
+//     policy := data.Policy{
+//         "admin": {"*"},
+//         "guest": {"name", "users.*.name"},
+//     }
+type Policy map[string][]string
+
+// FilterByPolicy 返回一个新的 Data，其中只包含 policy 中 role 允许访问的字段，
+// 这适合在 API 层统一过滤掉用户无权查看的字段。
+//
+// 如果 role 不在 policy 中，返回空 Data。
+// 如果过滤之后某个 object 中的字段全部被去掉了，这个 object 也不会出现在结果里；
+// 数组中没有被允许访问的元素会被去掉，剩下的元素保持原有顺序。
+// 返回的 Data 是 d 的深拷贝，修改它不会影响 d 的内容。
+func (d Data) FilterByPolicy(policy Policy, role string) Data {
+	patterns := policy[role]
+
+	if len(patterns) == 0 || d.Len() == 0 {
+		return emptyData
+	}
+
+	root := &policyNode{}
+
+	for _, pattern := range patterns {
+		root.add(pattern)
+	}
+
+	filtered, order := filterObject(d.data, d.order, []*policyNode{root})
+
+	if len(filtered) == 0 {
+		return emptyData
+	}
+
+	return Data{
+		data:  filtered,
+		order: order,
+	}
+}
+
+// policyNode 是路径模式组成的前缀树中的一个节点。
+type policyNode struct {
+	children map[string]*policyNode
+	allowed  bool
+}
+
+func (node *policyNode) add(pattern string) {
+	for _, field := range SplitQuery(pattern) {
+		if node.children == nil {
+			node.children = map[string]*policyNode{}
+		}
+
+		child := node.children[field]
+
+		if child == nil {
+			child = &policyNode{}
+			node.children[field] = child
+		}
+
+		node = child
+	}
+
+	node.allowed = true
+}
+
+// matchPolicy 返回 nodes 中所有能匹配 field 的子节点，如果其中有节点允许访问全部内容，allowed 为 true。
+func matchPolicy(nodes []*policyNode, field string) (matched []*policyNode, allowed bool) {
+	for _, node := range nodes {
+		for _, key := range [...]string{field, "*"} {
+			if child := node.children[key]; child != nil {
+				matched = append(matched, child)
+				allowed = allowed || child.allowed
+			}
+		}
+	}
+
+	return
+}
+
+func filterObject(d RawData, order *keyOrder, nodes []*policyNode) (RawData, *keyOrder) {
+	var filtered RawData
+	var filteredOrder *keyOrder
+
+	if order != nil {
+		filteredOrder = newKeyOrder()
+	}
+
+	for _, k := range order.sortedKeys(d) {
+		matched, allowed := matchPolicy(nodes, k)
+
+		if len(matched) == 0 {
+			continue
+		}
+
+		v, childOrder, ok := filterValue(d[k], order.child(k), matched, allowed)
+
+		if !ok {
+			continue
+		}
+
+		if filtered == nil {
+			filtered = RawData{}
+		}
+
+		filtered[k] = v
+
+		if filteredOrder != nil {
+			filteredOrder.add(k)
+			filteredOrder.children[k] = childOrder
+		}
+	}
+
+	return filtered, filteredOrder
+}
+
+func filterValue(v interface{}, order *keyOrder, nodes []*policyNode, allowed bool) (interface{}, *keyOrder, bool) {
+	if allowed {
+		return cloneValue(v), order.clone(), true
+	}
+
+	switch val := v.(type) {
+	case RawData:
+		filtered, filteredOrder := filterObject(val, order, nodes)
+		return filtered, filteredOrder, len(filtered) != 0
+
+	case Data:
+		filtered, filteredOrder := filterObject(val.data, order, nodes)
+		return filtered, filteredOrder, len(filtered) != 0
+	}
+
+	elems, ok := sliceElems(v)
+
+	if !ok {
+		return nil, nil, false
+	}
+
+	var filtered []interface{}
+	var orders []*keyOrder
+	found := false
+
+	for i, elem := range elems {
+		matched, elemAllowed := matchPolicy(nodes, strconv.Itoa(i))
+
+		if len(matched) == 0 {
+			continue
+		}
+
+		fv, fo, ok := filterValue(elem, order.elem(i), matched, elemAllowed)
+
+		if !ok {
+			continue
+		}
+
+		filtered = append(filtered, fv)
+		orders = append(orders, fo)
+		found = found || fo != nil
+	}
+
+	if len(filtered) == 0 {
+		return nil, nil, false
+	}
+
+	var filteredOrder *keyOrder
+
+	if found {
+		filteredOrder = &keyOrder{
+			elems: orders,
+		}
+	}
+
+	return normalizeSlice(filtered), filteredOrder, true
+}
@@ -0,0 +1,88 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestDataFilterByPolicy(t *testing.T) {
+	policy := Policy{
+		"admin": {"*"},
+		"user":  {"name", "profile", "users.*.name"},
+		"first": {"users.0"},
+		"deep":  {"profile.email", "users.*.secret.token"},
+		"none":  {"not_exist"},
+	}
+	d := Make(RawData{
+		"name":     "go-data",
+		"password": "secret",
+		"profile": RawData{
+			"email": "a@b.c",
+			"age":   18,
+		},
+		"users": []RawData{
+			{"name": "alice", "secret": RawData{"token": "t1"}},
+			{"name": "bob"},
+		},
+	})
+	cases := []struct {
+		Role   string
+		Result RawData
+	}{
+		{"admin", d.data},
+		{"user", RawData{
+			"name": "go-data",
+			"profile": RawData{
+				"email": "a@b.c",
+				"age":   int64(18),
+			},
+			"users": []RawData{
+				{"name": "alice"},
+				{"name": "bob"},
+			},
+		}},
+		{"first", RawData{
+			"users": []RawData{
+				{"name": "alice", "secret": RawData{"token": "t1"}},
+			},
+		}},
+		{"deep", RawData{
+			"profile": RawData{
+				"email": "a@b.c",
+			},
+			"users": []RawData{
+				{"secret": RawData{"token": "t1"}},
+			},
+		}},
+		{"none", nil},
+		{"unknown", nil},
+	}
+	a := assert.New(t)
+
+	for i, c := range cases {
+		a.Use(&i, &c)
+		filtered := d.FilterByPolicy(policy, c.Role)
+		a.Equal(filtered.data, c.Result)
+	}
+
+	// 修改过滤结果不会影响原来的 Data。
+	filtered := d.FilterByPolicy(policy, "admin")
+	filtered.data["profile"].(RawData)["email"] = "changed"
+	a.Equal(d.Get("profile", "email"), "a@b.c")
+}
+
+func TestDataFilterByPolicyKeepOrder(t *testing.T) {
+	a := assert.New(t)
+	p := &Parser{
+		KeepOrder: true,
+	}
+	d, err := p.ParseJSON(`{"z":1,"list":[{"y":1,"x":2},{"w":3}],"a":{"d":1,"c":2},"b":3}`)
+	a.NilError(err)
+
+	policy := Policy{
+		"user": {"z", "list.*.x", "list.*.w", "a"},
+	}
+	filtered := d.FilterByPolicy(policy, "user")
+	a.Equal(filtered.JSON(false), `{"z":1,"list":[{"x":2},{"w":3}],"a":{"d":1,"c":2}}`)
+}
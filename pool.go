@@ -0,0 +1,47 @@
+package data
+
+import "sync"
+
+var dataPool = sync.Pool{
+	New: func() interface{} {
+		return RawData{}
+	},
+}
+
+// AcquireData 从对象池中取出一个空的 Data，Data 内部的 map 已经分配好，可以直接用 `MergeTo` 等方法写入数据。
+// 使用完毕后应该调用 `Data#Release` 将其放回对象池。
+//
+// 对象池适合在短时间内大量创建并丢弃小 Data 的场景，用来减少内存分配。
+func AcquireData() Data {
+	return Data{
+		data: dataPool.Get().(RawData),
+	}
+}
+
+// Release 清空 d 并将 d 内部的 map 放回对象池，之后 d 会变成一个空 Data。
+//
+// 需要注意，Release 之后不能再使用任何之前从 d 中得到的值，
+// 也不能使用 d 的任何副本，因为它们共享同一个 map，这个 map 可能已经被其他人取出重新使用。
+func (d *Data) Release() {
+	if d == nil || d.data == nil {
+		return
+	}
+
+	data := d.data
+	d.Reset()
+	*d = emptyData
+	dataPool.Put(data)
+}
+
+// Reset 清空 d 的所有内容，但会保留 d 内部的 map 以便复用，d 的所有副本也会同时被清空。
+func (d *Data) Reset() {
+	if d == nil {
+		return
+	}
+
+	for k := range d.data {
+		delete(d.data, k)
+	}
+
+	d.order = nil
+}
@@ -0,0 +1,50 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestDataPool(t *testing.T) {
+	a := assert.New(t)
+	d := AcquireData()
+	a.Equal(d.Len(), 0)
+	a.Assert(d.data != nil)
+
+	MergeTo(&d, Make(RawData{
+		"a": 1,
+		"b": RawData{"c": 2},
+	}))
+	a.Equal(d.Query("b.c"), int64(2))
+
+	copied := d
+	d.Reset()
+	a.Equal(d.Len(), 0)
+	a.Equal(copied.Len(), 0)
+	a.Assert(d.data != nil)
+
+	MergeTo(&d, Make(RawData{"a": 1}))
+	d.Release()
+	a.Equal(d, emptyData)
+
+	// 重复 Release 和 Release 空值都不应该 panic。
+	d.Release()
+	var nilData *Data
+	nilData.Release()
+	nilData.Reset()
+
+	d = AcquireData()
+	a.Equal(d.Len(), 0)
+	d.Release()
+}
+
+func BenchmarkAcquireData(b *testing.B) {
+	src := Make(RawData{"a": 1, "b": "2"})
+
+	for i := 0; i < b.N; i++ {
+		d := AcquireData()
+		MergeTo(&d, src)
+		d.Release()
+	}
+}
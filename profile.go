@@ -0,0 +1,74 @@
+package data
+
+import (
+	"reflect"
+	"time"
+)
+
+// Profile 是 `Data#Profile` 返回的统计信息，用于容量规划和排查数据过大等问题。
+type Profile struct {
+	Types           map[string]int // 各种类型的值的个数，object 记作 "object"，数组记作 "array"，其他值记作类型名，比如 "int64"、"string"、"nil"。
+	MaxDepth        int            // 最深的值的深度，顶层 key 的深度为 1，每进入一层 object 或者数组深度加 1。
+	LargestArray    string         // 元素最多的数组的路径，路径格式与 `Data#Query` 相同，如果没有数组则为空。
+	LargestArrayLen int            // 元素最多的数组的长度。
+	Sections        map[string]int // 每个顶层 key 下所有 object 中 key 的总数，不包含顶层 key 本身。
+}
+
+// Profile 遍历 d 中的所有值，统计每种类型的值的个数、最大深度、最大的数组以及每个顶层 key 下的 key 总数。
+// 数组中的每个元素都会被统计一次，d 本身不计入 Types。
+//
+// 如果元素个数相同的数组有多个，LargestArray 是按照 `Data#FindKey` 的遍历顺序找到的第一个。
+func (d Data) Profile() *Profile {
+	p := &Profile{
+		Types:    map[string]int{},
+		Sections: map[string]int{},
+	}
+	hasArray := false
+
+	walkValues(d.data, d.order, nil, func(path []string, isKey bool, v interface{}) {
+		if len(path) > p.MaxDepth {
+			p.MaxDepth = len(path)
+		}
+
+		// walkValues 总是先访问顶层 key 再访问它下面的值。
+		if len(path) == 1 {
+			p.Sections[path[0]] = 0
+		} else if isKey {
+			p.Sections[path[0]]++
+		}
+
+		typeName := profileTypeName(v)
+		p.Types[typeName]++
+
+		if typeName != "array" {
+			return
+		}
+
+		if l := reflect.ValueOf(v).Len(); !hasArray || l > p.LargestArrayLen {
+			hasArray = true
+			p.LargestArray = JoinQuery(path...)
+			p.LargestArrayLen = l
+		}
+	})
+
+	return p
+}
+
+func profileTypeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "nil"
+	case RawData, Data:
+		return "object"
+	case time.Time:
+		return "time"
+	}
+
+	t := reflect.TypeOf(v)
+
+	if t.Kind() == reflect.Slice {
+		return "array"
+	}
+
+	return t.String()
+}
@@ -0,0 +1,59 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestDataProfile(t *testing.T) {
+	a := assert.New(t)
+	d := Make(RawData{
+		"server": RawData{
+			"addr":    ":80",
+			"timeout": time.Second,
+			"tls": RawData{
+				"enabled": true,
+				"ciphers": []string{"a", "b"},
+			},
+		},
+		"users": []RawData{
+			{"name": "alice", "age": 20},
+			{"name": "bob", "tags": []int{1, 2, 3}},
+		},
+		"ratio":  0.5,
+		"nil":    nil,
+		"a.b":    uint64(1),
+		"nested": Make(RawData{"x": RawData{}}),
+	})
+	p := d.Profile()
+	a.Equal(p, &Profile{
+		Types: map[string]int{
+			"object":  6,
+			"array":   3,
+			"string":  6, // time.Duration 会被编码成字符串。
+			"int64":   4,
+			"uint64":  1,
+			"float64": 1,
+			"bool":    1,
+			"nil":     1,
+		},
+		MaxDepth:        4,
+		LargestArray:    "users.1.tags",
+		LargestArrayLen: 3,
+		Sections: map[string]int{
+			"server": 5,
+			"users":  4,
+			"ratio":  0,
+			"nil":    0,
+			"a.b":    0,
+			"nested": 1,
+		},
+	})
+
+	a.Equal(Data{}.Profile(), &Profile{
+		Types:    map[string]int{},
+		Sections: map[string]int{},
+	})
+}
@@ -0,0 +1,171 @@
+package data
+
+import (
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// QueryTrace 记录了一次 Get 或 Query 的查找过程，用于找出线上最慢的动态查找。
+type QueryTrace struct {
+	Fields   []string       // 查找的完整路径。
+	Segments []QuerySegment // 实际访问过的每一级路径，找不到值时会提前结束。
+	Found    bool           // 是否找到了值。
+
+	// ReflectFallbacks 是需要使用反射才能访问的层级数，比如 map[int]T、[]int 等非标准类型的值。
+	// 这些值的访问比 RawData 慢很多，如果这个数字很大，可以考虑在构造 Data 时将它们标准化。
+	ReflectFallbacks int
+}
+
+// QuerySegment 记录了访问一级路径的情况。
+type QuerySegment struct {
+	Field    string        // 这一级的 key 或者数组下标。
+	Duration time.Duration // 访问这一级的耗时。
+	Reflect  bool          // 是否使用了反射。
+}
+
+// QueryTracer 在每次 Get 或 Query 结束后被调用，trace 在调用结束后不会再被使用，可以被保存下来。
+//
+// 实现者需要保证这个函数是并发安全的，并且应该尽快返回，避免影响正常业务逻辑。
+type QueryTracer func(trace *QueryTrace)
+
+type queryTracerHolder struct {
+	tracer QueryTracer
+}
+
+var defaultQueryTracer atomic.Value
+
+// SetQueryTracer 设置全局的 QueryTracer，设置为 nil 则关闭追踪。
+// 开启追踪后所有的 Get 和 Query 都会变慢，建议只在采样或者排查问题时开启。
+//
+// 需要注意，Patch、Delete 等修改数据的操作不会被追踪。
+func SetQueryTracer(tracer QueryTracer) {
+	defaultQueryTracer.Store(queryTracerHolder{
+		tracer: tracer,
+	})
+}
+
+func currentQueryTracer() QueryTracer {
+	holder, _ := defaultQueryTracer.Load().(queryTracerHolder)
+	return holder.tracer
+}
+
+// getTraced 与 `RawData#Get` 的查找规则相同，但会记录每一级的访问情况并且在结束后调用 tracer。
+func (d RawData) getTraced(fields []string, tracer QueryTracer) interface{} {
+	trace := &QueryTrace{
+		Fields:   fields,
+		Segments: make([]QuerySegment, 0, len(fields)),
+	}
+	var v interface{} = d
+
+	for _, f := range fields {
+		start := time.Now()
+		next, ok, reflected := getField(v, f)
+		trace.Segments = append(trace.Segments, QuerySegment{
+			Field:    f,
+			Duration: time.Since(start),
+			Reflect:  reflected,
+		})
+
+		if reflected {
+			trace.ReflectFallbacks++
+		}
+
+		if !ok {
+			v = nil
+			break
+		}
+
+		v = next
+	}
+
+	trace.Found = v != nil
+	tracer(trace)
+	return v
+}
+
+// getField 访问 v 中 f 对应的值，如果 v 不是 RawData、Data 或者常见的标准 slice 类型，
+// 则使用与 `RawData#get` 相同的反射规则访问，这时 reflected 为 true。
+func getField(v interface{}, f string) (next interface{}, ok bool, reflected bool) {
+	switch val := v.(type) {
+	case RawData:
+		next, ok = val[f]
+		ok = ok && next != nil
+		return
+
+	case Data:
+		next, ok = val.data[f]
+		ok = ok && next != nil
+		return
+
+	case []interface{}:
+		if idx, valid := sliceIndex(f, len(val)); valid {
+			next = val[idx]
+			ok = next != nil
+		}
+
+		return
+
+	case []RawData:
+		if idx, valid := sliceIndex(f, len(val)); valid {
+			next, ok = val[idx], true
+		}
+
+		return
+
+	case []int64:
+		if idx, valid := sliceIndex(f, len(val)); valid {
+			next, ok = val[idx], true
+		}
+
+		return
+
+	case []uint64:
+		if idx, valid := sliceIndex(f, len(val)); valid {
+			next, ok = val[idx], true
+		}
+
+		return
+
+	case []float64:
+		if idx, valid := sliceIndex(f, len(val)); valid {
+			next, ok = val[idx], true
+		}
+
+		return
+
+	case []string:
+		if idx, valid := sliceIndex(f, len(val)); valid {
+			next, ok = val[idx], true
+		}
+
+		return
+
+	case []bool:
+		if idx, valid := sliceIndex(f, len(val)); valid {
+			next, ok = val[idx], true
+		}
+
+		return
+	}
+
+	reflected = true
+	found := RawData{"": v}.get([]string{"", f}, nil)
+
+	if found.IsValid() {
+		next = found.Interface()
+		ok = next != nil
+	}
+
+	return
+}
+
+func sliceIndex(f string, l int) (int, bool) {
+	n, err := strconv.Atoi(f)
+
+	if err != nil || n < 0 || n >= l {
+		return 0, false
+	}
+
+	return n, true
+}
@@ -0,0 +1,80 @@
+package data
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestQueryTracer(t *testing.T) {
+	a := assert.New(t)
+	d := Make(RawData{
+		"a": RawData{
+			"list":  []RawData{{"b": 1}, nil},
+			"mixed": []interface{}{1, RawData{"c": "x"}},
+			"ints":  []int{1, 2},
+			"map":   map[int]string{1: "one"},
+			"data":  Make(RawData{"d": true}),
+		},
+	})
+	cases := []struct {
+		Query    string
+		Found    bool
+		Segments int
+		Reflect  int
+	}{
+		{"a.list.0.b", true, 4, 0},
+		{"a.list.1", true, 3, 0},
+		{"a.list.2.b", false, 3, 0},
+		{"a.mixed.1.c", true, 4, 0},
+		{"a.ints.1", true, 3, 0},
+		{"a.data.d", true, 3, 0},
+		{"a.not_exist.b", false, 2, 0},
+		{"x", false, 1, 0},
+	}
+
+	// 先记录没有追踪时的查询结果，追踪后的结果必须与之相同。
+	results := make([]interface{}, 0, len(cases))
+
+	for _, c := range cases {
+		results = append(results, d.Query(c.Query))
+	}
+
+	var mu sync.Mutex
+	var traces []*QueryTrace
+	SetQueryTracer(func(trace *QueryTrace) {
+		mu.Lock()
+		defer mu.Unlock()
+		traces = append(traces, trace)
+	})
+	defer SetQueryTracer(nil)
+
+	for i, c := range cases {
+		a.Use(&i, &c)
+		traces = nil
+		a.Equal(d.Query(c.Query), results[i])
+		a.Equal(len(traces), 1)
+
+		trace := traces[0]
+		a.Equal(trace.Found, c.Found)
+		a.Equal(len(trace.Segments), c.Segments)
+		a.Equal(trace.ReflectFallbacks, c.Reflect)
+	}
+
+	// 非标准类型需要使用反射访问。
+	raw := RawData{
+		"ints": []int{1, 2},
+		"map":  map[int]string{1: "one"},
+	}
+	traces = nil
+	a.Equal(raw.Query("ints.1"), 2)
+	a.Equal(raw.Get("map", "1"), "one")
+	a.Equal(raw.Get("map", "2"), nil)
+	a.Equal(len(traces), 3)
+	a.Equal(traces[0].ReflectFallbacks, 1)
+	a.Assert(traces[0].Segments[1].Reflect)
+	a.Equal(traces[0].Segments[1].Field, "1")
+	a.Assert(traces[1].Found)
+	a.Assert(!traces[2].Found)
+}
@@ -0,0 +1,209 @@
+package data
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RelativeTime 解析相对时间表达式，设置到 `Decoder#RelativeTime` 之后，
+// time.Time 和 time.Duration 字段可以直接从这类字符串中解析。
+//
+// 支持的格式如下：
+//     - time.Duration：与 time.ParseDuration 相同，额外支持 d（24 小时）和 w（7 天）两个单位，比如 "2d"、"1w2d12h"、"-1.5d"；
+//     - time.Time："now" 代表当前时间，"now-24h"、"now+1d" 代表当前时间加上或者减去一个 time.Duration，
+//       time.Duration 的格式与上面相同。
+//
+// 需要注意，d 和 w 总是固定的小时数，不考虑夏令时等因素。
+type RelativeTime struct {
+	// Now 返回当前时间，默认是 time.Now。
+	// 同一次解码中的所有 "now" 都会调用 Now，如果需要它们完全一致，可以返回一个固定的时间。
+	Now func() time.Time
+}
+
+const relativeTimeNow = "now"
+
+// ParseTime 将相对时间表达式 s 解析成 time.Time。
+func (rt *RelativeTime) ParseTime(s string) (time.Time, error) {
+	if !strings.HasPrefix(s, relativeTimeNow) {
+		return time.Time{}, fmt.Errorf("go-data: invalid relative time `%v`", s)
+	}
+
+	now := time.Now
+
+	if rt.Now != nil {
+		now = rt.Now
+	}
+
+	offset := s[len(relativeTimeNow):]
+
+	if offset == "" {
+		return now(), nil
+	}
+
+	if offset[0] != '+' && offset[0] != '-' {
+		return time.Time{}, fmt.Errorf("go-data: invalid relative time `%v`", s)
+	}
+
+	dur, err := rt.ParseDuration(offset)
+
+	if err != nil {
+		return time.Time{}, fmt.Errorf("go-data: invalid relative time `%v`: %w", s, err)
+	}
+
+	return now().Add(dur), nil
+}
+
+// ParseDuration 将 s 解析成 time.Duration，与 time.ParseDuration 相比，额外支持 d 和 w 两个单位。
+func (rt *RelativeTime) ParseDuration(s string) (time.Duration, error) {
+	// 标准库的单位中都没有 d 和 w，这种情况直接交给标准库处理。
+	if !strings.ContainsAny(s, "dw") {
+		dur, err := time.ParseDuration(s)
+
+		if err != nil {
+			return 0, fmt.Errorf("go-data: invalid duration `%v`", s)
+		}
+
+		return dur, nil
+	}
+
+	str := s
+	neg := false
+
+	if str != "" && (str[0] == '+' || str[0] == '-') {
+		neg = str[0] == '-'
+		str = str[1:]
+	}
+
+	if str == "" {
+		return 0, fmt.Errorf("go-data: invalid duration `%v`", s)
+	}
+
+	// 与标准库一样，使用 uint64 累加，这样才能表示 math.MinInt64 的绝对值。
+	var total uint64
+
+	for str != "" {
+		// 每一段都是一个数字加一个单位。
+		i := strings.IndexFunc(str, func(r rune) bool {
+			return (r < '0' || r > '9') && r != '.'
+		})
+
+		if i <= 0 {
+			return 0, fmt.Errorf("go-data: invalid duration `%v`", s)
+		}
+
+		num := str[:i]
+		str = str[i:]
+		j := strings.IndexFunc(str, func(r rune) bool {
+			return (r >= '0' && r <= '9') || r == '.'
+		})
+
+		if j < 0 {
+			j = len(str)
+		}
+
+		unit := str[:j]
+		str = str[j:]
+
+		var v uint64
+
+		switch unit {
+		case "d", "w":
+			scale := uint64(24 * time.Hour)
+
+			if unit == "w" {
+				scale *= 7
+			}
+
+			var ok bool
+			v, ok = parseDurationUnit(num, scale)
+
+			if !ok {
+				return 0, fmt.Errorf("go-data: invalid duration `%v`", s)
+			}
+
+		default:
+			dur, err := time.ParseDuration(num + unit)
+
+			if err != nil {
+				return 0, fmt.Errorf("go-data: invalid duration `%v`", s)
+			}
+
+			v = uint64(dur)
+		}
+
+		if v > 1<<63-total {
+			return 0, fmt.Errorf("go-data: invalid duration `%v` due to overflow", s)
+		}
+
+		total += v
+	}
+
+	if neg {
+		return -time.Duration(total), nil
+	}
+
+	if total > math.MaxInt64 {
+		return 0, fmt.Errorf("go-data: invalid duration `%v` due to overflow", s)
+	}
+
+	return time.Duration(total), nil
+}
+
+// parseDurationUnit 将 num 乘以 scale 纳秒，num 是一个可以带小数的非负数。
+// 整数部分的计算是精确的，小数部分的精度与 time.ParseDuration 相同。
+// 如果 num 不合法或者结果超过 1<<63，返回 false。
+func parseDurationUnit(num string, scale uint64) (uint64, bool) {
+	intPart, fracPart := num, ""
+
+	if dot := strings.IndexByte(num, '.'); dot >= 0 {
+		intPart, fracPart = num[:dot], num[dot+1:]
+	}
+
+	if intPart == "" && fracPart == "" {
+		return 0, false
+	}
+
+	var v uint64
+
+	if intPart != "" {
+		n, err := strconv.ParseUint(intPart, 10, 64)
+
+		if err != nil || n > (1<<63)/scale {
+			return 0, false
+		}
+
+		v = n * scale
+	}
+
+	var f uint64
+	div := 1.0
+	overflow := false
+
+	for _, c := range fracPart {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+
+		// 超出精度的部分直接忽略。
+		if overflow || f > (1<<63-1)/10 {
+			overflow = true
+			continue
+		}
+
+		f = f*10 + uint64(c-'0')
+		div *= 10
+	}
+
+	if f > 0 {
+		v += uint64(float64(f) * (float64(scale) / div))
+
+		if v > 1<<63 {
+			return 0, false
+		}
+	}
+
+	return v, true
+}
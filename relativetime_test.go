@@ -0,0 +1,151 @@
+package data
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestRelativeTimeParseDuration(t *testing.T) {
+	cases := []struct {
+		Value    string
+		Expected time.Duration
+		Err      bool
+	}{
+		{"2d", 48 * time.Hour, false},
+		{"1w2d12h", 9*24*time.Hour + 12*time.Hour, false},
+		{"-1.5d", -36 * time.Hour, false},
+		{"+90m", 90 * time.Minute, false},
+		{"1h30m10.5s", time.Hour + 30*time.Minute + 10500*time.Millisecond, false},
+		{"300ms", 300 * time.Millisecond, false},
+		{"", 0, true},
+		{"-", 0, true},
+		{"d", 0, true},
+		{"10", 0, true},
+		{"1y", 0, true},
+		{"1..5d", 0, true},
+		{"100000000w", 0, true},
+		{"0", 0, false},
+		{"-0", 0, false},
+		{"0.5w", 84 * time.Hour, false},
+		{".5d", 12 * time.Hour, false},
+		{"300d1ns", 300*24*time.Hour + 1, false},
+		{"2562047h47m16.854775807s", math.MaxInt64, false},
+		{"-2562047h47m16.854775808s", math.MinInt64, false},
+		{"106751d23h47m16.854775807s", math.MaxInt64, false},
+		{"-106751d23h47m16.854775808s", math.MinInt64, false},
+		{"106751d23h47m16.854775808s", 0, true},
+		{"106752d", 0, true},
+		{"53375d23h53m38.427387904s53375d23h53m38.427387904s", 0, true},
+		{"1.d", 24 * time.Hour, false},
+		{".d", 0, true},
+	}
+	rt := &RelativeTime{}
+
+	for i, c := range cases {
+		a := assert.New(t)
+		a.Use(&i, &c)
+
+		dur, err := rt.ParseDuration(c.Value)
+
+		if c.Err {
+			a.NonNilError(err)
+			continue
+		}
+
+		a.NilError(err)
+		a.Equal(dur, c.Expected)
+	}
+}
+
+func TestRelativeTimeParseTime(t *testing.T) {
+	now := time.Date(2020, 3, 4, 5, 6, 7, 0, time.UTC)
+	cases := []struct {
+		Value    string
+		Expected time.Time
+		Err      bool
+	}{
+		{"now", now, false},
+		{"now-24h", now.Add(-24 * time.Hour), false},
+		{"now+1d2h", now.Add(26 * time.Hour), false},
+		{"now-1w", now.AddDate(0, 0, -7), false},
+		{"now24h", time.Time{}, true},
+		{"now-", time.Time{}, true},
+		{"now-1x", time.Time{}, true},
+		{"yesterday", time.Time{}, true},
+		{"2020-03-04T05:06:07Z", time.Time{}, true},
+	}
+	rt := &RelativeTime{
+		Now: func() time.Time {
+			return now
+		},
+	}
+
+	for i, c := range cases {
+		a := assert.New(t)
+		a.Use(&i, &c)
+
+		tm, err := rt.ParseTime(c.Value)
+
+		if c.Err {
+			a.NonNilError(err)
+			continue
+		}
+
+		a.NilError(err)
+		a.Equal(tm, c.Expected)
+	}
+
+	a := assert.New(t)
+	before := time.Now()
+	tm, err := (&RelativeTime{}).ParseTime("now")
+	a.NilError(err)
+	a.Assert(!tm.Before(before))
+}
+
+func TestDecoderRelativeTime(t *testing.T) {
+	type Alert struct {
+		From     time.Time      `data:"from"`
+		To       *time.Time     `data:"to"`
+		Window   time.Duration  `data:"window"`
+		Cooldown *time.Duration `data:"cooldown"`
+	}
+
+	a := assert.New(t)
+	now := time.Date(2020, 3, 4, 5, 6, 7, 0, time.UTC)
+	d := Make(RawData{
+		"from":     "now-24h",
+		"to":       "now",
+		"window":   "2d",
+		"cooldown": "30m",
+	})
+	dec := &Decoder{
+		RelativeTime: &RelativeTime{
+			Now: func() time.Time {
+				return now
+			},
+		},
+	}
+
+	var alert Alert
+	a.NilError(dec.Decode(d, &alert))
+	a.Equal(alert.From, now.Add(-24*time.Hour))
+	a.Equal(*alert.To, now)
+	a.Equal(alert.Window, 48*time.Hour)
+	a.Equal(*alert.Cooldown, 30*time.Minute)
+
+	// time.Time 类型的值依然可以直接解析。
+	alert = Alert{}
+	a.NilError(dec.Decode(Make(RawData{"from": now}), &alert))
+	a.Equal(alert.From, now)
+
+	err := dec.Decode(Make(RawData{"from": "tomorrow"}), &alert)
+	a.NonNilError(err)
+	a.Equal(err.(*DecodeError).Fields, []string{"from"})
+
+	// 默认不解析相对时间。
+	a.NonNilError((&Decoder{}).Decode(Make(RawData{"window": "2d"}), &alert))
+	a.NonNilError((&Decoder{}).Decode(Make(RawData{"from": "now"}), &alert))
+}
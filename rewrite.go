@@ -0,0 +1,165 @@
+package data
+
+import (
+	"sort"
+	"strconv"
+)
+
+// RewritePaths 按照 mapping 中旧路径到新路径的对应关系，将 d 中的子树整体移动到新的位置，返回移动后的新 Data，
+// 这适合在数据结构重构时一次性移动大量字段。d 本身不会被修改。
+//
+// 路径的格式与 `Data#Query` 相同。所有旧路径对应的值都是从 d 中读取的，因此 mapping 之间不会相互影响，
+// 比如 `{"a": "b", "b": "a"}` 会交换 a 和 b 的值。旧路径在 d 中不存在时忽略这一项。
+// 新路径中不存在的 object 会被自动创建，如果新路径无法设置，规则详见 `Data#SetJSON`，返回错误。
+func (d Data) RewritePaths(mapping map[string]string) (Data, error) {
+	result := d.Clone()
+
+	if len(mapping) == 0 {
+		return result, nil
+	}
+
+	olds := make([]string, 0, len(mapping))
+
+	for old := range mapping {
+		olds = append(olds, old)
+	}
+
+	sort.Strings(olds)
+
+	type move struct {
+		fields []string
+		val    interface{}
+		order  *keyOrder
+	}
+	moves := make([]move, 0, len(olds))
+	deletes := make([]string, 0, len(olds))
+
+	for _, old := range olds {
+		val := result.Query(old)
+
+		if val == nil {
+			continue
+		}
+
+		moves = append(moves, move{
+			fields: SplitQuery(mapping[old]),
+			val:    val,
+			order:  orderAt(result.order, old),
+		})
+		deletes = append(deletes, old)
+	}
+
+	result.data.Delete(deletes...)
+
+	if result.data == nil {
+		result.data = RawData{}
+	}
+
+	for _, m := range moves {
+		if len(m.fields) == 1 && m.fields[0] == "" {
+			if root, ok := m.val.(RawData); ok {
+				MergeTo(&result, Data{data: root, order: m.order})
+				continue
+			}
+		}
+
+		if err := checkSetJSONPath(result.data, m.fields); err != nil {
+			return emptyData, err
+		}
+
+		setJSON(result.data, result.order, m.fields, m.val, m.order)
+	}
+
+	return result, nil
+}
+
+// Nest 返回一个新的 Data，将 d 的全部内容放在 prefix 对应的路径下，
+// 比如 prefix 为 `tenants.t1` 时，返回 `{"tenants":{"t1":d}}`。
+// 这适合将多个租户的文档组合成一个文档，比如 `Merge(d1.Nest("t1"), d2.Nest("t2"))`。
+//
+// 返回值与 d 共享内部数据，如果需要修改返回值，应该先调用 `Data#Clone`。
+// 如果 prefix 为空字符串，直接返回 d；如果 d 为空，返回空 Data。
+func (d Data) Nest(prefix string) Data {
+	if prefix == "" || d.Len() == 0 {
+		return d
+	}
+
+	fields := SplitQuery(prefix)
+	nested := d
+
+	for i := len(fields) - 1; i >= 0; i-- {
+		field := fields[i]
+		wrapped := Data{
+			data: RawData{
+				field: nested.data,
+			},
+		}
+
+		if d.order != nil {
+			wrapped.order = newKeyOrder()
+			wrapped.order.add(field)
+			wrapped.order.children[field] = nested.order
+		}
+
+		nested = wrapped
+	}
+
+	return nested
+}
+
+// Unnest 是 Nest 的逆操作，返回 prefix 对应的 object，
+// 比如 `{"tenants":{"t1":{"a":1}}}` 使用 `tenants.t1` 调用 Unnest 会得到 `{"a":1}`。
+//
+// 返回值与 d 共享内部数据，如果需要修改返回值，应该先调用 `Data#Clone`。
+// 如果 prefix 为空字符串，直接返回 d；如果 prefix 对应的值不存在或者不是 object，返回空 Data。
+func (d Data) Unnest(prefix string) Data {
+	if prefix == "" {
+		return d
+	}
+
+	var data RawData
+
+	switch v := d.Query(prefix).(type) {
+	case RawData:
+		data = v
+	case Data:
+		data = v.data
+	}
+
+	if len(data) == 0 {
+		return emptyData
+	}
+
+	return Data{
+		data:  data,
+		order: orderAt(d.order, prefix),
+	}
+}
+
+// orderAt 返回 order 中 query 对应的值的顺序信息。
+func orderAt(order *keyOrder, query string) *keyOrder {
+	if order == nil || query == "" {
+		return order
+	}
+
+	for _, field := range SplitQuery(query) {
+		if order == nil {
+			return nil
+		}
+
+		if order.children != nil {
+			order = order.child(field)
+			continue
+		}
+
+		i, err := strconv.Atoi(field)
+
+		if err != nil || i < 0 {
+			return nil
+		}
+
+		order = order.elem(i)
+	}
+
+	return order
+}
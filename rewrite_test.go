@@ -0,0 +1,181 @@
+package data
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestDataRewritePaths(t *testing.T) {
+	d := Make(RawData{
+		"host": "localhost",
+		"port": 80,
+		"db": RawData{
+			"user": "root",
+			"pass": "secret",
+		},
+		"list": []RawData{
+			{"name": "a"},
+		},
+	})
+	cases := []struct {
+		Mapping map[string]string
+		Result  RawData
+		Err     error
+	}{
+		{
+			nil,
+			d.data,
+			nil,
+		},
+		{
+			map[string]string{
+				"host":    "server.host",
+				"port":    "server.port",
+				"db.pass": "secrets.db",
+				"missing": "anything",
+			},
+			RawData{
+				"server": RawData{
+					"host": "localhost",
+					"port": int64(80),
+				},
+				"db": RawData{
+					"user": "root",
+				},
+				"secrets": RawData{
+					"db": "secret",
+				},
+				"list": []RawData{
+					{"name": "a"},
+				},
+			},
+			nil,
+		},
+		{ // 交换两个值。
+			map[string]string{
+				"host": "port",
+				"port": "host",
+			},
+			RawData{
+				"host": int64(80),
+				"port": "localhost",
+				"db": RawData{
+					"user": "root",
+					"pass": "secret",
+				},
+				"list": []RawData{
+					{"name": "a"},
+				},
+			},
+			nil,
+		},
+		{ // 移动数组元素中的值。
+			map[string]string{
+				"list.0.name": "list.0.title",
+			},
+			RawData{
+				"host": "localhost",
+				"port": int64(80),
+				"db": RawData{
+					"user": "root",
+					"pass": "secret",
+				},
+				"list": []RawData{
+					{"title": "a"},
+				},
+			},
+			nil,
+		},
+		{ // 将 db 的内容移动到顶层。
+			map[string]string{
+				"db": "",
+			},
+			RawData{
+				"host": "localhost",
+				"port": int64(80),
+				"user": "root",
+				"pass": "secret",
+				"list": []RawData{
+					{"name": "a"},
+				},
+			},
+			nil,
+		},
+		{ // 新路径的上一级不是 object。
+			map[string]string{
+				"db": "host.db",
+			},
+			nil,
+			ErrNotObject,
+		},
+	}
+	a := assert.New(t)
+	expected := d.Clone()
+
+	for i, c := range cases {
+		a.Use(&i, &c)
+		result, err := d.RewritePaths(c.Mapping)
+
+		if c.Err != nil {
+			a.Assert(errors.Is(err, c.Err))
+			continue
+		}
+
+		a.NilError(err)
+		a.Equal(result.data, c.Result)
+		a.Equal(d, expected)
+	}
+}
+
+func TestDataRewritePathsKeepOrder(t *testing.T) {
+	a := assert.New(t)
+	p := &Parser{
+		KeepOrder: true,
+	}
+	d, err := p.ParseJSON(`{"z":1,"old":{"y":1,"x":2},"a":2}`)
+	a.NilError(err)
+
+	result, err := d.RewritePaths(map[string]string{
+		"old": "new.section",
+	})
+	a.NilError(err)
+	a.Equal(result.JSON(false), `{"z":1,"a":2,"new":{"section":{"y":1,"x":2}}}`)
+}
+
+func TestDataNest(t *testing.T) {
+	a := assert.New(t)
+	t1 := Make(RawData{"a": 1})
+	t2 := Make(RawData{"b": RawData{"c": 2}})
+
+	nested := t1.Nest("tenants.t1")
+	a.Equal(nested.data, RawData{
+		"tenants": RawData{
+			"t1": RawData{"a": int64(1)},
+		},
+	})
+	a.Equal(nested.Unnest("tenants.t1"), t1)
+	a.Equal(t1.Nest(""), t1)
+	a.Equal(Data{}.Nest("t1"), Data{})
+
+	merged := Merge(t1.Nest("tenants.t1"), t2.Nest("tenants.t2"))
+	a.Equal(merged.data, RawData{
+		"tenants": RawData{
+			"t1": RawData{"a": int64(1)},
+			"t2": RawData{"b": RawData{"c": int64(2)}},
+		},
+	})
+	a.Equal(merged.Unnest("tenants.t2"), t2)
+	a.Equal(merged.Unnest("tenants.t2.b.c"), Data{})
+	a.Equal(merged.Unnest("not_exist"), Data{})
+	a.Equal(merged.Unnest(""), merged)
+
+	p := &Parser{
+		KeepOrder: true,
+	}
+	d, err := p.ParseJSON(`{"z":1,"a":{"y":1,"x":2}}`)
+	a.NilError(err)
+	a.Equal(d.Nest("t").JSON(false), `{"t":{"z":1,"a":{"y":1,"x":2}}}`)
+	a.Equal(d.Nest("t").Unnest("t.a").JSON(false), `{"y":1,"x":2}`)
+}
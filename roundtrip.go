@@ -0,0 +1,121 @@
+package data
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// CheckRoundTrip 使用 Encoder 的零值将 v 编码成 Data，再使用 Decoder 的零值解析到一个新的同类型的值中，
+// 检查这个新值的每个字段是否与 v 相同。这适合在测试中检查所有的配置结构，
+// 提前发现不支持的类型、写错的 tag、数值宽度等导致数据丢失或者改变的问题。
+//
+// v 必须是 struct 或者 struct 的指针。如果有字段发生了变化，返回 `*RoundTripError`，
+// 如果编码或者解析出错，直接返回这个错误。
+//
+// 比较时忽略私有字段，并且认为 nil 与空的 slice 或 map 相同，time.Time 使用 `time.Time#Equal` 比较。
+func CheckRoundTrip(v interface{}) error {
+	rv := reflect.ValueOf(v)
+
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("go-data: CheckRoundTrip requires a struct but got %T", v)
+	}
+
+	enc := Encoder{}
+	d, err := enc.EncodeE(rv.Interface())
+
+	if err != nil {
+		return err
+	}
+
+	decoded := reflect.New(rv.Type())
+	dec := Decoder{}
+
+	if err := dec.Decode(d, decoded.Interface()); err != nil {
+		return err
+	}
+
+	var fields []string
+	compareRoundTrip(&fields, "", rv, decoded.Elem())
+
+	if len(fields) != 0 {
+		return &RoundTripError{
+			Fields: fields,
+		}
+	}
+
+	return nil
+}
+
+func compareRoundTrip(fields *[]string, path string, expected, actual reflect.Value) {
+	if expected.Type() == typeOfTime {
+		if !expected.Interface().(time.Time).Equal(actual.Interface().(time.Time)) {
+			*fields = append(*fields, path)
+		}
+
+		return
+	}
+
+	switch expected.Kind() {
+	case reflect.Struct:
+		t := expected.Type()
+
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+
+			if f.PkgPath != "" {
+				continue
+			}
+
+			compareRoundTrip(fields, joinFieldPath(path, f.Name), expected.Field(i), actual.Field(i))
+		}
+
+		return
+
+	case reflect.Ptr:
+		if expected.IsNil() || actual.IsNil() {
+			if expected.IsNil() != actual.IsNil() {
+				*fields = append(*fields, path)
+			}
+
+			return
+		}
+
+		compareRoundTrip(fields, path, expected.Elem(), actual.Elem())
+		return
+
+	case reflect.Slice, reflect.Array:
+		if expected.Len() != actual.Len() {
+			*fields = append(*fields, path)
+			return
+		}
+
+		for i := 0; i < expected.Len(); i++ {
+			compareRoundTrip(fields, joinFieldPath(path, strconv.Itoa(i)), expected.Index(i), actual.Index(i))
+		}
+
+		return
+
+	case reflect.Map:
+		if expected.Len() == 0 && actual.Len() == 0 {
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(expected.Interface(), actual.Interface()) {
+		*fields = append(*fields, path)
+	}
+}
+
+func joinFieldPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
@@ -0,0 +1,90 @@
+package data
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestCheckRoundTrip(t *testing.T) {
+	type Server struct {
+		Host string `data:"host"`
+		Port int    `data:"port"`
+	}
+	type Good struct {
+		Name     string            `data:"name"`
+		Servers  []Server          `data:"servers"`
+		Primary  *Server           `data:"primary"`
+		Labels   map[string]string `data:"labels"`
+		Tags     []string          `data:"tags"`
+		Created  time.Time         `data:"created"`
+		Timeout  time.Duration     `data:"timeout"`
+		internal int
+	}
+	type Bad struct {
+		Name    string      `data:"name"`
+		Skipped int         `data:"-"`
+		Value   interface{} `data:"value"`
+		Server  Server      `data:"server"`
+	}
+	type Dup struct {
+		A string `data:"key"`
+		B string `data:"key"`
+	}
+
+	cases := []struct {
+		Value  interface{}
+		Fields []string
+	}{
+		{
+			&Good{
+				Name:     "good",
+				Servers:  []Server{{"a", 1}, {"b", 2}},
+				Primary:  &Server{"p", 3},
+				Labels:   map[string]string{"k": "v"},
+				Tags:     []string{},
+				Created:  time.Date(2020, 1, 2, 3, 4, 5, 0, time.Local),
+				Timeout:  time.Second,
+				internal: 1,
+			},
+			nil,
+		},
+		{
+			Good{},
+			nil,
+		},
+		{
+			Bad{
+				Name:    "bad",
+				Skipped: 1,
+				Value:   1,
+				Server:  Server{"s", 0},
+			},
+			[]string{"Skipped", "Value"},
+		},
+		{
+			Dup{"a", "b"},
+			[]string{"A"},
+		},
+	}
+	a := assert.New(t)
+
+	for i, c := range cases {
+		a.Use(&i, &c)
+		err := CheckRoundTrip(c.Value)
+
+		if c.Fields == nil {
+			a.NilError(err)
+			continue
+		}
+
+		var rtErr *RoundTripError
+		a.Assert(errors.As(err, &rtErr))
+		a.Equal(rtErr.Fields, c.Fields)
+	}
+
+	a.NonNilError(CheckRoundTrip(1))
+	a.Equal((&RoundTripError{Fields: []string{"A", "B.C"}}).Error(), "go-data: fields changed after round trip: `A`, `B.C`")
+}
@@ -0,0 +1,285 @@
+package data
+
+import (
+	"math"
+	"strings"
+)
+
+// Exists 判断 query 对应的值是否存在，结果与 `d.Query(query) != nil` 相同。
+// 与 Query 不同，Exists 在查找 RawData、Data 和标准 slice 组成的路径时不会分配任何内存，
+// 适合在每个请求都会调用的计量、鉴权等代码中使用。
+func (d Data) Exists(query string) bool {
+	if query == "" {
+		return d.data != nil
+	}
+
+	if currentQueryTracer() != nil {
+		return d.Query(query) != nil
+	}
+
+	parent, last, ok := d.data.queryParent(query)
+
+	if !ok {
+		return false
+	}
+
+	switch val := parent.(type) {
+	case RawData:
+		return val[last] != nil
+	case Data:
+		return val.data[last] != nil
+	case []interface{}:
+		idx, ok := sliceIndex(last, len(val))
+		return ok && val[idx] != nil
+	case []RawData:
+		_, ok := sliceIndex(last, len(val))
+		return ok
+	case []int64:
+		_, ok := sliceIndex(last, len(val))
+		return ok
+	case []uint64:
+		_, ok := sliceIndex(last, len(val))
+		return ok
+	case []float64:
+		_, ok := sliceIndex(last, len(val))
+		return ok
+	case []string:
+		_, ok := sliceIndex(last, len(val))
+		return ok
+	case []bool:
+		_, ok := sliceIndex(last, len(val))
+		return ok
+	}
+
+	next, ok, _ := getField(parent, last)
+	return ok && next != nil
+}
+
+// QueryInt64 查询 query 对应的整数，如果值不存在或者不是整数则返回 false。
+// uint64 只要不超过 math.MaxInt64 也会被转化成 int64，float64 不会被转化。
+//
+// 与 `Data#Exists` 一样，查找标准类型组成的路径时不会分配任何内存。
+func (d Data) QueryInt64(query string) (int64, bool) {
+	parent, last, ok := d.data.queryScalarParent(query)
+
+	if !ok {
+		return 0, false
+	}
+
+	if s, ok := parent.([]int64); ok {
+		if idx, ok := sliceIndex(last, len(s)); ok {
+			return s[idx], true
+		}
+
+		return 0, false
+	}
+
+	if s, ok := parent.([]uint64); ok {
+		if idx, ok := sliceIndex(last, len(s)); ok && s[idx] <= math.MaxInt64 {
+			return int64(s[idx]), true
+		}
+
+		return 0, false
+	}
+
+	switch val := scalarField(parent, last).(type) {
+	case int64:
+		return val, true
+	case uint64:
+		if val <= math.MaxInt64 {
+			return int64(val), true
+		}
+	}
+
+	return 0, false
+}
+
+// QueryUint64 查询 query 对应的无符号整数，如果值不存在或者不是无符号整数则返回 false。
+// 非负的 int64 也会被转化成 uint64，float64 不会被转化。
+//
+// 与 `Data#Exists` 一样，查找标准类型组成的路径时不会分配任何内存。
+func (d Data) QueryUint64(query string) (uint64, bool) {
+	parent, last, ok := d.data.queryScalarParent(query)
+
+	if !ok {
+		return 0, false
+	}
+
+	if s, ok := parent.([]uint64); ok {
+		if idx, ok := sliceIndex(last, len(s)); ok {
+			return s[idx], true
+		}
+
+		return 0, false
+	}
+
+	if s, ok := parent.([]int64); ok {
+		if idx, ok := sliceIndex(last, len(s)); ok && s[idx] >= 0 {
+			return uint64(s[idx]), true
+		}
+
+		return 0, false
+	}
+
+	switch val := scalarField(parent, last).(type) {
+	case uint64:
+		return val, true
+	case int64:
+		if val >= 0 {
+			return uint64(val), true
+		}
+	}
+
+	return 0, false
+}
+
+// QueryFloat64 查询 query 对应的数字，如果值不存在或者不是数字则返回 false，int64 和 uint64 也会被转化成 float64。
+//
+// 与 `Data#Exists` 一样，查找标准类型组成的路径时不会分配任何内存。
+func (d Data) QueryFloat64(query string) (float64, bool) {
+	parent, last, ok := d.data.queryScalarParent(query)
+
+	if !ok {
+		return 0, false
+	}
+
+	switch s := parent.(type) {
+	case []float64:
+		if idx, ok := sliceIndex(last, len(s)); ok {
+			return s[idx], true
+		}
+
+		return 0, false
+	case []int64:
+		if idx, ok := sliceIndex(last, len(s)); ok {
+			return float64(s[idx]), true
+		}
+
+		return 0, false
+	case []uint64:
+		if idx, ok := sliceIndex(last, len(s)); ok {
+			return float64(s[idx]), true
+		}
+
+		return 0, false
+	}
+
+	switch val := scalarField(parent, last).(type) {
+	case float64:
+		return val, true
+	case int64:
+		return float64(val), true
+	case uint64:
+		return float64(val), true
+	}
+
+	return 0, false
+}
+
+// QueryString 查询 query 对应的字符串，如果值不存在或者不是字符串则返回 false。
+//
+// 与 `Data#Exists` 一样，查找标准类型组成的路径时不会分配任何内存。
+func (d Data) QueryString(query string) (string, bool) {
+	parent, last, ok := d.data.queryScalarParent(query)
+
+	if !ok {
+		return "", false
+	}
+
+	if s, ok := parent.([]string); ok {
+		if idx, ok := sliceIndex(last, len(s)); ok {
+			return s[idx], true
+		}
+
+		return "", false
+	}
+
+	str, ok := scalarField(parent, last).(string)
+	return str, ok
+}
+
+// QueryBool 查询 query 对应的布尔值，如果值不存在或者不是布尔值则返回 false。
+//
+// 与 `Data#Exists` 一样，查找标准类型组成的路径时不会分配任何内存。
+func (d Data) QueryBool(query string) (bool, bool) {
+	parent, last, ok := d.data.queryScalarParent(query)
+
+	if !ok {
+		return false, false
+	}
+
+	if s, ok := parent.([]bool); ok {
+		if idx, ok := sliceIndex(last, len(s)); ok {
+			return s[idx], true
+		}
+
+		return false, false
+	}
+
+	b, ok := scalarField(parent, last).(bool)
+	return b, ok
+}
+
+// queryScalarParent 与 queryParent 相同，但是空 query 以及开启了 QueryTracer 的情况会被特殊处理：
+// 空 query 对应的是 d 本身，一定不是标量；开启 QueryTracer 时，通过 Query 查找以便记录查找过程。
+func (d RawData) queryScalarParent(query string) (parent interface{}, last string, ok bool) {
+	if query == "" {
+		return
+	}
+
+	if currentQueryTracer() != nil {
+		// 将查到的值包装成一个只有一个 key 的 RawData，这样后续的处理逻辑不需要改变。
+		if v := d.Query(query); v != nil {
+			return RawData{"": v}, "", true
+		}
+
+		return
+	}
+
+	return d.queryParent(query)
+}
+
+// queryParent 查找 query 最后一级的上一级值，返回这个值以及最后一级的字段，query 不能为空。
+// 如果 query 中没有转义字符，查找过程不会分配内存。
+func (d RawData) queryParent(query string) (parent interface{}, last string, ok bool) {
+	parent = d
+
+	if strings.IndexByte(query, queryEscape) >= 0 {
+		fields := SplitQuery(query)
+
+		for _, f := range fields[:len(fields)-1] {
+			if parent, ok, _ = getField(parent, f); !ok {
+				return
+			}
+		}
+
+		return parent, fields[len(fields)-1], true
+	}
+
+	for {
+		idx := strings.IndexByte(query, querySeparator)
+
+		if idx < 0 {
+			return parent, query, true
+		}
+
+		if parent, ok, _ = getField(parent, query[:idx]); !ok {
+			return
+		}
+
+		query = query[idx+1:]
+	}
+}
+
+// scalarField 返回 parent 中 f 对应的值，调用者需要先处理 parent 是标量 slice 的情况，否则会分配内存。
+func scalarField(parent interface{}, f string) interface{} {
+	switch val := parent.(type) {
+	case RawData:
+		return val[f]
+	case Data:
+		return val.data[f]
+	}
+
+	next, _, _ := getField(parent, f)
+	return next
+}
@@ -0,0 +1,138 @@
+package data
+
+import (
+	"math"
+	"testing"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestDataExistsAndScalars(t *testing.T) {
+	d := Make(RawData{
+		"int":   -1,
+		"uint":  uint64(math.MaxUint64),
+		"float": 1.5,
+		"str":   "str",
+		"bool":  true,
+		"nil":   nil,
+		"obj": RawData{
+			"a.b":  int64(2),
+			"list": []RawData{{"n": 3}},
+		},
+		"data":   Make(RawData{"n": uint64(4)}),
+		"ints":   []int64{5},
+		"uints":  []uint64{6, math.MaxUint64},
+		"floats": []float64{7.5},
+		"strs":   []string{"s"},
+		"bools":  []bool{false},
+		"mixed":  []interface{}{8, nil, "m"},
+	})
+
+	cases := []struct {
+		Query   string
+		Exists  bool
+		Int64   interface{}
+		Uint64  interface{}
+		Float64 interface{}
+		String  interface{}
+		Bool    interface{}
+	}{
+		{"int", true, int64(-1), nil, -1.0, nil, nil},
+		{"uint", true, nil, uint64(math.MaxUint64), float64(math.MaxUint64), nil, nil},
+		{"float", true, nil, nil, 1.5, nil, nil},
+		{"str", true, nil, nil, nil, "str", nil},
+		{"bool", true, nil, nil, nil, nil, true},
+		{"nil", false, nil, nil, nil, nil, nil},
+		{"not_exist", false, nil, nil, nil, nil, nil},
+		{"obj", true, nil, nil, nil, nil, nil},
+		{`obj.a\.b`, true, int64(2), uint64(2), 2.0, nil, nil},
+		{"obj.list.0.n", true, int64(3), uint64(3), 3.0, nil, nil},
+		{"obj.list.1.n", false, nil, nil, nil, nil, nil},
+		{"obj.list.1", false, nil, nil, nil, nil, nil},
+		{"data.n", true, int64(4), uint64(4), 4.0, nil, nil},
+		{"ints.0", true, int64(5), uint64(5), 5.0, nil, nil},
+		{"ints.1", false, nil, nil, nil, nil, nil},
+		{"uints.0", true, int64(6), uint64(6), 6.0, nil, nil},
+		{"uints.1", true, nil, uint64(math.MaxUint64), float64(math.MaxUint64), nil, nil},
+		{"floats.0", true, nil, nil, 7.5, nil, nil},
+		{"strs.0", true, nil, nil, nil, "s", nil},
+		{"strs.x", false, nil, nil, nil, nil, nil},
+		{"bools.0", true, nil, nil, nil, nil, false},
+		{"mixed.0", true, int64(8), uint64(8), 8.0, nil, nil},
+		{"mixed.1", false, nil, nil, nil, nil, nil},
+		{"mixed.2", true, nil, nil, nil, "m", nil},
+		{"str.x", false, nil, nil, nil, nil, nil},
+		{"", true, nil, nil, nil, nil, nil},
+	}
+
+	optional := func(v interface{}, ok bool) interface{} {
+		if !ok {
+			return nil
+		}
+
+		return v
+	}
+
+	run := func(t *testing.T) {
+		for i, c := range cases {
+			a := assert.New(t)
+			a.Use(&i, &c)
+
+			a.Equal(d.Exists(c.Query), c.Exists)
+			a.Equal(d.Exists(c.Query), d.Query(c.Query) != nil)
+			a.Equal(optional(d.QueryInt64(c.Query)), c.Int64)
+			a.Equal(optional(d.QueryUint64(c.Query)), c.Uint64)
+			a.Equal(optional(d.QueryFloat64(c.Query)), c.Float64)
+			a.Equal(optional(d.QueryString(c.Query)), c.String)
+			a.Equal(optional(d.QueryBool(c.Query)), c.Bool)
+		}
+	}
+
+	run(t)
+
+	// 开启 QueryTracer 之后结果相同，并且查找过程会被记录下来。
+	traced := 0
+	SetQueryTracer(func(trace *QueryTrace) {
+		traced++
+	})
+	defer SetQueryTracer(nil)
+	run(t)
+	a := assert.New(t)
+	a.Assert(traced > 0)
+}
+
+func TestDataScalarsNoAlloc(t *testing.T) {
+	a := assert.New(t)
+	d := Make(RawData{
+		"meter": RawData{
+			"count": 1,
+			"rate":  0.5,
+			"name":  "api",
+			"on":    true,
+			"list":  []RawData{{"ids": []int64{1, 2}}},
+		},
+	})
+	allocs := testing.AllocsPerRun(100, func() {
+		d.Exists("meter.count")
+		d.Exists("meter.list.0.ids.1")
+		d.QueryInt64("meter.count")
+		d.QueryInt64("meter.list.0.ids.1")
+		d.QueryUint64("meter.count")
+		d.QueryFloat64("meter.rate")
+		d.QueryString("meter.name")
+		d.QueryBool("meter.on")
+	})
+	a.Equal(allocs, 0.0)
+}
+
+func BenchmarkDataQueryInt64(b *testing.B) {
+	d := Make(RawData{
+		"meter": RawData{
+			"list": []RawData{{"ids": []int64{1, 2}}},
+		},
+	})
+
+	for i := 0; i < b.N; i++ {
+		d.QueryInt64("meter.list.0.ids.1")
+	}
+}
@@ -0,0 +1,415 @@
+package data
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema 声明了 Data 中各个值应有的类型，key 是值的路径，value 是值的类型。
+// 路径的格式与 `Data#Query` 相同，其中 `*` 可以匹配任意一个 key 或数组下标，比如 `servers.*.port`。
+//
+// 类型会先被标准化成 Data 中使用的类型，比如 int、int32 等都等同于 int64，当前支持以下类型：
+//     - 整型、浮点、bool 和 string；
+//     - time.Time，字符串需要符合 RFC3339 格式；
+//     - 以上类型的 slice，数组中每个元素都会被转化成 slice 的元素类型。
+type Schema map[string]reflect.Type
+
+// ParseJSONWithSchema 解析 JSON 字符串并且按照 schema 声明的类型转化其中的值，
+// 比如将字符串 "8080" 转化成 int64，将字符串 "true" 转化成 bool，
+// 这适合在边界处统一来自不规范上游的数据。
+//
+// schema 中声明的路径如果在 JSON 中不存在，则直接忽略；如果值无法转化成声明的类型，返回错误。
+func ParseJSONWithSchema(str string, schema Schema) (d Data, err error) {
+	p := Parser{}
+	return p.ParseJSONWithSchema(str, schema)
+}
+
+// ParseJSONWithSchema 解析 JSON 字符串并且按照 schema 声明的类型转化其中的值，
+// 详见 `ParseJSONWithSchema` 文档。
+func (p *Parser) ParseJSONWithSchema(str string, schema Schema) (d Data, err error) {
+	if d, err = p.ParseJSON(str); err != nil {
+		return
+	}
+
+	if err = schema.apply(d.data); err != nil {
+		d = emptyData
+	}
+
+	return
+}
+
+func (schema Schema) apply(d RawData) error {
+	if len(d) == 0 {
+		return nil
+	}
+
+	paths := make([]string, 0, len(schema))
+
+	for path := range schema {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		t, err := schemaType(schema[path])
+
+		if err != nil {
+			return err
+		}
+
+		if _, err := coerceSchemaPath(d, SplitQuery(path), t, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 所有的校验规则名，用于 ValidationIssue 的 Rule 字段。
+const (
+	ValidationRuleType        = "type"        // 值无法转化成 schema 声明的类型。
+	ValidationRuleUnsupported = "unsupported" // schema 声明的类型不被支持。
+)
+
+// ValidationIssue 是 `Schema#Validate` 发现的一个问题。
+type ValidationIssue struct {
+	Path     string `data:"path"`     // 出问题的值的路径，格式与 `Data#Query` 相同。
+	Expected string `data:"expected"` // schema 声明的类型。
+	Actual   string `data:"actual"`   // 值的实际类型。
+	Rule     string `data:"rule"`     // 违反的规则，见 ValidationRuleType 等常量。
+}
+
+// ValidationReport 记录了 `Schema#Validate` 发现的所有问题。
+// ValidationReport 实现了 error 接口，也可以通过 `ValidationReport#Data` 转化成 Data，
+// 方便在 API 的错误响应中返回机器可读的校验结果。
+type ValidationReport struct {
+	Issues []ValidationIssue `data:"issues"` // 所有问题，按照 schema 路径的字典序排列。
+}
+
+// Validate 检查 d 中的值是否都能转化成 schema 声明的类型，返回发现的所有问题，d 本身不会被修改。
+// 转化规则与 `ParseJSONWithSchema` 相同，schema 中声明的路径如果在 d 中不存在，则直接忽略。
+//
+// 如果没有发现任何问题，返回 nil，否则返回的 err 是一个 *ValidationReport，可以通过 errors.As 获得所有问题。
+func (schema Schema) Validate(d Data) error {
+	report := &ValidationReport{}
+	paths := make([]string, 0, len(schema))
+
+	for path := range schema {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		expected := schema[path]
+		t, err := schemaType(expected)
+
+		if err != nil {
+			report.add(path, expected, "", ValidationRuleUnsupported)
+			continue
+		}
+
+		walkSchemaPath(d.data, SplitQuery(path), nil, func(p []string, v interface{}) {
+			if _, err := coerceValue(v, t, p); err != nil {
+				report.add(JoinQuery(p...), t, fmt.Sprintf("%T", v), ValidationRuleType)
+			}
+		})
+	}
+
+	if report.Len() == 0 {
+		return nil
+	}
+
+	return report
+}
+
+func (report *ValidationReport) add(path string, expected reflect.Type, actual, rule string) {
+	report.Issues = append(report.Issues, ValidationIssue{
+		Path:     path,
+		Expected: expected.String(),
+		Actual:   actual,
+		Rule:     rule,
+	})
+}
+
+// Len 返回问题的个数。
+func (report *ValidationReport) Len() int {
+	return len(report.Issues)
+}
+
+// Error 返回所有问题的描述。
+func (report *ValidationReport) Error() string {
+	msgs := make([]string, 0, len(report.Issues))
+
+	for _, issue := range report.Issues {
+		if issue.Rule == ValidationRuleUnsupported {
+			msgs = append(msgs, fmt.Sprintf("type %v of `%v` is not supported by schema", issue.Expected, issue.Path))
+			continue
+		}
+
+		msgs = append(msgs, fmt.Sprintf("`%v` should be %v but got %v", issue.Path, issue.Expected, issue.Actual))
+	}
+
+	return "go-data: fail to validate data: " + strings.Join(msgs, "; ")
+}
+
+// Data 将 report 转化成 Data，格式为 `{"issues":[{"path":"a.b","expected":"int64","actual":"string","rule":"type"}]}`。
+func (report *ValidationReport) Data() Data {
+	enc := Encoder{}
+	return enc.Encode(report)
+}
+
+// walkSchemaPath 对 v 中所有与 fields 匹配的值调用 fn，fields 中的 `*` 可以匹配任意一个 key 或数组下标。
+func walkSchemaPath(v interface{}, fields []string, path []string, fn func(path []string, v interface{})) {
+	if len(fields) == 0 {
+		fn(path, v)
+		return
+	}
+
+	field := fields[0]
+
+	if m, ok := v.(RawData); ok {
+		if field != "*" {
+			if val, ok := m[field]; ok {
+				walkSchemaPath(val, fields[1:], append(path[:len(path):len(path)], field), fn)
+			}
+
+			return
+		}
+
+		var order *keyOrder
+
+		for _, k := range order.sortedKeys(m) {
+			walkSchemaPath(m[k], fields[1:], append(path[:len(path):len(path)], k), fn)
+		}
+
+		return
+	}
+
+	elems, ok := sliceElems(v)
+
+	if !ok {
+		return
+	}
+
+	for i, elem := range elems {
+		if field != "*" && field != strconv.Itoa(i) {
+			continue
+		}
+
+		walkSchemaPath(elem, fields[1:], append(path[:len(path):len(path)], strconv.Itoa(i)), fn)
+	}
+}
+
+// schemaType 将 t 标准化成 Data 中使用的类型。
+func schemaType(t reflect.Type) (reflect.Type, error) {
+	if t == typeOfTime {
+		return t, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return typeOfInt64, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return typeOfUint64, nil
+	case reflect.Float32, reflect.Float64:
+		return typeOfFloat64, nil
+	case reflect.Bool:
+		return typeOfBool, nil
+	case reflect.String:
+		return typeOfString, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Slice || t.Elem().Kind() == reflect.Array {
+			break
+		}
+
+		elem, err := schemaType(t.Elem())
+
+		if err != nil {
+			return nil, err
+		}
+
+		return reflect.SliceOf(elem), nil
+	}
+
+	return nil, fmt.Errorf("go-data: type %v is not supported by schema", t)
+}
+
+// coerceSchemaPath 将 v 中 fields 对应的值转化成类型 t，返回转化后的 v。
+func coerceSchemaPath(v interface{}, fields []string, t reflect.Type, path []string) (interface{}, error) {
+	if len(fields) == 0 {
+		return coerceValue(v, t, path)
+	}
+
+	field := fields[0]
+
+	if m, ok := v.(RawData); ok {
+		if field != "*" {
+			if val, ok := m[field]; ok {
+				cv, err := coerceSchemaPath(val, fields[1:], t, append(path, field))
+
+				if err != nil {
+					return nil, err
+				}
+
+				m[field] = cv
+			}
+
+			return m, nil
+		}
+
+		var order *keyOrder
+
+		for _, k := range order.sortedKeys(m) {
+			cv, err := coerceSchemaPath(m[k], fields[1:], t, append(path, k))
+
+			if err != nil {
+				return nil, err
+			}
+
+			m[k] = cv
+		}
+
+		return m, nil
+	}
+
+	elems, ok := sliceElems(v)
+
+	if !ok {
+		return v, nil
+	}
+
+	for i, elem := range elems {
+		if field != "*" && field != strconv.Itoa(i) {
+			continue
+		}
+
+		cv, err := coerceSchemaPath(elem, fields[1:], t, append(path, strconv.Itoa(i)))
+
+		if err != nil {
+			return nil, err
+		}
+
+		elems[i] = cv
+	}
+
+	return normalizeSlice(elems), nil
+}
+
+// coerceValue 将 v 转化成类型 t，t 必须是已经标准化过的类型。
+func coerceValue(v interface{}, t reflect.Type, path []string) (interface{}, error) {
+	if v == nil || reflect.TypeOf(v) == t {
+		return v, nil
+	}
+
+	if t.Kind() == reflect.Slice {
+		elems, ok := sliceElems(v)
+
+		if !ok {
+			return nil, coerceError(v, t, path, nil)
+		}
+
+		slice := reflect.MakeSlice(t, 0, len(elems))
+
+		for i, elem := range elems {
+			cv, err := coerceValue(elem, t.Elem(), append(path, strconv.Itoa(i)))
+
+			if err != nil {
+				return nil, err
+			}
+
+			if cv == nil {
+				return nil, coerceError(elem, t.Elem(), append(path, strconv.Itoa(i)), nil)
+			}
+
+			slice = reflect.Append(slice, reflect.ValueOf(cv))
+		}
+
+		return slice.Interface(), nil
+	}
+
+	var cv interface{}
+	var err error
+
+	switch t {
+	case typeOfInt64:
+		switch val := v.(type) {
+		case string:
+			cv, err = strconv.ParseInt(strings.TrimSpace(val), 10, 64)
+		case uint64:
+			if val <= math.MaxInt64 {
+				cv = int64(val)
+			}
+		case float64:
+			if val == math.Trunc(val) && val >= math.MinInt64 && val < math.MaxInt64 {
+				cv = int64(val)
+			}
+		}
+
+	case typeOfUint64:
+		switch val := v.(type) {
+		case string:
+			cv, err = strconv.ParseUint(strings.TrimSpace(val), 10, 64)
+		case int64:
+			if val >= 0 {
+				cv = uint64(val)
+			}
+		case float64:
+			if val == math.Trunc(val) && val >= 0 && val < math.MaxUint64 {
+				cv = uint64(val)
+			}
+		}
+
+	case typeOfFloat64:
+		switch val := v.(type) {
+		case string:
+			cv, err = strconv.ParseFloat(strings.TrimSpace(val), 64)
+		case int64:
+			cv = float64(val)
+		case uint64:
+			cv = float64(val)
+		}
+
+	case typeOfBool:
+		if val, ok := v.(string); ok {
+			cv, err = strconv.ParseBool(strings.TrimSpace(val))
+		}
+
+	case typeOfString:
+		switch val := v.(type) {
+		case int64:
+			cv = strconv.FormatInt(val, 10)
+		case uint64:
+			cv = strconv.FormatUint(val, 10)
+		case float64:
+			cv = strconv.FormatFloat(val, 'g', -1, 64)
+		case bool:
+			cv = strconv.FormatBool(val)
+		}
+
+	case typeOfTime:
+		if val, ok := v.(string); ok {
+			cv, err = time.Parse(time.RFC3339Nano, strings.TrimSpace(val))
+		}
+	}
+
+	if cv == nil || err != nil {
+		return nil, coerceError(v, t, path, err)
+	}
+
+	return cv, nil
+}
+
+func coerceError(v interface{}, t reflect.Type, path []string, err error) error {
+	if err != nil {
+		return fmt.Errorf("go-data: fail to coerce `%v` to %v: %w", JoinQuery(path...), t, err)
+	}
+
+	return fmt.Errorf("go-data: fail to coerce `%v` to %v: invalid value %v", JoinQuery(path...), t, v)
+}
@@ -0,0 +1,134 @@
+package data
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestParseJSONWithSchema(t *testing.T) {
+	schema := Schema{
+		"port":           reflect.TypeOf(0),
+		"debug":          reflect.TypeOf(false),
+		"ratio":          reflect.TypeOf(float32(0)),
+		"id":             reflect.TypeOf(""),
+		"created":        reflect.TypeOf(time.Time{}),
+		"ids":            reflect.TypeOf([]uint{}),
+		"servers.*.port": reflect.TypeOf(int32(0)),
+		"not_exist":      reflect.TypeOf(0),
+	}
+	cases := []struct {
+		JSON     string
+		Result   RawData
+		HasError bool
+	}{
+		{
+			`{"port":"8080","debug":"true","ratio":"0.5","id":123,"created":"2020-01-02T03:04:05Z","ids":["1",2],"servers":[{"port":"80"},{"port":443}],"other":"1"}`,
+			RawData{
+				"port":    int64(8080),
+				"debug":   true,
+				"ratio":   0.5,
+				"id":      "123",
+				"created": time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+				"ids":     []uint64{1, 2},
+				"servers": []RawData{
+					{"port": int64(80)},
+					{"port": int64(443)},
+				},
+				"other": "1",
+			},
+			false,
+		},
+		{ // 已经是正确类型的值保持不变。
+			`{"port":8080,"debug":false,"ratio":1.5}`,
+			RawData{
+				"port":  int64(8080),
+				"debug": false,
+				"ratio": 1.5,
+			},
+			false,
+		},
+		{
+			`{"port":"http"}`,
+			nil,
+			true,
+		},
+		{
+			`{"servers":[{"port":"80"},{"port":true}]}`,
+			nil,
+			true,
+		},
+		{
+			`{"ids":"1,2"}`,
+			nil,
+			true,
+		},
+		{
+			`{"port":1.5}`,
+			nil,
+			true,
+		},
+	}
+	a := assert.New(t)
+
+	for i, c := range cases {
+		a.Use(&i, &c)
+		d, err := ParseJSONWithSchema(c.JSON, schema)
+
+		if c.HasError {
+			a.NonNilError(err)
+			continue
+		}
+
+		a.NilError(err)
+		a.Equal(d.data, c.Result)
+	}
+
+	_, err := ParseJSONWithSchema(`{"a":1}`, Schema{"a": reflect.TypeOf(map[string]int{})})
+	a.NonNilError(err)
+
+	_, err = ParseJSONWithSchema(`{"servers":[{"port":"80"},{"port":true}]}`, schema)
+	a.Equal(err.Error(), "go-data: fail to coerce `servers.1.port` to int64: invalid value true")
+}
+
+func TestSchemaValidate(t *testing.T) {
+	a := assert.New(t)
+	schema := Schema{
+		"port":           reflect.TypeOf(0),
+		"debug":          reflect.TypeOf(false),
+		"servers.*.port": reflect.TypeOf(int32(0)),
+		"handler":        reflect.TypeOf(func() {}),
+		"not_exist":      reflect.TypeOf(0),
+	}
+	d, err := ParseJSON(`{"port":"http","debug":"true","servers":[{"port":"80"},{"port":true},{"port":1.5}]}`)
+	a.NilError(err)
+
+	err = schema.Validate(d)
+	var report *ValidationReport
+	a.Assert(errors.As(err, &report))
+	a.Equal(report.Len(), 4)
+	a.Equal(report.Issues, []ValidationIssue{
+		{Path: "handler", Expected: "func()", Rule: ValidationRuleUnsupported},
+		{Path: "port", Expected: "int64", Actual: "string", Rule: ValidationRuleType},
+		{Path: "servers.1.port", Expected: "int64", Actual: "bool", Rule: ValidationRuleType},
+		{Path: "servers.2.port", Expected: "int64", Actual: "float64", Rule: ValidationRuleType},
+	})
+	a.Equal(report.Error(), "go-data: fail to validate data: type func() of `handler` is not supported by schema; `port` should be int64 but got string; `servers.1.port` should be int64 but got bool; `servers.2.port` should be int64 but got float64")
+	a.Equal(report.Data().Query("issues.1"), RawData{
+		"path":     "port",
+		"expected": "int64",
+		"actual":   "string",
+		"rule":     "type",
+	})
+
+	// Validate 不会修改 d。
+	a.Equal(d.Get("debug"), "true")
+
+	delete(schema, "handler")
+	d, err = ParseJSON(`{"port":"8080","servers":[{"port":80}]}`)
+	a.NilError(err)
+	a.NilError(schema.Validate(d))
+}
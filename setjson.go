@@ -0,0 +1,310 @@
+package data
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/tidwall/gjson"
+)
+
+// SetJSON 将一段 JSON rawJSON 解析后写入 d 中 path 对应的位置，只有 rawJSON 对应的子树需要解析和标准化，
+// 这适合在代理服务中用最少的解析成本改写请求或响应的内容。
+//
+// path 的格式与 `Data#Query` 相同，是以“.”分隔的字段，例如 a.b.c 代表设置 d["a"]["b"]["c"]。
+// 与 sjson 类似，路径中不存在的 object 会被自动创建；
+// 如果路径中的值是数组，字段必须是数组下标，下标为 -1 或者等于数组长度时代表在数组末尾追加一个元素。
+//
+// 如果 rawJSON 不是合法的 JSON，返回的错误满足 `errors.Is(err, ErrInvalidJSON)`；
+// 如果路径上的值不是 object 或数组，返回的错误满足 `errors.Is(err, ErrNotObject)`；
+// 如果数组下标超出范围，返回的错误满足 `errors.Is(err, ErrQueryNotFound)`。
+// 出错时 d 不会被修改。
+//
+// SetJSON 会直接修改 d 内部的 map，所有共享同一个 map 的 Data 都会看到这个修改。
+func (d *Data) SetJSON(path string, rawJSON []byte) error {
+	if path == "" {
+		return fmt.Errorf("go-data: path of SetJSON must not be empty")
+	}
+
+	if !gjson.ValidBytes(rawJSON) {
+		return fmt.Errorf("%w: %q", ErrInvalidJSON, rawJSON)
+	}
+
+	res := gjson.ParseBytes(rawJSON)
+	jp := &jsonParser{}
+	val, _ := jp.parseValue(res)
+
+	// 只有 d 记录了 key 的顺序时，才需要记录新值的 key 顺序。
+	var valOrder *keyOrder
+
+	if d.order != nil {
+		valOrder = parseJSONOrder(res)
+	}
+
+	fields := SplitQuery(path)
+
+	if err := checkSetJSONPath(d.data, fields); err != nil {
+		return err
+	}
+
+	if d.data == nil {
+		d.data = RawData{}
+	}
+
+	setJSON(d.data, d.order, fields, val, valOrder)
+	return nil
+}
+
+// Set 将 v 写入 d 中 p 对应的位置，v 会先使用 `Encoder` 转化成 Data 支持的类型。
+// 路径和出错的规则与 `Data#SetJSON` 相同，出错时 d 不会被修改。
+//
+// Set 会直接修改 d 内部的 map，所有共享同一个 map 的 Data 都会看到这个修改。
+func (d *Data) Set(p Path, v interface{}) error {
+	if len(p) == 0 {
+		return fmt.Errorf("go-data: path of Set must not be empty")
+	}
+
+	enc := Encoder{}
+	wrapped, err := enc.EncodeE(map[string]interface{}{
+		"": v,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if err := checkSetJSONPath(d.data, p); err != nil {
+		return err
+	}
+
+	if d.data == nil {
+		d.data = RawData{}
+	}
+
+	setJSON(d.data, d.order, p, wrapped.data[""], nil)
+	return nil
+}
+
+// Append 将 values 追加到 d 中 query 对应的数组末尾，values 会先使用 `Encoder` 转化成 Data 支持的类型。
+// 追加之后的数组依然是标准的 slice 类型：如果所有元素类型相同，使用这个类型的 slice，否则使用 []interface{}。
+//
+// 如果 query 对应的值不存在，会新建一个数组，路径中不存在的 object 也会被自动创建，规则与 `Data#SetJSON` 相同；
+// 如果 query 对应的值不是数组，返回的错误满足 `errors.Is(err, ErrNotArray)`。出错时 d 不会被修改。
+//
+// Append 总是会生成一个新的 slice，不会修改原来的 slice，但会直接修改 d 内部的 map，
+// 所有共享同一个 map 的 Data 都会看到这个修改。
+func (d *Data) Append(query string, values ...interface{}) error {
+	if query == "" {
+		return fmt.Errorf("go-data: query of Append must not be empty")
+	}
+
+	enc := Encoder{}
+	elems := make([]interface{}, 0, len(values))
+
+	for _, v := range values {
+		wrapped, err := enc.EncodeE(map[string]interface{}{
+			"": v,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		elems = append(elems, wrapped.data[""])
+	}
+
+	fields := SplitQuery(query)
+
+	if err := checkSetJSONPath(d.data, fields); err != nil {
+		return err
+	}
+
+	if existing := d.data.Get(fields...); existing != nil {
+		old, ok := sliceElems(existing)
+
+		if !ok {
+			return fmt.Errorf("%w: cannot append to `%v` of type %T", ErrNotArray, query, existing)
+		}
+
+		elems = append(old, elems...)
+	}
+
+	if d.data == nil {
+		d.data = RawData{}
+	}
+
+	setJSON(d.data, d.order, fields, normalizeSlice(elems), orderAt(d.order, query))
+	return nil
+}
+
+// checkSetJSONPath 检查 fields 是否可以设置到 v 中，这样可以保证 setJSON 不会在修改到一半的时候出错。
+func checkSetJSONPath(v interface{}, fields []string) error {
+	for i := range fields {
+		next, err := setJSONChild(v, fields, i)
+
+		if err != nil {
+			return err
+		}
+
+		if next == nil {
+			return nil
+		}
+
+		v = next
+	}
+
+	return nil
+}
+
+// setJSONChild 返回 v 中 fields[i] 对应的值，如果这个值不存在则返回 nil。
+func setJSONChild(v interface{}, fields []string, i int) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	field := fields[i]
+
+	switch val := v.(type) {
+	case RawData:
+		return val[field], nil
+	case Data:
+		return val.data[field], nil
+	}
+
+	rv := reflect.ValueOf(v)
+
+	if rv.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("%w: cannot set `%v` in value of type %T", ErrNotObject, JoinQuery(fields[:i+1]...), v)
+	}
+
+	idx, err := strconv.Atoi(field)
+
+	if err != nil || idx < -1 || idx > rv.Len() {
+		return nil, fmt.Errorf("%w: index `%v` is out of range", ErrQueryNotFound, JoinQuery(fields[:i+1]...))
+	}
+
+	if idx == -1 || idx == rv.Len() {
+		return nil, nil
+	}
+
+	return rv.Index(idx).Interface(), nil
+}
+
+// setJSON 将 val 设置到 v 中 fields 对应的位置，返回设置后的值和顺序信息。
+// 调用前必须已经用 checkSetJSONPath 检查过 fields。
+func setJSON(v interface{}, order *keyOrder, fields []string, val interface{}, valOrder *keyOrder) (interface{}, *keyOrder) {
+	if len(fields) == 0 {
+		return val, valOrder
+	}
+
+	field := fields[0]
+
+	if data, ok := v.(Data); ok {
+		v = data.data
+	}
+
+	if v == nil {
+		v = RawData{}
+
+		if order == nil && valOrder != nil {
+			order = newKeyOrder()
+		}
+	}
+
+	if m, ok := v.(RawData); ok {
+		child, childOrder := setJSON(m[field], order.child(field), fields[1:], val, valOrder)
+		m[field] = child
+
+		if order != nil {
+			order.add(field)
+			order.children[field] = childOrder
+		}
+
+		return m, order
+	}
+
+	// checkSetJSONPath 已经保证这里的 v 一定是 slice，且下标合法。
+	elems, _ := sliceElems(v)
+	l := len(elems)
+	idx, _ := strconv.Atoi(field)
+
+	if idx == -1 {
+		idx = l
+	}
+
+	if idx == l {
+		elems = append(elems, nil)
+	}
+
+	elem, elemOrder := setJSON(elems[idx], order.elem(idx), fields[1:], val, valOrder)
+	elems[idx] = elem
+
+	if order != nil || elemOrder != nil {
+		orders := make([]*keyOrder, len(elems))
+
+		for i := range orders {
+			orders[i] = order.elem(i)
+		}
+
+		orders[idx] = elemOrder
+		order = &keyOrder{
+			elems: orders,
+		}
+	}
+
+	return normalizeSlice(elems), order
+}
+
+// normalizeSlice 将 elems 转化成 Data 中标准的 slice 类型：
+// 如果所有元素类型相同，返回这个类型的 slice，否则返回 []interface{}。
+func normalizeSlice(elems []interface{}) interface{} {
+	var elemType reflect.Type
+
+	for _, elem := range elems {
+		t := typeOfInterface
+
+		if elem != nil {
+			t = reflect.TypeOf(elem)
+		}
+
+		if elemType == nil {
+			elemType = t
+		} else if elemType != t {
+			elemType = typeOfInterface
+			break
+		}
+	}
+
+	if elemType == nil {
+		elemType = typeOfInterface
+	}
+
+	slice := reflect.MakeSlice(reflect.SliceOf(elemType), len(elems), len(elems))
+
+	for i, elem := range elems {
+		if elem != nil {
+			slice.Index(i).Set(reflect.ValueOf(elem))
+		}
+	}
+
+	return slice.Interface()
+}
+
+// sliceElems 将 slice v 中的元素复制到一个 []interface{} 里，如果 v 不是 slice，ok 为 false。
+func sliceElems(v interface{}) (elems []interface{}, ok bool) {
+	rv := reflect.ValueOf(v)
+
+	if rv.Kind() != reflect.Slice {
+		return
+	}
+
+	l := rv.Len()
+	elems = make([]interface{}, 0, l)
+
+	for i := 0; i < l; i++ {
+		elems = append(elems, rv.Index(i).Interface())
+	}
+
+	ok = true
+	return
+}
@@ -0,0 +1,154 @@
+package data
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestDataSetJSON(t *testing.T) {
+	cases := []struct {
+		Path   string
+		JSON   string
+		Result RawData
+		Err    error
+	}{
+		{
+			"name", `"new"`,
+			RawData{"name": "new", "list": []int64{1, 2}, "obj": RawData{"a": int64(1)}},
+			nil,
+		},
+		{
+			"obj.b.c", `{"d":[1,2]}`,
+			RawData{"name": "old", "list": []int64{1, 2}, "obj": RawData{"a": int64(1), "b": RawData{"c": RawData{"d": []int64{1, 2}}}}},
+			nil,
+		},
+		{
+			"list.0", `10`,
+			RawData{"name": "old", "list": []int64{10, 2}, "obj": RawData{"a": int64(1)}},
+			nil,
+		},
+		{ // 追加不同类型的元素后，slice 会变成 []interface{}。
+			"list.-1", `"three"`,
+			RawData{"name": "old", "list": []interface{}{int64(1), int64(2), "three"}, "obj": RawData{"a": int64(1)}},
+			nil,
+		},
+		{
+			"list.2", `3`,
+			RawData{"name": "old", "list": []int64{1, 2, 3}, "obj": RawData{"a": int64(1)}},
+			nil,
+		},
+		{
+			"list.3", `3`,
+			nil,
+			ErrQueryNotFound,
+		},
+		{
+			"name.first", `"a"`,
+			nil,
+			ErrNotObject,
+		},
+		{
+			"name", `{"a":`,
+			nil,
+			ErrInvalidJSON,
+		},
+	}
+	a := assert.New(t)
+
+	for i, c := range cases {
+		a.Use(&i, &c)
+		d := Make(RawData{
+			"name": "old",
+			"list": []int{1, 2},
+			"obj": RawData{
+				"a": 1,
+			},
+		})
+		err := d.SetJSON(c.Path, []byte(c.JSON))
+
+		if c.Err != nil {
+			a.Assert(errors.Is(err, c.Err))
+			a.Equal(d.Get("name"), "old")
+			continue
+		}
+
+		a.NilError(err)
+		a.Equal(d.data, c.Result)
+	}
+
+	var empty Data
+	a.NilError(empty.SetJSON("a.b", []byte(`true`)))
+	a.Equal(empty.data, RawData{"a": RawData{"b": true}})
+	a.NonNilError(empty.SetJSON("", []byte(`true`)))
+}
+
+func TestDataSetJSONKeepOrder(t *testing.T) {
+	a := assert.New(t)
+	p := &Parser{
+		KeepOrder: true,
+	}
+	d, err := p.ParseJSON(`{"z":1,"list":[{"y":1,"x":2}],"a":2}`)
+	a.NilError(err)
+
+	a.NilError(d.SetJSON("m", []byte(`{"k2":1,"k1":2}`)))
+	a.NilError(d.SetJSON("list.0.w", []byte(`3`)))
+	a.NilError(d.SetJSON("list.-1", []byte(`{"b":1,"a":2}`)))
+	a.Equal(d.JSON(false), `{"z":1,"list":[{"y":1,"x":2,"w":3},{"b":1,"a":2}],"a":2,"m":{"k2":1,"k1":2}}`)
+}
+
+func TestDataAppend(t *testing.T) {
+	cases := []struct {
+		Query  string
+		Values []interface{}
+		Result interface{}
+		Err    error
+	}{
+		{"ints", []interface{}{3, int8(4)}, []int64{1, 2, 3, 4}, nil},
+		{"ints", []interface{}{"x", nil}, []interface{}{int64(1), int64(2), "x", nil}, nil},
+		{"ints", nil, []int64{1, 2}, nil},
+		{"objs", []interface{}{map[string]int{"b": 2}}, []RawData{{"a": int64(1)}, {"b": int64(2)}}, nil},
+		{"new", []interface{}{"a", "b"}, []string{"a", "b"}, nil},
+		{"new", nil, []interface{}{}, nil},
+		{"obj.new.list", []interface{}{true}, []bool{true}, nil},
+		{"objs.0.list", []interface{}{1.5}, []float64{1.5}, nil},
+		{"obj", []interface{}{1}, nil, ErrNotArray},
+		{"str", []interface{}{1}, nil, ErrNotArray},
+		{"str.list", []interface{}{1}, nil, ErrNotObject},
+		{"ints.9", []interface{}{1}, nil, ErrQueryNotFound},
+	}
+
+	for i, c := range cases {
+		a := assert.New(t)
+		a.Use(&i, &c)
+
+		original := Make(RawData{
+			"ints": []int{1, 2},
+			"objs": []RawData{{"a": 1}},
+			"obj":  RawData{},
+			"str":  "str",
+		})
+		d := original.Clone()
+		ints := d.Get("ints")
+		err := d.Append(c.Query, c.Values...)
+
+		if c.Err != nil {
+			a.Assert(errors.Is(err, c.Err))
+			a.Equal(d, original)
+			continue
+		}
+
+		a.NilError(err)
+		a.Equal(d.Query(c.Query), c.Result)
+
+		// 原来的 slice 不会被修改。
+		a.Equal(ints, []int64{1, 2})
+	}
+
+	a := assert.New(t)
+	var d Data
+	a.NilError(d.Append("list", 1))
+	a.Equal(d, Make(RawData{"list": []int{1}}))
+	a.NonNilError(d.Append("", 1))
+}
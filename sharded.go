@@ -0,0 +1,208 @@
+package data
+
+import (
+	"hash/fnv"
+	"runtime"
+	"sync"
+)
+
+// ShardedData 是一个可以并发读写的 Data 容器，顶层的 key 按照哈希值被分到多个分片中，每个分片有独立的锁，
+// 修改不相关的顶层 key 的 Patch 可以并行应用，适合在多核机器上频繁更新一个大文档的不同部分。
+//
+// 与 SyncData 一样，每个分片都使用写时复制的方式更新，Load 和 Query 返回的值不会被后续的更新修改。
+// 需要注意，Load 只保证每个分片内部是一致的，并不是整个文档在某一时刻的原子快照。
+type ShardedData struct {
+	shards []*dataShard
+}
+
+type dataShard struct {
+	mu   sync.RWMutex
+	data RawData
+}
+
+// NewShardedData 创建一个有 n 个分片的 ShardedData，初始值为 d。如果 n 不大于 0，分片数等于 CPU 个数。
+// ShardedData 会与 d 共享数据，调用者之后不应该再修改 d。
+func NewShardedData(d Data, n int) *ShardedData {
+	if n <= 0 {
+		n = runtime.NumCPU()
+	}
+
+	sd := &ShardedData{
+		shards: make([]*dataShard, n),
+	}
+
+	for i := range sd.shards {
+		sd.shards[i] = &dataShard{
+			data: RawData{},
+		}
+	}
+
+	for k, v := range d.data {
+		sd.shardOf(k).data[k] = v
+	}
+
+	return sd
+}
+
+func (sd *ShardedData) shardIndex(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(len(sd.shards)))
+}
+
+func (sd *ShardedData) shardOf(key string) *dataShard {
+	return sd.shards[sd.shardIndex(key)]
+}
+
+// Len 返回顶层 key 的个数。
+func (sd *ShardedData) Len() int {
+	l := 0
+
+	for _, shard := range sd.shards {
+		shard.mu.RLock()
+		l += len(shard.data)
+		shard.mu.RUnlock()
+	}
+
+	return l
+}
+
+// Load 将所有分片合并成一个 Data 返回。
+// 调用者不应该修改返回值中的内容，因为它们与 ShardedData 共享数据。
+func (sd *ShardedData) Load() Data {
+	d := RawData{}
+
+	for _, shard := range sd.shards {
+		shard.mu.RLock()
+
+		for k, v := range shard.data {
+			d[k] = v
+		}
+
+		shard.mu.RUnlock()
+	}
+
+	return Data{
+		data: d,
+	}
+}
+
+// Query 使用与 `Data#Query` 相同的规则查询数据，只会锁住 query 第一级 key 所在的分片。
+// 调用者不应该修改返回值中的内容。
+func (sd *ShardedData) Query(query string) interface{} {
+	if query == "" {
+		return sd.Load().data
+	}
+
+	shard := sd.shardOf(SplitQuery(query)[0])
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+	return shard.data.Query(query)
+}
+
+// Apply 将 patch 应用到数据上，出错时数据不会被修改。
+//
+// Apply 只会锁住 patch 涉及的顶层 key 所在的分片，修改不同分片的多个 Apply 可以并行执行，
+// 修改同一个分片的多个 Apply 会依次执行，不会丢失任何一个 patch 的修改。
+// 如果 patch 替换了整个文档，或者删除了根节点，所有分片都会被锁住。
+//
+// 需要注意，patch 的 hook（见 `Patch#OnBeforeAction`）看到的 Data 只包含涉及的分片中的数据，
+// 如果 hook 修改 action 之后涉及了其他分片，Apply 返回 ErrUnlockedShard，数据不会被修改。
+func (sd *ShardedData) Apply(patch *Patch) error {
+	indexes := sd.shardsOfPatch(patch)
+
+	for _, i := range indexes {
+		sd.shards[i].mu.Lock()
+		defer sd.shards[i].mu.Unlock()
+	}
+
+	d := RawData{}
+
+	for _, i := range indexes {
+		for k, v := range sd.shards[i].data {
+			d[k] = v
+		}
+	}
+
+	applied, err := patch.Apply(Data{
+		data: d,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	results := make(map[int]RawData, len(indexes))
+
+	for _, i := range indexes {
+		results[i] = RawData{}
+	}
+
+	for k, v := range applied.data {
+		i := sd.shardIndex(k)
+
+		// hook 可以在应用 action 之前修改它，比如增加其他分片中的修改，这些分片没有被锁住，不能修改。
+		if results[i] == nil {
+			return ErrUnlockedShard
+		}
+
+		results[i][k] = v
+	}
+
+	for i, res := range results {
+		sd.shards[i].data = res
+	}
+
+	return nil
+}
+
+// shardsOfPatch 返回 patch 涉及的所有分片的下标，下标从小到大排列，这样加锁的顺序是固定的，不会死锁。
+func (sd *ShardedData) shardsOfPatch(patch *Patch) []int {
+	set := map[int]bool{}
+	all := false
+
+	addQuery := func(query string) {
+		if query == "" {
+			all = true
+			return
+		}
+
+		set[sd.shardIndex(SplitQuery(query)[0])] = true
+	}
+
+	for _, action := range patch.Actions() {
+		for _, query := range action.Deletes {
+			addQuery(query)
+		}
+
+		for query := range action.DeleteMatches {
+			addQuery(query)
+		}
+
+		for query := range action.Replaces {
+			addQuery(query)
+		}
+
+		for query, d := range action.Updates {
+			if query != "" {
+				addQuery(query)
+				continue
+			}
+
+			// 在根更新数据只会涉及 d 中的顶层 key。
+			for k := range d.data {
+				set[sd.shardIndex(k)] = true
+			}
+		}
+	}
+
+	indexes := make([]int, 0, len(sd.shards))
+
+	for i := range sd.shards {
+		if all || set[i] {
+			indexes = append(indexes, i)
+		}
+	}
+
+	return indexes
+}
@@ -0,0 +1,100 @@
+package data
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestShardedData(t *testing.T) {
+	a := assert.New(t)
+	d := Make(RawData{
+		"a": RawData{"n": 1},
+		"b": []int{1},
+		"c": "str",
+	})
+	sd := NewShardedData(d, 4)
+	a.Equal(sd.Len(), 3)
+	a.Equal(sd.Load(), d)
+	a.Equal(sd.Query("a.n"), int64(1))
+	a.Equal(sd.Query("c"), "str")
+	a.Equal(sd.Query("x.y"), nil)
+
+	p := NewPatch()
+	p.Add([]string{"c"}, map[string]Data{
+		"":  Make(RawData{"b": []int{2}, "d": true}),
+		"a": Make(RawData{"m": 2}),
+	})
+	expected, err := p.Apply(d)
+	a.NilError(err)
+	a.NilError(sd.Apply(p))
+	a.Equal(sd.Load(), expected)
+
+	// 出错时数据不会被修改。
+	bad := NewPatch()
+	bad.Add([]string{"a"}, map[string]Data{"not_exist": Make(RawData{"x": 1})})
+	a.NonNilError(sd.Apply(bad))
+	a.Equal(sd.Load(), expected)
+
+	// 替换整个文档。
+	replace := NewPatch()
+	replace.Replace("", Make(RawData{"z": 1}))
+	a.NilError(sd.Apply(replace))
+	a.Equal(sd.Load(), Make(RawData{"z": 1}))
+
+	// hook 修改了没有被锁住的分片。
+	key := "y"
+
+	for i := 0; sd.shardIndex(key) == sd.shardIndex("z"); i++ {
+		key = fmt.Sprintf("y%v", i)
+	}
+
+	hooked := NewPatch()
+	hooked.Replace("z", Make(RawData{"n": 2}))
+	hooked.OnBeforeAction(func(action *PatchAction, target Data) error {
+		action.Replaces[key] = Make(RawData{"n": 3})
+		return nil
+	})
+	a.Equal(sd.Apply(hooked), ErrUnlockedShard)
+	a.Equal(sd.Load(), Make(RawData{"z": 1}))
+
+	a.Assert(len(NewShardedData(Data{}, 0).shards) > 0)
+}
+
+func TestShardedDataConcurrent(t *testing.T) {
+	a := assert.New(t)
+	sd := NewShardedData(Data{}, 8)
+	const keys = 16
+	const loops = 50
+	wg := &sync.WaitGroup{}
+
+	for i := 0; i < keys; i++ {
+		key := fmt.Sprintf("key%v", i)
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for n := 0; n < loops; n++ {
+				p := NewPatch()
+				p.Add(nil, map[string]Data{"": Make(RawData{key: []int{n}})})
+
+				if err := sd.Apply(p); err != nil {
+					panic(err)
+				}
+
+				sd.Query(key)
+			}
+		}()
+	}
+
+	wg.Wait()
+	d := sd.Load()
+	a.Equal(d.Len(), keys)
+
+	for i := 0; i < keys; i++ {
+		a.Equal(len(d.Get(fmt.Sprintf("key%v", i)).([]int64)), loops)
+	}
+}
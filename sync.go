@@ -0,0 +1,118 @@
+package data
+
+import (
+	"context"
+	"sync"
+)
+
+// SyncData 是一个可以并发读写的 Data 容器，适合用来保存会被动态更新的配置。
+//
+// SyncData 使用写时复制的方式更新数据：每次更新都会生成一个新的 Data 并替换原有的值，
+// 因此通过 Load 得到的 Data 永远不会被后续的更新修改，可以放心的在多个 goroutine 中读取。
+// 需要注意，调用者不应该直接修改 Load 返回的 Data。
+type SyncData struct {
+	mu      sync.RWMutex
+	data    Data
+	version uint64 // 每次修改 data 时加 1，用来判断 Apply 期间 data 是否被其他调用修改。
+}
+
+// NewSyncData 创建一个新的 SyncData，初始值为 d。
+func NewSyncData(d Data) *SyncData {
+	return &SyncData{
+		data: d,
+	}
+}
+
+// Load 返回当前的 Data。
+func (sd *SyncData) Load() Data {
+	sd.mu.RLock()
+	defer sd.mu.RUnlock()
+	return sd.data
+}
+
+// Store 将当前的 Data 替换成 d。
+func (sd *SyncData) Store(d Data) {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	sd.data = d
+	sd.version++
+}
+
+// Apply 将 patch 应用到当前的 Data 上，出错时当前的 Data 不会被修改。
+// 多个 Apply 可以并发调用，不会丢失任何一个 patch 的修改。
+//
+// patch 是在锁外应用到当前 Data 的副本上的，因此不会阻塞 Load。
+// 如果在此期间 Data 被其他调用修改，Apply 会基于新的 Data 重新应用 patch，
+// 所以 patch 的回调（见 `Patch#OnBeforeAction`）可能被调用多次。
+func (sd *SyncData) Apply(patch *Patch) error {
+	return sd.apply(patch, nil)
+}
+
+// ApplyContext 与 Apply 相同，但在应用变更的过程中会定期检查 ctx，
+// 如果 ctx 已经结束则放弃这个 patch 并返回 ctx.Err()，当前的 Data 不会被修改。
+func (sd *SyncData) ApplyContext(ctx context.Context, patch *Patch) error {
+	return sd.apply(patch, newContextChecker(ctx))
+}
+
+func (sd *SyncData) apply(patch *Patch, checker *contextChecker) error {
+	for {
+		sd.mu.RLock()
+		current, version := sd.data, sd.version
+		sd.mu.RUnlock()
+
+		d := current.Clone()
+
+		if err := patch.applyTo(&d, checker); err != nil {
+			return err
+		}
+
+		sd.mu.Lock()
+
+		if sd.version == version {
+			sd.data = d
+			sd.version++
+			sd.mu.Unlock()
+			return nil
+		}
+
+		sd.mu.Unlock()
+	}
+}
+
+// ApplyStream 按顺序将 patches 中收到的每个 patch 应用到 target 上，直到 patches 被关闭或者 ctx 结束，
+// 这适合用来处理从消息队列、etcd watch 等订阅中收到的变更，实现动态配置的客户端。
+//
+// 如果某个 patch 应用失败，target 不会被这个 patch 修改。
+// 这时如果 onError 不为 nil，会调用 onError 并继续处理后面的 patch；否则停止处理并返回这个错误。
+//
+// patches 被关闭时返回 nil，ctx 结束时返回 ctx.Err()。
+// 每个 patch 都是完整应用或者完全不应用的，ctx 结束时不会留下应用了一半的 patch。
+func ApplyStream(ctx context.Context, target *SyncData, patches <-chan *Patch, onError func(patch *Patch, err error)) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case patch, ok := <-patches:
+			if !ok {
+				return nil
+			}
+
+			if patch == nil {
+				continue
+			}
+
+			if err := target.ApplyContext(ctx, patch); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+
+				if onError == nil {
+					return err
+				}
+
+				onError(patch, err)
+			}
+		}
+	}
+}
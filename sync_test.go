@@ -0,0 +1,108 @@
+package data
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestSyncData(t *testing.T) {
+	a := assert.New(t)
+	sd := NewSyncData(Make(RawData{"count": 0}))
+	before := sd.Load()
+
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			patch := NewPatch()
+			patch.Add(nil, map[string]Data{
+				"": Make(RawData{"list": []int{i}}),
+			})
+			errs[i] = sd.Apply(patch)
+			sd.Load().Get("list")
+		}(i)
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		a.NilError(err)
+	}
+
+	a.Equal(len(sd.Load().Get("list").([]int64)), 10)
+	a.Equal(before, Make(RawData{"count": 0}))
+
+	sd.Store(Data{})
+	a.Equal(sd.Load(), Data{})
+
+	// 应用 patch 时没有持有锁，Data 被其他调用修改时会基于新的 Data 重新应用。
+	calls := 0
+	patch := NewPatch()
+	patch.Add(nil, map[string]Data{
+		"": Make(RawData{"b": 2}),
+	})
+	patch.OnBeforeAction(func(action *PatchAction, target Data) error {
+		calls++
+
+		if calls == 1 {
+			sd.Store(Make(RawData{"a": 1}))
+		}
+
+		return nil
+	})
+	a.NilError(sd.Apply(patch))
+	a.Equal(calls, 2)
+	a.Equal(sd.Load(), Make(RawData{"a": 1, "b": 2}))
+
+	// ctx 结束时不会修改 Data。
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	a.Equal(sd.ApplyContext(ctx, NewPatch()), context.Canceled)
+	a.Equal(sd.Load(), Make(RawData{"a": 1, "b": 2}))
+}
+
+func TestApplyStream(t *testing.T) {
+	a := assert.New(t)
+	sd := NewSyncData(Make(RawData{"v": 1}))
+	patches := make(chan *Patch, 4)
+
+	p1 := NewPatch()
+	p1.Add(nil, map[string]Data{"": Make(RawData{"v": 2})})
+	p2 := NewPatch()
+	p2.Add(nil, map[string]Data{"not_exist": Make(RawData{"v": 3})})
+	p3 := NewPatch()
+	p3.Add(nil, map[string]Data{"": Make(RawData{"w": 4})})
+
+	patches <- p1
+	patches <- p2
+	patches <- nil
+	patches <- p3
+	close(patches)
+
+	var failed []*Patch
+	err := ApplyStream(context.Background(), sd, patches, func(patch *Patch, err error) {
+		a.NonNilError(err)
+		failed = append(failed, patch)
+	})
+	a.NilError(err)
+	a.Equal(failed, []*Patch{p2})
+	a.Equal(sd.Load(), Make(RawData{"v": 2, "w": 4}))
+
+	// 没有 onError 时遇到错误直接返回。
+	patches = make(chan *Patch, 2)
+	patches <- p2
+	patches <- p1
+	a.NonNilError(ApplyStream(context.Background(), sd, patches, nil))
+	a.Equal(len(patches), 1)
+
+	// ctx 结束时返回 ctx.Err()。
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	a.Equal(ApplyStream(ctx, sd, make(chan *Patch), nil), context.Canceled)
+}
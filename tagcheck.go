@@ -0,0 +1,169 @@
+package data
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Problem 是 ValidateStructTags 发现的一个 field tag 问题。
+type Problem struct {
+	Field   string // 出问题的字段，是以“.”分隔的 Go 字段名路径，比如 "Server.Port"。
+	Message string // 问题描述。
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%v: %v", p.Field, p.Message)
+}
+
+// ValidateStructTags 检查 v 的类型中所有 field tag 是否正确，返回发现的所有问题，如果没有问题则返回 nil。
+// 这些问题在编解码时并不会报错，但会导致数据被放到错误的位置或者被静默丢弃，
+// 适合在项目的测试中对所有需要编解码的结构调用这个函数。
+//
+// v 必须是 struct 或者 struct 的指针，否则 panic。嵌套的 struct 字段也会被检查，当前会检查以下问题：
+//     - 多个字段使用了相同的 key，包括 squash 展开后的字段；
+//     - 不支持的选项，比如拼错的 `omitemtpy`，以及格式错误的 enum 选项；
+//     - 在非 struct 字段上使用 squash；
+//     - 在私有字段上设置了 tag。
+func ValidateStructTags(v interface{}) []Problem {
+	t := reflect.TypeOf(v)
+
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == nil || t.Kind() != reflect.Struct {
+		panic(fmt.Errorf("go-data: ValidateStructTags requires a struct but got %T", v))
+	}
+
+	tv := &tagValidator{
+		tagName: defaultTagName,
+		visited: map[reflect.Type]bool{},
+	}
+	tv.validate(t, "")
+	return tv.problems
+}
+
+type tagValidator struct {
+	tagName  string
+	visited  map[reflect.Type]bool
+	problems []Problem
+}
+
+func (tv *tagValidator) report(field, format string, args ...interface{}) {
+	tv.problems = append(tv.problems, Problem{
+		Field:   field,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (tv *tagValidator) validate(t reflect.Type, path string) {
+	if tv.visited[t] {
+		return
+	}
+
+	tv.visited[t] = true
+	tv.collectKeys(t, path, map[string]string{})
+}
+
+// collectKeys 检查 t 中的每个字段，并将字段的 key 记录在 owners 中，用来发现重复的 key。
+// squash 展开的字段会使用同一个 owners 递归检查。
+func (tv *tagValidator) collectKeys(t reflect.Type, path string, owners map[string]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := joinFieldPath(path, f.Name)
+		tag, hasTag := f.Tag.Lookup(tv.tagName)
+
+		if f.PkgPath != "" && !f.Anonymous {
+			if hasTag {
+				tv.report(name, "tag is set on an unexported field and will be ignored")
+			}
+
+			continue
+		}
+
+		ft := ParseFieldTag(tag)
+		tv.checkOptions(name, tag)
+
+		if ft.Skipped {
+			continue
+		}
+
+		fieldType := f.Type
+
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+
+		isStruct := fieldType.Kind() == reflect.Struct && fieldType != typeOfTime && !fieldType.AssignableTo(typeOfData)
+
+		if ft.Squash {
+			if isStruct {
+				tv.collectKeys(fieldType, name, owners)
+				continue
+			}
+
+			tv.report(name, "squash has no effect on a field of type %v", f.Type)
+		}
+
+		k := f.Name
+
+		if ft.Alias != "" {
+			k = ft.Alias
+		}
+
+		if other, ok := owners[k]; ok {
+			tv.report(name, "key `%v` is also used by field `%v`", k, other)
+		} else {
+			owners[k] = name
+		}
+
+		tv.validateElem(f.Type, name)
+	}
+}
+
+// validateElem 检查字段类型 t 中嵌套的 struct 类型。
+func (tv *tagValidator) validateElem(t reflect.Type, path string) {
+	for {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+			continue
+
+		case reflect.Struct:
+			if t != typeOfTime && !t.AssignableTo(typeOfData) {
+				tv.validate(t, path)
+			}
+		}
+
+		return
+	}
+}
+
+func (tv *tagValidator) checkOptions(field, tag string) {
+	opts := strings.Split(tag, ",")
+
+	for _, opt := range opts[1:] {
+		switch opt {
+		case "omitempty", "squash", "alloc":
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(opt, "enum="):
+			str := opt[len("enum="):]
+
+			if len(parseEnumItems(str)) != len(strings.Split(str, "|")) {
+				tv.report(field, "invalid enum option `%v`", opt)
+			}
+
+		case strings.HasPrefix(opt, "encoder="), strings.HasPrefix(opt, "decoder="):
+			if strings.TrimSpace(opt[strings.Index(opt, "=")+1:]) == "" {
+				tv.report(field, "transformer name is empty in option `%v`", opt)
+			}
+
+		default:
+			tv.report(field, "unsupported option `%v`", opt)
+		}
+	}
+}
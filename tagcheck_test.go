@@ -0,0 +1,75 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"github.com/huandu/go-assert"
+)
+
+type tagCheckInner struct {
+	Port  int    `data:"port"`
+	Name  string `data:"name,omitemtpy"`
+	Level int    `data:"level,enum=low:1|high"`
+}
+
+type tagCheckGood struct {
+	Name    string          `data:"name,omitempty"`
+	Inner   *tagCheckOther  `data:",squash"`
+	List    []tagCheckOther `data:"list"`
+	Created time.Time       `data:"created"`
+	Data    Data            `data:"data"`
+	Skipped int             `data:"-"`
+	Status  int             `data:"status,enum=active:1|inactive:0"`
+	Value   string          `data:"value,encoder=upper,decoder=lower"`
+	private int
+}
+
+type tagCheckOther struct {
+	ID int `data:"id"`
+}
+
+func TestValidateStructTags(t *testing.T) {
+	type Bad struct {
+		Port    int            `data:"port"`
+		Inner   tagCheckInner  `data:",squash"`
+		Dup     string         `data:"dup"`
+		Dup2    string         `data:"dup,alloc"`
+		Count   int            `data:"count,squash"`
+		Value   string         `data:"value,encoder="`
+		Others  []tagCheckBad  `data:"others"`
+		Pointer **tagCheckBad  `data:"pointer"`
+		secret  string         `data:"secret"`
+		Map     map[string]Bad `data:"map"`
+	}
+	cases := []struct {
+		Value    interface{}
+		Problems []Problem
+	}{
+		{&tagCheckGood{}, nil},
+		{tagCheckOther{}, nil},
+		{Bad{}, []Problem{
+			{"Inner.Port", "key `port` is also used by field `Port`"},
+			{"Inner.Name", "unsupported option `omitemtpy`"},
+			{"Inner.Level", "invalid enum option `enum=low:1|high`"},
+			{"Dup2", "key `dup` is also used by field `Dup`"},
+			{"Count", "squash has no effect on a field of type int"},
+			{"Value", "transformer name is empty in option `encoder=`"},
+			{"Others.Name", "key `Name` is also used by field `Others.Other`"},
+			{"secret", "tag is set on an unexported field and will be ignored"},
+		}},
+	}
+	a := assert.New(t)
+
+	for i, c := range cases {
+		a.Use(&i, &c)
+		a.Equal(ValidateStructTags(c.Value), c.Problems)
+	}
+
+	a.Equal(Problem{"A.B", "message"}.String(), "A.B: message")
+}
+
+type tagCheckBad struct {
+	Other string `data:"Name"`
+	Name  string
+}
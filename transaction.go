@@ -0,0 +1,69 @@
+package data
+
+// Transaction 将多个 Patch 应用到多个有名字的 Data 上，要么全部成功，要么全部不生效。
+// 这适合用于需要同时更新多个相关联的文档、并且保证这些文档始终一致的场景。
+type Transaction struct {
+	names   []string
+	patches map[string][]*Patch
+}
+
+// NewTransaction 创建一个新 Transaction 对象。
+func NewTransaction() *Transaction {
+	return &Transaction{
+		patches: map[string][]*Patch{},
+	}
+}
+
+// Add 为名为 name 的文档增加一个 patch。
+// 同一个文档的 patch 按照增加的顺序应用，不同文档之间按照第一次增加的顺序应用。
+func (tx *Transaction) Add(name string, patch *Patch) {
+	if _, ok := tx.patches[name]; !ok {
+		tx.names = append(tx.names, name)
+	}
+
+	tx.patches[name] = append(tx.patches[name], patch)
+}
+
+// Names 返回所有涉及的文档名。
+func (tx *Transaction) Names() []string {
+	return tx.names
+}
+
+// ApplyTo 将所有 patch 应用到 docs 中对应的文档上。
+//
+// 所有 patch 都会先应用在文档的副本上，只有全部成功之后才会替换 docs 中的文档，
+// 如果任何一个 patch 失败，docs 中所有文档都保持不变，并返回 `*TransactionError`。
+// 如果某个文档在 docs 中不存在或者为 nil，返回的错误是 `ErrDocNotFound`。
+func (tx *Transaction) ApplyTo(docs map[string]*Data) error {
+	applied := make([]Data, 0, len(tx.names))
+
+	for _, name := range tx.names {
+		target := docs[name]
+
+		if target == nil {
+			return &TransactionError{
+				Name: name,
+				Err:  ErrDocNotFound,
+			}
+		}
+
+		d := target.Clone()
+
+		for _, patch := range tx.patches[name] {
+			if err := patch.ApplyTo(&d); err != nil {
+				return &TransactionError{
+					Name: name,
+					Err:  err,
+				}
+			}
+		}
+
+		applied = append(applied, d)
+	}
+
+	for i, name := range tx.names {
+		*docs[name] = applied[i]
+	}
+
+	return nil
+}
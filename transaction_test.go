@@ -0,0 +1,63 @@
+package data
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestTransaction(t *testing.T) {
+	a := assert.New(t)
+	users := Make(RawData{
+		"alice": RawData{"balance": 100},
+	})
+	orders := Make(RawData{
+		"count": 1,
+	})
+	docs := map[string]*Data{
+		"users":  &users,
+		"orders": &orders,
+	}
+
+	pay := NewPatch()
+	pay.Replace("alice.balance", Make(RawData{"amount": 90}))
+	order := NewPatch()
+	order.Add(nil, map[string]Data{
+		"": Make(RawData{"count": 2}),
+	})
+
+	tx := NewTransaction()
+	tx.Add("users", pay)
+	tx.Add("orders", order)
+	a.Equal(tx.Names(), []string{"users", "orders"})
+	a.NilError(tx.ApplyTo(docs))
+	a.Equal(users.Query("alice.balance.amount"), int64(90))
+	a.Equal(orders.Query("count"), int64(2))
+
+	// 任何一个 patch 失败，所有文档都不能被修改。
+	broken := NewPatch()
+	broken.Add(nil, map[string]Data{
+		"not_exist": Make(RawData{"a": 1}),
+	})
+	tx = NewTransaction()
+	tx.Add("users", pay)
+	tx.Add("orders", order)
+	tx.Add("orders", broken)
+	err := tx.ApplyTo(docs)
+	a.Assert(errors.Is(err, ErrQueryNotFound))
+
+	var txErr *TransactionError
+	a.Assert(errors.As(err, &txErr))
+	a.Equal(txErr.Name, "orders")
+	a.Equal(err.Error(), "go-data: fail to apply transaction to document `orders`: fail to apply patch when updating `not_exist`: query not found")
+	a.Equal(users.Query("alice.balance.amount"), int64(90))
+	a.Equal(orders.Query("count"), int64(2))
+
+	// 文档不存在。
+	tx = NewTransaction()
+	tx.Add("users", pay)
+	tx.Add("products", order)
+	err = tx.ApplyTo(docs)
+	a.Assert(errors.Is(err, ErrDocNotFound))
+}
@@ -0,0 +1,202 @@
+package data
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TTLData 是一个可以并发读写的 Data 容器，与 `SyncData` 相比，它还会记录 patch 中带有过期时间的值，
+// 并且可以通过 Sweep 删除已经过期的值，这适合用来实现“将这个限制临时提高 2 小时”之类的临时修改。
+//
+// 带有过期时间的值通过 `Patch#UpdateUntil`、`Patch#ReplaceUntil` 或者 `PatchAction` 的 Expires 设置。
+// 过期的值会被直接删除，而不是恢复成修改前的值，如果希望过期后恢复成默认值，
+// 可以将临时修改单独保存在一个 TTLData 中，再通过 `Coalesce` 或者 `Merge` 叠加到基础配置上。
+//
+// 数组元素的下标会随着删除等操作变化，所以不能给数组元素以及数组元素下面的值设置过期时间。
+//
+// 与 `SyncData` 一样，通过 Load 得到的 Data 永远不会被后续的更新修改，调用者也不应该直接修改它。
+type TTLData struct {
+	mu      sync.RWMutex
+	data    Data
+	expires map[string]time.Time
+}
+
+// NewTTLData 创建一个新的 TTLData，初始值为 d，d 中所有的值都不会过期。
+func NewTTLData(d Data) *TTLData {
+	return &TTLData{
+		data:    d,
+		expires: map[string]time.Time{},
+	}
+}
+
+// Load 返回当前的 Data。
+func (td *TTLData) Load() Data {
+	td.mu.RLock()
+	defer td.mu.RUnlock()
+	return td.data
+}
+
+// Apply 将 patch 应用到当前的 Data 上，并记录 patch 中所有值的过期时间，出错时当前的 Data 不会被修改。
+//
+// 如果一个值被 patch 删除、替换或者更新，它和它下面所有值的过期时间都会被清除，
+// 也就是说，没有设置过期时间的修改会让这个值永久生效，重新设置过期时间则会覆盖之前的过期时间。
+//
+// 如果过期时间对应的值位于数组中，返回 ErrNotObject。
+func (td *TTLData) Apply(patch *Patch) error {
+	td.mu.Lock()
+	defer td.mu.Unlock()
+
+	applied, err := patch.Apply(td.data)
+
+	if err != nil {
+		return err
+	}
+
+	for _, action := range patch.actions {
+		for query := range action.Expires {
+			if inArray(applied, queryFields(query)) {
+				return &PatchError{
+					Op:    "expiring",
+					Query: query,
+					Err:   ErrNotObject,
+				}
+			}
+		}
+	}
+
+	td.data = applied
+
+	for _, action := range patch.actions {
+		for _, query := range action.Deletes {
+			td.clear(query)
+		}
+
+		for query := range action.Replaces {
+			td.clear(query)
+		}
+
+		for query, d := range action.Updates {
+			fields := queryFields(query)
+
+			for k := range d.data {
+				td.clear(JoinQuery(append(fields[:len(fields):len(fields)], k)...))
+			}
+		}
+
+		for query, at := range action.Expires {
+			if query == "" {
+				continue
+			}
+
+			td.expires[JoinQuery(SplitQuery(query)...)] = at
+		}
+	}
+
+	return nil
+}
+
+// inArray 判断 fields 对应的值是否位于数组中，也就是路径上是否有数组。
+func inArray(d Data, fields []string) bool {
+	var parent interface{} = d.data
+
+	for _, f := range fields {
+		if parent == nil {
+			return false
+		}
+
+		if k := reflect.ValueOf(parent).Kind(); k == reflect.Slice || k == reflect.Array {
+			return true
+		}
+
+		parent, _, _ = getField(parent, f)
+	}
+
+	return false
+}
+
+// clear 清除 query 以及它下面所有值的过期时间。
+func (td *TTLData) clear(query string) {
+	prefix := queryFields(query)
+
+	for q := range td.expires {
+		fields := SplitQuery(q)
+
+		if len(fields) < len(prefix) {
+			continue
+		}
+
+		matched := true
+
+		for i, f := range prefix {
+			if fields[i] != f {
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			delete(td.expires, q)
+		}
+	}
+}
+
+// Expires 返回所有值的过期时间，key 是值的 query。
+func (td *TTLData) Expires() map[string]time.Time {
+	td.mu.RLock()
+	defer td.mu.RUnlock()
+
+	expires := make(map[string]time.Time, len(td.expires))
+
+	for q, at := range td.expires {
+		expires[q] = at
+	}
+
+	return expires
+}
+
+// NextExpiry 返回最早的过期时间，调用者可以据此决定下次调用 Sweep 的时间。
+// 如果没有任何值会过期，ok 为 false。
+func (td *TTLData) NextExpiry() (at time.Time, ok bool) {
+	td.mu.RLock()
+	defer td.mu.RUnlock()
+
+	for _, t := range td.expires {
+		if !ok || t.Before(at) {
+			at, ok = t, true
+		}
+	}
+
+	return
+}
+
+// Sweep 删除所有在 now 或者 now 之前过期的值，返回这些值的 query，按照字典序排列。
+// 如果没有任何值过期，返回 nil，当前的 Data 不会被修改。
+func (td *TTLData) Sweep(now time.Time) (removed []string) {
+	td.mu.Lock()
+	defer td.mu.Unlock()
+
+	for q, at := range td.expires {
+		if !at.After(now) {
+			removed = append(removed, q)
+		}
+	}
+
+	if len(removed) == 0 {
+		return
+	}
+
+	sort.Strings(removed)
+	d := td.data.Clone()
+
+	// 只有删除操作，不会出错。
+	(&PatchAction{Deletes: removed}).ApplyTo(&d)
+	td.data = d
+
+	for _, q := range removed {
+		td.clear(q)
+	}
+
+	return
+}
@@ -0,0 +1,126 @@
+package data
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestTTLData(t *testing.T) {
+	a := assert.New(t)
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	td := NewTTLData(Make(RawData{
+		"limits": RawData{
+			"qps":   100,
+			"burst": 10,
+		},
+		"hosts": RawData{},
+	}))
+
+	_, ok := td.NextExpiry()
+	a.Assert(!ok)
+	a.Assert(td.Sweep(now) == nil)
+
+	patch := NewPatch()
+	patch.UpdateUntil("limits", Make(RawData{"qps": 200}), now.Add(2*time.Hour))
+	patch.ReplaceUntil("hosts.example\\.com", Make(RawData{"weight": 1}), now.Add(time.Hour))
+	patch.UpdateUntil("", Make(RawData{"banner": "maintenance"}), now.Add(3*time.Hour))
+	a.NilError(td.Apply(patch))
+	before := td.Load()
+	a.Equal(before, Make(RawData{
+		"limits": RawData{
+			"qps":   200,
+			"burst": 10,
+		},
+		"hosts": RawData{
+			"example.com": RawData{"weight": 1},
+		},
+		"banner": "maintenance",
+	}))
+	a.Equal(td.Expires(), map[string]time.Time{
+		"limits.qps":          now.Add(2 * time.Hour),
+		"hosts.example\\.com": now.Add(time.Hour),
+		"banner":              now.Add(3 * time.Hour),
+	})
+
+	next, ok := td.NextExpiry()
+	a.Assert(ok)
+	a.Equal(next, now.Add(time.Hour))
+
+	a.Assert(td.Sweep(now.Add(time.Minute)) == nil)
+	a.Equal(td.Sweep(now.Add(2*time.Hour)), []string{"hosts.example\\.com", "limits.qps"})
+	a.Equal(td.Load(), Make(RawData{
+		"limits": RawData{
+			"burst": 10,
+		},
+		"hosts":  RawData{},
+		"banner": "maintenance",
+	}))
+	a.Equal(before.Get("limits", "qps"), int64(200))
+
+	// 没有过期时间的修改会让值永久生效。
+	patch = NewPatch()
+	patch.Add(nil, map[string]Data{
+		"": Make(RawData{"banner": "welcome"}),
+	})
+	a.NilError(td.Apply(patch))
+	a.Equal(td.Expires(), map[string]time.Time{})
+	a.Assert(td.Sweep(now.Add(24*time.Hour)) == nil)
+	a.Equal(td.Load().Get("banner"), "welcome")
+
+	// 删除上一级的值会清除下面所有值的过期时间；出错时不会记录过期时间。
+	patch = NewPatch()
+	patch.UpdateUntil("limits", Make(RawData{"qps": 300}), now)
+	a.NilError(td.Apply(patch))
+	patch = NewPatch()
+	patch.Add([]string{"limits"}, nil)
+	a.NilError(td.Apply(patch))
+	a.Equal(td.Expires(), map[string]time.Time{})
+
+	patch = NewPatch()
+	patch.UpdateUntil("not_exist", Make(RawData{"a": 1}), now)
+	a.NonNilError(td.Apply(patch))
+	a.Equal(td.Expires(), map[string]time.Time{})
+
+	// 数组元素的下标会变化，不能设置过期时间。
+	td = NewTTLData(Make(RawData{
+		"list": []RawData{{"a": 1}, {"a": 2}, {"a": 3}},
+	}))
+	before = td.Load()
+	patch = NewPatch()
+	patch.ReplaceUntil("list.1", Make(RawData{"a": 4}), now)
+	patch.ReplaceUntil("list.2", Make(RawData{"a": 5}), now)
+	err := td.Apply(patch)
+	a.Assert(errors.Is(err, ErrNotObject))
+	a.Equal(td.Load(), before)
+	a.Equal(td.Expires(), map[string]time.Time{})
+
+	patch = NewPatch()
+	patch.UpdateUntil("list.0", Make(RawData{"b": 1}), now)
+	a.Assert(errors.Is(td.Apply(patch), ErrNotObject))
+	a.Equal(td.Load(), before)
+	a.Assert(td.Sweep(now) == nil)
+}
+
+func TestPatchActionExpires(t *testing.T) {
+	a := assert.New(t)
+	at := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	patch := NewPatch()
+	patch.UpdateUntil("limits", Make(RawData{"qps": 200}), at)
+	action := patch.Actions()[0]
+	a.Equal(action.Expires, map[string]time.Time{"limits.qps": at})
+
+	// 过期时间可以随 action 一起序列化，比如写入 `Journal`。
+	var decoded PatchAction
+	enc := &Encoder{}
+	dec := &Decoder{}
+	a.NilError(dec.Decode(enc.Encode(action), &decoded))
+	a.Equal(decoded.Expires, action.Expires)
+
+	// 直接应用时忽略过期时间。
+	applied, err := patch.Apply(Make(RawData{"limits": RawData{}}))
+	a.NilError(err)
+	a.Equal(applied, Make(RawData{"limits": RawData{"qps": 200}}))
+}
@@ -0,0 +1,160 @@
+package data
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// TypePlan 是预先编译好的 struct 解析计划，记录了每个字段的下标、key 和解析好的 field tag。
+// 解析 struct 时，Decoder 默认需要为每个字段解析一次 field tag，
+// 对于需要反复解析同一类型的场景，可以通过 `Decoder#Compile` 预先生成 TypePlan 来避免这些重复的工作。
+type TypePlan struct {
+	typ       reflect.Type
+	tagName   string
+	fields    []fieldPlan
+	collision error
+}
+
+type fieldPlan struct {
+	index  int
+	name   string
+	key    reflect.Value
+	typ    reflect.Type
+	tag    *FieldTag
+	squash bool
+}
+
+// Type 返回 plan 对应的 struct 类型。
+func (plan *TypePlan) Type() reflect.Type {
+	return plan.typ
+}
+
+// NumField 返回 plan 中需要解析的字段个数，被跳过的字段和私有字段不计算在内。
+func (plan *TypePlan) NumField() int {
+	return len(plan.fields)
+}
+
+// Compile 为 struct 类型 t 生成 TypePlan 并缓存在 dec 中，之后所有解析成 t 的操作都会直接使用这个 plan。
+// t 中嵌套的 struct 类型，包括指针、slice、array 和 map 的元素类型，也会一并生成 TypePlan。
+//
+// 与 `Decoder#RegisterInterface` 一样，Compile 应该在使用 dec 解析数据之前调用，不能与 Decode 并发调用。
+// 如果 t 不是 struct 或 struct 的指针，panic。
+func (dec *Decoder) Compile(t reflect.Type) *TypePlan {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Errorf("go-data: type %v is not a struct", t))
+	}
+
+	if dec.plans == nil {
+		dec.plans = map[reflect.Type]*TypePlan{}
+	}
+
+	return dec.compile(t)
+}
+
+func (dec *Decoder) compile(t reflect.Type) *TypePlan {
+	tagName := dec.tagName()
+
+	if plan, ok := dec.plans[t]; ok && plan.tagName == tagName {
+		return plan
+	}
+
+	plan := newTypePlan(t, tagName)
+	plan.collision = checkSquashCollisions(t, tagName)
+	dec.plans[t] = plan
+
+	for _, fp := range plan.fields {
+		dec.compileElem(fp.typ)
+	}
+
+	return plan
+}
+
+func (dec *Decoder) compileElem(t reflect.Type) {
+	for {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+			continue
+
+		case reflect.Struct:
+			if t != typeOfTime && !t.AssignableTo(typeOfData) {
+				dec.compile(t)
+			}
+		}
+
+		return
+	}
+}
+
+// planOf 返回类型 t 的 TypePlan，如果 t 没有通过 `Decoder#Compile` 编译过，则生成一个临时的 plan。
+func (dec *Decoder) planOf(t reflect.Type) (plan *TypePlan, err error) {
+	tagName := dec.tagName()
+
+	if plan = dec.plans[t]; plan != nil && plan.tagName == tagName {
+		if dec.DetectSquashCollisions {
+			err = plan.collision
+		}
+
+		return
+	}
+
+	if dec.DetectSquashCollisions {
+		if err = checkSquashCollisions(t, tagName); err != nil {
+			return
+		}
+	}
+
+	plan = newTypePlan(t, tagName)
+	return
+}
+
+func newTypePlan(t reflect.Type, tagName string) *TypePlan {
+	numField := t.NumField()
+	fields := make([]fieldPlan, 0, numField)
+
+	for i := 0; i < numField; i++ {
+		f := t.Field(i)
+
+		// 私有字段无法赋值，直接跳过。
+		if f.PkgPath != "" {
+			continue
+		}
+
+		ft := ParseFieldTag(f.Tag.Get(tagName))
+
+		if ft.Skipped {
+			continue
+		}
+
+		fieldType := f.Type
+
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+
+		name := f.Name
+
+		if ft.Alias != "" {
+			name = ft.Alias
+		}
+
+		fields = append(fields, fieldPlan{
+			index:  i,
+			name:   name,
+			key:    reflect.ValueOf(name),
+			typ:    f.Type,
+			tag:    ft,
+			squash: ft.Squash && fieldType.Kind() == reflect.Struct,
+		})
+	}
+
+	return &TypePlan{
+		typ:     t,
+		tagName: tagName,
+		fields:  fields,
+	}
+}
@@ -1,6 +1,7 @@
 package data
 
 import (
+	"encoding/json"
 	"reflect"
 	"time"
 )
@@ -17,4 +18,26 @@ var (
 	typeOfData       = reflect.TypeOf(Data{})
 	typeOfTime       = reflect.TypeOf(time.Time{})
 	typeOfDuration   = reflect.TypeOf(time.Duration(0))
+
+	typeOfJSONMarshaler   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	typeOfJSONUnmarshaler = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
 )
+
+// numericTypes 是每种数字 kind 对应的基础类型，Data 不支持的 kind 会被转化成支持的类型。
+var numericTypes = map[reflect.Kind]reflect.Type{
+	reflect.Int:        reflect.TypeOf(int(0)),
+	reflect.Int8:       reflect.TypeOf(int8(0)),
+	reflect.Int16:      reflect.TypeOf(int16(0)),
+	reflect.Int32:      reflect.TypeOf(int32(0)),
+	reflect.Int64:      reflect.TypeOf(int64(0)),
+	reflect.Uint:       reflect.TypeOf(uint(0)),
+	reflect.Uint8:      reflect.TypeOf(uint8(0)),
+	reflect.Uint16:     reflect.TypeOf(uint16(0)),
+	reflect.Uint32:     reflect.TypeOf(uint32(0)),
+	reflect.Uint64:     reflect.TypeOf(uint64(0)),
+	reflect.Uintptr:    typeOfUint64, // uintptr 不是 Data 支持的类型，只能统一成 uint64。
+	reflect.Float32:    reflect.TypeOf(float32(0)),
+	reflect.Float64:    reflect.TypeOf(float64(0)),
+	reflect.Complex64:  reflect.TypeOf(complex64(0)),
+	reflect.Complex128: reflect.TypeOf(complex128(0)),
+}
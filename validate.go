@@ -0,0 +1,89 @@
+package data
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// validate 检查 d 中的所有值是否都是 Data 支持的标准类型，如果不是则返回第一个不合法的值的错误。
+// Data 的很多操作，比如 merge，都假定了 d 中只有标准类型，这个函数用来在测试和 fuzz 中检查这个假定。
+//
+// 标准类型包括：nil、int64、uint64、float64、complex128、string、bool、time.Time、RawData，
+// 以及元素为这些类型的 slice，slice 可以嵌套，[]interface{} 中的每个元素也必须是标准类型。
+func (d Data) validate() error {
+	problems := nonCanonicalValues(nil, d.data, nil)
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("go-data: value at `%v` is not canonical", problems[0])
+}
+
+// nonCanonicalValues 将 v 中所有不是标准类型的值的路径追加到 problems 中。
+func nonCanonicalValues(problems []string, v interface{}, path []string) []string {
+	switch val := v.(type) {
+	case nil, int64, uint64, float64, complex128, string, bool:
+		return problems
+
+	case RawData:
+		var order *keyOrder
+
+		for _, k := range order.sortedKeys(val) {
+			problems = nonCanonicalValues(problems, val[k], append(path[:len(path):len(path)], k))
+		}
+
+		return problems
+	}
+
+	rv := reflect.ValueOf(v)
+	t := rv.Type()
+
+	if t == typeOfTime {
+		return problems
+	}
+
+	if t.Kind() != reflect.Slice || !isCanonicalElemType(t.Elem()) {
+		return append(problems, JoinQuery(path...))
+	}
+
+	for i := 0; i < rv.Len(); i++ {
+		problems = nonCanonicalValues(problems, rv.Index(i).Interface(), append(path[:len(path):len(path)], strconv.Itoa(i)))
+	}
+
+	return problems
+}
+
+func isCanonicalElemType(t reflect.Type) bool {
+	switch t {
+	case typeOfInt64, typeOfUint64, typeOfFloat64, typeOfComplex128, typeOfString, typeOfBool,
+		typeOfTime, typeOfObject, typeOfInterface:
+		return true
+	}
+
+	return t.Kind() == reflect.Slice && isCanonicalElemType(t.Elem())
+}
+
+// IsCanonical 检查 r 中的所有值是否都是 Data 支持的标准类型，
+// 如果不是，返回 false 以及所有不合法的值的路径，路径格式与 `Data#Query` 相同。
+//
+// 直接用字面量构造的 RawData 往往包含 int、[]int 之类的非标准类型，
+// 在合并到其他 Data 之前可以用这个函数检查，或者使用 `Data#Normalize` 修复。
+func IsCanonical(r RawData) (bool, []string) {
+	problems := nonCanonicalValues(nil, r, nil)
+	return len(problems) == 0, problems
+}
+
+// Normalize 使用 `Encoder` 的规则重新处理 d 中的所有值，返回一个只包含标准类型的副本，d 本身不会被修改。
+// d 中 key 的顺序会被保留。
+func (d Data) Normalize() Data {
+	if d.data == nil {
+		return d
+	}
+
+	enc := Encoder{}
+	normalized := enc.Encode(d.data)
+	normalized.order = d.order.clone()
+	return normalized
+}
@@ -0,0 +1,125 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestDataValidate(t *testing.T) {
+	cases := []struct {
+		Data     Data
+		HasError bool
+	}{
+		{Data{}, false},
+		{complexData, false},
+		{Make(RawData{
+			"int":   1,
+			"uints": []uint8{1, 2},
+			"time":  time.Now(),
+			"c":     complex(1, 2),
+			"list":  [][]int{{1}, {2, 3}},
+			"mixed": []interface{}{1, "a", nil, RawData{"a": 1}},
+			"map":   map[string]int{"a": 1},
+			"nil":   nil,
+		}), false},
+		{Data{data: RawData{"int": 1}}, true},
+		{Data{data: RawData{"a": RawData{"b": float32(1)}}}, true},
+		{Data{data: RawData{"map": map[string]interface{}{}}}, true},
+		{Data{data: RawData{"list": []int{1}}}, true},
+		{Data{data: RawData{"list": []interface{}{int64(1), 2}}}, true},
+		{Data{data: RawData{"list": [][]int{{1}}}}, true},
+		{Data{data: RawData{"ptr": new(int64)}}, true},
+	}
+	a := assert.New(t)
+
+	for i, c := range cases {
+		a.Use(&i, &c)
+		err := c.Data.validate()
+
+		if c.HasError {
+			a.NonNilError(err)
+			continue
+		}
+
+		a.NilError(err)
+	}
+
+	a.Equal(Data{data: RawData{"a": []interface{}{int64(1), RawData{"b": 1}}}}.validate().Error(), "go-data: value at `a.1.b` is not canonical")
+}
+
+func TestParseJSONCanonical(t *testing.T) {
+	cases := []string{
+		`{"a":[1,null]}`,
+		`{"a":[null,null]}`,
+		`{"a":[[1,2],[3.5],[]]}`,
+		`{"a":9223372036854775808}`,
+		`{"a":9.223372036854775807e18}`,
+		`{"a":-9223372036854775808}`,
+		`{"a":1e400}`,
+		`{"a":{"b":[{"c":[true,"x"]}]}}`,
+	}
+	a := assert.New(t)
+
+	for i, c := range cases {
+		a.Use(&i, &c)
+		d, err := ParseJSON(c)
+		a.NilError(err)
+		a.NilError(d.validate())
+		a.NilError(Merge(d, d).validate())
+	}
+
+	d, err := ParseJSON(`{"a":[1,null],"b":9.223372036854775807e18}`)
+	a.NilError(err)
+	a.Equal(d.data, RawData{
+		"a": []interface{}{int64(1), nil},
+		"b": 9.223372036854775807e18,
+	})
+}
+
+func TestIsCanonical(t *testing.T) {
+	cases := []struct {
+		Raw      RawData
+		Problems []string
+	}{
+		{nil, nil},
+		{RawData{"a": int64(1), "b": []string{"x"}}, nil},
+		{RawData{"a": 1, "b": RawData{"c": []int{1}, "d": "ok"}}, []string{"a", "b.c"}},
+		{RawData{"a": []interface{}{int64(1), float32(2)}}, []string{"a.1"}},
+	}
+	a := assert.New(t)
+
+	for i, c := range cases {
+		a.Use(&i, &c)
+		ok, problems := IsCanonical(c.Raw)
+		a.Equal(ok, len(c.Problems) == 0)
+		a.Equal(problems, c.Problems)
+	}
+}
+
+func TestDataNormalize(t *testing.T) {
+	a := assert.New(t)
+	p := Parser{KeepOrder: true}
+	d, err := p.ParseJSON(`{"z":1,"a":{"y":2,"b":3}}`)
+	a.NilError(err)
+
+	d.data["z"] = 10
+	d.data["a"].(RawData)["b"] = []int{1, 2}
+	a.NonNilError(d.validate())
+
+	normalized := d.Normalize()
+	a.NilError(normalized.validate())
+	a.Equal(normalized.data, RawData{
+		"z": int64(10),
+		"a": RawData{
+			"y": int64(2),
+			"b": []int64{1, 2},
+		},
+	})
+	a.Equal(normalized.JSON(false), `{"z":10,"a":{"y":2,"b":[1,2]}}`)
+
+	// d 本身不会被修改。
+	a.Equal(d.data["z"], 10)
+	a.Equal(Data{}.Normalize(), Data{})
+}
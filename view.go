@@ -0,0 +1,76 @@
+package data
+
+// View 是 Data 的只读视图，可以在不复制数据的前提下读取 Data 的内容。
+//
+// View 没有任何修改数据的方法，Get/Query 查询到的 RawData 和 Data 也会被包装成 View 返回，
+// 这样读取路径可以避免调用 Clone 的开销，同时依然保证不会意外修改 Data。
+// 需要注意，Get/Query 返回的 slice 与 Data 共享内存，调用者不能修改它们。
+type View struct {
+	data RawData
+}
+
+// View 返回 d 的只读视图。
+func (d Data) View() View {
+	return View{
+		data: d.data,
+	}
+}
+
+// Len 返回 v 的数据个数。
+func (v View) Len() int {
+	return len(v.data)
+}
+
+// Keys 返回 v 顶层所有的 key，按照字典序排列。
+func (v View) Keys() []string {
+	if len(v.data) == 0 {
+		return nil
+	}
+
+	var order *keyOrder
+	return order.sortedKeys(v.data)
+}
+
+// Get 通过 fields 找到对应的值并且返回，如果找不到则返回 nil。
+// 如果找到的值是 RawData 或 Data，返回对应的 View。
+//
+// 其中，fields 的格式详见 `Data#Get` 文档。
+func (v View) Get(fields ...string) interface{} {
+	return toView(v.data.Get(fields...))
+}
+
+// Query 解析 query 找到对应的值并且返回，如果找不到则返回 nil。
+// 如果找到的值是 RawData 或 Data，返回对应的 View。
+//
+// 其中，query 的格式详见 `Data#Query` 文档。
+func (v View) Query(query string) interface{} {
+	return toView(v.data.Query(query))
+}
+
+// ForEach 遍历 v 顶层的所有 key 和 value，如果 fn 返回 false 则停止遍历。
+// 遍历的顺序是不确定的，value 的规则与 Get 相同。
+func (v View) ForEach(fn func(key string, value interface{}) bool) {
+	for k, val := range v.data {
+		if !fn(k, toView(val)) {
+			return
+		}
+	}
+}
+
+// String 返回 v 的可存储格式，与 `Data#String` 相同。
+func (v View) String() string {
+	return Data{data: v.data}.String()
+}
+
+func toView(v interface{}) interface{} {
+	switch val := v.(type) {
+	case RawData:
+		return View{
+			data: val,
+		}
+	case Data:
+		return val.View()
+	}
+
+	return v
+}
@@ -0,0 +1,72 @@
+package data
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestView(t *testing.T) {
+	a := assert.New(t)
+	d := Data{
+		data: RawData{
+			"a": int64(1),
+			"b": RawData{
+				"c":    "str",
+				"list": []RawData{{"d": true}},
+			},
+			"e": Make(RawData{"f": 2.5}),
+		},
+	}
+	v := d.View()
+
+	a.Equal(v.Len(), 3)
+	a.Equal(v.Keys(), []string{"a", "b", "e"})
+	a.Equal(v.Get("a"), int64(1))
+	a.Equal(v.Query("b.c"), "str")
+	a.Equal(v.Query("b.list.0.d"), true)
+	a.Equal(v.Query("e.f"), 2.5)
+	a.Equal(v.Query("not_exist"), nil)
+
+	// 查询到的 RawData 和 Data 被包装成 View。
+	b, ok := v.Query("b").(View)
+	a.Assert(ok)
+	a.Equal(b.Query("c"), "str")
+	e, ok := v.Get("e").(View)
+	a.Assert(ok)
+	a.Equal(e.Get("f"), 2.5)
+	_, ok = v.Query("b.list.0").(View)
+	a.Assert(ok)
+
+	// View 不复制数据。
+	d.data["b"].(RawData)["c"] = "changed"
+	a.Equal(b.Query("c"), "changed")
+
+	var keys []string
+	v.ForEach(func(key string, value interface{}) bool {
+		keys = append(keys, key)
+
+		if key == "b" {
+			_, ok := value.(View)
+			a.Assert(ok)
+		}
+
+		return true
+	})
+	sort.Strings(keys)
+	a.Equal(keys, []string{"a", "b", "e"})
+
+	count := 0
+	v.ForEach(func(key string, value interface{}) bool {
+		count++
+		return false
+	})
+	a.Equal(count, 1)
+
+	a.Equal(v.String(), d.String())
+	empty := Data{}.View()
+	a.Equal(empty.Len(), 0)
+	a.Equal(empty.Keys(), []string(nil))
+	a.Equal(empty.Query("a"), nil)
+}